@@ -53,6 +53,8 @@ app.add_middleware(
 class ProcessTextRequest(BaseModel):
     user_id: str
     entry_text: str
+    # Signed by the backend; authorizes callbacks on behalf of user_id only.
+    grant: str
 
 class AvatarInput(BaseModel):
     prompt: str
@@ -64,13 +66,20 @@ class QuestDetailsInput(BaseModel):
 # --- Backend Communication ---
 BACKEND_URL = os.getenv("BACKEND_URL", "http://localhost:8080/api/v1")
 
-async def update_character_xp_in_backend(user_id: str, xp_amount: int):
-    """Calls the backend to update the character's XP."""
+def grant_headers(grant: str) -> dict:
+    """Builds the headers that authenticate a callback with the grant issued by the backend."""
+    return {"X-Service-Grant": grant}
+
+async def update_character_xp_in_backend(grant: str, user_id: str, xp_amount: int, reason: str = "", text: str = "", categories: list = None):
+    """Calls the backend to update the character's XP. The reason is kept in the XP ledger,
+    the text lets the backend's XP rules check the entry the XP is for, and the categories
+    decide the character's class bonus."""
     try:
         async with httpx.AsyncClient() as client:
             response = await client.post(
                 f"{BACKEND_URL}/api/v1/users/{user_id}/character/xp",
-                json={"xp_amount": xp_amount}
+                json={"xp_amount": xp_amount, "reason": reason, "text": text, "categories": categories or []},
+                headers=grant_headers(grant)
             )
             response.raise_for_status()
             logger.info(f"Successfully updated XP for user {user_id} by {xp_amount}.")
@@ -82,11 +91,14 @@ async def update_character_xp_in_backend(user_id: str, xp_amount: int):
         logger.error(f"An unexpected error occurred during XP update for user {user_id}: {e}")
         return None
 
-async def get_active_quests_from_backend(user_id: str) -> list:
+async def get_active_quests_from_backend(grant: str, user_id: str) -> list:
     """Fetches active quests for a user from the Go backend."""
     try:
         async with httpx.AsyncClient() as client:
-            response = await client.get(f"{BACKEND_URL}/api/v1/quests/user/{user_id}")
+            response = await client.get(
+                f"{BACKEND_URL}/api/v1/quests/user/{user_id}",
+                headers=grant_headers(grant)
+            )
             response.raise_for_status()
             logger.info(f"Successfully fetched active quests for user {user_id}.")
             return response.json()
@@ -97,12 +109,12 @@ async def get_active_quests_from_backend(user_id: str) -> list:
         logger.error(f"An unexpected error occurred during quest fetching for user {user_id}: {e}")
         return []
 
-async def create_quest_in_backend(user_id: str, data: dict):
+async def create_quest_in_backend(grant: str, user_id: str, data: dict):
     """Calls the backend to create a new quest."""
     payload = {**data, "user_id": user_id}
     try:
         async with httpx.AsyncClient() as client:
-            response = await client.post(f"{BACKEND_URL}/api/v1/quests", json=payload)
+            response = await client.post(f"{BACKEND_URL}/api/v1/quests", json=payload, headers=grant_headers(grant))
             response.raise_for_status()
             logger.info(f"Successfully created quest for user {user_id}.")
             return response.json()
@@ -110,11 +122,15 @@ async def create_quest_in_backend(user_id: str, data: dict):
         logger.error(f"Error calling backend to create quest for user {user_id}: {e}")
         return None
 
-async def update_quest_in_backend(quest_id: str, data: dict):
-    """Calls the backend to update an existing quest."""
+async def update_quest_in_backend(grant: str, quest_id: str, data: dict):
+    """Calls the backend to update an existing quest owned by the granted user."""
     try:
         async with httpx.AsyncClient() as client:
-            response = await client.put(f"{BACKEND_URL}/api/v1/quests/{quest_id}", json=data)
+            response = await client.put(
+                f"{BACKEND_URL}/api/v1/quests/{quest_id}",
+                json=data,
+                headers=grant_headers(grant)
+            )
             response.raise_for_status()
             logger.info(f"Successfully updated quest {quest_id}.")
             return response.json()
@@ -122,11 +138,14 @@ async def update_quest_in_backend(quest_id: str, data: dict):
         logger.error(f"Error calling backend to update quest {quest_id}: {e}")
         return None
 
-async def complete_quest_in_backend(quest_id: str):
-    """Calls the backend to mark a quest as complete."""
+async def complete_quest_in_backend(grant: str, quest_id: str):
+    """Calls the backend to mark a quest owned by the granted user as complete."""
     try:
         async with httpx.AsyncClient() as client:
-            response = await client.post(f"{BACKEND_URL}/api/v1/quests/{quest_id}/complete")
+            response = await client.post(
+                f"{BACKEND_URL}/api/v1/quests/{quest_id}/complete",
+                headers=grant_headers(grant)
+            )
             response.raise_for_status()
             logger.info(f"Successfully completed quest {quest_id}.")
             return response.json()
@@ -152,10 +171,13 @@ async def agent_update_character_xp(input_data: ProcessTextRequest):
         
         if xp_call:
             xp_amount = xp_call.get("args", {}).get("xp_amount")
+            reason = xp_call.get("args", {}).get("reason", "")
+            categories = xp_call.get("args", {}).get("categories", [])
+            tags = xp_call.get("args", {}).get("tags", [])
             if isinstance(xp_amount, int):
                 logger.info(f"Agent decided to award {xp_amount} XP to user {input_data.user_id}.")
-                await update_character_xp_in_backend(input_data.user_id, xp_amount)
-                return {"status": "success", "action": "AWARD_XP", "xp_awarded": xp_amount}
+                await update_character_xp_in_backend(input_data.grant, input_data.user_id, xp_amount, reason, input_data.entry_text, categories)
+                return {"status": "success", "action": "AWARD_XP", "xp_awarded": xp_amount, "suggested_tags": tags}
 
     logger.info(f"XP Agent recognized no action for user {input_data.user_id}.")
     return {"status": "success", "action": "NO_ACTION_RECOGNIZED"}
@@ -164,7 +186,7 @@ async def agent_update_character_xp(input_data: ProcessTextRequest):
 async def agent_update_quests(input_data: ProcessTextRequest):
     logger.info(f"Received text from user {input_data.user_id} for quest analysis.")
     
-    active_quests = await get_active_quests_from_backend(input_data.user_id)
+    active_quests = await get_active_quests_from_backend(input_data.grant, input_data.user_id)
     
     agent_response = await process_text_for_quests(input_data.entry_text, active_quests)
     action = agent_response.get("action")
@@ -172,17 +194,17 @@ async def agent_update_quests(input_data: ProcessTextRequest):
 
     if action == "CREATE" and data:
         logger.info(f"Quest Agent decided to CREATE a quest for user {input_data.user_id}.")
-        await create_quest_in_backend(input_data.user_id, data)
+        await create_quest_in_backend(input_data.grant, input_data.user_id, data)
         return {"status": "success", "action": "CREATE"}
     
     elif action == "UPDATE" and data and "questId" in data:
         logger.info(f"Quest Agent decided to UPDATE quest {data['questId']}.")
-        await update_quest_in_backend(data["questId"], {"description": data.get("description")})
+        await update_quest_in_backend(input_data.grant, data["questId"], {"description": data.get("description")})
         return {"status": "success", "action": "UPDATE"}
 
     elif action == "COMPLETE" and data and "questId" in data:
         logger.info(f"Quest Agent decided to COMPLETE quest {data['questId']}.")
-        await complete_quest_in_backend(data["questId"])
+        await complete_quest_in_backend(input_data.grant, data["questId"])
         return {"status": "success", "action": "COMPLETE"}
 
     logger.info(f"Quest Agent recognized no action for user {input_data.user_id}.")
@@ -42,7 +42,10 @@ def initialize_text_agent():
                     parameters=glm.Schema(
                         type=glm.Type.OBJECT,
                         properties={
-                            "xp_amount": glm.Schema(type=glm.Type.INTEGER)
+                            "xp_amount": glm.Schema(type=glm.Type.INTEGER),
+                            "reason": glm.Schema(type=glm.Type.STRING),
+                            "categories": glm.Schema(type=glm.Type.ARRAY, items=glm.Schema(type=glm.Type.STRING)),
+                            "tags": glm.Schema(type=glm.Type.ARRAY, items=glm.Schema(type=glm.Type.STRING))
                         },
                         required=["xp_amount"],
                     ),
@@ -89,10 +92,13 @@ async def analyze_text_for_xp(paragraph: str) -> dict:
                 xp_call = next((call for call in tool_calls if call.get("name") == "update_xp"), None)
                 if xp_call and "args" in xp_call and "xp_amount" in xp_call["args"]:
                     xp_amount = int(xp_call["args"]["xp_amount"])
+                    reason = str(xp_call["args"].get("reason", ""))
+                    categories = [str(c) for c in xp_call["args"].get("categories", []) or []]
+                    tags = [str(t) for t in xp_call["args"].get("tags", []) or []]
                     # Return a dictionary that matches the structure expected by main.py
                     return {
                         "action": "AWARD_XP",
-                        "tool_calls": [{"name": "update_xp", "args": {"xp_amount": xp_amount}}]
+                        "tool_calls": [{"name": "update_xp", "args": {"xp_amount": xp_amount, "reason": reason, "categories": categories, "tags": tags}}]
                     }
         except (json.JSONDecodeError, IndexError, AttributeError) as e:
             logger.warning(f"Could not parse JSON from model response for XP agent: {e}. Response was: {response.text}")
@@ -0,0 +1,124 @@
+package achievement
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/events"
+)
+
+//go:embed achievements.json
+var defaultCatalogConfig []byte
+
+// criteriaEvents lists, for every criteria, the events that can change the statistic it is awarded for.
+var criteriaEvents = map[string][]string{
+	models.CriteriaEntries:         {events.EntrySaved},
+	models.CriteriaWords:           {events.EntrySaved},
+	models.CriteriaStreak:          {events.StreakExtended},
+	models.CriteriaQuestsCompleted: {events.QuestCompleted},
+	models.CriteriaLevel:           {events.LevelUp},
+}
+
+// criteriaDescriptions describe a criteria for a threshold, for achievements without a criteria description.
+var criteriaDescriptions = map[string]string{
+	models.CriteriaEntries:         "Write %d journal entries",
+	models.CriteriaWords:           "Write %d words",
+	models.CriteriaStreak:          "Write %d days in a row",
+	models.CriteriaQuestsCompleted: "Complete %d quests",
+	models.CriteriaLevel:           "Reach level %d",
+}
+
+// Catalog holds the achievements from the achievements config.
+type Catalog struct {
+	achievements []models.Achievement
+	byID         map[string]models.Achievement
+}
+
+// DefaultCatalog returns the achievements built into the server.
+func DefaultCatalog() *Catalog {
+	catalog, err := parseCatalog(defaultCatalogConfig, 0)
+	if err != nil {
+		panic(fmt.Sprintf("built-in achievements config: %v", err))
+	}
+	return catalog
+}
+
+// CatalogFromEnv loads the achievements from the file at ACHIEVEMENTS_CONFIG, or the built-in ones
+// when it is not set. Level achievements must be for levels up to maxLevel.
+func CatalogFromEnv(maxLevel int) (*Catalog, error) {
+	path := os.Getenv("ACHIEVEMENTS_CONFIG")
+	if path == "" {
+		catalog, err := parseCatalog(defaultCatalogConfig, maxLevel)
+		if err != nil {
+			return nil, fmt.Errorf("built-in achievements config: %w", err)
+		}
+		return catalog, nil
+	}
+	return LoadCatalog(path, maxLevel)
+}
+
+// LoadCatalog reads and validates an achievements config file.
+func LoadCatalog(path string, maxLevel int) (*Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read achievements config: %w", err)
+	}
+	catalog, err := parseCatalog(data, maxLevel)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return catalog, nil
+}
+
+// parseCatalog decodes and validates an achievements config. A maxLevel of 0 does not limit level achievements.
+func parseCatalog(data []byte, maxLevel int) (*Catalog, error) {
+	var config struct {
+		Achievements []models.Achievement `json:"achievements"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("invalid achievements config: %w", err)
+	}
+
+	catalog := &Catalog{byID: map[string]models.Achievement{}}
+	for _, achievement := range config.Achievements {
+		if strings.TrimSpace(achievement.ID) == "" || strings.TrimSpace(achievement.Name) == "" {
+			return nil, fmt.Errorf("every achievement needs an id and a name")
+		}
+		if _, ok := catalog.byID[achievement.ID]; ok {
+			return nil, fmt.Errorf("achievement %s is defined twice", achievement.ID)
+		}
+		if _, ok := criteriaEvents[achievement.Criteria]; !ok {
+			return nil, fmt.Errorf("achievement %s has unknown criteria %q", achievement.ID, achievement.Criteria)
+		}
+		if achievement.Threshold < 1 || achievement.Points < 0 {
+			return nil, fmt.Errorf("achievement %s needs a threshold of at least 1 and non-negative points", achievement.ID)
+		}
+		if achievement.Criteria == models.CriteriaLevel && maxLevel > 0 && achievement.Threshold > maxLevel {
+			return nil, fmt.Errorf("achievement %s is for level %d, above the highest level %d", achievement.ID, achievement.Threshold, maxLevel)
+		}
+		if achievement.CriteriaDescription == "" {
+			achievement.CriteriaDescription = fmt.Sprintf(criteriaDescriptions[achievement.Criteria], achievement.Threshold)
+		}
+		catalog.achievements = append(catalog.achievements, achievement)
+		catalog.byID[achievement.ID] = achievement
+	}
+	return catalog, nil
+}
+
+// Get returns the achievement with the given ID.
+func (c *Catalog) Get(id string) (models.Achievement, bool) {
+	achievement, ok := c.byID[id]
+	return achievement, ok
+}
+
+// List returns the achievements in the order of the config.
+func (c *Catalog) List() []models.Achievement {
+	return append([]models.Achievement{}, c.achievements...)
+}
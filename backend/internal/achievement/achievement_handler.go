@@ -0,0 +1,52 @@
+package achievement
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/adrianvalentim/gamify_journal/internal/auth"
+)
+
+// Handler handles the HTTP endpoints of achievements.
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new achievement handler.
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes sets up the routes for listing achievements and the user's progress toward them.
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Get("/achievements", h.handleListAchievements)
+	r.With(auth.AuthMiddleware).Get("/users/me/achievements", h.handleGetMyAchievements)
+}
+
+// handleListAchievements lists every achievement that can be unlocked.
+func (h *Handler) handleListAchievements(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.service.ListAchievements())
+}
+
+// handleGetMyAchievements returns the authenticated user's points and progress toward every achievement.
+func (h *Handler) handleGetMyAchievements(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	achievements, err := h.service.GetUserAchievements(userID)
+	if err != nil {
+		log.Printf("Error getting achievements of user %s: %v", userID, err)
+		http.Error(w, `{"error": "Failed to get achievements"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(achievements)
+}
@@ -0,0 +1,218 @@
+package achievement
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/events"
+)
+
+// Store defines the data access the achievement service needs.
+type Store interface {
+	// SaveAchievements creates or updates the achievement definitions.
+	SaveAchievements(achievements []models.Achievement) error
+	ListUserAchievements(userID string) ([]models.UserAchievement, error)
+	// Unlock records that the user unlocked the achievement and credits its points to the user,
+	// unless the user already has it. It reports whether the achievement was unlocked now.
+	Unlock(userID string, achievement models.Achievement, at time.Time) (bool, error)
+	// Points returns the points the user earned.
+	Points(userID string) (int, error)
+
+	// The statistics achievements are awarded for.
+	CountEntries(userID string) (int, error)
+	// AddWords adds words to the number of words the user wrote.
+	AddWords(userID string, words int, at time.Time) error
+	WordsWritten(userID string) (int, error)
+	// LongestStreak returns the longest daily writing streak of the user.
+	LongestStreak(userID string) (int, error)
+	CountCompletedQuests(userID string) (int, error)
+	// CharacterLevel returns the level of the user's character, or 0 without a character.
+	CharacterLevel(userID string) (int, error)
+}
+
+// Service defines, evaluates and awards achievements.
+type Service struct {
+	store   Store
+	catalog *Catalog
+	now     func() time.Time
+}
+
+// NewService creates an achievement service awarding the achievements of catalog.
+func NewService(store Store, catalog *Catalog) *Service {
+	return &Service{store: store, catalog: catalog, now: time.Now}
+}
+
+// Sync saves the catalog's achievements in the database, so unlocks can refer to them.
+// Achievements removed from the config are kept, together with their unlocks.
+func (s *Service) Sync() error {
+	return s.store.SaveAchievements(s.catalog.List())
+}
+
+// Subscribe evaluates the user's achievements whenever bus reports something that may unlock one.
+// The words of saves are counted first, so the words achievements see them.
+func (s *Service) Subscribe(bus *events.Bus) {
+	bus.Subscribe(func(event events.Event) error {
+		if event.Words <= 0 {
+			return nil
+		}
+		return s.store.AddWords(event.UserID, event.Words, event.At)
+	}, events.EntrySaved)
+	criteriaByEvent := map[string][]string{}
+	for criteria, types := range criteriaEvents {
+		for _, eventType := range types {
+			criteriaByEvent[eventType] = append(criteriaByEvent[eventType], criteria)
+		}
+	}
+	for eventType, criteria := range criteriaByEvent {
+		bus.Subscribe(func(event events.Event) error {
+			_, err := s.Evaluate(event.UserID, criteria...)
+			return err
+		}, eventType)
+	}
+}
+
+// Evaluate unlocks the achievements the user meets the criteria of and returns those unlocked now.
+// Only achievements for the given criteria are evaluated, or all of them when none are given.
+func (s *Service) Evaluate(userID string, criteria ...string) ([]models.Achievement, error) {
+	unlocked, err := s.unlockedAt(userID)
+	if err != nil {
+		return nil, err
+	}
+	wanted := map[string]bool{}
+	for _, c := range criteria {
+		wanted[c] = true
+	}
+
+	var newlyUnlocked []models.Achievement
+	stats := &userStats{store: s.store, userID: userID, values: map[string]int{}}
+	for _, achievement := range s.catalog.List() {
+		if _, ok := unlocked[achievement.ID]; ok || (len(wanted) > 0 && !wanted[achievement.Criteria]) {
+			continue
+		}
+		value, err := stats.get(achievement.Criteria)
+		if err != nil {
+			return nil, err
+		}
+		if value < achievement.Threshold {
+			continue
+		}
+		ok, err := s.store.Unlock(userID, achievement, s.now())
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			log.Printf("Info: User %s unlocked achievement %s", userID, achievement.ID)
+			newlyUnlocked = append(newlyUnlocked, achievement)
+		}
+	}
+	return newlyUnlocked, nil
+}
+
+// ListAchievements returns every achievement that can be unlocked.
+func (s *Service) ListAchievements() []models.Achievement {
+	return s.catalog.List()
+}
+
+// Progress is an achievement together with a user's progress toward it.
+type Progress struct {
+	models.Achievement
+	Unlocked   bool       `json:"unlocked"`
+	UnlockedAt *time.Time `json:"unlocked_at,omitempty"`
+	// Current is the user's value of the statistic the achievement is awarded for, up to its threshold.
+	Current int `json:"current"`
+}
+
+// UserAchievements is a user's progress toward every achievement.
+type UserAchievements struct {
+	Points       int        `json:"points"`
+	Unlocked     int        `json:"unlocked"`
+	Achievements []Progress `json:"achievements"`
+}
+
+// GetUserAchievements returns the user's progress toward every achievement, unlocked ones first.
+// Achievements whose criteria the user already meets are unlocked on the way, so users catch up
+// on achievements added after they met their criteria.
+func (s *Service) GetUserAchievements(userID string) (*UserAchievements, error) {
+	if _, err := s.Evaluate(userID); err != nil {
+		return nil, err
+	}
+	unlocked, err := s.unlockedAt(userID)
+	if err != nil {
+		return nil, err
+	}
+	points, err := s.store.Points(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &UserAchievements{Points: points, Achievements: []Progress{}}
+	stats := &userStats{store: s.store, userID: userID, values: map[string]int{}}
+	for _, achievement := range s.catalog.List() {
+		progress := Progress{Achievement: achievement, Current: achievement.Threshold}
+		if at, ok := unlocked[achievement.ID]; ok {
+			progress.Unlocked, progress.UnlockedAt = true, &at
+			result.Unlocked++
+		} else {
+			value, err := stats.get(achievement.Criteria)
+			if err != nil {
+				return nil, err
+			}
+			if value < achievement.Threshold {
+				progress.Current = value
+			}
+		}
+		result.Achievements = append(result.Achievements, progress)
+	}
+	sort.SliceStable(result.Achievements, func(i, j int) bool {
+		return result.Achievements[i].Unlocked && !result.Achievements[j].Unlocked
+	})
+	return result, nil
+}
+
+// unlockedAt returns when the user unlocked each of their achievements.
+func (s *Service) unlockedAt(userID string) (map[string]time.Time, error) {
+	unlocked, err := s.store.ListUserAchievements(userID)
+	if err != nil {
+		return nil, err
+	}
+	at := make(map[string]time.Time, len(unlocked))
+	for _, u := range unlocked {
+		at[u.AchievementID] = u.UnlockedAt
+	}
+	return at, nil
+}
+
+// userStats computes the statistics of a user on first use, so an evaluation only queries
+// what the evaluated achievements need.
+type userStats struct {
+	store  Store
+	userID string
+	values map[string]int
+}
+
+func (u *userStats) get(criteria string) (int, error) {
+	if value, ok := u.values[criteria]; ok {
+		return value, nil
+	}
+	var value int
+	var err error
+	switch criteria {
+	case models.CriteriaEntries:
+		value, err = u.store.CountEntries(u.userID)
+	case models.CriteriaWords:
+		value, err = u.store.WordsWritten(u.userID)
+	case models.CriteriaStreak:
+		value, err = u.store.LongestStreak(u.userID)
+	case models.CriteriaQuestsCompleted:
+		value, err = u.store.CountCompletedQuests(u.userID)
+	case models.CriteriaLevel:
+		value, err = u.store.CharacterLevel(u.userID)
+	}
+	if err != nil {
+		return 0, err
+	}
+	u.values[criteria] = value
+	return value, nil
+}
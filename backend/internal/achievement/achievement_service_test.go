@@ -0,0 +1,119 @@
+package achievement
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/database/databasetest"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/events"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/richtext"
+)
+
+func TestParseCatalog(t *testing.T) {
+	if achievements := DefaultCatalog().List(); len(achievements) == 0 {
+		t.Fatal("built-in achievements config is empty")
+	}
+
+	tests := []struct {
+		name, config, wantErr string
+	}{
+		{"valid", `{"achievements": [{"id": "first_entry", "name": "First Words", "criteria": "entries", "threshold": 1, "points": 10}]}`, ""},
+		{"unknown field", `{"achievements": [{"id": "first_entry", "name": "First Words", "criteria": "entries", "threshold": 1, "reward": 10}]}`, "unknown field"},
+		{"duplicate", `{"achievements": [{"id": "a", "name": "A", "criteria": "entries", "threshold": 1}, {"id": "a", "name": "B", "criteria": "words", "threshold": 1}]}`, "defined twice"},
+		{"unknown criteria", `{"achievements": [{"id": "a", "name": "A", "criteria": "likes", "threshold": 1}]}`, "unknown criteria"},
+		{"no threshold", `{"achievements": [{"id": "a", "name": "A", "criteria": "entries"}]}`, "threshold of at least 1"},
+		{"level beyond max level", `{"achievements": [{"id": "a", "name": "A", "criteria": "level", "threshold": 20}]}`, "above the highest level"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseCatalog([]byte(tt.config), 10)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("parseCatalog() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("parseCatalog() error = %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestService_UnlocksOnEvents(t *testing.T) {
+	db := databasetest.Open(t)
+	catalog, err := parseCatalog([]byte(`{"achievements": [
+		{"id": "first_entry", "name": "First Words", "criteria": "entries", "threshold": 1, "points": 10},
+		{"id": "5_words", "name": "Talkative", "criteria": "words", "threshold": 5, "points": 5},
+		{"id": "2_day_streak", "name": "Again", "criteria": "streak", "threshold": 2, "points": 20},
+		{"id": "first_quest", "name": "Adventurer", "criteria": "quests_completed", "threshold": 1, "points": 7},
+		{"id": "level_3", "name": "Rising", "criteria": "level", "threshold": 3, "points": 3}
+	]}`), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := NewService(NewStore(db), catalog)
+	if err := svc.Sync(); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	bus := events.NewBus()
+	svc.Subscribe(bus)
+
+	write := func(id, content string) {
+		t.Helper()
+		if err := db.Create(&models.JournalEntry{ID: id, UserID: "user-1", Title: id, Content: content}).Error; err != nil {
+			t.Fatal(err)
+		}
+		bus.Publish(events.Event{Type: events.EntrySaved, UserID: "user-1", SubjectID: id, Words: richtext.WordCount(content)})
+	}
+	if err := db.Create(&models.User{ID: "user-1", Username: "hero", Email: "hero@example.com"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	write("doc-1", "<p>one two three</p>")
+	// Saving again without new text counts no words.
+	bus.Publish(events.Event{Type: events.EntrySaved, UserID: "user-1", SubjectID: "doc-1"})
+	if points, _ := svc.store.Points("user-1"); points != 10 {
+		t.Errorf("points after the first entry = %d, want 10 once", points)
+	}
+
+	write("doc-2", "<p>four <em>five</em></p>")
+	if points, _ := svc.store.Points("user-1"); points != 15 {
+		t.Errorf("points after the second entry = %d, want 15", points)
+	}
+	if words, _ := svc.store.WordsWritten("user-1"); words != 5 {
+		t.Errorf("words written = %d, want 5", words)
+	}
+
+	streak := &models.UserProgress{LongestStreaks: map[string]int{models.StreakDailyWriting: 2}}
+	if err := db.Model(&models.UserProgress{}).Where("user_id = ?", "user-1").Select("longest_streaks").Updates(streak).Error; err != nil {
+		t.Fatal(err)
+	}
+	bus.Publish(events.Event{Type: events.StreakExtended, UserID: "user-1", SubjectID: models.StreakDailyWriting})
+	if points, _ := svc.store.Points("user-1"); points != 35 {
+		t.Errorf("points after a two-day streak = %d, want 35", points)
+	}
+
+	// A completed quest is only looked at when a quest is completed, or when the user checks.
+	if err := db.Create(&models.Quest{UserID: "user-1", Title: "Run", Status: models.QuestStatusCompleted}).Error; err != nil {
+		t.Fatal(err)
+	}
+	bus.Publish(events.Event{Type: events.LevelUp, UserID: "user-1", Level: 2})
+	if points, _ := svc.store.Points("user-1"); points != 35 {
+		t.Errorf("points after a level-up = %d, want 35", points)
+	}
+
+	got, err := svc.GetUserAchievements("user-1")
+	if err != nil {
+		t.Fatalf("GetUserAchievements() error = %v", err)
+	}
+	if got.Points != 42 || got.Unlocked != 4 || len(got.Achievements) != 5 {
+		t.Fatalf("achievements = %+v, want 4 of 5 unlocked for 42 points", got)
+	}
+	for _, progress := range got.Achievements[:4] {
+		if !progress.Unlocked || progress.UnlockedAt == nil || progress.Current != progress.Threshold {
+			t.Errorf("%s = %+v, want it unlocked", progress.ID, progress)
+		}
+	}
+	if locked := got.Achievements[4]; locked.ID != "level_3" || locked.Unlocked || locked.Current != 0 {
+		t.Errorf("last achievement = %+v, want level_3 locked without a character", locked)
+	}
+}
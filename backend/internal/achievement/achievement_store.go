@@ -0,0 +1,123 @@
+package achievement
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+)
+
+// gormStore is a GORM implementation of the Store interface.
+type gormStore struct {
+	db *gorm.DB
+}
+
+// NewStore creates a new GORM store for achievements.
+func NewStore(db *gorm.DB) Store {
+	return &gormStore{db: db}
+}
+
+// SaveAchievements creates the achievements, or updates those that already exist.
+func (s *gormStore) SaveAchievements(achievements []models.Achievement) error {
+	if len(achievements) == 0 {
+		return nil
+	}
+	return s.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&achievements).Error
+}
+
+func (s *gormStore) ListUserAchievements(userID string) ([]models.UserAchievement, error) {
+	var unlocked []models.UserAchievement
+	err := s.db.Where("user_id = ?", userID).Order("unlocked_at ASC").Find(&unlocked).Error
+	return unlocked, err
+}
+
+// Unlock records in one transaction that the user unlocked the achievement and credits its points.
+func (s *gormStore) Unlock(userID string, achievement models.Achievement, at time.Time) (bool, error) {
+	unlocked := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.UserAchievement{UserID: userID, AchievementID: achievement.ID, UnlockedAt: at})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		unlocked = true
+		if achievement.Points == 0 {
+			return nil
+		}
+		return addToProgress(tx, userID, "points", achievement.Points, at)
+	})
+	return unlocked, err
+}
+
+// addToProgress adds amount to a counter column of the user's progress, creating the progress
+// if the user has none yet.
+func addToProgress(db *gorm.DB, userID, column string, amount int, at time.Time) error {
+	// Only the scalar columns are written; the array and JSON ones keep their defaults.
+	progress := map[string]interface{}{"id": uuid.NewString(), "user_id": userID, "points": 0, "updated_at": at}
+	progress[column] = amount
+	return db.Model(&models.UserProgress{}).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			column:       gorm.Expr("user_progresses."+column+" + ?", amount),
+			"updated_at": at,
+		}),
+	}).Create(progress).Error
+}
+
+func (s *gormStore) Points(userID string) (int, error) {
+	var points []int
+	err := s.db.Model(&models.UserProgress{}).Where("user_id = ?", userID).Pluck("points", &points).Error
+	if err != nil || len(points) == 0 {
+		return 0, err
+	}
+	return points[0], nil
+}
+
+func (s *gormStore) CountEntries(userID string) (int, error) {
+	var count int64
+	err := s.db.Model(&models.JournalEntry{}).Where("user_id = ?", userID).Count(&count).Error
+	return int(count), err
+}
+
+func (s *gormStore) AddWords(userID string, words int, at time.Time) error {
+	return addToProgress(s.db, userID, "words_written", words, at)
+}
+
+func (s *gormStore) WordsWritten(userID string) (int, error) {
+	var words []int
+	err := s.db.Model(&models.UserProgress{}).Where("user_id = ?", userID).Pluck("words_written", &words).Error
+	if err != nil || len(words) == 0 {
+		return 0, err
+	}
+	return words[0], nil
+}
+
+func (s *gormStore) LongestStreak(userID string) (int, error) {
+	var progress models.UserProgress
+	err := s.db.Select("longest_streaks").Where("user_id = ?", userID).Take(&progress).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	return progress.LongestStreaks[models.StreakDailyWriting], err
+}
+
+func (s *gormStore) CountCompletedQuests(userID string) (int, error) {
+	var count int64
+	err := s.db.Model(&models.Quest{}).
+		Where("user_id = ? AND status = ?", userID, models.QuestStatusCompleted).
+		Count(&count).Error
+	return int(count), err
+}
+
+func (s *gormStore) CharacterLevel(userID string) (int, error) {
+	var levels []int
+	err := s.db.Model(&models.Character{}).Where("user_id = ?", userID).Pluck("level", &levels).Error
+	if err != nil || len(levels) == 0 {
+		return 0, err
+	}
+	return levels[0], nil
+}
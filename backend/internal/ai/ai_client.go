@@ -0,0 +1,113 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ErrNotSupported is returned by clients that cannot perform an operation, such as
+// the local client generating avatars.
+var ErrNotSupported = errors.New("operation not supported by this AI client")
+
+// Client analyses journal text on behalf of users. The agents behind it apply their results
+// themselves: ProcessText awards XP and ProcessTextForQuests creates, progresses and completes quests.
+// Calls give up when ctx is done.
+type Client interface {
+	ProcessText(ctx context.Context, text, userID, entryID string) (*AIResponse, error)
+	ProcessTextForQuests(ctx context.Context, text, userID, entryID string) error
+	GenerateAvatar(ctx context.Context, prompt string) (string, error)
+}
+
+// Ensure the implementations satisfy the interface.
+var (
+	_ Client = (*AIService)(nil)
+	_ Client = (*LocalClient)(nil)
+	_ Client = (*FallbackClient)(nil)
+)
+
+// Client modes.
+const (
+	// ModeHTTP calls the AI service, and switches to the local rules while its circuit is open.
+	ModeHTTP = "http"
+	// ModeLocal never calls the AI service and uses the local rules only.
+	ModeLocal = "local"
+)
+
+// Config tunes the AI client.
+type Config struct {
+	Mode    string
+	BaseURL string
+	// Timeouts bound a single call to each endpoint of the AI service.
+	XPTimeout     time.Duration
+	QuestsTimeout time.Duration
+	AvatarTimeout time.Duration
+	// An endpoint's circuit opens after BreakerThreshold consecutive failures; calls to it then fail
+	// fast until one trial call is let through after BreakerCooldown.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+// DefaultConfig returns the configuration used when no environment overrides are set.
+func DefaultConfig() Config {
+	return Config{
+		Mode:             ModeHTTP,
+		BaseURL:          "http://localhost:8002",
+		XPTimeout:        30 * time.Second,
+		QuestsTimeout:    60 * time.Second,
+		AvatarTimeout:    30 * time.Second,
+		BreakerThreshold: 5,
+		BreakerCooldown:  30 * time.Second,
+	}
+}
+
+// ConfigFromEnv reads AI_CLIENT, AI_SERVICE_URL, AI_XP_TIMEOUT, AI_QUESTS_TIMEOUT, AI_AVATAR_TIMEOUT,
+// AI_BREAKER_THRESHOLD and AI_BREAKER_COOLDOWN on top of DefaultConfig.
+func ConfigFromEnv() Config {
+	cfg := DefaultConfig()
+
+	switch mode := os.Getenv("AI_CLIENT"); mode {
+	case "":
+	case ModeHTTP, ModeLocal:
+		cfg.Mode = mode
+	default:
+		log.Printf("Warning: invalid AI_CLIENT value %q. Defaulting to %s", mode, cfg.Mode)
+	}
+	if url := os.Getenv("AI_SERVICE_URL"); url != "" {
+		cfg.BaseURL = url
+	}
+	if value := os.Getenv("AI_BREAKER_THRESHOLD"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			cfg.BreakerThreshold = n
+		} else {
+			log.Printf("Warning: invalid AI_BREAKER_THRESHOLD value %q. Defaulting to %d", value, cfg.BreakerThreshold)
+		}
+	}
+	for key, target := range map[string]*time.Duration{
+		"AI_XP_TIMEOUT":       &cfg.XPTimeout,
+		"AI_QUESTS_TIMEOUT":   &cfg.QuestsTimeout,
+		"AI_AVATAR_TIMEOUT":   &cfg.AvatarTimeout,
+		"AI_BREAKER_COOLDOWN": &cfg.BreakerCooldown,
+	} {
+		if value := os.Getenv(key); value != "" {
+			if d, err := time.ParseDuration(value); err == nil && d > 0 {
+				*target = d
+			} else {
+				log.Printf("Warning: invalid %s value %q. Defaulting to %s", key, value, *target)
+			}
+		}
+	}
+	return cfg
+}
+
+// NewClient creates the client selected by cfg.Mode. The AI service records its calls in metrics.
+func NewClient(cfg Config, local *LocalClient, metrics *Metrics) Client {
+	if cfg.Mode == ModeLocal {
+		log.Println("Info: AI_CLIENT=local, journal entries are scored by the built-in rules.")
+		return local
+	}
+	return NewFallbackClient(NewAIService(cfg, metrics), local)
+}
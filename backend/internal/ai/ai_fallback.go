@@ -0,0 +1,47 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"log"
+)
+
+// FallbackClient calls a primary client, usually the AI service, and switches to a fallback
+// client while the primary's circuit is open. Other failures of the primary are returned to
+// the caller (background jobs retry them); only a primary that fails fast with ErrCircuitOpen
+// is replaced by the fallback.
+type FallbackClient struct {
+	primary  Client
+	fallback Client
+}
+
+// NewFallbackClient creates a client that uses fallback while primary's circuit is open.
+func NewFallbackClient(primary, fallback Client) *FallbackClient {
+	return &FallbackClient{primary: primary, fallback: fallback}
+}
+
+func (c *FallbackClient) ProcessText(ctx context.Context, text, userID, entryID string) (*AIResponse, error) {
+	resp, err := c.primary.ProcessText(ctx, text, userID, entryID)
+	if errors.Is(err, ErrCircuitOpen) {
+		log.Printf("AI service circuit is open; scoring entry %s with the fallback client", entryID)
+		return c.fallback.ProcessText(ctx, text, userID, entryID)
+	}
+	return resp, err
+}
+
+func (c *FallbackClient) ProcessTextForQuests(ctx context.Context, text, userID, entryID string) error {
+	err := c.primary.ProcessTextForQuests(ctx, text, userID, entryID)
+	if errors.Is(err, ErrCircuitOpen) {
+		log.Printf("AI service circuit is open; matching quests for entry %s with the fallback client", entryID)
+		return c.fallback.ProcessTextForQuests(ctx, text, userID, entryID)
+	}
+	return err
+}
+
+func (c *FallbackClient) GenerateAvatar(ctx context.Context, prompt string) (string, error) {
+	url, err := c.primary.GenerateAvatar(ctx, prompt)
+	if errors.Is(err, ErrCircuitOpen) {
+		return c.fallback.GenerateAvatar(ctx, prompt)
+	}
+	return url, err
+}
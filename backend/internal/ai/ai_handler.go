@@ -2,30 +2,41 @@ package ai
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 
+	"github.com/adrianvalentim/gamify_journal/internal/auth"
 	"github.com/go-chi/chi/v5"
 )
 
 // AIHandler handles HTTP requests for AI functionalities.
 type AIHandler struct {
-	Service *AIService
+	Service Client
+	Metrics *Metrics
 }
 
 // NewAIHandler creates a new instance of AIHandler.
-func NewAIHandler(service *AIService) *AIHandler {
-	return &AIHandler{Service: service}
+func NewAIHandler(service Client, metrics *Metrics) *AIHandler {
+	return &AIHandler{Service: service, Metrics: metrics}
 }
 
 // RegisterRoutes registers AI-related routes.
 func (h *AIHandler) RegisterRoutes(r chi.Router) {
-	r.Post("/process", h.handleProcessText)
+	// Processing text awards XP, so it may only be done for the authenticated user.
+	r.With(auth.AuthMiddleware).Post("/process", h.handleProcessText)
 	r.Post("/generate-avatar", h.handleGenerateAvatar)
+	r.With(auth.AuthMiddleware, auth.RequireAdmin).Get("/admin/ai/metrics", h.handleMetrics)
 }
 
 // handleProcessText handles the request to process text.
 func (h *AIHandler) handleProcessText(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
 	var input ProcessTextInput
 	err := json.NewDecoder(r.Body).Decode(&input)
 	if err != nil {
@@ -40,9 +51,12 @@ func (h *AIHandler) handleProcessText(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Never trust a user ID from the payload; the grant handed to the agent is bound to it.
+	input.UserID = userID
+
 	log.Printf("AIHandler: Received request to process text: '%s' for user '%s'", input.Text, input.UserID)
 
-	output, err := h.Service.ProcessText(input.Text, input.UserID)
+	output, err := h.Service.ProcessText(r.Context(), input.Text, input.UserID, "")
 	if err != nil {
 		log.Printf("AIHandler: Error processing text: %v", err)
 		http.Error(w, "Failed to process text", http.StatusInternalServerError)
@@ -80,7 +94,11 @@ func (h *AIHandler) handleGenerateAvatar(w http.ResponseWriter, r *http.Request)
 
 	log.Printf("AIHandler: Received request to generate avatar with prompt: '%s'", prompt)
 
-	avatarURL, err := h.Service.GenerateAvatar(prompt)
+	avatarURL, err := h.Service.GenerateAvatar(r.Context(), prompt)
+	if errors.Is(err, ErrNotSupported) {
+		http.Error(w, "Avatar generation is not available", http.StatusServiceUnavailable)
+		return
+	}
 	if err != nil {
 		log.Printf("AIHandler: Error generating avatar: %v", err)
 		http.Error(w, "Failed to generate avatar", http.StatusInternalServerError)
@@ -96,3 +114,9 @@ func (h *AIHandler) handleGenerateAvatar(w http.ResponseWriter, r *http.Request)
 	}
 	log.Printf("AIHandler: Successfully generated avatar and sent response.")
 }
+
+// handleMetrics reports latency and failures per AI service endpoint.
+func (h *AIHandler) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.Metrics.Snapshot())
+}
@@ -0,0 +1,266 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/adrianvalentim/gamify_journal/internal/character"
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/streak"
+	"gorm.io/gorm"
+)
+
+// CharacterService finds and levels up characters; it is implemented by *character.Service.
+type CharacterService interface {
+	GetCharacterByUserID(userID string) (*models.Character, error)
+	GrantXP(characterID string, amount int, source models.XPSource) (*character.XPGrant, error)
+}
+
+// QuestService reads and completes quests; it is implemented by *quest.Service.
+type QuestService interface {
+	GetUserQuests(userID string) ([]models.Quest, error)
+	CompleteQuest(questID, userID string) (*models.Quest, error)
+}
+
+// StreakService reports writing streaks; it is implemented by *streak.Service.
+type StreakService interface {
+	DailyWriting(userID string) (streak.Streak, error)
+}
+
+// Rules of the local client.
+const (
+	localWordsPerXP      = 10 // One XP per this many words
+	localMaxWordXP       = 50
+	localKeywordXP       = 5 // Per activity category mentioned
+	localMaxKeywordXP    = 20
+	localStreakXPPerDay  = 2 // Per consecutive day written before today
+	localMaxStreakXP     = 14
+	localMinQuestKeyword = 4 // Shorter words are ignored when matching quests
+)
+
+// activityKeywords maps writing categories to words that suggest the writer did something in them.
+var activityKeywords = map[string][]string{
+	models.CategoryFitness:      {"run", "ran", "running", "gym", "workout", "exercise", "exercised", "walked", "hike", "hiked", "yoga", "swim", "swam", "cycled", "trained"},
+	models.CategoryDiscipline:   {"routine", "habit", "habits", "discipline", "disciplined", "consistent", "focused", "resisted", "woke"},
+	models.CategoryLearning:     {"studied", "study", "learned", "learnt", "read", "practiced", "practised", "course", "lesson", "book"},
+	models.CategoryReflection:   {"reflected", "reflecting", "realized", "realised", "thought", "wondered", "understood", "noticed", "insight"},
+	models.CategoryCreativity:   {"drew", "painted", "sketched", "composed", "designed", "crafted", "poem", "song", "story", "invented"},
+	models.CategoryProductivity: {"finished", "completed", "cleaned", "organized", "organised", "shipped", "fixed", "built", "wrote", "planned"},
+	models.CategoryWellbeing:    {"meditated", "meditation", "slept", "cooked", "rested", "grateful", "journaled", "therapy", "stretched"},
+	models.CategorySocial:       {"friend", "friends", "family", "called", "visited", "helped", "volunteered", "met"},
+}
+
+// completionKeywords suggest that something mentioned in the same text was achieved.
+var completionKeywords = []string{"finished", "completed", "complete", "done", "did", "achieved", "accomplished", "managed", "succeeded"}
+
+// questStopWords are ignored when extracting keywords from quest titles.
+var questStopWords = map[string]bool{
+	"with": true, "from": true, "that": true, "this": true, "your": true, "into": true, "about": true,
+	"have": true, "will": true, "each": true, "every": true, "daily": true, "quest": true, "week": true,
+}
+
+// LocalClient is a deterministic, rule-based Client that works without the AI service.
+// XP comes from the word count, the writing streak and activity keywords; quests are completed
+// when the text mentions their title's keywords together with a completion word.
+type LocalClient struct {
+	characters CharacterService
+	quests     QuestService
+	streaks    StreakService
+}
+
+// NewLocalClient creates a local client. The streak service is optional; without it no streak bonus is given.
+func NewLocalClient(characters CharacterService, quests QuestService, streaks StreakService) *LocalClient {
+	return &LocalClient{characters: characters, quests: quests, streaks: streaks}
+}
+
+// ProcessText awards XP to the user's character for text.
+func (c *LocalClient) ProcessText(ctx context.Context, text, userID, entryID string) (*AIResponse, error) {
+	char, err := c.characters.GetCharacterByUserID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Nothing to level up yet; retrying would not change that.
+			return &AIResponse{}, nil
+		}
+		return nil, fmt.Errorf("failed to load character: %w", err)
+	}
+
+	streak, err := c.streak(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load writing streak: %w", err)
+	}
+	amount := LocalXP(text, streak)
+	if amount == 0 {
+		return &AIResponse{}, nil
+	}
+	source := models.XPSource{
+		Type:       models.XPSourceLocal,
+		ID:         entryID,
+		Reason:     "Scored by the built-in rules",
+		Categories: ActivityCategories(tokenize(text)),
+		Text:       text,
+	}
+	if streak > 0 {
+		source.Reason = fmt.Sprintf("Scored by the built-in rules (%d-day writing streak)", streak+1)
+	}
+	grant, err := c.characters.GrantXP(char.ID, amount, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to grant XP: %w", err)
+	}
+
+	return &AIResponse{SuggestedActions: []SuggestedAction{{
+		Type:       "update_xp",
+		Target:     "character",
+		EntityID:   char.ID,
+		Parameters: map[string]interface{}{"xp_amount": grant.Granted, "requested_xp": amount, "source": "local"},
+	}}}, nil
+}
+
+// LocalXP computes the XP the local rules award for text written on the given
+// day of a writing streak (the number of consecutive days written before).
+func LocalXP(text string, streak int) int {
+	words := tokenize(text)
+	if len(words) == 0 {
+		return 0
+	}
+
+	xp := len(words) / localWordsPerXP
+	if xp < 1 {
+		xp = 1
+	}
+	if xp > localMaxWordXP {
+		xp = localMaxWordXP
+	}
+
+	keywordXP := len(ActivityCategories(words)) * localKeywordXP
+	if keywordXP > localMaxKeywordXP {
+		keywordXP = localMaxKeywordXP
+	}
+
+	streakXP := streak * localStreakXPPerDay
+	if streakXP > localMaxStreakXP {
+		streakXP = localMaxStreakXP
+	}
+	return xp + keywordXP + streakXP
+}
+
+// ActivityCategories returns the writing categories the words mention, sorted.
+func ActivityCategories(words []string) []string {
+	var categories []string
+	for category, keywords := range activityKeywords {
+		if containsAny(words, keywords) {
+			categories = append(categories, category)
+		}
+	}
+	sort.Strings(categories)
+	return categories
+}
+
+// streak returns for how many consecutive days before today the user wrote, as counted by
+// their daily writing streak.
+func (c *LocalClient) streak(userID string) (int, error) {
+	if c.streaks == nil {
+		return 0, nil
+	}
+	current, err := c.streaks.DailyWriting(userID)
+	if err != nil {
+		return 0, err
+	}
+	// The save being scored usually extended the streak already.
+	if current.WrittenToday {
+		return current.Current - 1, nil
+	}
+	return current.Current, nil
+}
+
+// ProcessTextForQuests completes the in-progress quests that text reports as achieved.
+func (c *LocalClient) ProcessTextForQuests(ctx context.Context, text, userID, entryID string) error {
+	words := tokenize(text)
+	if !containsAny(words, completionKeywords) {
+		return nil
+	}
+	quests, err := c.quests.GetUserQuests(userID)
+	if err != nil {
+		return fmt.Errorf("failed to load quests: %w", err)
+	}
+	for _, quest := range quests {
+		if quest.Status != models.QuestStatusInProgress || !MatchesQuest(words, quest.Title) {
+			continue
+		}
+		if _, err := c.quests.CompleteQuest(quest.ID, userID); err != nil {
+			return fmt.Errorf("failed to complete quest %s: %w", quest.ID, err)
+		}
+	}
+	return nil
+}
+
+// MatchesQuest reports whether the words of a text mention at least half of
+// the keywords of a quest title (and at least one).
+func MatchesQuest(words []string, title string) bool {
+	keywords := questKeywords(title)
+	if len(keywords) == 0 {
+		return false
+	}
+	present := map[string]bool{}
+	for _, word := range words {
+		present[stem(word)] = true
+	}
+	matched := 0
+	for _, keyword := range keywords {
+		if present[stem(keyword)] {
+			matched++
+		}
+	}
+	return matched*2 >= len(keywords)
+}
+
+// questKeywords returns the distinct significant words of a quest title, sorted.
+func questKeywords(title string) []string {
+	seen := map[string]bool{}
+	var keywords []string
+	for _, word := range tokenize(title) {
+		if len(word) < localMinQuestKeyword || questStopWords[word] || seen[word] {
+			continue
+		}
+		seen[word] = true
+		keywords = append(keywords, word)
+	}
+	sort.Strings(keywords)
+	return keywords
+}
+
+// GenerateAvatar is not available without the AI service.
+func (c *LocalClient) GenerateAvatar(ctx context.Context, prompt string) (string, error) {
+	return "", ErrNotSupported
+}
+
+// tokenize splits text into lower-case words.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+}
+
+// stem strips common English suffixes so "running" matches "run" and "books" matches "book".
+func stem(word string) string {
+	for _, suffix := range []string{"ing", "ed", "es", "s"} {
+		if len(word) > len(suffix)+2 && strings.HasSuffix(word, suffix) {
+			return strings.TrimSuffix(word, suffix)
+		}
+	}
+	return word
+}
+
+func containsAny(words, keywords []string) bool {
+	for _, word := range words {
+		for _, keyword := range keywords {
+			if word == keyword {
+				return true
+			}
+		}
+	}
+	return false
+}
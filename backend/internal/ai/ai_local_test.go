@@ -0,0 +1,141 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/adrianvalentim/gamify_journal/internal/character"
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/streak"
+	"gorm.io/gorm"
+)
+
+type fakeCharacters struct {
+	character *models.Character
+	granted   []int
+	sources   []models.XPSource
+}
+
+func (f *fakeCharacters) GetCharacterByUserID(userID string) (*models.Character, error) {
+	if f.character == nil || f.character.UserID != userID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return f.character, nil
+}
+
+func (f *fakeCharacters) GrantXP(characterID string, amount int, source models.XPSource) (*character.XPGrant, error) {
+	f.granted = append(f.granted, amount)
+	f.sources = append(f.sources, source)
+	return &character.XPGrant{Character: f.character, Requested: amount, Granted: amount}, nil
+}
+
+type fakeQuests struct {
+	quests    []models.Quest
+	completed []string
+}
+
+func (f *fakeQuests) GetUserQuests(userID string) ([]models.Quest, error) {
+	return f.quests, nil
+}
+
+func (f *fakeQuests) CompleteQuest(questID, userID string) (*models.Quest, error) {
+	f.completed = append(f.completed, questID)
+	return nil, nil
+}
+
+type fakeStreaks struct {
+	streak streak.Streak
+}
+
+func (f fakeStreaks) DailyWriting(userID string) (streak.Streak, error) {
+	return f.streak, nil
+}
+
+func TestLocalXP(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		streak int
+		want   int
+	}{
+		{"empty", "  ", 3, 0},
+		{"short text earns the minimum", "Quiet day.", 0, 1},
+		{"one XP per ten words", strings.Repeat("word ", 35), 0, 3},
+		{"word XP is capped", strings.Repeat("word ", 2000), 0, localMaxWordXP},
+		{"each activity category counts once", "I ran, went running and then studied.", 0, 1 + 2*localKeywordXP},
+		{"streak bonus", "Quiet day.", 3, 1 + 3*localStreakXPPerDay},
+		{"streak bonus is capped", "Quiet day.", 60, 1 + localMaxStreakXP},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LocalXP(tt.text, tt.streak); got != tt.want {
+				t.Errorf("LocalXP() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalClient_ProcessText(t *testing.T) {
+	characters := &fakeCharacters{character: &models.Character{ID: "char-1", UserID: "user-1"}}
+	// The save extended a three-day streak: written on the two days before today.
+	streaks := fakeStreaks{streak.Streak{Kind: models.StreakDailyWriting, Current: 3, WrittenToday: true}}
+	client := NewLocalClient(characters, &fakeQuests{}, streaks)
+
+	resp, err := client.ProcessText(context.Background(), "Went to the gym.", "user-1", "doc-1")
+	if err != nil {
+		t.Fatalf("ProcessText() error = %v", err)
+	}
+	want := 1 + localKeywordXP + 2*localStreakXPPerDay
+	if len(characters.granted) != 1 || characters.granted[0] != want {
+		t.Errorf("granted %v, want [%d]", characters.granted, want)
+	}
+	if len(characters.sources) != 1 || characters.sources[0].Type != models.XPSourceLocal || characters.sources[0].ID != "doc-1" {
+		t.Errorf("XP sources = %+v, want the local rules and the entry", characters.sources)
+	}
+	if len(characters.sources) == 1 {
+		if categories := characters.sources[0].Categories; len(categories) != 1 || categories[0] != models.CategoryFitness {
+			t.Errorf("categories = %v, want [%s]", categories, models.CategoryFitness)
+		}
+	}
+	if len(resp.SuggestedActions) != 1 || resp.SuggestedActions[0].EntityID != "char-1" {
+		t.Errorf("SuggestedActions = %+v, want one XP update for char-1", resp.SuggestedActions)
+	}
+
+	// Users without a character are skipped rather than failing the job forever.
+	resp, err = client.ProcessText(context.Background(), "Went to the gym.", "user-2", "doc-2")
+	if err != nil || len(resp.SuggestedActions) != 0 {
+		t.Errorf("ProcessText() without character = %+v, %v; want no actions and no error", resp, err)
+	}
+}
+
+func TestLocalClient_ProcessTextForQuests(t *testing.T) {
+	quests := &fakeQuests{quests: []models.Quest{
+		{ID: "q-run", Title: "Run a marathon", Status: models.QuestStatusInProgress},
+		{ID: "q-book", Title: "Read the whole book", Status: models.QuestStatusInProgress},
+		{ID: "q-done", Title: "Run every morning", Status: models.QuestStatusCompleted},
+	}}
+	client := NewLocalClient(&fakeCharacters{}, quests, nil)
+
+	// A mention without a completion word is not enough.
+	if err := client.ProcessTextForQuests(context.Background(), "Thinking about running a marathon.", "user-1", "doc-1"); err != nil {
+		t.Fatalf("ProcessTextForQuests() error = %v", err)
+	}
+	if len(quests.completed) != 0 {
+		t.Fatalf("completed %v without a completion word", quests.completed)
+	}
+
+	if err := client.ProcessTextForQuests(context.Background(), "Finally finished my first marathon today!", "user-1", "doc-1"); err != nil {
+		t.Fatalf("ProcessTextForQuests() error = %v", err)
+	}
+	if len(quests.completed) != 1 || quests.completed[0] != "q-run" {
+		t.Errorf("completed %v, want [q-run]", quests.completed)
+	}
+}
+
+func TestLocalClient_GenerateAvatarIsNotSupported(t *testing.T) {
+	client := NewLocalClient(&fakeCharacters{}, &fakeQuests{}, nil)
+	if _, err := client.GenerateAvatar(context.Background(), "a knight"); err != ErrNotSupported {
+		t.Errorf("GenerateAvatar() error = %v, want ErrNotSupported", err)
+	}
+}
@@ -0,0 +1,165 @@
+package ai
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the upper bounds of the latency histogram.
+var latencyBuckets = []time.Duration{
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	time.Minute,
+}
+
+// Metrics counts the calls made to each endpoint of the AI service. It is safe for concurrent use;
+// a nil *Metrics records nothing.
+type Metrics struct {
+	mu        sync.Mutex
+	endpoints map[string]*EndpointStats
+}
+
+// EndpointStats are the totals of one endpoint since the server started.
+type EndpointStats struct {
+	Endpoint string `json:"endpoint"`
+	// Requests counts the calls sent to the AI service; rejected calls are not included.
+	Requests int64 `json:"requests"`
+	// Failures counts the requests that failed, including timeouts.
+	Failures int64 `json:"failures"`
+	Timeouts int64 `json:"timeouts"`
+	// Canceled counts the requests abandoned because the caller went away.
+	Canceled int64 `json:"canceled"`
+	// Rejected counts the calls failed fast while the endpoint's circuit was open.
+	Rejected     int64   `json:"rejected"`
+	CircuitOpen  bool    `json:"circuit_open"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+	MaxLatencyMs float64 `json:"max_latency_ms"`
+	// LatencyBuckets maps an upper bound ("100ms", ..., "+Inf") to the number of requests that took at most that long.
+	LatencyBuckets map[string]int64 `json:"latency_buckets"`
+
+	totalLatency time.Duration
+	maxLatency   time.Duration
+	buckets      []int64
+}
+
+// NewMetrics creates an empty set of metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{endpoints: map[string]*EndpointStats{}}
+}
+
+// callOutcome classifies a finished request.
+type callOutcome int
+
+const (
+	outcomeSuccess callOutcome = iota
+	outcomeFailure
+	outcomeTimeout
+	outcomeCanceled
+)
+
+func (m *Metrics) stats(endpoint string) *EndpointStats {
+	stats, ok := m.endpoints[endpoint]
+	if !ok {
+		stats = &EndpointStats{Endpoint: endpoint, buckets: make([]int64, len(latencyBuckets)+1)}
+		m.endpoints[endpoint] = stats
+	}
+	return stats
+}
+
+// observe records a request that was sent to an endpoint.
+func (m *Metrics) observe(endpoint string, latency time.Duration, outcome callOutcome) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := m.stats(endpoint)
+	stats.Requests++
+	switch outcome {
+	case outcomeFailure:
+		stats.Failures++
+	case outcomeTimeout:
+		stats.Failures++
+		stats.Timeouts++
+	case outcomeCanceled:
+		stats.Canceled++
+	}
+	stats.totalLatency += latency
+	if latency > stats.maxLatency {
+		stats.maxLatency = latency
+	}
+	bucket := sort.Search(len(latencyBuckets), func(i int) bool { return latency <= latencyBuckets[i] })
+	stats.buckets[bucket]++
+}
+
+// reject records a call that was failed fast because the endpoint's circuit was open.
+func (m *Metrics) reject(endpoint string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats(endpoint).Rejected++
+}
+
+// circuit records whether the endpoint's circuit is open.
+func (m *Metrics) circuit(endpoint string, open bool) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats(endpoint).CircuitOpen = open
+}
+
+// Snapshot returns the current totals, sorted by endpoint.
+func (m *Metrics) Snapshot() []EndpointStats {
+	if m == nil {
+		return []EndpointStats{}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := make([]EndpointStats, 0, len(m.endpoints))
+	for _, stats := range m.endpoints {
+		s := EndpointStats{
+			Endpoint:       stats.Endpoint,
+			Requests:       stats.Requests,
+			Failures:       stats.Failures,
+			Timeouts:       stats.Timeouts,
+			Canceled:       stats.Canceled,
+			Rejected:       stats.Rejected,
+			CircuitOpen:    stats.CircuitOpen,
+			MaxLatencyMs:   milliseconds(stats.maxLatency),
+			LatencyBuckets: map[string]int64{},
+		}
+		if stats.Requests > 0 {
+			s.AvgLatencyMs = milliseconds(stats.totalLatency / time.Duration(stats.Requests))
+		}
+		// Buckets are cumulative, as in Prometheus histograms.
+		var cumulative int64
+		for i, count := range stats.buckets {
+			cumulative += count
+			bound := "+Inf"
+			if i < len(latencyBuckets) {
+				bound = latencyBuckets[i].String()
+			}
+			s.LatencyBuckets[bound] = cumulative
+		}
+		snapshot = append(snapshot, s)
+	}
+	sort.Slice(snapshot, func(i, j int) bool { return snapshot[i].Endpoint < snapshot[j].Endpoint })
+	return snapshot
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -2,32 +2,58 @@ package ai
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"time"
+
+	"github.com/adrianvalentim/gamify_journal/internal/auth"
+)
+
+// grantTTL bounds how long the agents may call back into the API after a request.
+const grantTTL = 5 * time.Minute
+
+// Endpoints of the AI service.
+const (
+	EndpointXP     = "/agent/update_character_xp"
+	EndpointQuests = "/agent/update_quests"
+	EndpointAvatar = "/generate-avatar"
 )
 
-// AIService handles AI-related business logic.
+// ErrCircuitOpen is returned without calling the AI service while an endpoint keeps failing.
+var ErrCircuitOpen = errors.New("AI service circuit is open")
+
+// AIService is the Client that calls the Python AI service over HTTP.
+// Every endpoint has its own timeout and circuit breaker, and its calls are recorded in Metrics.
 type AIService struct {
 	HttpClient *http.Client
 	BaseURL    string
+	Metrics    *Metrics
+	timeouts   map[string]time.Duration
+	breakers   map[string]*Breaker
 }
 
 // NewAIService creates a new instance of AIService.
-func NewAIService() *AIService {
-	aiServiceURL := os.Getenv("AI_SERVICE_URL")
-	if aiServiceURL == "" {
-		aiServiceURL = "http://localhost:8002"
-	}
-	return &AIService{
-		HttpClient: &http.Client{
-			Timeout: 60 * time.Second,
+func NewAIService(cfg Config, metrics *Metrics) *AIService {
+	s := &AIService{
+		// Calls are bounded by their context; see cfg's per-endpoint timeouts.
+		HttpClient: &http.Client{},
+		BaseURL:    cfg.BaseURL,
+		Metrics:    metrics,
+		timeouts: map[string]time.Duration{
+			EndpointXP:     cfg.XPTimeout,
+			EndpointQuests: cfg.QuestsTimeout,
+			EndpointAvatar: cfg.AvatarTimeout,
 		},
-		BaseURL: aiServiceURL,
+		breakers: map[string]*Breaker{},
+	}
+	for endpoint := range s.timeouts {
+		s.breakers[endpoint] = NewBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown)
 	}
+	return s
 }
 
 // ProcessTextInput DTO for ProcessText
@@ -43,103 +69,164 @@ type ProcessTextOutput struct {
 
 // AIResponse DTO for ProcessText
 type AIResponse struct {
-	SuggestedActions []struct {
-		Type       string                 `json:"type"`
-		Target     string                 `json:"target_entity"`
-		EntityID   string                 `json:"entity_id"`
-		Parameters map[string]interface{} `json:"parameters"`
-	} `json:"suggested_actions"`
+	SuggestedActions []SuggestedAction `json:"suggested_actions"`
+	// SuggestedTags are tags the XP agent proposes for the entry. They are never attached automatically.
+	SuggestedTags []string `json:"suggested_tags,omitempty"`
+}
+
+// SuggestedAction is a change an agent made or proposes, such as awarding XP to a character.
+type SuggestedAction struct {
+	Type       string                 `json:"type"`
+	Target     string                 `json:"target_entity"`
+	EntityID   string                 `json:"entity_id"`
+	Parameters map[string]interface{} `json:"parameters"`
 }
 
 // ProcessText sends text to the AI service for XP analysis.
-func (s *AIService) ProcessText(text, userID string) (*AIResponse, error) {
-	requestBody, err := json.Marshal(map[string]string{
+// The agent receives a grant that only allows it to award XP to userID.
+func (s *AIService) ProcessText(ctx context.Context, text, userID, entryID string) (*AIResponse, error) {
+	grant, err := auth.IssueServiceGrant(userID, entryID, []string{auth.ScopeXPGrant}, grantTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue grant for xp agent: %w", err)
+	}
+
+	// Apart from the suggested tags, the response is for logging/confirmation only.
+	var resp AIResponse
+	err = s.post(ctx, EndpointXP, map[string]string{
 		"entry_text": text,
 		"user_id":    userID,
-	})
+		"grant":      grant,
+	}, &resp)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request body for xp agent: %w", err)
+		return nil, fmt.Errorf("xp agent: %w", err)
 	}
+	return &resp, nil
+}
 
-	req, err := http.NewRequest("POST", s.BaseURL+"/agent/update_character_xp", bytes.NewBuffer(requestBody))
+// ProcessTextForQuests sends text to the AI service for quest processing.
+// The agent receives a grant that only allows it to read and manage the quests of userID.
+func (s *AIService) ProcessTextForQuests(ctx context.Context, text, userID, entryID string) error {
+	grant, err := auth.IssueServiceGrant(userID, entryID, []string{auth.ScopeQuestsRead, auth.ScopeQuestsWrite}, grantTTL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request for xp agent: %w", err)
+		return fmt.Errorf("failed to issue grant for quest agent: %w", err)
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := s.HttpClient.Do(req)
+	err = s.post(ctx, EndpointQuests, map[string]string{
+		"entry_text": text,
+		"user_id":    userID,
+		"grant":      grant,
+	}, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request to xp agent: %w", err)
+		return fmt.Errorf("quest agent: %w", err)
 	}
-	defer resp.Body.Close()
+	return nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("xp agent returned an error: %s - %s", resp.Status, string(bodyBytes))
+// GenerateAvatar proxies the request to the Python AI service.
+func (s *AIService) GenerateAvatar(ctx context.Context, prompt string) (string, error) {
+	var result map[string]string
+	if err := s.post(ctx, EndpointAvatar, map[string]string{"prompt": prompt}, &result); err != nil {
+		return "", fmt.Errorf("avatar generation: %w", err)
 	}
 
-	// The response for this endpoint is for logging/confirmation, not complex data.
-	// We can return nil for the AIResponse as the old structure is no longer used.
-	return nil, nil
+	avatarURL, ok := result["avatar_url"]
+	if !ok {
+		return "", fmt.Errorf("response from AI service did not contain avatar_url")
+	}
+
+	return avatarURL, nil
 }
 
-// ProcessTextForQuests sends text to the AI service for quest processing.
-func (s *AIService) ProcessTextForQuests(text, userID string) error {
-	requestBody, err := json.Marshal(map[string]string{
-		"entry_text": text,
-		"user_id":    userID,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to marshal request body for quest agent: %w", err)
-	}
+// statusError is returned when the AI service answers with a status other than 200 OK.
+type statusError struct {
+	status string
+	code   int
+	body   string
+}
 
-	req, err := http.NewRequest("POST", s.BaseURL+"/agent/update_quests", bytes.NewBuffer(requestBody))
-	if err != nil {
-		return fmt.Errorf("failed to create request for quest agent: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
+func (e *statusError) Error() string {
+	return fmt.Sprintf("AI service returned an error: %s - %s", e.status, e.body)
+}
 
-	resp, err := s.HttpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to make request to quest agent: %w", err)
+// post sends body as JSON to an endpoint and decodes the response into out, unless it is nil.
+// The call fails fast with ErrCircuitOpen while the endpoint's circuit is open, and is bounded
+// by the endpoint's timeout as well as by ctx.
+func (s *AIService) post(ctx context.Context, endpoint string, body, out interface{}) error {
+	breaker := s.breakers[endpoint]
+	if breaker != nil && !breaker.Allow() {
+		s.Metrics.reject(endpoint)
+		return ErrCircuitOpen
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("quest agent returned an error: %s - %s", resp.Status, string(bodyBytes))
+	callCtx := ctx
+	if timeout := s.timeouts[endpoint]; timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
 	}
 
-	return nil
+	start := time.Now()
+	err := s.do(callCtx, endpoint, body, out)
+	latency := time.Since(start)
+
+	// Requests the service rejected (4xx) are failures, but they do not mean it is down.
+	outcome, healthy := outcomeSuccess, true
+	var statusErr *statusError
+	switch {
+	case err == nil:
+	case ctx.Err() != nil:
+		// The caller gave up (client disconnected, job shut down); that says nothing about the service.
+		outcome = outcomeCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		outcome, healthy = outcomeTimeout, false
+	case errors.As(err, &statusErr) && statusErr.code < http.StatusInternalServerError:
+		outcome = outcomeFailure
+	default:
+		outcome, healthy = outcomeFailure, false
+	}
+	s.Metrics.observe(endpoint, latency, outcome)
+
+	if breaker != nil {
+		switch {
+		case outcome == outcomeCanceled:
+			breaker.Release()
+		case healthy:
+			breaker.Record(nil)
+		default:
+			breaker.Record(err)
+		}
+		s.Metrics.circuit(endpoint, breaker.Open())
+	}
+	return err
 }
 
-// GenerateAvatar proxies the request to the Python AI service.
-func (s *AIService) GenerateAvatar(prompt string) (string, error) {
-	requestBody, err := json.Marshal(map[string]string{"prompt": prompt})
+func (s *AIService) do(ctx context.Context, endpoint string, body, out interface{}) error {
+	requestBody, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.BaseURL+endpoint, bytes.NewBuffer(requestBody))
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request body: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
 	}
+	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := s.HttpClient.Post(s.BaseURL+"/generate-avatar", "application/json", bytes.NewBuffer(requestBody))
+	resp, err := s.HttpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to make request to AI service: %w", err)
+		return fmt.Errorf("failed to make request to AI service: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("AI service returned an error: %s - %s", resp.Status, string(bodyBytes))
+		return &statusError{status: resp.Status, code: resp.StatusCode, body: string(bodyBytes)}
 	}
 
-	var result map[string]string
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("failed to decode response from AI service: %w", err)
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode response from AI service: %w", err)
+		}
 	}
-
-	avatarURL, ok := result["avatar_url"]
-	if !ok {
-		return "", fmt.Errorf("response from AI service did not contain avatar_url")
-	}
-
-	return avatarURL, nil
+	return nil
 }
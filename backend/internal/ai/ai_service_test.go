@@ -0,0 +1,145 @@
+package ai_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/adrianvalentim/gamify_journal/internal/ai"
+	"github.com/adrianvalentim/gamify_journal/internal/ai/aitest"
+)
+
+// newTestService returns an AIService for a fake AI service answering with handler.
+func newTestService(t *testing.T, handler http.HandlerFunc) (*ai.AIService, *ai.Metrics) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	cfg := ai.DefaultConfig()
+	cfg.BaseURL = server.URL
+	cfg.QuestsTimeout = 50 * time.Millisecond
+	cfg.BreakerThreshold = 2
+	cfg.BreakerCooldown = time.Hour
+	metrics := ai.NewMetrics()
+	return ai.NewAIService(cfg, metrics), metrics
+}
+
+func stats(metrics *ai.Metrics, endpoint string) ai.EndpointStats {
+	for _, s := range metrics.Snapshot() {
+		if s.Endpoint == endpoint {
+			return s
+		}
+	}
+	return ai.EndpointStats{}
+}
+
+func TestAIService_CircuitOpensAndFallsBack(t *testing.T) {
+	var requests int32
+	service, metrics := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.Error(w, "agent crashed", http.StatusInternalServerError)
+	})
+	fallback := &aitest.Recorder{}
+	client := ai.NewFallbackClient(service, fallback)
+	ctx := context.Background()
+
+	// Failures below the threshold are returned, so background jobs retry them.
+	for i := 0; i < 2; i++ {
+		if err := client.ProcessTextForQuests(ctx, "text", "user-1", "doc-1"); err == nil || errors.Is(err, ai.ErrCircuitOpen) {
+			t.Fatalf("call %d error = %v, want the agent's error", i, err)
+		}
+	}
+
+	// Then the circuit is open: the service is not called and the fallback takes over.
+	if err := client.ProcessTextForQuests(ctx, "text", "user-1", "doc-1"); err != nil {
+		t.Fatalf("ProcessTextForQuests() with open circuit error = %v", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("AI service received %d requests, want 2", n)
+	}
+	if got := fallback.Texts(aitest.ProcessTextForQuests); len(got) != 1 {
+		t.Errorf("fallback received %d calls, want 1", len(got))
+	}
+
+	// Endpoints have their own circuits.
+	if _, err := service.ProcessText(ctx, "text", "user-1", "doc-1"); errors.Is(err, ai.ErrCircuitOpen) {
+		t.Error("XP endpoint circuit opened because of quest endpoint failures")
+	}
+
+	got := stats(metrics, ai.EndpointQuests)
+	if got.Requests != 2 || got.Failures != 2 || got.Rejected != 1 || !got.CircuitOpen {
+		t.Errorf("quest endpoint stats = %+v, want 2 failed requests, 1 rejected and an open circuit", got)
+	}
+}
+
+func TestAIService_Timeouts(t *testing.T) {
+	service, metrics := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(300 * time.Millisecond):
+		}
+	})
+
+	start := time.Now()
+	err := service.ProcessTextForQuests(context.Background(), "text", "user-1", "doc-1")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ProcessTextForQuests() error = %v, want a deadline error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Errorf("call took %s, want it cut off by the endpoint timeout", elapsed)
+	}
+	if got := stats(metrics, ai.EndpointQuests); got.Timeouts != 1 || got.Failures != 1 {
+		t.Errorf("stats = %+v, want one timeout", got)
+	}
+
+	// A caller that gives up does not count against the service.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := service.ProcessTextForQuests(ctx, "text", "user-1", "doc-1"); err == nil {
+		t.Fatal("ProcessTextForQuests() with canceled context succeeded")
+	}
+	if got := stats(metrics, ai.EndpointQuests); got.Canceled != 1 || got.Failures != 1 || got.CircuitOpen {
+		t.Errorf("stats = %+v, want the cancellation recorded separately", got)
+	}
+}
+
+func TestAIService_ClientErrorsDoNotOpenCircuit(t *testing.T) {
+	service, metrics := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad prompt", http.StatusUnprocessableEntity)
+	})
+	for i := 0; i < 3; i++ {
+		if _, err := service.GenerateAvatar(context.Background(), "a knight"); err == nil || errors.Is(err, ai.ErrCircuitOpen) {
+			t.Fatalf("call %d error = %v, want the service's rejection", i, err)
+		}
+	}
+	if got := stats(metrics, ai.EndpointAvatar); got.Failures != 3 || got.CircuitOpen {
+		t.Errorf("stats = %+v, want 3 failures and a closed circuit", got)
+	}
+}
+
+func TestBreaker_HalfOpenTrial(t *testing.T) {
+	breaker := ai.NewBreaker(1, 10*time.Millisecond)
+	if !breaker.Allow() {
+		t.Fatal("new breaker rejects calls")
+	}
+	breaker.Record(aitest.ErrUnavailable)
+	if breaker.Allow() {
+		t.Fatal("open breaker allowed a call before the cooldown")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if !breaker.Allow() {
+		t.Fatal("breaker rejected the trial call after the cooldown")
+	}
+	if breaker.Allow() {
+		t.Fatal("breaker allowed a second call while the trial is in flight")
+	}
+	breaker.Record(nil)
+	if breaker.Open() || !breaker.Allow() {
+		t.Fatal("breaker did not close after a successful trial")
+	}
+}
@@ -0,0 +1,93 @@
+// Package aitest provides an ai.Client fake for tests.
+package aitest
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/adrianvalentim/gamify_journal/internal/ai"
+)
+
+// ErrUnavailable is returned by a Recorder for the calls it is told to fail.
+var ErrUnavailable = errors.New("aitest: AI service unavailable")
+
+// Methods of ai.Client, as recorded in Call.Method.
+const (
+	ProcessText          = "ProcessText"
+	ProcessTextForQuests = "ProcessTextForQuests"
+	GenerateAvatar       = "GenerateAvatar"
+)
+
+// Call is one call made to a Recorder.
+type Call struct {
+	Method  string
+	Text    string // the text or, for GenerateAvatar, the prompt
+	UserID  string
+	EntryID string
+}
+
+// Recorder is an ai.Client that records its calls and returns canned results.
+// It is safe for concurrent use.
+type Recorder struct {
+	mu sync.Mutex
+	// Failures is how many of the next calls fail with ErrUnavailable. Failed calls are not recorded.
+	Failures int
+	// Response is returned by ProcessText; nil returns an empty response.
+	Response *ai.AIResponse
+	// AvatarURL is returned by GenerateAvatar.
+	AvatarURL string
+	calls     []Call
+}
+
+var _ ai.Client = (*Recorder)(nil)
+
+func (r *Recorder) record(call Call) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Failures > 0 {
+		r.Failures--
+		return ErrUnavailable
+	}
+	r.calls = append(r.calls, call)
+	return nil
+}
+
+func (r *Recorder) ProcessText(ctx context.Context, text, userID, entryID string) (*ai.AIResponse, error) {
+	if err := r.record(Call{Method: ProcessText, Text: text, UserID: userID, EntryID: entryID}); err != nil {
+		return nil, err
+	}
+	if r.Response != nil {
+		return r.Response, nil
+	}
+	return &ai.AIResponse{}, nil
+}
+
+func (r *Recorder) ProcessTextForQuests(ctx context.Context, text, userID, entryID string) error {
+	return r.record(Call{Method: ProcessTextForQuests, Text: text, UserID: userID, EntryID: entryID})
+}
+
+func (r *Recorder) GenerateAvatar(ctx context.Context, prompt string) (string, error) {
+	if err := r.record(Call{Method: GenerateAvatar, Text: prompt}); err != nil {
+		return "", err
+	}
+	return r.AvatarURL, nil
+}
+
+// Calls returns the successful calls made so far, in order.
+func (r *Recorder) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Call(nil), r.calls...)
+}
+
+// Texts returns the texts passed to method, in order.
+func (r *Recorder) Texts(method string) []string {
+	var texts []string
+	for _, call := range r.Calls() {
+		if call.Method == method {
+			texts = append(texts, call.Text)
+		}
+	}
+	return texts
+}
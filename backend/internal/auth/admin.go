@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"net/http"
+	"os"
+	"strings"
+)
+
+// adminUserIDs lists the users allowed to call administrative endpoints, from ADMIN_USER_IDS.
+var adminUserIDs = parseUserIDs(os.Getenv("ADMIN_USER_IDS"))
+
+// parseUserIDs splits a comma-separated list of user IDs.
+func parseUserIDs(value string) map[string]bool {
+	ids := map[string]bool{}
+	for _, id := range strings.Split(value, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids[id] = true
+		}
+	}
+	return ids
+}
+
+// SetAdminUserIDs replaces the users allowed to call administrative endpoints.
+func SetAdminUserIDs(ids ...string) {
+	adminUserIDs = parseUserIDs(strings.Join(ids, ","))
+}
+
+// RequireAdmin rejects requests from users that are not listed in ADMIN_USER_IDS.
+// It must be mounted after AuthMiddleware.
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := r.Context().Value(UserIDKey).(string)
+		if userID == "" || !adminUserIDs[userID] {
+			http.Error(w, "Admin access required", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -1,46 +1,64 @@
 package auth
 
 import (
+	"log"
+	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// Note: This secret should be loaded from a secure configuration (e.g., environment variables)
-// and should be a long, complex, randomly generated string.
-var jwtSecret = []byte("a_very_secret_key_that_should_be_in_env")
+// AccessTokenTTL is how long an access token stays valid.
+// Access tokens are short-lived; clients renew them with a refresh token.
+// It can be overridden with the ACCESS_TOKEN_TTL environment variable (e.g., "15m").
+var AccessTokenTTL = durationFromEnv("ACCESS_TOKEN_TTL", 15*time.Minute)
 
 // Claims defines the structure of the JWT claims.
 type Claims struct {
-	UserID string `json:"user_id"`
+	UserID    string `json:"user_id"`
+	SessionID string `json:"sid,omitempty"` // Server-side session the token was issued for
 	jwt.RegisteredClaims
 }
 
-// GenerateToken creates a new JWT for a given user ID.
-func GenerateToken(userID string) (string, error) {
-	// Token expires in 24 hours
-	expirationTime := time.Now().Add(24 * time.Hour)
+// GenerateToken creates a new access token for a given user ID and session ID.
+// The token is signed with the active key of the key ring (see ConfigureKeysFromEnv).
+func GenerateToken(userID, sessionID string) (string, error) {
+	expirationTime := time.Now().Add(AccessTokenTTL)
 
 	claims := &Claims{
-		UserID: userID,
+		UserID:    userID,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			// In JWT, the expiry time is expressed in Unix time
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "gamify_journal",
+			Issuer:    keyRing.Issuer,
+			Audience:  jwt.ClaimStrings{keyRing.Audience},
 			Subject:   "user_login",
 		},
 	}
 
-	// Create token with claims
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	// Sign the token with the secret
-	tokenString, err := token.SignedString(jwtSecret)
+	// Sign the token with the active key
+	tokenString, err := keyRing.sign(claims)
 	if err != nil {
 		return "", err
 	}
 
 	return tokenString, nil
 }
+
+// durationFromEnv reads a time.Duration from the environment, falling back to def
+// when the variable is unset or invalid.
+func durationFromEnv(key string, def time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("Warning: invalid %s value %q. Defaulting to %s", key, value, def)
+		return def
+	}
+	return d
+}
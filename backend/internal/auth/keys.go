@@ -0,0 +1,326 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	defaultIssuer   = "gamify_journal"
+	defaultAudience = "gamify_journal_api"
+	// minHMACSecretLength is the shortest HS256 secret accepted, from JWT_SECRET or a key ring file.
+	minHMACSecretLength = 32
+)
+
+// SigningKey is one entry of the key ring.
+// Keys without private material can only verify tokens; they are kept around after a
+// rotation so tokens signed with them stay valid until they expire.
+type SigningKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	signKey   interface{} // []byte, *rsa.PrivateKey or ed25519.PrivateKey; nil for verify-only keys
+	verifyKey interface{} // []byte, *rsa.PublicKey or ed25519.PublicKey
+}
+
+// KeyRing holds the keys used to sign and verify access tokens.
+// Tokens are signed with the active key and carry its ID in the "kid" header,
+// so verification can pick the right key while older keys are being retired.
+type KeyRing struct {
+	Issuer   string
+	Audience string
+	active   *SigningKey
+	keys     map[string]*SigningKey
+}
+
+// keyRing defaults to a random per-process HMAC key until ConfigureKeysFromEnv runs at startup.
+var keyRing = newEphemeralKeyRing()
+
+// keyRingFile is the on-disk format of JWT_KEYS_FILE.
+//
+//	{
+//	  "active_kid": "2025-02",
+//	  "keys": [
+//	    {"kid": "2025-02", "alg": "EdDSA", "private_key_file": "/run/secrets/jwt-2025-02.pem"},
+//	    {"kid": "2024-11", "alg": "RS256", "public_key_file": "/run/secrets/jwt-2024-11.pub.pem"},
+//	    {"kid": "legacy", "alg": "HS256", "secret": "..."}
+//	  ]
+//	}
+type keyRingFile struct {
+	ActiveKID string          `json:"active_kid"`
+	Keys      []keyFileConfig `json:"keys"`
+}
+
+type keyFileConfig struct {
+	KID            string `json:"kid"`
+	Alg            string `json:"alg"`
+	Secret         string `json:"secret,omitempty"`           // HS256
+	PrivateKeyFile string `json:"private_key_file,omitempty"` // RS256/EdDSA, PEM
+	PublicKeyFile  string `json:"public_key_file,omitempty"`  // RS256/EdDSA, PEM; verify-only keys
+}
+
+// ConfigureKeysFromEnv loads the signing keys and should be called once at startup.
+//
+//   - JWT_KEYS_FILE points to a key ring file (see keyRingFile) and enables rotation
+//     and asymmetric signing.
+//   - JWT_SECRET configures a single HS256 key with ID "default". It must be at least 32 bytes.
+//   - JWT_ISSUER and JWT_AUDIENCE override the "iss" and "aud" claims.
+//
+// If neither JWT_KEYS_FILE nor JWT_SECRET is set, a random per-process key is used and
+// every token is invalidated on restart.
+func ConfigureKeysFromEnv() error {
+	var ring *KeyRing
+	var err error
+
+	switch {
+	case os.Getenv("JWT_KEYS_FILE") != "":
+		ring, err = LoadKeyRingFile(os.Getenv("JWT_KEYS_FILE"))
+		if err != nil {
+			return err
+		}
+	case os.Getenv("JWT_SECRET") != "":
+		if len(os.Getenv("JWT_SECRET")) < minHMACSecretLength {
+			return fmt.Errorf("JWT_SECRET must be at least %d bytes", minHMACSecretLength)
+		}
+		ring, err = NewKeyRing("default", []*SigningKey{NewHMACKey("default", []byte(os.Getenv("JWT_SECRET")))})
+		if err != nil {
+			return err
+		}
+	default:
+		log.Println("Warning: neither JWT_KEYS_FILE nor JWT_SECRET is set. Using a random per-process signing key; tokens will not survive a restart.")
+		ring = newEphemeralKeyRing()
+	}
+
+	if issuer := os.Getenv("JWT_ISSUER"); issuer != "" {
+		ring.Issuer = issuer
+	}
+	if audience := os.Getenv("JWT_AUDIENCE"); audience != "" {
+		ring.Audience = audience
+	}
+
+	SetKeyRing(ring)
+	log.Printf("Info: JWT key ring loaded with %d key(s); signing with %q (%s).", len(ring.keys), ring.active.ID, ring.active.Method.Alg())
+	return nil
+}
+
+// SetKeyRing replaces the key ring used by GenerateToken and AuthMiddleware.
+func SetKeyRing(ring *KeyRing) {
+	keyRing = ring
+}
+
+// NewKeyRing builds a key ring signing with the key identified by activeKID.
+func NewKeyRing(activeKID string, keys []*SigningKey) (*KeyRing, error) {
+	ring := &KeyRing{Issuer: defaultIssuer, Audience: defaultAudience, keys: make(map[string]*SigningKey, len(keys))}
+	for _, key := range keys {
+		if key.ID == "" {
+			return nil, errors.New("jwt key ring: every key needs a kid")
+		}
+		if _, dup := ring.keys[key.ID]; dup {
+			return nil, fmt.Errorf("jwt key ring: duplicate kid %q", key.ID)
+		}
+		ring.keys[key.ID] = key
+	}
+
+	active, ok := ring.keys[activeKID]
+	if !ok {
+		return nil, fmt.Errorf("jwt key ring: active kid %q not found", activeKID)
+	}
+	if active.signKey == nil {
+		return nil, fmt.Errorf("jwt key ring: active key %q has no private key", activeKID)
+	}
+	ring.active = active
+	return ring, nil
+}
+
+// LoadKeyRingFile reads a key ring from a JSON file.
+func LoadKeyRingFile(path string) (*KeyRing, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("jwt key ring: could not read %s: %w", path, err)
+	}
+	var cfg keyRingFile
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("jwt key ring: could not parse %s: %w", path, err)
+	}
+
+	keys := make([]*SigningKey, 0, len(cfg.Keys))
+	for _, kc := range cfg.Keys {
+		key, err := loadKey(kc)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key ring: key %q: %w", kc.KID, err)
+		}
+		keys = append(keys, key)
+	}
+	return NewKeyRing(cfg.ActiveKID, keys)
+}
+
+// NewHMACKey creates an HS256 key. HMAC keys are never published in the JWKS.
+func NewHMACKey(kid string, secret []byte) *SigningKey {
+	return &SigningKey{ID: kid, Method: jwt.SigningMethodHS256, signKey: secret, verifyKey: secret}
+}
+
+// NewRSAKey creates an RS256 key. A nil private key makes it verify-only.
+func NewRSAKey(kid string, private *rsa.PrivateKey, public *rsa.PublicKey) *SigningKey {
+	key := &SigningKey{ID: kid, Method: jwt.SigningMethodRS256, verifyKey: public}
+	if private != nil {
+		key.signKey = private
+		key.verifyKey = &private.PublicKey
+	}
+	return key
+}
+
+// NewEd25519Key creates an EdDSA key. A nil private key makes it verify-only.
+func NewEd25519Key(kid string, private ed25519.PrivateKey, public ed25519.PublicKey) *SigningKey {
+	key := &SigningKey{ID: kid, Method: jwt.SigningMethodEdDSA, verifyKey: public}
+	if private != nil {
+		key.signKey = private
+		key.verifyKey = private.Public().(ed25519.PublicKey)
+	}
+	return key
+}
+
+func loadKey(kc keyFileConfig) (*SigningKey, error) {
+	switch kc.Alg {
+	case "HS256":
+		if len(kc.Secret) < minHMACSecretLength {
+			return nil, fmt.Errorf("HS256 secret must be at least %d bytes", minHMACSecretLength)
+		}
+		return NewHMACKey(kc.KID, []byte(kc.Secret)), nil
+	case "RS256":
+		if kc.PrivateKeyFile != "" {
+			pem, err := os.ReadFile(kc.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			private, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			return NewRSAKey(kc.KID, private, nil), nil
+		}
+		pem, err := os.ReadFile(kc.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		public, err := jwt.ParseRSAPublicKeyFromPEM(pem)
+		if err != nil {
+			return nil, err
+		}
+		return NewRSAKey(kc.KID, nil, public), nil
+	case "EdDSA":
+		if kc.PrivateKeyFile != "" {
+			pem, err := os.ReadFile(kc.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			private, err := jwt.ParseEdPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			return NewEd25519Key(kc.KID, private.(ed25519.PrivateKey), nil), nil
+		}
+		pem, err := os.ReadFile(kc.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		public, err := jwt.ParseEdPublicKeyFromPEM(pem)
+		if err != nil {
+			return nil, err
+		}
+		return NewEd25519Key(kc.KID, nil, public.(ed25519.PublicKey)), nil
+	default:
+		return nil, fmt.Errorf("unsupported alg %q (use HS256, RS256 or EdDSA)", kc.Alg)
+	}
+}
+
+func newEphemeralKeyRing() *KeyRing {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Fatalf("Fatal Error: Could not generate JWT signing key: %v", err)
+	}
+	ring, _ := NewKeyRing("ephemeral", []*SigningKey{NewHMACKey("ephemeral", secret)})
+	return ring
+}
+
+// sign signs claims with the active key and stamps its kid.
+func (kr *KeyRing) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(kr.active.Method, claims)
+	token.Header["kid"] = kr.active.ID
+	return token.SignedString(kr.active.signKey)
+}
+
+// keyFunc selects the verification key by the token's kid and checks the algorithm matches it.
+func (kr *KeyRing) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := kr.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.verifyKey, nil
+}
+
+// validMethods lists the algorithms present in the ring.
+func (kr *KeyRing) validMethods() []string {
+	seen := map[string]bool{}
+	var methods []string
+	for _, key := range kr.keys {
+		if alg := key.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	return methods
+}
+
+// JWK is a JSON Web Key as published in the JWKS document.
+type JWK struct {
+	KID string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // OKP curve
+	X   string `json:"x,omitempty"`   // OKP public key
+}
+
+// PublicJWKs returns the public keys of the ring. HMAC keys are secret and never included.
+func (kr *KeyRing) PublicJWKs() []JWK {
+	jwks := []JWK{}
+	for _, key := range kr.keys {
+		switch pub := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwks = append(jwks, JWK{
+				KID: key.ID, Kty: "RSA", Alg: key.Method.Alg(), Use: "sig",
+				N: base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			jwks = append(jwks, JWK{
+				KID: key.ID, Kty: "OKP", Alg: key.Method.Alg(), Use: "sig",
+				Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	return jwks
+}
+
+// JWKSHandler serves the public verification keys so other services can validate access tokens.
+func JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(map[string][]JWK{"keys": keyRing.PublicJWKs()})
+}
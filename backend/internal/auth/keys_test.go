@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestKeyRing(t *testing.T, activeKID string, keys ...*SigningKey) *KeyRing {
+	t.Helper()
+	ring, err := NewKeyRing(activeKID, keys)
+	if err != nil {
+		t.Fatalf("NewKeyRing() error = %v", err)
+	}
+	return ring
+}
+
+// useKeyRing installs ring for the duration of the test.
+func useKeyRing(t *testing.T, ring *KeyRing) {
+	t.Helper()
+	previous := keyRing
+	SetKeyRing(ring)
+	t.Cleanup(func() { SetKeyRing(previous) })
+}
+
+func authenticate(token string) int {
+	handler := AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestAuthMiddleware_KeyRotation(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey() error = %v", err)
+	}
+	oldHMAC := NewHMACKey("2024-hmac", []byte("0123456789abcdef0123456789abcdef"))
+	rsaSigning := NewRSAKey("2025-rsa", rsaKey, nil)
+	edSigning := NewEd25519Key("2025-ed", edKey, nil)
+
+	// Tokens signed before the rotation.
+	useKeyRing(t, newTestKeyRing(t, "2024-hmac", oldHMAC))
+	oldToken, err := GenerateToken("user-1", "")
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		ring   *KeyRing
+		token  func() string
+		wantOK bool
+	}{
+		{
+			name:   "old key still verifies after rotating to RS256",
+			ring:   newTestKeyRing(t, "2025-rsa", rsaSigning, oldHMAC),
+			token:  func() string { return oldToken },
+			wantOK: true,
+		},
+		{
+			name:   "token signed with the new RS256 key",
+			ring:   newTestKeyRing(t, "2025-rsa", rsaSigning, oldHMAC),
+			token:  func() string { tok, _ := GenerateToken("user-1", ""); return tok },
+			wantOK: true,
+		},
+		{
+			name:   "token signed with EdDSA",
+			ring:   newTestKeyRing(t, "2025-ed", edSigning),
+			token:  func() string { tok, _ := GenerateToken("user-1", ""); return tok },
+			wantOK: true,
+		},
+		{
+			name:  "retired key is rejected",
+			ring:  newTestKeyRing(t, "2025-rsa", rsaSigning),
+			token: func() string { return oldToken },
+		},
+		{
+			name: "wrong audience is rejected",
+			ring: func() *KeyRing {
+				ring := newTestKeyRing(t, "2024-hmac", oldHMAC)
+				ring.Audience = "another_api"
+				return ring
+			}(),
+			token: func() string { return oldToken },
+		},
+		{
+			name: "wrong issuer is rejected",
+			ring: func() *KeyRing {
+				ring := newTestKeyRing(t, "2024-hmac", oldHMAC)
+				ring.Issuer = "someone_else"
+				return ring
+			}(),
+			token: func() string { return oldToken },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useKeyRing(t, tt.ring)
+			got := authenticate(tt.token())
+			if tt.wantOK && got != http.StatusOK {
+				t.Errorf("expected token to be accepted, got status %d", got)
+			}
+			if !tt.wantOK && got == http.StatusOK {
+				t.Error("expected token to be rejected")
+			}
+		})
+	}
+}
+
+func TestJWKSHandler_PublishesOnlyPublicKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+	useKeyRing(t, newTestKeyRing(t, "2025-rsa",
+		NewRSAKey("2025-rsa", rsaKey, nil),
+		NewHMACKey("legacy", []byte("0123456789abcdef0123456789abcdef")),
+	))
+
+	rec := httptest.NewRecorder()
+	JWKSHandler(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+	var body struct {
+		Keys []JWK `json:"keys"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("could not decode JWKS: %v", err)
+	}
+	if len(body.Keys) != 1 || body.Keys[0].KID != "2025-rsa" || body.Keys[0].Kty != "RSA" || body.Keys[0].N == "" {
+		t.Errorf("expected only the RSA public key, got %+v", body.Keys)
+	}
+}
+
+func TestNewKeyRing_RejectsVerifyOnlyActiveKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+	if _, err := NewKeyRing("pub", []*SigningKey{NewRSAKey("pub", nil, &rsaKey.PublicKey)}); err == nil {
+		t.Error("NewKeyRing() should refuse to sign with a public key")
+	}
+}
+
+func TestConfigureKeysFromEnv_RejectsShortSecret(t *testing.T) {
+	t.Setenv("JWT_KEYS_FILE", "")
+	t.Setenv("JWT_SECRET", "too-short-to-be-a-secret")
+	if err := ConfigureKeysFromEnv(); err == nil {
+		t.Error("ConfigureKeysFromEnv() should refuse a JWT_SECRET under 32 bytes")
+	}
+}
@@ -2,7 +2,7 @@ package auth
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"net/http"
 	"strings"
 
@@ -15,6 +15,24 @@ type contextKey string
 // UserIDKey is the key used to store the user ID in the request context.
 const UserIDKey contextKey = "userID"
 
+// SessionIDKey is the key used to store the session ID of the access token in the request context.
+const SessionIDKey contextKey = "sessionID"
+
+// SessionChecker reports whether a server-side session is still active.
+type SessionChecker interface {
+	IsSessionActive(sessionID string) (bool, error)
+}
+
+// sessionChecker is consulted by AuthMiddleware to reject tokens of revoked sessions.
+// When nil, tokens are accepted on signature and expiry alone.
+var sessionChecker SessionChecker
+
+// SetSessionChecker makes AuthMiddleware verify that each token's session is still active.
+// Once set, tokens that are not bound to a session are rejected.
+func SetSessionChecker(checker SessionChecker) {
+	sessionChecker = checker
+}
+
 // Middleware decodes the share session and packs the session into context
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -27,19 +45,25 @@ func AuthMiddleware(next http.Handler) http.Handler {
 		tokenString := authHeader[1]
 		claims := &Claims{}
 
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			// Check the signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return jwtSecret, nil
-		})
+		// The key is selected by the token's kid, and the method must match that key.
+		ring := keyRing
+		token, err := jwt.ParseWithClaims(tokenString, claims, ring.keyFunc,
+			jwt.WithValidMethods(ring.validMethods()),
+			jwt.WithIssuer(ring.Issuer),
+			jwt.WithAudience(ring.Audience),
+			jwt.WithExpirationRequired(),
+		)
 
 		if err != nil {
-			if err == jwt.ErrSignatureInvalid {
+			if errors.Is(err, jwt.ErrTokenSignatureInvalid) {
 				http.Error(w, "Invalid token signature", http.StatusUnauthorized)
 				return
 			}
+			if errors.Is(err, jwt.ErrTokenExpired) {
+				// Access tokens are short-lived; tell the client to use its refresh token.
+				http.Error(w, "Token expired", http.StatusUnauthorized)
+				return
+			}
 			http.Error(w, "Bad token", http.StatusBadRequest)
 			return
 		}
@@ -49,8 +73,25 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		if sessionChecker != nil {
+			if claims.SessionID == "" {
+				http.Error(w, "Token is not bound to a session", http.StatusUnauthorized)
+				return
+			}
+			active, err := sessionChecker.IsSessionActive(claims.SessionID)
+			if err != nil {
+				http.Error(w, "Failed to verify session", http.StatusInternalServerError)
+				return
+			}
+			if !active {
+				http.Error(w, "Session has been revoked", http.StatusUnauthorized)
+				return
+			}
+		}
+
 		// Token is valid, pass down the userID to the next handler
 		ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
+		ctx = context.WithValue(ctx, SessionIDKey, claims.SessionID)
 
 		// And send the request on to the next handler
 		next.ServeHTTP(w, r.WithContext(ctx))
@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Scopes that can be granted to internal services calling back into the API.
+const (
+	ScopeQuestsRead  = "quests:read"
+	ScopeQuestsWrite = "quests:write"
+	ScopeXPGrant     = "xp:grant"
+)
+
+// ServiceGrantHeader is the request header carrying a service grant.
+const ServiceGrantHeader = "X-Service-Grant"
+
+// ServiceGrantKey is the key used to store the verified *ServiceClaims in the request context.
+const ServiceGrantKey contextKey = "serviceGrant"
+
+const serviceGrantAudience = "gamify_journal_services"
+
+var (
+	// ErrInvalidServiceGrant is returned when a grant is missing, malformed, expired or badly signed.
+	ErrInvalidServiceGrant = errors.New("invalid service grant")
+
+	serviceSecret = loadServiceSecret()
+)
+
+// ServiceClaims are the claims of a grant minted for an internal service, such as the AI agents.
+// A grant lets the bearer act on behalf of exactly one user, for a limited set of scopes.
+type ServiceClaims struct {
+	UserID  string   `json:"user_id"`
+	Scopes  []string `json:"scopes"`
+	EntryID string   `json:"entry_id,omitempty"` // Journal entry whose processing triggered the grant, if any
+	jwt.RegisteredClaims
+}
+
+// HasScope reports whether the grant includes the given scope.
+func (c *ServiceClaims) HasScope(scope string) bool {
+	return slices.Contains(c.Scopes, scope)
+}
+
+// loadServiceSecret reads the shared secret used to sign service grants from SERVICE_AUTH_SECRET.
+// Grants are both minted and verified by this API, so when the variable is not set a random
+// per-process secret is used; this only works while a single API instance is running.
+func loadServiceSecret() []byte {
+	if secret := os.Getenv("SERVICE_AUTH_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+	log.Println("Warning: SERVICE_AUTH_SECRET environment variable not set. Using a random per-process secret for service grants.")
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Fatalf("Fatal Error: Could not generate service grant secret: %v", err)
+	}
+	return secret
+}
+
+// IssueServiceGrant mints a short-lived grant allowing an internal service to act on behalf
+// of userID with the given scopes. entryID optionally records the journal entry that triggered it.
+func IssueServiceGrant(userID, entryID string, scopes []string, ttl time.Duration) (string, error) {
+	if userID == "" {
+		return "", fmt.Errorf("%w: user ID is required", ErrInvalidServiceGrant)
+	}
+	now := time.Now()
+	claims := &ServiceClaims{
+		UserID:  userID,
+		Scopes:  scopes,
+		EntryID: entryID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    "gamify_journal",
+			Subject:   userID,
+			Audience:  jwt.ClaimStrings{serviceGrantAudience},
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(serviceSecret)
+}
+
+// VerifyServiceGrant parses and validates a grant minted by IssueServiceGrant.
+func VerifyServiceGrant(grant string) (*ServiceClaims, error) {
+	claims := &ServiceClaims{}
+	token, err := jwt.ParseWithClaims(grant, claims, func(token *jwt.Token) (interface{}, error) {
+		return serviceSecret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithAudience(serviceGrantAudience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || !token.Valid || claims.UserID == "" {
+		return nil, ErrInvalidServiceGrant
+	}
+	return claims, nil
+}
+
+// ServiceMiddleware authenticates internal services through the X-Service-Grant header.
+// The granted user ID is stored under UserIDKey, so handlers can apply the same ownership
+// checks they use for end users, and the full claims are stored under ServiceGrantKey.
+func ServiceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		grant := r.Header.Get(ServiceGrantHeader)
+		if grant == "" {
+			http.Error(w, "Missing service grant", http.StatusUnauthorized)
+			return
+		}
+
+		claims, err := VerifyServiceGrant(grant)
+		if err != nil {
+			http.Error(w, "Invalid service grant", http.StatusUnauthorized)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
+		ctx = context.WithValue(ctx, ServiceGrantKey, claims)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequireScope rejects requests whose service grant does not include scope.
+// It must be mounted after ServiceMiddleware.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := r.Context().Value(ServiceGrantKey).(*ServiceClaims)
+			if !ok || !claims.HasScope(scope) {
+				http.Error(w, "Service grant does not allow this operation", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
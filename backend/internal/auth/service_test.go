@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestVerifyServiceGrant(t *testing.T) {
+	valid, err := IssueServiceGrant("user-1", "doc-1", []string{ScopeXPGrant}, time.Minute)
+	if err != nil {
+		t.Fatalf("IssueServiceGrant() error = %v", err)
+	}
+	expired, err := IssueServiceGrant("user-1", "", []string{ScopeXPGrant}, -time.Minute)
+	if err != nil {
+		t.Fatalf("IssueServiceGrant() error = %v", err)
+	}
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &ServiceClaims{
+		UserID:           "user-1",
+		Scopes:           []string{ScopeXPGrant},
+		RegisteredClaims: jwt.RegisteredClaims{Audience: jwt.ClaimStrings{serviceGrantAudience}, ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))},
+	}).SignedString([]byte("not-the-secret"))
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+	userToken, err := GenerateToken("user-1", "")
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		grant   string
+		wantErr bool
+	}{
+		{name: "valid grant", grant: valid},
+		{name: "expired grant", grant: expired, wantErr: true},
+		{name: "forged signature", grant: forged, wantErr: true},
+		{name: "end-user token", grant: userToken, wantErr: true},
+		{name: "garbage", grant: "not-a-grant", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := VerifyServiceGrant(tt.grant)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifyServiceGrant() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (claims.UserID != "user-1" || claims.EntryID != "doc-1" || !claims.HasScope(ScopeXPGrant)) {
+				t.Errorf("VerifyServiceGrant() returned unexpected claims: %+v", claims)
+			}
+		})
+	}
+}
+
+func TestServiceMiddleware_RequireScope(t *testing.T) {
+	var gotUserID string
+	handler := ServiceMiddleware(RequireScope(ScopeQuestsWrite)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUserID, _ = r.Context().Value(UserIDKey).(string)
+	})))
+
+	tests := []struct {
+		name       string
+		scopes     []string
+		wantStatus int
+	}{
+		{name: "scope granted", scopes: []string{ScopeQuestsRead, ScopeQuestsWrite}, wantStatus: http.StatusOK},
+		{name: "scope missing", scopes: []string{ScopeXPGrant}, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotUserID = ""
+			grant, err := IssueServiceGrant("user-1", "", tt.scopes, time.Minute)
+			if err != nil {
+				t.Fatalf("IssueServiceGrant() error = %v", err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			req.Header.Set(ServiceGrantHeader, grant)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if tt.wantStatus == http.StatusOK && gotUserID != "user-1" {
+				t.Errorf("expected granted user in context, got %q", gotUserID)
+			}
+		})
+	}
+}
@@ -0,0 +1,301 @@
+package character
+
+import (
+	"bytes"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+)
+
+// RuleClassBonus is the adjustment of a grant by the character's class XP multipliers.
+const RuleClassBonus = "class_bonus"
+
+// maxXPMultiplier and maxStatModifier bound the multipliers of classes.
+const (
+	maxXPMultiplier = 5
+	maxStatModifier = 5
+)
+
+//go:embed classes.json
+var defaultClassesConfig []byte
+
+// neutralClass is used for characters whose class is no longer configured. Its attributes
+// are those every character started with before classes had their own.
+var neutralClass = Class{
+	Attributes:     Attributes{Strength: 10, Defense: 10, Vitality: 10, Mana: 10},
+	StartingPoints: 5,
+}
+
+// Attributes are the core attributes of a character.
+type Attributes struct {
+	Strength int `json:"strength"`
+	Defense  int `json:"defense"`
+	Vitality int `json:"vitality"`
+	Mana     int `json:"mana"`
+}
+
+func (a Attributes) total() int {
+	return a.Strength + a.Defense + a.Vitality + a.Mana
+}
+
+// Class defines the mechanics of a character class.
+type Class struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Attributes and StartingPoints are given to new characters of the class.
+	Attributes     Attributes `json:"attributes"`
+	StartingPoints int        `json:"starting_points"`
+	// XPMultipliers scale XP granted for text in the given writing categories.
+	XPMultipliers map[string]float64 `json:"xp_multipliers"`
+	// StatModifiers scale the derived stats of characters of the class; see ComputeStats.
+	StatModifiers map[string]float64 `json:"stat_modifiers,omitempty"`
+	// Rewards are given on reaching a level, on top of the leveling config's rewards.
+	// A class title replaces the title of the leveling config.
+	Rewards map[int]LevelReward `json:"rewards,omitempty"`
+}
+
+// ClassRegistry holds the character classes from the classes config, together with the
+// stat bonuses of the items characters can unlock.
+type ClassRegistry struct {
+	classes   []Class
+	byName    map[string]Class
+	equipment map[string]map[string]int
+	version   string
+}
+
+// DefaultClasses returns the classes built into the server.
+func DefaultClasses() *ClassRegistry {
+	registry, err := parseClasses(defaultClassesConfig, DefaultLeveling().MaxLevel)
+	if err != nil {
+		panic(fmt.Sprintf("built-in classes config: %v", err))
+	}
+	return registry
+}
+
+// ClassesFromEnv loads the classes from the file at CLASSES_CONFIG, or the built-in ones when
+// it is not set. Class rewards must be for levels up to leveling's highest level.
+func ClassesFromEnv(leveling *Leveling) (*ClassRegistry, error) {
+	path := os.Getenv("CLASSES_CONFIG")
+	if path == "" {
+		registry, err := parseClasses(defaultClassesConfig, leveling.MaxLevel)
+		if err != nil {
+			return nil, fmt.Errorf("built-in classes config: %w", err)
+		}
+		return registry, nil
+	}
+	return LoadClasses(path, leveling.MaxLevel)
+}
+
+// LoadClasses reads and validates a classes config file.
+func LoadClasses(path string, maxLevel int) (*ClassRegistry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read classes config: %w", err)
+	}
+	registry, err := parseClasses(data, maxLevel)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return registry, nil
+}
+
+func parseClasses(data []byte, maxLevel int) (*ClassRegistry, error) {
+	var config struct {
+		Classes []Class `json:"classes"`
+		// Equipment maps item names to the stats they add to.
+		Equipment map[string]map[string]int `json:"equipment"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("invalid classes config: %w", err)
+	}
+	if len(config.Classes) == 0 {
+		return nil, fmt.Errorf("at least one class is needed")
+	}
+
+	categories := map[string]bool{}
+	for _, category := range models.WritingCategories {
+		categories[category] = true
+	}
+	for item, bonuses := range config.Equipment {
+		if strings.TrimSpace(item) == "" {
+			return nil, fmt.Errorf("an equipment item has no name")
+		}
+		if err := validateStats(bonuses); err != nil {
+			return nil, fmt.Errorf("equipment %s: %w", item, err)
+		}
+	}
+
+	registry := &ClassRegistry{byName: map[string]Class{}, equipment: config.Equipment}
+	for _, class := range config.Classes {
+		if strings.TrimSpace(class.Name) == "" {
+			return nil, fmt.Errorf("a class has no name")
+		}
+		if _, ok := registry.byName[class.Name]; ok {
+			return nil, fmt.Errorf("class %s is defined twice", class.Name)
+		}
+		a := class.Attributes
+		if a.Strength < 1 || a.Defense < 1 || a.Vitality < 1 || a.Mana < 1 || class.StartingPoints < 0 {
+			return nil, fmt.Errorf("class %s needs attributes of at least 1 and non-negative starting points", class.Name)
+		}
+		for category, multiplier := range class.XPMultipliers {
+			if !categories[category] {
+				return nil, fmt.Errorf("class %s has a multiplier for unknown writing category %q", class.Name, category)
+			}
+			if multiplier < 1 || multiplier > maxXPMultiplier {
+				return nil, fmt.Errorf("class %s has multiplier %g for %s; it must be between 1 and %d", class.Name, multiplier, category, maxXPMultiplier)
+			}
+		}
+		if err := validateStats(class.StatModifiers); err != nil {
+			return nil, fmt.Errorf("class %s: %w", class.Name, err)
+		}
+		for stat, modifier := range class.StatModifiers {
+			if modifier <= 0 || modifier > maxStatModifier {
+				return nil, fmt.Errorf("class %s has modifier %g for %s; it must be above 0 and at most %d", class.Name, modifier, stat, maxStatModifier)
+			}
+		}
+		for level, reward := range class.Rewards {
+			if level < 2 || level > maxLevel {
+				return nil, fmt.Errorf("class %s has a reward for level %d, outside levels 2 to %d", class.Name, level, maxLevel)
+			}
+			if reward.AttributePoints < 0 {
+				return nil, fmt.Errorf("class %s has negative attribute points for level %d", class.Name, level)
+			}
+		}
+		registry.classes = append(registry.classes, class)
+		registry.byName[class.Name] = class
+	}
+	registry.version = classesVersion(registry.classes)
+	return registry, nil
+}
+
+// classesVersion hashes what recomputing a character takes from its class, so characters are
+// recomputed when a new classes config changes it.
+func classesVersion(classes []Class) string {
+	type leveling struct {
+		Name           string              `json:"name"`
+		StartingPoints int                 `json:"starting_points"`
+		Rewards        map[int]LevelReward `json:"rewards"`
+	}
+	var levelings []leveling
+	for _, class := range classes {
+		levelings = append(levelings, leveling{class.Name, class.StartingPoints, class.Rewards})
+	}
+	sort.Slice(levelings, func(i, j int) bool { return levelings[i].Name < levelings[j].Name })
+	data, err := json.Marshal(levelings)
+	if err != nil {
+		panic(fmt.Sprintf("hashing classes: %v", err))
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Get returns the class with the given name.
+func (r *ClassRegistry) Get(name string) (Class, bool) {
+	class, ok := r.byName[name]
+	return class, ok
+}
+
+// Equipment returns the stat bonuses of an item.
+func (r *ClassRegistry) Equipment(item string) (map[string]int, bool) {
+	bonuses, ok := r.equipment[item]
+	return bonuses, ok
+}
+
+// Version identifies the starting points and rewards of the classes. Characters leveled with
+// another version are recomputed like after a new leveling config version.
+func (r *ClassRegistry) Version() string {
+	return r.version
+}
+
+// List returns the classes in the order of the config.
+func (r *ClassRegistry) List() []Class {
+	return append([]Class{}, r.classes...)
+}
+
+// ListClasses returns the classes characters can be created with.
+func (s *Service) ListClasses() []Class {
+	return s.classes.List()
+}
+
+// class returns the class a character belongs to.
+func (s *Service) class(name string) Class {
+	if class, ok := s.classes.Get(name); ok {
+		return class
+	}
+	return neutralClass
+}
+
+// reward returns what a character of the class receives on reaching level.
+func (s *Service) reward(className string, level int) LevelReward {
+	reward := s.leveling.Reward(level)
+	extra, ok := s.class(className).Rewards[level]
+	if !ok {
+		return reward
+	}
+	reward.AttributePoints += extra.AttributePoints
+	if extra.Title != "" {
+		reward.Title = extra.Title
+	}
+	reward.Items = append(append([]string{}, reward.Items...), extra.Items...)
+	reward.Features = append(append([]string{}, reward.Features...), extra.Features...)
+	return reward
+}
+
+// titleAt returns the title of the highest level up to level that grants one to the class.
+func (s *Service) titleAt(className string, level int) string {
+	for lvl := level; lvl >= 2; lvl-- {
+		if title := s.reward(className, lvl).Title; title != "" {
+			return title
+		}
+	}
+	return ""
+}
+
+// classBonus returns the extra XP the class earns on a grant of amount XP for text in the
+// given writing categories. Multipliers do not stack: the highest one applies.
+func (s *Service) classBonus(className string, amount int, categories []string) (int, []models.XPAdjustment) {
+	multiplier, favoured := 1.0, ""
+	for _, category := range categories {
+		if m, ok := s.class(className).XPMultipliers[category]; ok && m > multiplier {
+			multiplier, favoured = m, category
+		}
+	}
+	bonus := int(math.Round(float64(amount) * (multiplier - 1)))
+	if bonus <= 0 {
+		return 0, nil
+	}
+	return bonus, []models.XPAdjustment{{
+		Rule:   RuleClassBonus,
+		Added:  bonus,
+		Reason: fmt.Sprintf("%s bonus for %s (x%g)", className, favoured, multiplier),
+	}}
+}
+
+// normalizeCategories returns the known writing categories among categories, sorted and without duplicates.
+func normalizeCategories(categories []string) []string {
+	known := map[string]bool{}
+	for _, category := range models.WritingCategories {
+		known[category] = true
+	}
+	seen := map[string]bool{}
+	var normalized []string
+	for _, category := range categories {
+		category = strings.ToLower(strings.TrimSpace(category))
+		if known[category] && !seen[category] {
+			seen[category] = true
+			normalized = append(normalized, category)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized
+}
@@ -0,0 +1,109 @@
+package character
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+)
+
+func TestParseClasses(t *testing.T) {
+	if classes := DefaultClasses().List(); len(classes) != 3 {
+		t.Fatalf("built-in classes = %d, want Warrior, Mage and Rogue", len(classes))
+	}
+
+	tests := []struct {
+		name, config, wantErr string
+	}{
+		{"valid", `{"classes": [{"name": "Bard", "attributes": {"strength": 8, "defense": 8, "vitality": 10, "mana": 14}, "xp_multipliers": {"social": 1.5}}]}`, ""},
+		{"no classes", `{"classes": []}`, "at least one class"},
+		{"duplicate", `{"classes": [{"name": "Bard", "attributes": {"strength": 1, "defense": 1, "vitality": 1, "mana": 1}}, {"name": "Bard", "attributes": {"strength": 1, "defense": 1, "vitality": 1, "mana": 1}}]}`, "defined twice"},
+		{"missing attributes", `{"classes": [{"name": "Bard"}]}`, "attributes of at least 1"},
+		{"unknown category", `{"classes": [{"name": "Bard", "attributes": {"strength": 1, "defense": 1, "vitality": 1, "mana": 1}, "xp_multipliers": {"music": 1.5}}]}`, "unknown writing category"},
+		{"penalty multiplier", `{"classes": [{"name": "Bard", "attributes": {"strength": 1, "defense": 1, "vitality": 1, "mana": 1}, "xp_multipliers": {"social": 0.5}}]}`, "between 1 and"},
+		{"unknown stat", `{"classes": [{"name": "Bard", "attributes": {"strength": 1, "defense": 1, "vitality": 1, "mana": 1}, "stat_modifiers": {"luck": 1.5}}]}`, "unknown stat"},
+		{"unknown equipment stat", `{"classes": [{"name": "Bard", "attributes": {"strength": 1, "defense": 1, "vitality": 1, "mana": 1}}], "equipment": {"Lute": {"charm": 3}}}`, "unknown stat"},
+		{"reward beyond max level", `{"classes": [{"name": "Bard", "attributes": {"strength": 1, "defense": 1, "vitality": 1, "mana": 1}, "rewards": {"20": {"title": "Minstrel"}}}]}`, "outside levels"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseClasses([]byte(tt.config), 10)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("parseClasses() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("parseClasses() error = %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestService_ClassMechanics(t *testing.T) {
+	svc, mage := newTestCharacter(t)
+	mageClass, _ := svc.classes.Get(string(models.Mage))
+	if mage.Mana != mageClass.Attributes.Mana || mage.Strength != mageClass.Attributes.Strength || mage.AttributePoints != mageClass.StartingPoints {
+		t.Errorf("new Mage has mana %d, strength %d and %d points; want the class's starting spread", mage.Mana, mage.Strength, mage.AttributePoints)
+	}
+	if _, err := svc.CreateCharacter(CreateCharacterInput{UserID: "user-1", Name: "Bob", Class: "Bard"}); err == nil {
+		t.Error("CreateCharacter() accepted a class that is not configured")
+	}
+
+	// Reflection earns the Mage a 25% bonus; the higher multiplier applies, they do not stack.
+	source := models.XPSource{Type: models.XPSourceAgent, Categories: []string{"Learning", "reflection", "fitness"}}
+	grant, err := svc.GrantXP(mage.ID, 40, source)
+	if err != nil {
+		t.Fatalf("GrantXP() error = %v", err)
+	}
+	if grant.Requested != 40 || grant.Granted != 50 || len(grant.Adjustments) != 1 || grant.Adjustments[0].Rule != RuleClassBonus || grant.Adjustments[0].Added != 10 || grant.Adjustments[0].Removed != 0 {
+		t.Errorf("grant = %+v, want 50 XP with a class bonus of 10", grant)
+	}
+
+	// A Warrior gets nothing extra for the same text.
+	warrior, err := svc.CreateCharacter(CreateCharacterInput{UserID: "user-1", Name: "Conan", Class: models.Warrior})
+	if err != nil {
+		t.Fatalf("CreateCharacter() error = %v", err)
+	}
+	grant, err = svc.GrantXP(warrior.ID, 40, models.XPSource{Type: models.XPSourceAgent, Categories: []string{"reflection"}})
+	if err != nil {
+		t.Fatalf("GrantXP() error = %v", err)
+	}
+	if grant.Granted != 40 || len(grant.Adjustments) != 0 {
+		t.Errorf("grant = %+v, want 40 XP without a bonus", grant)
+	}
+
+	// Class rewards come on top of the leveling config's.
+	grant, err = svc.GrantXP(warrior.ID, 1000, models.XPSource{Type: models.XPSourceQuest})
+	if err != nil {
+		t.Fatalf("GrantXP() error = %v", err)
+	}
+	var items []string
+	for _, levelUp := range grant.LevelUps {
+		items = append(items, levelUp.Reward.Items...)
+	}
+	if len(items) != 1 || items[0] != "Iron Gauntlets" {
+		t.Errorf("items = %v, want the Warrior's Iron Gauntlets at level 5", items)
+	}
+}
+
+func TestService_ReadsClassBonusesOfOlderLedgerEntries(t *testing.T) {
+	svc, mage := newTestCharacter(t)
+	db := svc.store.(*Store).db
+	// Before Added existed, a bonus was recorded as negative removed XP.
+	if err := db.Create(&models.XPTransaction{ID: "tx-1", CharacterID: mage.ID, UserID: mage.UserID, Amount: 50, RequestedAmount: 40, SourceType: models.XPSourceAgent}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&models.XPTransaction{}).Where("id = ?", "tx-1").Update("adjustments", `[{"rule":"class_bonus","removed":-10,"reason":"Mage bonus"}]`).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	transactions, _, err := svc.store.ListXPTransactions(mage.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("ListXPTransactions() error = %v", err)
+	}
+	if len(transactions) != 1 || len(transactions[0].Adjustments) != 1 {
+		t.Fatalf("transactions = %+v, want the one with a class bonus", transactions)
+	}
+	if got := transactions[0].Adjustments[0]; got.Added != 10 || got.Removed != 0 {
+		t.Errorf("adjustment = %+v, want 10 XP added and none removed", got)
+	}
+}
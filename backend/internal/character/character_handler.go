@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/adrianvalentim/gamify_journal/internal/auth"
 	"github.com/adrianvalentim/gamify_journal/internal/models" // Project specific models
@@ -16,7 +17,12 @@ import (
 type ICharacterService interface {
 	CreateCharacter(input CreateCharacterInput) (*models.Character, error)
 	GetCharacterByUserID(userID string) (*models.Character, error)
-	GrantXP(characterID string, amount int) (char *models.Character, leveledUp bool, err error)
+	GrantXP(characterID string, amount int, source models.XPSource) (*XPGrant, error)
+	GetXPHistory(characterID string, limit, offset, days int) (*XPHistory, error)
+	GetLevelProgress(characterID string, next int) (*LevelProgress, error)
+	RecomputeLevels() (int, error)
+	ListClasses() []Class
+	CharacterStats(char *models.Character) (Stats, error)
 	GetCharacter(characterID string) (*models.Character, error) // Added for GrantXP consistency
 	SpendAttributePoints(characterID string, input SpendAttributePointsInput) (*models.Character, error)
 }
@@ -37,23 +43,47 @@ func (h *Handler) RegisterRoutes(r chi.Router) {
 			r.Use(auth.AuthMiddleware)
 			r.Post("/", h.handleCreateCharacter) // POST /api/v1/characters
 			r.Get("/me", h.handleGetMyCharacter) // GET /api/v1/characters/me
+			r.Get("/me/xp-history", h.handleGetMyXPHistory) // GET /api/v1/characters/me/xp-history
+			r.Get("/me/levels", h.handleGetMyLevels) // GET /api/v1/characters/me/levels
 			r.Post("/me/spend-points", h.handleSpendAttributePoints) // POST /api/v1/characters/me/spend-points
 		})
 
 		// Public or other character routes
+		r.Get("/classes", h.handleListClasses)                // GET /api/v1/characters/classes
 		r.Get("/user/{userID}", h.handleGetCharacterByUserID) // GET /api/v1/characters/user/{userID}
 		r.Get("/{characterID}", h.handleGetCharacterByID)     // GET /api/v1/characters/{characterID}
-		r.Post("/{characterID}/grant-xp", h.handleGrantXP)    // POST /api/v1/characters/{characterID}/grant-xp
+
+		// AI Service route, authorized by a grant bound to the character's owner
+		r.With(auth.ServiceMiddleware, auth.RequireScope(auth.ScopeXPGrant)).
+			Post("/{characterID}/grant-xp", h.handleGrantXP) // POST /api/v1/characters/{characterID}/grant-xp
 	})
 
+	// Moves every character to the current leveling config; this also runs at startup.
+	r.With(auth.AuthMiddleware, auth.RequireAdmin).
+		Post("/admin/characters/recompute-levels", h.handleRecomputeLevels)
+
 	// This route seems misplaced, let's keep it separate for now if it serves a unique purpose.
 	// It's better to have a more RESTful approach like POST /characters/{characterID}/xp
-	r.Post("/users/{userID}/character/xp", h.handleGrantXPByUserID)
+	r.With(auth.ServiceMiddleware, auth.RequireScope(auth.ScopeXPGrant)).
+		Post("/users/{userID}/character/xp", h.handleGrantXPByUserID)
 }
 
 // GrantXPInput defines the expected JSON payload for the grant XP endpoint.
 type GrantXPInput struct {
-	Amount int `json:"xp_amount"`
+	Amount int    `json:"xp_amount"`
+	Reason string `json:"reason"` // Why the agent awarded the XP; recorded in the XP ledger
+	Text   string `json:"text"`   // The journal text the XP is for; checked by the XP rules
+	// Categories are the writing categories the agent classified the text in; classes earn more XP in some.
+	Categories []string `json:"categories"`
+}
+
+// agentXPSource describes an XP grant made through a service grant, for the XP ledger.
+func agentXPSource(r *http.Request, input GrantXPInput) models.XPSource {
+	source := models.XPSource{Type: models.XPSourceAgent, Reason: input.Reason, Text: input.Text, Categories: input.Categories}
+	if claims, ok := r.Context().Value(auth.ServiceGrantKey).(*auth.ServiceClaims); ok {
+		source.ID = claims.EntryID
+	}
+	return source
 }
 
 type createCharacterRequest struct {
@@ -157,7 +187,19 @@ func (h *Handler) handleGrantXP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	char, leveledUp, err := h.service.GrantXP(characterIDStr, input.Amount)
+	// The grant only covers its own user's character; anything else looks like a missing character.
+	grantedUserID, _ := r.Context().Value(auth.UserIDKey).(string)
+	target, err := h.service.GetCharacter(characterIDStr)
+	if err != nil || target.UserID != grantedUserID {
+		if err == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, `{"error": "Character not found"}`, http.StatusNotFound)
+			return
+		}
+		http.Error(w, `{"error": "Failed to retrieve character before granting XP"}`, http.StatusInternalServerError)
+		return
+	}
+
+	grant, err := h.service.GrantXP(characterIDStr, input.Amount, agentXPSource(r, input))
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			http.Error(w, `{"error": "Character not found"}`, http.StatusNotFound)
@@ -167,12 +209,15 @@ func (h *Handler) handleGrantXP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The character is returned as before, with a report of how the XP rules adjusted the grant.
 	response := struct {
 		*models.Character
-		LeveledUp bool `json:"leveled_up"`
+		LeveledUp bool     `json:"leveled_up"`
+		Grant     *XPGrant `json:"xp_grant"`
 	}{
-		Character: char,
-		LeveledUp: leveledUp,
+		Character: grant.Character,
+		LeveledUp: grant.LeveledUp,
+		Grant:     grant,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -188,6 +233,12 @@ func (h *Handler) handleGrantXPByUserID(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	grantedUserID, _ := r.Context().Value(auth.UserIDKey).(string)
+	if userIDStr != grantedUserID {
+		http.Error(w, `{"error": "Character not found for this user"}`, http.StatusNotFound)
+		return
+	}
+
 	var input GrantXPInput
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 		http.Error(w, "Invalid request payload: "+err.Error(), http.StatusBadRequest)
@@ -209,7 +260,7 @@ func (h *Handler) handleGrantXPByUserID(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	char, leveledUp, err := h.service.GrantXP(character.ID, input.Amount)
+	grant, err := h.service.GrantXP(character.ID, input.Amount, agentXPSource(r, input))
 	if err != nil {
 		// This should be rare if the character was just fetched, but handle it
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -222,10 +273,12 @@ func (h *Handler) handleGrantXPByUserID(w http.ResponseWriter, r *http.Request)
 
 	response := struct {
 		*models.Character
-		LeveledUp bool `json:"leveled_up"`
+		LeveledUp bool     `json:"leveled_up"`
+		Grant     *XPGrant `json:"xp_grant"`
 	}{
-		Character: char,
-		LeveledUp: leveledUp,
+		Character: grant.Character,
+		LeveledUp: grant.LeveledUp,
+		Grant:     grant,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -249,9 +302,123 @@ func (h *Handler) handleGetMyCharacter(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	stats, err := h.service.CharacterStats(character)
+	if err != nil {
+		http.Error(w, "Failed to get character", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(character)
+	json.NewEncoder(w).Encode(characterWithStats{Character: character, Stats: stats})
+}
+
+// characterWithStats is a character together with its derived stats.
+type characterWithStats struct {
+	*models.Character
+	Stats Stats `json:"stats"`
+}
+
+// handleGetMyXPHistory returns a page of the XP ledger of the authenticated user's character.
+// Query parameters: limit and offset page through the transactions; days sets how many days
+// the per-day aggregation covers.
+func (h *Handler) handleGetMyXPHistory(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	params := map[string]int{"limit": DefaultXPHistoryLimit, "offset": 0, "days": DefaultXPHistoryDays}
+	maxima := map[string]int{"limit": MaxXPHistoryLimit, "days": MaxXPHistoryDays}
+	for name := range params {
+		value := r.URL.Query().Get(name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 || (name != "offset" && n == 0) || (maxima[name] > 0 && n > maxima[name]) {
+			http.Error(w, "invalid value for "+name, http.StatusBadRequest)
+			return
+		}
+		params[name] = n
+	}
+
+	char, err := h.service.GetCharacterByUserID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, `{"error": "Character not found for this user"}`, http.StatusNotFound)
+			return
+		}
+		http.Error(w, `{"error": "Failed to retrieve character"}`, http.StatusInternalServerError)
+		return
+	}
+
+	history, err := h.service.GetXPHistory(char.ID, params["limit"], params["offset"], params["days"])
+	if err != nil {
+		http.Error(w, `{"error": "Failed to retrieve XP history"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+// handleListClasses lists the classes characters can be created with.
+func (h *Handler) handleListClasses(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.service.ListClasses())
+}
+
+// handleGetMyLevels returns the XP the authenticated user's character needs for its next levels.
+// The query parameter next sets how many levels are listed.
+func (h *Handler) handleGetMyLevels(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	next := DefaultLevelsAhead
+	if value := r.URL.Query().Get("next"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > MaxLevelsAhead {
+			http.Error(w, "invalid value for next", http.StatusBadRequest)
+			return
+		}
+		next = n
+	}
+
+	char, err := h.service.GetCharacterByUserID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, `{"error": "Character not found for this user"}`, http.StatusNotFound)
+			return
+		}
+		http.Error(w, `{"error": "Failed to retrieve character"}`, http.StatusInternalServerError)
+		return
+	}
+
+	progress, err := h.service.GetLevelProgress(char.ID, next)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to retrieve level progress"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(progress)
+}
+
+// handleRecomputeLevels recomputes the characters leveled with another leveling config version.
+func (h *Handler) handleRecomputeLevels(w http.ResponseWriter, r *http.Request) {
+	recomputed, err := h.service.RecomputeLevels()
+	if err != nil {
+		http.Error(w, `{"error": "Failed to recompute character levels"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"recomputed": recomputed})
 }
 
 func (h *Handler) handleSpendAttributePoints(w http.ResponseWriter, r *http.Request) {
@@ -280,12 +447,23 @@ func (h *Handler) handleSpendAttributePoints(w http.ResponseWriter, r *http.Requ
 
 	updatedChar, err := h.service.SpendAttributePoints(char.ID, input)
 	if err != nil {
-		// Basic error handling, can be improved to check for validation errors
+		var validationErr *ValidationError
+		if errors.As(err, &validationErr) {
+			http.Error(w, "Failed to spend attribute points: "+err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, "Failed to spend attribute points: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	// The new stats show what the points changed.
+	stats, err := h.service.CharacterStats(updatedChar)
+	if err != nil {
+		http.Error(w, "Failed to get character stats", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(updatedChar)
+	json.NewEncoder(w).Encode(characterWithStats{Character: updatedChar, Stats: stats})
 }
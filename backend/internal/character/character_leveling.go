@@ -0,0 +1,412 @@
+package character
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/google/uuid"
+)
+
+// Leveling curves, as set in Curve.Type.
+const (
+	CurveLinear      = "linear"
+	CurveQuadratic   = "quadratic"
+	CurveExponential = "exponential"
+	CurveTable       = "table"
+)
+
+// Limits of the endpoint listing the next levels.
+const (
+	DefaultLevelsAhead = 5
+	MaxLevelsAhead     = 50
+)
+
+// maxLevelXP bounds the XP a single level may require.
+const maxLevelXP = 1 << 30
+
+//go:embed leveling.json
+var defaultLevelingConfig []byte
+
+// legacyLeveling is how characters leveled before the leveling config existed.
+// Characters with LevelingVersion 0 are converted from it.
+var legacyLeveling = &Leveling{
+	MaxLevel:      100,
+	Curve:         Curve{Type: CurveLinear, Base: 100, Growth: 100},
+	DefaultReward: LevelReward{AttributePoints: 5},
+}
+
+// Curve defines the XP needed to go from one level to the next.
+type Curve struct {
+	Type string `json:"type"`
+	// Base is the XP from level 1 to 2. For level L, linear curves add Growth*(L-1),
+	// quadratic curves add Growth*(L-1)² and exponential curves multiply by Growth^(L-1).
+	Base   int     `json:"base"`
+	Growth float64 `json:"growth"`
+	// Table lists the XP from level i+1 to i+2 for table curves.
+	Table []int `json:"table"`
+}
+
+// LevelReward is what a character receives on reaching a level.
+type LevelReward struct {
+	AttributePoints int      `json:"attribute_points,omitempty"`
+	Title           string   `json:"title,omitempty"`
+	Items           []string `json:"items,omitempty"`
+	Features        []string `json:"features,omitempty"` // Names of features the level unlocks
+}
+
+// Leveling is the versioned leveling config: the XP curve, the highest level and the rewards.
+// Bump Version whenever the curve or the rewards change, so characters are recomputed. Changes
+// to the rewards of classes are detected without a version; see ClassRegistry.Version.
+type Leveling struct {
+	Version  int   `json:"version"`
+	MaxLevel int   `json:"max_level"`
+	Curve    Curve `json:"curve"`
+	// DefaultReward is given on every level up; it may only grant attribute points.
+	DefaultReward LevelReward `json:"default_reward"`
+	// Rewards are given on reaching a level, on top of DefaultReward.
+	Rewards map[int]LevelReward `json:"rewards"`
+}
+
+// DefaultLeveling returns the leveling config built into the server.
+func DefaultLeveling() *Leveling {
+	leveling, err := parseLeveling(defaultLevelingConfig)
+	if err != nil {
+		panic(fmt.Sprintf("built-in leveling config: %v", err))
+	}
+	return leveling
+}
+
+// LevelingFromEnv loads the leveling config from the file at LEVELING_CONFIG,
+// or returns the built-in one when it is not set.
+func LevelingFromEnv() (*Leveling, error) {
+	path := os.Getenv("LEVELING_CONFIG")
+	if path == "" {
+		return DefaultLeveling(), nil
+	}
+	return LoadLeveling(path)
+}
+
+// LoadLeveling reads and validates a leveling config file.
+func LoadLeveling(path string) (*Leveling, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read leveling config: %w", err)
+	}
+	leveling, err := parseLeveling(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return leveling, nil
+}
+
+func parseLeveling(data []byte) (*Leveling, error) {
+	var leveling Leveling
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&leveling); err != nil {
+		return nil, fmt.Errorf("invalid leveling config: %w", err)
+	}
+	if err := leveling.Validate(); err != nil {
+		return nil, err
+	}
+	return &leveling, nil
+}
+
+// Validate checks that the curve is well formed for every level and that rewards
+// are given for levels that exist.
+func (l *Leveling) Validate() error {
+	if l.Version < 1 {
+		return fmt.Errorf("version must be at least 1")
+	}
+	if l.MaxLevel < 2 {
+		return fmt.Errorf("max_level must be at least 2")
+	}
+
+	c := l.Curve
+	switch c.Type {
+	case CurveLinear, CurveQuadratic:
+		if c.Base <= 0 || c.Growth < 0 {
+			return fmt.Errorf("%s curve needs a positive base and a growth of at least 0", c.Type)
+		}
+	case CurveExponential:
+		if c.Base <= 0 || c.Growth < 1 {
+			return fmt.Errorf("exponential curve needs a positive base and a growth of at least 1")
+		}
+	case CurveTable:
+		if len(c.Table) < l.MaxLevel-1 {
+			return fmt.Errorf("table curve lists %d levels, max_level %d needs %d", len(c.Table), l.MaxLevel, l.MaxLevel-1)
+		}
+	default:
+		return fmt.Errorf("unknown curve type %q", c.Type)
+	}
+	for level := 1; level < l.MaxLevel; level++ {
+		if xp := l.curveXP(level); xp < 1 || xp > maxLevelXP {
+			return fmt.Errorf("level %d needs %.0f XP; it must be between 1 and %d", level+1, xp, maxLevelXP)
+		}
+	}
+
+	d := l.DefaultReward
+	if d.AttributePoints < 0 || d.Title != "" || len(d.Items) > 0 || len(d.Features) > 0 {
+		return fmt.Errorf("default_reward may only grant a non-negative number of attribute points")
+	}
+	for level, reward := range l.Rewards {
+		if level < 2 || level > l.MaxLevel {
+			return fmt.Errorf("reward for level %d is outside levels 2 to %d", level, l.MaxLevel)
+		}
+		if reward.AttributePoints < 0 {
+			return fmt.Errorf("reward for level %d has negative attribute points", level)
+		}
+		for _, name := range append(append([]string{}, reward.Items...), reward.Features...) {
+			if name == "" {
+				return fmt.Errorf("reward for level %d has an empty item or feature", level)
+			}
+		}
+	}
+	return nil
+}
+
+// curveXP is the XP from level to level+1 as the curve defines it, before rounding.
+func (l *Leveling) curveXP(level int) float64 {
+	c, steps := l.Curve, float64(level-1)
+	switch c.Type {
+	case CurveLinear:
+		return float64(c.Base) + c.Growth*steps
+	case CurveQuadratic:
+		return float64(c.Base) + c.Growth*steps*steps
+	case CurveExponential:
+		return math.Round(float64(c.Base) * math.Pow(c.Growth, steps))
+	case CurveTable:
+		if level-1 < len(c.Table) {
+			return float64(c.Table[level-1])
+		}
+	}
+	return 0
+}
+
+// XPToNext returns the XP needed to go from level to the next one, or 0 at the highest level.
+func (l *Leveling) XPToNext(level int) int {
+	if level >= l.MaxLevel {
+		return 0
+	}
+	return int(l.curveXP(level))
+}
+
+// Reward returns what a character receives on reaching level.
+func (l *Leveling) Reward(level int) LevelReward {
+	reward := l.Rewards[level]
+	reward.AttributePoints += l.DefaultReward.AttributePoints
+	return reward
+}
+
+// TotalXPFor returns all the XP a character at level with xp towards the next one has earned.
+func (l *Leveling) TotalXPFor(level, xp int) int {
+	total := xp
+	for lvl := 1; lvl < level && lvl < l.MaxLevel; lvl++ {
+		total += l.XPToNext(lvl)
+	}
+	return total
+}
+
+// levelFor returns the level reached with totalXP and the XP left towards the next level.
+// XP keeps accumulating at the highest level.
+func (l *Leveling) levelFor(totalXP int) (level, xp int) {
+	level, xp = 1, totalXP
+	for level < l.MaxLevel && xp >= l.XPToNext(level) {
+		xp -= l.XPToNext(level)
+		level++
+	}
+	return level, xp
+}
+
+// LevelUp is a level a character reached and the reward it received.
+type LevelUp struct {
+	Level  int         `json:"level"`
+	Reward LevelReward `json:"reward"`
+}
+
+// levelUp raises the character's level while it has the XP for the next one and gives it
+// the rewards of every level reached. Titles, items and features are recorded by store.
+func (s *Service) levelUp(store ICharacterStore, char *models.Character) ([]LevelUp, error) {
+	var levelUps []LevelUp
+	for char.Level < s.leveling.MaxLevel && char.XP >= s.leveling.XPToNext(char.Level) {
+		char.XP -= s.leveling.XPToNext(char.Level)
+		char.Level++
+		reward := s.reward(char.Class, char.Level)
+		char.AttributePoints += reward.AttributePoints
+		if reward.Title != "" {
+			char.Title = reward.Title
+		}
+		levelUps = append(levelUps, LevelUp{Level: char.Level, Reward: reward})
+	}
+	if len(levelUps) == 0 {
+		return nil, nil
+	}
+	return levelUps, store.CreateCharacterRewards(s.rewardRows(char, levelUps[0].Level, char.Level))
+}
+
+// rewardRows returns the titles, items and features of the levels from..to as rewards of char.
+func (s *Service) rewardRows(char *models.Character, from, to int) []models.CharacterReward {
+	var rows []models.CharacterReward
+	add := func(level int, kind, value string) {
+		rows = append(rows, models.CharacterReward{
+			ID: uuid.NewString(), CharacterID: char.ID, UserID: char.UserID,
+			Level: level, Kind: kind, Value: value, CreatedAt: s.now(),
+		})
+	}
+	for level := from; level <= to; level++ {
+		reward := s.reward(char.Class, level)
+		if reward.Title != "" {
+			add(level, models.RewardTitle, reward.Title)
+		}
+		for _, item := range reward.Items {
+			add(level, models.RewardItem, item)
+		}
+		for _, feature := range reward.Features {
+			add(level, models.RewardFeature, feature)
+		}
+	}
+	return rows
+}
+
+// recompute moves a character to the current leveling and classes config, keeping all the XP it earned.
+// Attribute points are those of its new level minus the points already spent, counted from
+// the attributes it started with, but never negative; titles, items and features it unlocked
+// before are kept.
+func (s *Service) recompute(store ICharacterStore, char *models.Character) error {
+	legacy := char.LevelingVersion == 0
+	if legacy {
+		char.TotalXP = legacyLeveling.TotalXPFor(char.Level, char.XP)
+	}
+	char.Level, char.XP = s.leveling.levelFor(char.TotalXP)
+
+	// Characters from before the leveling config also predate classes, and all started like
+	// neutralClass. Those created since, before their starting attributes were recorded,
+	// started like their class.
+	start := s.class(char.Class)
+	if legacy {
+		start = neutralClass
+	}
+	if char.StartingAttributes == 0 {
+		char.StartingAttributes = start.Attributes.total()
+	}
+	earned := start.StartingPoints
+	for level := 2; level <= char.Level; level++ {
+		earned += s.reward(char.Class, level).AttributePoints
+	}
+	spent := char.Strength + char.Defense + char.Vitality + char.Mana - char.StartingAttributes
+	if spent < 0 {
+		spent = 0
+	}
+	char.AttributePoints = earned - spent
+	if char.AttributePoints < 0 {
+		char.AttributePoints = 0
+	}
+
+	char.Title = s.titleAt(char.Class, char.Level)
+	char.LevelingVersion = s.leveling.Version
+	char.ClassesVersion = s.classes.Version()
+	return store.CreateCharacterRewards(s.rewardRows(char, 2, char.Level))
+}
+
+// outdated reports whether char was leveled with another version of the leveling or classes config.
+func (s *Service) outdated(char *models.Character) bool {
+	return char.LevelingVersion != s.leveling.Version || char.ClassesVersion != s.classes.Version()
+}
+
+// RecomputeLevels moves every character leveled with another version of the leveling config
+// to the current one, and returns how many characters it changed.
+func (s *Service) RecomputeLevels() (int, error) {
+	ids, err := s.store.OutdatedCharacterIDs(s.leveling.Version, s.classes.Version())
+	if err != nil {
+		return 0, err
+	}
+	recomputed := 0
+	for _, id := range ids {
+		err := s.store.Transaction(func(store ICharacterStore) error {
+			char, err := store.LockCharacterByID(id)
+			if err != nil {
+				return err
+			}
+			if !s.outdated(char) {
+				return nil // Recomputed by a grant in the meantime
+			}
+			if err := s.recompute(store, char); err != nil {
+				return err
+			}
+			recomputed++
+			return store.UpdateCharacter(char)
+		})
+		if err != nil {
+			return recomputed, fmt.Errorf("could not recompute character %s: %w", id, err)
+		}
+	}
+	return recomputed, nil
+}
+
+// LevelStep is one of the levels ahead of a character.
+type LevelStep struct {
+	Level       int         `json:"level"`
+	XPRequired  int         `json:"xp_required"`  // XP from the level before
+	XPRemaining int         `json:"xp_remaining"` // XP the character still needs to reach the level
+	Reward      LevelReward `json:"reward"`
+}
+
+// LevelProgress is where a character stands on the leveling curve.
+type LevelProgress struct {
+	Version  int                      `json:"version"`
+	Level    int                      `json:"level"`
+	XP       int                      `json:"xp"`
+	TotalXP  int                      `json:"total_xp"`
+	MaxLevel int                      `json:"max_level"`
+	Next     []LevelStep              `json:"next"`     // Empty at the highest level
+	Unlocked []models.CharacterReward `json:"unlocked"` // Titles, items and features unlocked so far
+}
+
+// GetLevelProgress returns the XP a character needs for each of its next levels,
+// up to the highest level.
+func (s *Service) GetLevelProgress(characterID string, next int) (*LevelProgress, error) {
+	if next <= 0 {
+		next = DefaultLevelsAhead
+	}
+	if next > MaxLevelsAhead {
+		next = MaxLevelsAhead
+	}
+
+	char, err := s.store.GetCharacterByID(characterID)
+	if err != nil {
+		return nil, err
+	}
+	unlocked, err := s.store.ListCharacterRewards(characterID)
+	if err != nil {
+		return nil, err
+	}
+	if unlocked == nil {
+		unlocked = []models.CharacterReward{}
+	}
+
+	progress := &LevelProgress{
+		Version:  s.leveling.Version,
+		Level:    char.Level,
+		XP:       char.XP,
+		TotalXP:  char.TotalXP,
+		MaxLevel: s.leveling.MaxLevel,
+		Next:     []LevelStep{},
+		Unlocked: unlocked,
+	}
+	remaining := -char.XP
+	for level := char.Level + 1; level <= s.leveling.MaxLevel && len(progress.Next) < next; level++ {
+		required := s.leveling.XPToNext(level - 1)
+		remaining += required
+		step := LevelStep{Level: level, XPRequired: required, XPRemaining: remaining, Reward: s.reward(char.Class, level)}
+		if step.XPRemaining < 0 {
+			step.XPRemaining = 0
+		}
+		progress.Next = append(progress.Next, step)
+	}
+	return progress, nil
+}
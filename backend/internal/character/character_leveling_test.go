@@ -0,0 +1,176 @@
+package character
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+)
+
+func TestLeveling_XPToNext(t *testing.T) {
+	tests := []struct {
+		curve Curve
+		want  []int // XP from level 1 to 2, 2 to 3, ...
+	}{
+		{Curve{Type: CurveLinear, Base: 100, Growth: 100}, []int{100, 200, 300, 400}},
+		{Curve{Type: CurveQuadratic, Base: 50, Growth: 25}, []int{50, 75, 150, 275}},
+		{Curve{Type: CurveExponential, Base: 100, Growth: 1.5}, []int{100, 150, 225, 338}},
+		{Curve{Type: CurveTable, Table: []int{10, 20, 40, 80}}, []int{10, 20, 40, 80}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.curve.Type, func(t *testing.T) {
+			leveling := &Leveling{Version: 1, MaxLevel: 5, Curve: tt.curve}
+			if err := leveling.Validate(); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			for i, want := range tt.want {
+				if got := leveling.XPToNext(i + 1); got != want {
+					t.Errorf("XPToNext(%d) = %d, want %d", i+1, got, want)
+				}
+			}
+			if got := leveling.XPToNext(5); got != 0 {
+				t.Errorf("XPToNext(max level) = %d, want 0", got)
+			}
+		})
+	}
+}
+
+func TestLoadLeveling(t *testing.T) {
+	if err := DefaultLeveling().Validate(); err != nil {
+		t.Fatalf("built-in leveling config is invalid: %v", err)
+	}
+
+	tests := []struct {
+		name, config, wantErr string
+	}{
+		{"valid", `{"version": 2, "max_level": 3, "curve": {"type": "table", "table": [10, 20]}, "rewards": {"3": {"title": "Knight"}}}`, ""},
+		{"missing version", `{"max_level": 3, "curve": {"type": "linear", "base": 10}}`, "version"},
+		{"unknown field", `{"version": 1, "max_level": 3, "curve": {"type": "linear", "base": 10}, "bonus": 1}`, "unknown field"},
+		{"unknown curve", `{"version": 1, "max_level": 3, "curve": {"type": "cubic", "base": 10}}`, "unknown curve type"},
+		{"short table", `{"version": 1, "max_level": 4, "curve": {"type": "table", "table": [10, 20]}}`, "table curve"},
+		{"shrinking exponential", `{"version": 1, "max_level": 3, "curve": {"type": "exponential", "base": 10, "growth": 0.5}}`, "growth of at least 1"},
+		{"overflowing curve", `{"version": 1, "max_level": 100, "curve": {"type": "exponential", "base": 100, "growth": 2}}`, "must be between"},
+		{"reward beyond max level", `{"version": 1, "max_level": 3, "curve": {"type": "linear", "base": 10}, "rewards": {"4": {"title": "Knight"}}}`, "outside levels"},
+		{"title for every level", `{"version": 1, "max_level": 3, "curve": {"type": "linear", "base": 10}, "default_reward": {"title": "Knight"}}`, "default_reward"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "leveling.json")
+			if err := os.WriteFile(path, []byte(tt.config), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadLeveling(path)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("LoadLeveling() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("LoadLeveling() error = %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestService_RecomputeLevels(t *testing.T) {
+	svc, char := newTestCharacter(t)
+	db := svc.store.(*Store).db
+
+	// A level 3 character from before the leveling config and classes (100 + 200 + 50 XP
+	// earned) that spent 3 attribute points on strength.
+	legacy := map[string]interface{}{
+		"level": 3, "experience_points": 50, "attribute_points": 12, "leveling_version": 0, "starting_attributes": 0,
+		"strength": 13, "defense": 10, "vitality": 10, "mana": 10,
+	}
+	if err := db.Model(&models.Character{}).Where("id = ?", char.ID).Updates(legacy).Error; err != nil {
+		t.Fatal(err)
+	}
+	// Mages start stronger than characters did before classes, which must not count as spent points.
+	classes, err := parseClasses(bytes.Replace(defaultClassesConfig, []byte(`"strength": 6,`), []byte(`"strength": 16,`), 1), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc.classes = classes
+
+	svc.leveling = &Leveling{
+		Version:       2,
+		MaxLevel:      6,
+		Curve:         Curve{Type: CurveExponential, Base: 50, Growth: 2},
+		DefaultReward: LevelReward{AttributePoints: 3},
+		Rewards:       map[int]LevelReward{4: {Title: "Seasoned", Items: []string{"Lantern"}}, 5: {Title: "Knight"}},
+	}
+	if n, err := svc.RecomputeLevels(); err != nil || n != 1 {
+		t.Fatalf("RecomputeLevels() = %d, %v; want 1 character", n, err)
+	}
+	if n, err := svc.RecomputeLevels(); err != nil || n != 0 {
+		t.Fatalf("second RecomputeLevels() = %d, %v; want nothing left to do", n, err)
+	}
+
+	got, err := svc.GetCharacter(char.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 350 XP is exactly level 4 on the new curve (50 + 100 + 200); 5 starting points and
+	// 3 per level reached, minus the 3 spent.
+	if got.Level != 4 || got.XP != 0 || got.TotalXP != 350 || got.AttributePoints != 11 || got.Title != "Seasoned" || got.LevelingVersion != 2 {
+		t.Errorf("recomputed character = level %d, XP %d, total %d, points %d, title %q, version %d; want 4, 0, 350, 11, Seasoned, 2",
+			got.Level, got.XP, got.TotalXP, got.AttributePoints, got.Title, got.LevelingVersion)
+	}
+
+	progress, err := svc.GetLevelProgress(char.ID, 5)
+	if err != nil {
+		t.Fatalf("GetLevelProgress() error = %v", err)
+	}
+	// Only two levels are left before the highest one.
+	if len(progress.Next) != 2 || progress.Next[0].XPRemaining != 400 || progress.Next[1].XPRequired != 800 || progress.Next[1].XPRemaining != 1200 {
+		t.Errorf("next levels = %+v, want 5 at 400 XP and 6 at 1200 XP", progress.Next)
+	}
+	if len(progress.Unlocked) != 2 {
+		t.Errorf("unlocked = %+v, want the title and the item of level 4", progress.Unlocked)
+	}
+
+	grant, err := svc.GrantXP(char.ID, 450, models.XPSource{Type: models.XPSourceQuest})
+	if err != nil {
+		t.Fatalf("GrantXP() error = %v", err)
+	}
+	if !grant.LeveledUp || len(grant.LevelUps) != 1 || grant.LevelUps[0].Level != 5 || grant.LevelUps[0].Reward.Title != "Knight" {
+		t.Errorf("level ups = %+v, want level 5 with the Knight title", grant.LevelUps)
+	}
+	if grant.Character.XP != 50 || grant.Character.TotalXP != 800 || grant.Character.AttributePoints != 14 || grant.Character.Title != "Knight" {
+		t.Errorf("character after grant = %+v", grant.Character)
+	}
+}
+
+func TestService_RecomputeLevelsAfterClassRewardsChange(t *testing.T) {
+	svc, char := newTestCharacter(t)
+	if _, err := svc.GrantXP(char.ID, 100, models.XPSource{Type: models.XPSourceQuest}); err != nil {
+		t.Fatalf("GrantXP() error = %v", err)
+	}
+	if n, err := svc.RecomputeLevels(); err != nil || n != 0 {
+		t.Fatalf("RecomputeLevels() = %d, %v; want nothing to do with the same configs", n, err)
+	}
+
+	// Mages now get 3 more points on reaching level 2, without a new leveling config version.
+	config := bytes.Replace(defaultClassesConfig, []byte(`"5": {"items": ["Apprentice Staff"]}`), []byte(`"2": {"attribute_points": 3}, "5": {"items": ["Apprentice Staff"]}`), 1)
+	classes, err := parseClasses(config, svc.leveling.MaxLevel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if classes.Version() == svc.classes.Version() {
+		t.Fatal("changing class rewards kept the classes version")
+	}
+	svc.classes = classes
+	if n, err := svc.RecomputeLevels(); err != nil || n != 1 {
+		t.Fatalf("RecomputeLevels() = %d, %v; want 1 character", n, err)
+	}
+
+	got, err := svc.GetCharacter(char.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 5 starting points, 5 for reaching level 2 and the 3 of the Mage reward.
+	if got.Level != 2 || got.AttributePoints != 13 {
+		t.Errorf("recomputed character = level %d, %d points; want level 2, 13 points", got.Level, got.AttributePoints)
+	}
+}
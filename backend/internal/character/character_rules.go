@@ -0,0 +1,238 @@
+package character
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/calendar"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/richtext"
+)
+
+// Rules that can reduce an XP grant, as reported in models.XPAdjustment.Rule.
+const (
+	RuleMinWords     = "min_words"
+	RuleRepeatedText = "repeated_text"
+	RuleDiminishing  = "diminishing_returns"
+	RuleDailyCap     = "daily_cap"
+	RuleWeeklyCap    = "weekly_cap"
+)
+
+// XPRules decides how much of a requested XP grant is credited. Zero values disable a rule.
+// Days and weeks are those of the user's calendar: their time zone and first day of the week.
+type XPRules struct {
+	// DailyCaps and WeeklyCaps limit the XP credited per source type.
+	DailyCaps  map[string]int
+	WeeklyCaps map[string]int
+	// XP a source credits in a day beyond DiminishingThreshold is multiplied by DiminishingRate.
+	DiminishingThreshold int
+	DiminishingRate      float64
+	// MinWords is the fewest words a text must have to earn XP.
+	MinWords int
+	// RepeatedText scales grants for a text down by the share of it the user
+	// already wrote in other journal entries.
+	RepeatedText bool
+}
+
+// DefaultXPRules returns the rules used when no environment overrides are set.
+func DefaultXPRules() XPRules {
+	return XPRules{
+		DailyCaps:            map[string]int{models.XPSourceAgent: 500, models.XPSourceLocal: 300, models.XPSourceQuest: 1000},
+		WeeklyCaps:           map[string]int{models.XPSourceAgent: 2000, models.XPSourceLocal: 1200, models.XPSourceQuest: 3000},
+		DiminishingThreshold: 200,
+		DiminishingRate:      0.5,
+		MinWords:             3,
+		RepeatedText:         true,
+	}
+}
+
+// XPRulesFromEnv reads XP_DAILY_CAPS and XP_WEEKLY_CAPS (e.g. "agent=500,local=300,quest=1000"),
+// XP_DIMINISHING_THRESHOLD, XP_DIMINISHING_RATE, XP_MIN_WORDS and XP_REPEATED_TEXT on top of DefaultXPRules.
+func XPRulesFromEnv() XPRules {
+	rules := DefaultXPRules()
+
+	for key, target := range map[string]*map[string]int{"XP_DAILY_CAPS": &rules.DailyCaps, "XP_WEEKLY_CAPS": &rules.WeeklyCaps} {
+		if value := os.Getenv(key); value != "" {
+			if caps, err := parseCaps(value); err == nil {
+				*target = caps
+			} else {
+				log.Printf("Warning: invalid %s value %q (%v). Using the default caps", key, value, err)
+			}
+		}
+	}
+	for key, target := range map[string]*int{"XP_DIMINISHING_THRESHOLD": &rules.DiminishingThreshold, "XP_MIN_WORDS": &rules.MinWords} {
+		if value := os.Getenv(key); value != "" {
+			if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+				*target = n
+			} else {
+				log.Printf("Warning: invalid %s value %q. Defaulting to %d", key, value, *target)
+			}
+		}
+	}
+	if value := os.Getenv("XP_DIMINISHING_RATE"); value != "" {
+		if rate, err := strconv.ParseFloat(value, 64); err == nil && rate >= 0 && rate <= 1 {
+			rules.DiminishingRate = rate
+		} else {
+			log.Printf("Warning: invalid XP_DIMINISHING_RATE value %q. Defaulting to %g", value, rules.DiminishingRate)
+		}
+	}
+	if value := os.Getenv("XP_REPEATED_TEXT"); value != "" {
+		if enabled, err := strconv.ParseBool(value); err == nil {
+			rules.RepeatedText = enabled
+		} else {
+			log.Printf("Warning: invalid XP_REPEATED_TEXT value %q. Defaulting to %t", value, rules.RepeatedText)
+		}
+	}
+	return rules
+}
+
+// parseCaps parses "source=amount" pairs separated by commas.
+func parseCaps(value string) (map[string]int, error) {
+	caps := map[string]int{}
+	for _, pair := range strings.Split(value, ",") {
+		source, amount, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || source == "" {
+			return nil, fmt.Errorf("expected source=amount, got %q", pair)
+		}
+		n, err := strconv.Atoi(amount)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid amount for %s", source)
+		}
+		caps[source] = n
+	}
+	return caps, nil
+}
+
+// XPGrant reports the outcome of a grant: how much was asked for, how much was credited,
+// which rules took off the rest and the levels the character reached.
+type XPGrant struct {
+	Character   *models.Character     `json:"-"`
+	LeveledUp   bool                  `json:"leveled_up"`
+	Requested   int                   `json:"requested"`
+	Granted     int                   `json:"granted"`
+	Adjustments []models.XPAdjustment `json:"adjustments"`
+	LevelUps    []LevelUp             `json:"level_ups,omitempty"`
+}
+
+// xpUsage is the XP a source already credited a character in the current day and week.
+type xpUsage struct {
+	today, week int
+}
+
+// textQuality describes the text a grant is for.
+type textQuality struct {
+	words    int
+	repeated float64 // Share of the words in paragraphs the user already wrote in other entries
+}
+
+// apply returns how much of a grant of amount XP is credited, and why the rest is not.
+// quality is nil for grants that are not for a text, such as quest rewards.
+func (r XPRules) apply(amount int, sourceType string, usage xpUsage, quality *textQuality) (int, []models.XPAdjustment) {
+	granted := amount
+	var adjustments []models.XPAdjustment
+	reduce := func(rule string, to int, reason string) {
+		if to < granted {
+			adjustments = append(adjustments, models.XPAdjustment{Rule: rule, Removed: granted - to, Reason: reason})
+			granted = to
+		}
+	}
+
+	if quality != nil {
+		if quality.words < r.MinWords {
+			reduce(RuleMinWords, 0, fmt.Sprintf("the text has %d words; at least %d are needed", quality.words, r.MinWords))
+		}
+		if r.RepeatedText && quality.repeated > 0 {
+			to := int(math.Round(float64(granted) * (1 - quality.repeated)))
+			reduce(RuleRepeatedText, to, fmt.Sprintf("%.0f%% of the text repeats earlier journal entries", quality.repeated*100))
+		}
+	}
+
+	if r.DiminishingThreshold > 0 && r.DiminishingRate < 1 {
+		full := r.DiminishingThreshold - usage.today
+		if full < 0 {
+			full = 0
+		}
+		if granted > full {
+			to := full + int(float64(granted-full)*r.DiminishingRate)
+			reduce(RuleDiminishing, to, fmt.Sprintf("XP from %s beyond %d a day counts %.0f%%", sourceType, r.DiminishingThreshold, r.DiminishingRate*100))
+		}
+	}
+
+	if limit := r.DailyCaps[sourceType]; limit > 0 {
+		reduce(RuleDailyCap, remaining(limit, usage.today, granted), fmt.Sprintf("at most %d XP from %s a day", limit, sourceType))
+	}
+	if limit := r.WeeklyCaps[sourceType]; limit > 0 {
+		reduce(RuleWeeklyCap, remaining(limit, usage.week, granted), fmt.Sprintf("at most %d XP from %s a week", limit, sourceType))
+	}
+	return granted, adjustments
+}
+
+// remaining returns how much of amount fits under limit after used.
+func remaining(limit, used, amount int) int {
+	left := limit - used
+	if left < 0 {
+		left = 0
+	}
+	if amount < left {
+		return amount
+	}
+	return left
+}
+
+// usage returns the XP a source already credited a character in the current day and week of
+// cal, the calendar of the character's user.
+func (s *Service) usage(store ICharacterStore, cal calendar.Calendar, characterID, sourceType string) (xpUsage, error) {
+	now := s.now()
+	today, err := store.XPCreditedSince(characterID, sourceType, cal.StartOfDay(now))
+	if err != nil {
+		return xpUsage{}, err
+	}
+	thisWeek, err := store.XPCreditedSince(characterID, sourceType, cal.StartOfWeek(now))
+	if err != nil {
+		return xpUsage{}, err
+	}
+	return xpUsage{today: today, week: thisWeek}, nil
+}
+
+// quality measures the text of a grant. The text is split into the paragraphs the journal
+// sent for scoring, which are compared against the user's other entries.
+func (s *Service) quality(store ICharacterStore, userID string, source models.XPSource) (*textQuality, error) {
+	if strings.TrimSpace(source.Text) == "" {
+		return nil, nil
+	}
+
+	quality := &textQuality{}
+	words := map[string]int{} // paragraph hash -> words
+	var hashes []string
+	for _, paragraph := range strings.Split(source.Text, "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+		n := len(strings.Fields(paragraph))
+		quality.words += n
+		hash := richtext.ParagraphHash(paragraph)
+		if _, ok := words[hash]; !ok {
+			hashes = append(hashes, hash)
+		}
+		words[hash] += n
+	}
+	if !s.rules.RepeatedText || quality.words == 0 {
+		return quality, nil
+	}
+
+	repeated, err := store.RepeatedParagraphs(userID, source.ID, hashes)
+	if err != nil {
+		return nil, err
+	}
+	repeatedWords := 0
+	for _, hash := range repeated {
+		repeatedWords += words[hash]
+	}
+	quality.repeated = float64(repeatedWords) / float64(quality.words)
+	return quality, nil
+}
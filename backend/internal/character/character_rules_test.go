@@ -0,0 +1,125 @@
+package character
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/richtext"
+)
+
+func TestXPRules_Apply(t *testing.T) {
+	rules := XPRules{
+		DailyCaps:            map[string]int{models.XPSourceAgent: 100},
+		WeeklyCaps:           map[string]int{models.XPSourceAgent: 300},
+		DiminishingThreshold: 50,
+		DiminishingRate:      0.5,
+		MinWords:             3,
+		RepeatedText:         true,
+	}
+	tests := []struct {
+		name    string
+		amount  int
+		source  string
+		usage   xpUsage
+		quality *textQuality
+		want    int
+		rules   []string
+	}{
+		{"under every limit", 20, models.XPSourceAgent, xpUsage{}, &textQuality{words: 10}, 20, nil},
+		{"too few words", 20, models.XPSourceAgent, xpUsage{}, &textQuality{words: 2}, 0, []string{RuleMinWords}},
+		{"repeated text", 20, models.XPSourceAgent, xpUsage{}, &textQuality{words: 10, repeated: 0.75}, 5, []string{RuleRepeatedText}},
+		{"diminishing returns", 40, models.XPSourceAgent, xpUsage{today: 30, week: 30}, nil, 30, []string{RuleDiminishing}},
+		{"daily cap", 40, models.XPSourceAgent, xpUsage{today: 90, week: 90}, nil, 10, []string{RuleDiminishing, RuleDailyCap}},
+		{"weekly cap", 40, models.XPSourceAgent, xpUsage{week: 290}, nil, 10, []string{RuleWeeklyCap}},
+		{"source without caps", 40, models.XPSourceQuest, xpUsage{today: 50, week: 1000}, nil, 20, []string{RuleDiminishing}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, adjustments := rules.apply(tt.amount, tt.source, tt.usage, tt.quality)
+			if got != tt.want {
+				t.Errorf("apply() granted %d, want %d (adjustments %+v)", got, tt.want, adjustments)
+			}
+			if len(adjustments) != len(tt.rules) {
+				t.Fatalf("adjustments = %+v, want rules %v", adjustments, tt.rules)
+			}
+			removed := 0
+			for i, adjustment := range adjustments {
+				if adjustment.Rule != tt.rules[i] {
+					t.Errorf("adjustment %d rule = %q, want %q", i, adjustment.Rule, tt.rules[i])
+				}
+				removed += adjustment.Removed
+			}
+			if removed != tt.amount-got {
+				t.Errorf("adjustments remove %d XP, want %d", removed, tt.amount-got)
+			}
+		})
+	}
+}
+
+func TestService_GrantXPAppliesRules(t *testing.T) {
+	svc, char := newTestCharacter(t)
+	svc.rules = XPRules{DailyCaps: map[string]int{models.XPSourceAgent: 50}, MinWords: 3, RepeatedText: true}
+	now := time.Date(2026, 4, 8, 12, 0, 0, 0, time.UTC) // A Wednesday
+	svc.now = func() time.Time { return now }
+
+	// The user already wrote the first paragraph in another entry.
+	copied := "I went for a long run by the river."
+	db := svc.store.(*Store).db
+	if err := db.Create(&models.JournalEntry{ID: "doc-old", UserID: char.UserID, Title: "Old"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.ScoredParagraph{ID: "sp-1", EntryID: "doc-old", UserID: char.UserID, Hash: richtext.ParagraphHash(copied)}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	source := models.XPSource{Type: models.XPSourceAgent, ID: "doc-new", Text: copied + "\n\nThen I cooked dinner for friends."}
+	grant, err := svc.GrantXP(char.ID, 40, source)
+	if err != nil {
+		t.Fatalf("GrantXP() error = %v", err)
+	}
+	// 9 of the 15 words are repeated, so 40 XP becomes 16.
+	if grant.Requested != 40 || grant.Granted != 16 || len(grant.Adjustments) != 1 || grant.Adjustments[0].Rule != RuleRepeatedText {
+		t.Errorf("grant = %+v, want 16 of 40 XP after the repeated text rule", grant)
+	}
+
+	grant, err = svc.GrantXP(char.ID, 40, models.XPSource{Type: models.XPSourceAgent, ID: "doc-new", Text: "Finished the quarterly report today."})
+	if err != nil {
+		t.Fatalf("GrantXP() error = %v", err)
+	}
+	if grant.Granted != 34 || len(grant.Adjustments) != 1 || grant.Adjustments[0].Rule != RuleDailyCap {
+		t.Errorf("grant = %+v, want 34 XP left under the daily cap", grant)
+	}
+
+	grant, err = svc.GrantXP(char.ID, 40, models.XPSource{Type: models.XPSourceAgent, Text: "Done."})
+	if err != nil {
+		t.Fatalf("GrantXP() error = %v", err)
+	}
+	if grant.Granted != 0 || grant.Adjustments[0].Rule != RuleMinWords {
+		t.Errorf("grant = %+v, want nothing for a one-word text", grant)
+	}
+
+	// The next day the cap starts over.
+	svc.now = func() time.Time { return now.AddDate(0, 0, 1) }
+	grant, err = svc.GrantXP(char.ID, 40, models.XPSource{Type: models.XPSourceAgent})
+	if err != nil {
+		t.Fatalf("GrantXP() error = %v", err)
+	}
+	if grant.Granted != 40 {
+		t.Errorf("granted %d XP the next day, want 40", grant.Granted)
+	}
+
+	history, err := svc.GetXPHistory(char.ID, 10, 0, 7)
+	if err != nil {
+		t.Fatalf("GetXPHistory() error = %v", err)
+	}
+	if history.Total != 4 {
+		t.Fatalf("got %d ledger rows, want 4", history.Total)
+	}
+	// Grants cut to nothing are still recorded, with the amount asked for and why.
+	for _, tx := range history.Transactions {
+		if tx.Amount == 0 && (tx.RequestedAmount != 40 || len(tx.Adjustments) != 1 || tx.Adjustments[0].Rule != RuleMinWords) {
+			t.Errorf("ledger row = %+v, want the requested amount and the min words adjustment", tx)
+		}
+	}
+}
@@ -1,7 +1,13 @@
 package character
 
 import (
+	"time"
+
 	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/calendar"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/events"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // ICharacterStore defines the interface for character data storage.
@@ -11,17 +17,48 @@ type ICharacterStore interface {
 	GetCharacterByUserID(userID string) (*models.Character, error)
 	UpdateCharacter(character *models.Character) error
 	GetCharacterByID(id string) (*models.Character, error) // Added for completeness, might be needed later
+	// LockCharacterByID retrieves a character and locks it until the surrounding transaction ends.
+	LockCharacterByID(id string) (*models.Character, error)
+	CreateXPTransaction(transaction *models.XPTransaction) error
+	ListXPTransactions(characterID string, limit, offset int) ([]models.XPTransaction, int64, error)
+	XPTransactionsSince(characterID string, since time.Time) ([]models.XPTransaction, error)
+	XPTotalsBySource(characterID string) ([]XPTotal, error)
+	// XPCreditedSince sums the XP a source credited a character since the given time.
+	XPCreditedSince(characterID, sourceType string, since time.Time) (int, error)
+	// RepeatedParagraphs returns which of the paragraph hashes the user already wrote in
+	// journal entries other than entryID.
+	RepeatedParagraphs(userID, entryID string, hashes []string) ([]string, error)
+	// CreateCharacterRewards records unlocked rewards, skipping those the character already has.
+	CreateCharacterRewards(rewards []models.CharacterReward) error
+	ListCharacterRewards(characterID string) ([]models.CharacterReward, error)
+	// OutdatedCharacterIDs lists the characters leveled with another version of the leveling
+	// or classes config.
+	OutdatedCharacterIDs(version int, classesVersion string) ([]string, error)
+	// Transaction runs fn with a store bound to a single database transaction.
+	Transaction(fn func(store ICharacterStore) error) error
+	// WithTx returns a store whose operations belong to tx, a transaction begun by another store.
+	WithTx(tx *gorm.DB) ICharacterStore
 }
 
 // Service handles the business logic for characters.
 // This struct will have methods attached to it, forming our "object-oriented" approach.
 type Service struct {
-	store ICharacterStore
+	store    ICharacterStore
+	rules    XPRules
+	leveling *Leveling
+	classes  *ClassRegistry
+	bus      *events.Bus
+	// calendars tell when the days and weeks of XP caps and history begin for each user.
+	calendars calendar.Source
+	now       func() time.Time
 }
 
-// NewService creates a new character service.
-func NewService(store ICharacterStore) *Service {
-	return &Service{store: store}
+// NewService creates a new character service that credits XP according to rules, levels
+// characters up according to leveling and gives them the mechanics of their class in classes.
+// Level-ups are published on bus. Days are counted in the calendar of each user from
+// calendars, or in UTC when calendars is nil.
+func NewService(store ICharacterStore, rules XPRules, leveling *Leveling, classes *ClassRegistry, bus *events.Bus, calendars calendar.Source) *Service {
+	return &Service{store: store, rules: rules, leveling: leveling, classes: classes, bus: bus, calendars: calendars, now: time.Now}
 }
 
 // CreateCharacterInput defines the input for creating a character.
@@ -52,11 +89,9 @@ func (s *Service) CreateCharacter(input CreateCharacterInput) (*models.Character
 	if input.UserID == "" {
 		return nil, &ValidationError{Field: "UserID", Message: "UserID cannot be nil"}
 	}
-	// Validate character class (optional, depends on how strictly you want to enforce enum values here vs. relying on DB)
-	switch input.Class {
-	case models.Warrior, models.Mage, models.Rogue:
-		// Valid class
-	default:
+	// Classes come from the classes config; see ClassRegistry.
+	class, ok := s.classes.Get(string(input.Class))
+	if !ok {
 		return nil, &ValidationError{Field: "Class", Message: "Invalid character class"}
 	}
 
@@ -76,12 +111,16 @@ func (s *Service) CreateCharacter(input CreateCharacterInput) (*models.Character
 		AvatarURL: input.AvatarURL,
 		Level:     1,
 		XP:        0,
-		// Set default base attributes
-		Strength:        10,
-		Defense:         10,
-		Vitality:        10,
-		Mana:            10,
-		AttributePoints: 5, // Give some points to start
+		// Each class starts with its own spread of attributes
+		Strength:        class.Attributes.Strength,
+		Defense:         class.Attributes.Defense,
+		Vitality:        class.Attributes.Vitality,
+		Mana:            class.Attributes.Mana,
+		AttributePoints: class.StartingPoints, // Give some points to start
+		LevelingVersion: s.leveling.Version,
+		ClassesVersion:  s.classes.Version(),
+
+		StartingAttributes: class.Attributes.total(),
 	}
 
 	if err := s.store.CreateCharacter(character); err != nil {
@@ -93,42 +132,124 @@ func (s *Service) CreateCharacter(input CreateCharacterInput) (*models.Character
 }
 
 // GrantXP grants experience points to a character and handles leveling up.
-func (s *Service) GrantXP(characterID string, amount int) (char *models.Character, leveledUp bool, err error) {
-	if amount <= 0 {
-		// No XP granted or invalid amount, return current state without error or specific error
-		char, err = s.store.GetCharacterByID(characterID)
-		return char, false, err
+// The character's class may add a bonus for the source's writing categories, and the XP rules
+// decide how much is credited; the grant is recorded in the character's XP ledger together
+// with its source, and the returned report explains the difference to amount.
+func (s *Service) GrantXP(characterID string, amount int, source models.XPSource) (*XPGrant, error) {
+	// The calendar is resolved before the character is locked, so reading the user's settings
+	// does not hold a second connection while the lock is held.
+	char, err := s.store.GetCharacterByID(characterID)
+	if err != nil {
+		return nil, err
+	}
+	cal, err := s.Calendar(char.UserID)
+	if err != nil {
+		return nil, err
 	}
 
-	char, err = s.store.GetCharacterByID(characterID)
+	var grant *XPGrant
+	err = s.store.Transaction(func(store ICharacterStore) error {
+		var err error
+		grant, err = s.grantXP(store, cal, characterID, amount, source)
+		return err
+	})
 	if err != nil {
-		return nil, false, err // e.g., character not found
+		return nil, err
 	}
+	s.PublishLevelUp(grant)
+	return grant, nil
+}
 
-	char.XP += amount
-	leveledUp = s.levelUpIfNeeded(char) // Call another method of the service
+// GrantXPTx grants experience points like GrantXP, as part of the transaction tx of another
+// store, so the grant is committed or rolled back together with the caller's own changes.
+// XP caps are counted in cal, the calendar of the character's user, which callers resolve
+// with Calendar before tx begins. Nothing is published; pass the grant to PublishLevelUp once
+// tx is committed.
+func (s *Service) GrantXPTx(tx *gorm.DB, cal calendar.Calendar, characterID string, amount int, source models.XPSource) (*XPGrant, error) {
+	return s.grantXP(s.store.WithTx(tx), cal, characterID, amount, source)
+}
 
-	if err := s.store.UpdateCharacter(char); err != nil {
-		return char, leveledUp, err // Return current char state even if update fails, but with error
+// Calendar returns the calendar in which the days and weeks of a user's XP are counted.
+func (s *Service) Calendar(userID string) (calendar.Calendar, error) {
+	return calendar.For(s.calendars, userID)
+}
+
+// PublishLevelUp announces the level-up of a committed grant, if it leveled the character up.
+func (s *Service) PublishLevelUp(grant *XPGrant) {
+	if grant.LeveledUp {
+		s.bus.Publish(events.Event{Type: events.LevelUp, UserID: grant.Character.UserID, SubjectID: grant.Character.ID, Level: grant.Character.Level})
 	}
-	return char, leveledUp, nil
 }
 
-// levelUpIfNeeded checks if the character has enough XP to level up and does so.
-// This is like a "private" method for our service logic (though Go doesn't have private methods, convention is lowercase).
-func (s *Service) levelUpIfNeeded(character *models.Character) bool {
-	leveledUp := false
-	// Define your leveling logic, e.g., XP threshold per level
-	xpToNextLevel := character.Level * 100 // Example: 100 XP for L1->L2, 200 XP for L2->L3, etc.
+// grantXP credits a grant with a store bound to a transaction, counting caps in cal.
+func (s *Service) grantXP(store ICharacterStore, cal calendar.Calendar, characterID string, amount int, source models.XPSource) (*XPGrant, error) {
+	if amount <= 0 {
+		// No XP granted or invalid amount, return current state without error or specific error
+		char, err := store.GetCharacterByID(characterID)
+		if err != nil {
+			return nil, err
+		}
+		return &XPGrant{Character: char, Adjustments: []models.XPAdjustment{}}, nil
+	}
 
-	for character.XP >= xpToNextLevel && character.Level < 100 { // Cap level at 100 for example
-		character.Level++
-		character.XP -= xpToNextLevel
-		character.AttributePoints += 5 // Grant 5 attribute points per level up
-		leveledUp = true
-		xpToNextLevel = character.Level * 100 // Recalculate for next potential level
+	grant := &XPGrant{Requested: amount}
+	categories := normalizeCategories(source.Categories)
+	// The character stays locked from the read to the write, so concurrent grants can neither
+	// overwrite each other nor both fit under the same cap.
+	char, err := store.LockCharacterByID(characterID)
+	if err != nil {
+		return nil, err // e.g., character not found
 	}
-	return leveledUp
+	if s.outdated(char) {
+		if err := s.recompute(store, char); err != nil {
+			return nil, err
+		}
+	}
+	usage, err := s.usage(store, cal, characterID, source.Type)
+	if err != nil {
+		return nil, err
+	}
+	quality, err := s.quality(store, char.UserID, source)
+	if err != nil {
+		return nil, err
+	}
+	// The class bonus comes first, so the rules cap the XP actually credited.
+	bonus, bonusAdjustments := s.classBonus(char.Class, amount, categories)
+	granted, ruleAdjustments := s.rules.apply(amount+bonus, source.Type, usage, quality)
+	grant.Granted, grant.Adjustments = granted, append(bonusAdjustments, ruleAdjustments...)
+
+	char.XP += grant.Granted
+	char.TotalXP += grant.Granted
+	grant.LevelUps, err = s.levelUp(store, char)
+	if err != nil {
+		return nil, err
+	}
+	grant.LeveledUp = len(grant.LevelUps) > 0
+	grant.Character = char
+
+	if err := store.UpdateCharacter(char); err != nil {
+		return nil, err
+	}
+	err = store.CreateXPTransaction(&models.XPTransaction{
+		ID:              uuid.NewString(),
+		CharacterID:     char.ID,
+		UserID:          char.UserID,
+		Amount:          grant.Granted,
+		RequestedAmount: amount,
+		Adjustments:     grant.Adjustments,
+		SourceType:      source.Type,
+		SourceID:        source.ID,
+		Reason:          source.Reason,
+		Categories:      categories,
+		CreatedAt:       s.now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if grant.Adjustments == nil {
+		grant.Adjustments = []models.XPAdjustment{}
+	}
+	return grant, nil
 }
 
 // SpendAttributePointsInput defines the input for spending attribute points.
@@ -140,32 +261,38 @@ type SpendAttributePointsInput struct {
 }
 
 // SpendAttributePoints applies spent points to a character's attributes.
+// The balance is checked and debited while the character is locked, so concurrent
+// requests cannot spend the same points twice.
 func (s *Service) SpendAttributePoints(characterID string, input SpendAttributePointsInput) (*models.Character, error) {
-	char, err := s.store.GetCharacterByID(characterID)
-	if err != nil {
-		return nil, err // Character not found
-	}
-
-	totalPointsToSpend := input.Strength + input.Defense + input.Vitality + input.Mana
-	if totalPointsToSpend > char.AttributePoints {
-		return nil, &ValidationError{Field: "AttributePoints", Message: "not enough points to spend"}
-	}
-
 	if input.Strength < 0 || input.Defense < 0 || input.Vitality < 0 || input.Mana < 0 {
 		return nil, &ValidationError{Field: "Attributes", Message: "points to spend cannot be negative"}
 	}
+	totalPointsToSpend := input.Strength + input.Defense + input.Vitality + input.Mana
+
+	var char *models.Character
+	err := s.store.Transaction(func(store ICharacterStore) error {
+		var err error
+		char, err = store.LockCharacterByID(characterID)
+		if err != nil {
+			return err // Character not found
+		}
 
-	// Apply points
-	char.Strength += input.Strength
-	char.Defense += input.Defense
-	char.Vitality += input.Vitality
-	char.Mana += input.Mana
-	char.AttributePoints -= totalPointsToSpend
+		if totalPointsToSpend > char.AttributePoints {
+			return &ValidationError{Field: "AttributePoints", Message: "not enough points to spend"}
+		}
 
-	if err := s.store.UpdateCharacter(char); err != nil {
+		// Apply points
+		char.Strength += input.Strength
+		char.Defense += input.Defense
+		char.Vitality += input.Vitality
+		char.Mana += input.Mana
+		char.AttributePoints -= totalPointsToSpend
+
+		return store.UpdateCharacter(char)
+	})
+	if err != nil {
 		return nil, err
 	}
-
 	return char, nil
 }
 
@@ -0,0 +1,160 @@
+package character
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/calendar"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/database/databasetest"
+)
+
+// slowStore widens the window between reading a character and writing it back,
+// so lost updates show up reliably when the read does not lock the character.
+//
+// The concurrency tests run on SQLite and, with TEST_DB_DSN set, on PostgreSQL. SQLite has no
+// row locks and runs the transactions one after the other, so only PostgreSQL proves that
+// LockCharacterByID locks.
+type slowStore struct {
+	ICharacterStore
+}
+
+func (s slowStore) LockCharacterByID(id string) (*models.Character, error) {
+	char, err := s.ICharacterStore.LockCharacterByID(id)
+	time.Sleep(2 * time.Millisecond)
+	return char, err
+}
+
+func (s slowStore) Transaction(fn func(store ICharacterStore) error) error {
+	return s.ICharacterStore.Transaction(func(store ICharacterStore) error {
+		return fn(slowStore{store})
+	})
+}
+
+func TestService_GrantXPConcurrently(t *testing.T) {
+	databasetest.Run(t, testGrantXPConcurrently)
+}
+
+func testGrantXPConcurrently(t *testing.T, db *gorm.DB) {
+	svc, char := newTestCharacterIn(t, db)
+	svc.store = slowStore{svc.store}
+
+	const grants, amount = 40, 30
+	var wg sync.WaitGroup
+	errs := make(chan error, grants)
+	for i := 0; i < grants; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := svc.GrantXP(char.ID, amount, models.XPSource{Type: models.XPSourceAgent}); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("GrantXP() error = %v", err)
+	}
+
+	got, err := svc.GetCharacter(char.ID)
+	if err != nil {
+		t.Fatalf("GetCharacter() error = %v", err)
+	}
+	// 1200 XP takes the character from level 1 to 5 (100+200+300+400) with 200 XP to spare.
+	if got.Level != 5 || got.XP != 200 || got.AttributePoints != 5+4*5 {
+		t.Errorf("character = level %d, %d XP, %d points; want level 5, 200 XP, 25 points", got.Level, got.XP, got.AttributePoints)
+	}
+	history, err := svc.GetXPHistory(char.ID, MaxXPHistoryLimit, 0, 1)
+	if err != nil {
+		t.Fatalf("GetXPHistory() error = %v", err)
+	}
+	if history.Total != grants || history.ByDay[0].Amount != grants*amount {
+		t.Errorf("ledger has %d transactions worth %d XP, want %d worth %d", history.Total, history.ByDay[0].Amount, grants, grants*amount)
+	}
+}
+
+func TestService_SpendAttributePointsConcurrently(t *testing.T) {
+	databasetest.Run(t, testSpendAttributePointsConcurrently)
+}
+
+func testSpendAttributePointsConcurrently(t *testing.T, db *gorm.DB) {
+	svc, char := newTestCharacterIn(t, db)
+	svc.store = slowStore{svc.store}
+
+	// The character starts with 5 points, so only 5 of these requests can succeed.
+	const requests = 20
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	spent, rejected := 0, 0
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := svc.SpendAttributePoints(char.ID, SpendAttributePointsInput{Strength: 1})
+			mu.Lock()
+			defer mu.Unlock()
+			var validationErr *ValidationError
+			switch {
+			case err == nil:
+				spent++
+			case errors.As(err, &validationErr):
+				rejected++
+			default:
+				t.Errorf("SpendAttributePoints() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := svc.GetCharacter(char.ID)
+	if err != nil {
+		t.Fatalf("GetCharacter() error = %v", err)
+	}
+	if spent != 5 || rejected != requests-5 {
+		t.Errorf("%d requests spent points and %d were rejected, want 5 and %d", spent, rejected, requests-5)
+	}
+	if got.AttributePoints != 0 || got.Strength != char.Strength+5 {
+		t.Errorf("character has %d points and strength %d, want 0 and %d", got.AttributePoints, got.Strength, char.Strength+5)
+	}
+}
+
+// settingsCalendar reads the settings of a user from the database, like the settings service.
+type settingsCalendar struct {
+	db *gorm.DB
+}
+
+func (c settingsCalendar) Calendar(userID string) (calendar.Calendar, error) {
+	var settings []models.UserSettings
+	if err := c.db.Where("user_id = ?", userID).Find(&settings).Error; err != nil {
+		return calendar.Calendar{}, err
+	}
+	return calendar.UTC, nil
+}
+
+// TestService_GrantXPReadsCalendarBeforeLocking grants XP with a calendar read from the
+// database. The test database has a single connection, so reading it while the character is
+// locked would wait for the grant's own transaction forever.
+func TestService_GrantXPReadsCalendarBeforeLocking(t *testing.T) {
+	db := databasetest.Open(t)
+	svc, char := newTestCharacterIn(t, db)
+	svc.calendars = settingsCalendar{db}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := svc.GrantXP(char.ID, 10, models.XPSource{Type: models.XPSourceAgent})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("GrantXP() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("GrantXP() waited for a second connection while the character was locked")
+	}
+}
@@ -0,0 +1,96 @@
+package character
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+)
+
+// Derived stats, as named in class stat modifiers and equipment bonuses.
+const (
+	StatMaxHP      = "max_hp"
+	StatPower      = "power"
+	StatArmor      = "armor"
+	StatMaxMana    = "max_mana"
+	StatSpellPower = "spell_power"
+)
+
+// Stats are the combat stats derived from a character's core attributes and level.
+// Every game system should use ComputeStats rather than its own formula.
+type Stats struct {
+	MaxHP      int `json:"max_hp"`      // From Vitality
+	Power      int `json:"power"`       // From Strength
+	Armor      int `json:"armor"`       // From Defense
+	MaxMana    int `json:"max_mana"`    // From Mana
+	SpellPower int `json:"spell_power"` // From Mana
+}
+
+// stat returns the field of a stat by name, or nil for an unknown name.
+func (s *Stats) stat(name string) *int {
+	switch name {
+	case StatMaxHP:
+		return &s.MaxHP
+	case StatPower:
+		return &s.Power
+	case StatArmor:
+		return &s.Armor
+	case StatMaxMana:
+		return &s.MaxMana
+	case StatSpellPower:
+		return &s.SpellPower
+	}
+	return nil
+}
+
+// validateStats checks that every key of a stat modifier names a stat.
+func validateStats[T any](modifiers map[string]T) error {
+	var probe Stats
+	for name := range modifiers {
+		if probe.stat(name) == nil {
+			return fmt.Errorf("unknown stat %q", name)
+		}
+	}
+	return nil
+}
+
+// ComputeStats derives a character's stats. The base stats come from its attributes and level;
+// the bonuses of its equipment are added, and the result is scaled by its class's stat modifiers.
+func ComputeStats(char *models.Character, class Class, equipment []map[string]int) Stats {
+	stats := Stats{
+		MaxHP:      10*char.Vitality + 5*char.Level,
+		Power:      2*char.Strength + char.Level,
+		Armor:      2 * char.Defense,
+		MaxMana:    10 * char.Mana,
+		SpellPower: 2*char.Mana + char.Level,
+	}
+	for _, bonuses := range equipment {
+		for name, bonus := range bonuses {
+			if stat := stats.stat(name); stat != nil {
+				*stat += bonus
+			}
+		}
+	}
+	for name, multiplier := range class.StatModifiers {
+		if stat := stats.stat(name); stat != nil {
+			*stat = int(math.Round(float64(*stat) * multiplier))
+		}
+	}
+	return stats
+}
+
+// CharacterStats returns a character's derived stats. Every item the character unlocked
+// counts as equipped.
+func (s *Service) CharacterStats(char *models.Character) (Stats, error) {
+	rewards, err := s.store.ListCharacterRewards(char.ID)
+	if err != nil {
+		return Stats{}, err
+	}
+	var equipment []map[string]int
+	for _, reward := range rewards {
+		if bonuses, ok := s.classes.Equipment(reward.Value); reward.Kind == models.RewardItem && ok {
+			equipment = append(equipment, bonuses)
+		}
+	}
+	return ComputeStats(char, s.class(char.Class), equipment), nil
+}
@@ -0,0 +1,71 @@
+package character
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/adrianvalentim/gamify_journal/internal/auth"
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+)
+
+func TestComputeStats(t *testing.T) {
+	char := &models.Character{Level: 3, Strength: 12, Defense: 8, Vitality: 10, Mana: 6}
+	tests := []struct {
+		name      string
+		class     Class
+		equipment []map[string]int
+		want      Stats
+	}{
+		{"attributes and level", Class{}, nil, Stats{MaxHP: 115, Power: 27, Armor: 16, MaxMana: 60, SpellPower: 15}},
+		{"equipment adds", Class{}, []map[string]int{{StatPower: 4}, {StatPower: 1, StatArmor: 8}}, Stats{MaxHP: 115, Power: 32, Armor: 24, MaxMana: 60, SpellPower: 15}},
+		{"class scales after equipment", Class{StatModifiers: map[string]float64{StatPower: 1.5, StatArmor: 0.5}}, []map[string]int{{StatPower: 3}}, Stats{MaxHP: 115, Power: 45, Armor: 8, MaxMana: 60, SpellPower: 15}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ComputeStats(char, tt.class, tt.equipment); got != tt.want {
+				t.Errorf("ComputeStats() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler_CharacterStatsFollowAttributes(t *testing.T) {
+	svc, char := newTestCharacter(t)
+	r := chi.NewRouter()
+	NewHandler(svc).RegisterRoutes(r)
+	token, err := auth.GenerateToken("user-1", "")
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+
+	do := func(method, target, body string) characterWithStats {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s %s: status %d (%s)", method, target, rec.Code, rec.Body.String())
+		}
+		var got characterWithStats
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("decoding character: %v", err)
+		}
+		return got
+	}
+
+	before := do(http.MethodGet, "/characters/me", "")
+	if before.Character == nil || before.ID != char.ID || before.Stats != ComputeStats(char, svc.class(char.Class), nil) {
+		t.Fatalf("GET /characters/me = %+v, want the character with its stats", before)
+	}
+
+	after := do(http.MethodPost, "/characters/me/spend-points", `{"vitality": 2}`)
+	if after.Stats.MaxHP != before.Stats.MaxHP+20 || after.Stats.Power != before.Stats.Power {
+		t.Errorf("stats after spending 2 points on vitality = %+v, want 20 more HP than %+v", after.Stats, before.Stats)
+	}
+}
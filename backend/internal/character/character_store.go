@@ -2,9 +2,13 @@ package character
 
 import (
 	"errors" // Standard Go errors package
+	"time"
+
 	"github.com/adrianvalentim/gamify_journal/internal/models" // Adjust path as necessary
+	"github.com/adrianvalentim/gamify_journal/internal/platform/database"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Store handles database operations for characters.
@@ -45,6 +49,118 @@ func (s *Store) UpdateCharacter(character *models.Character) error {
 	return s.db.Save(character).Error
 }
 
+// Transaction runs fn with a store whose operations all belong to one database transaction.
+// The transaction is committed if fn returns nil and rolled back otherwise.
+func (s *Store) Transaction(fn func(store ICharacterStore) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&Store{db: tx})
+	})
+}
+
+// WithTx returns a store whose operations belong to tx.
+func (s *Store) WithTx(tx *gorm.DB) ICharacterStore {
+	return &Store{db: tx}
+}
+
+// LockCharacterByID retrieves a character and locks its row until the transaction ends, so
+// read-modify-write updates of concurrent transactions are applied one after the other.
+func (s *Store) LockCharacterByID(id string) (*models.Character, error) {
+	var character models.Character
+	if err := database.ForUpdate(s.db).Where("id = ?", id).First(&character).Error; err != nil {
+		return nil, err
+	}
+	return &character, nil
+}
+
+// CreateXPTransaction appends a transaction to the XP ledger.
+func (s *Store) CreateXPTransaction(transaction *models.XPTransaction) error {
+	return s.db.Create(transaction).Error
+}
+
+// ListXPTransactions returns a page of a character's XP transactions, newest first, and their total count.
+func (s *Store) ListXPTransactions(characterID string, limit, offset int) ([]models.XPTransaction, int64, error) {
+	var total int64
+	if err := s.db.Model(&models.XPTransaction{}).Where("character_id = ?", characterID).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var transactions []models.XPTransaction
+	err := s.db.Where("character_id = ?", characterID).
+		Order("created_at DESC, id DESC").
+		Limit(limit).Offset(offset).
+		Find(&transactions).Error
+	return transactions, total, err
+}
+
+// XPTransactionsSince returns a character's XP transactions created at or after since, oldest first.
+func (s *Store) XPTransactionsSince(characterID string, since time.Time) ([]models.XPTransaction, error) {
+	var transactions []models.XPTransaction
+	err := s.db.Where("character_id = ? AND created_at >= ?", characterID, since).
+		Order("created_at ASC").
+		Find(&transactions).Error
+	return transactions, err
+}
+
+// XPTotalsBySource sums a character's XP transactions per source type.
+func (s *Store) XPTotalsBySource(characterID string) ([]XPTotal, error) {
+	var totals []XPTotal
+	err := s.db.Model(&models.XPTransaction{}).
+		Select("source_type AS source, SUM(amount) AS amount, COUNT(*) AS count").
+		Where("character_id = ?", characterID).
+		Group("source_type").
+		Order("source_type").
+		Scan(&totals).Error
+	return totals, err
+}
+
+// XPCreditedSince sums the XP a source credited a character since the given time.
+func (s *Store) XPCreditedSince(characterID, sourceType string, since time.Time) (int, error) {
+	var total int
+	err := s.db.Model(&models.XPTransaction{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("character_id = ? AND source_type = ? AND created_at >= ?", characterID, sourceType, since).
+		Scan(&total).Error
+	return total, err
+}
+
+// RepeatedParagraphs returns which of the paragraph hashes the user already wrote in
+// journal entries other than entryID.
+func (s *Store) RepeatedParagraphs(userID, entryID string, hashes []string) ([]string, error) {
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+	var repeated []string
+	err := s.db.Model(&models.ScoredParagraph{}).
+		Distinct("hash").
+		Where("user_id = ? AND entry_id <> ? AND hash IN ?", userID, entryID, hashes).
+		Pluck("hash", &repeated).Error
+	return repeated, err
+}
+
+// CreateCharacterRewards records unlocked rewards, skipping those the character already has.
+func (s *Store) CreateCharacterRewards(rewards []models.CharacterReward) error {
+	if len(rewards) == 0 {
+		return nil
+	}
+	return s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&rewards).Error
+}
+
+// ListCharacterRewards lists the rewards a character unlocked, by level.
+func (s *Store) ListCharacterRewards(characterID string) ([]models.CharacterReward, error) {
+	var rewards []models.CharacterReward
+	err := s.db.Where("character_id = ?", characterID).Order("level, kind, value").Find(&rewards).Error
+	return rewards, err
+}
+
+// OutdatedCharacterIDs lists the characters leveled with another version of the leveling or
+// classes config.
+func (s *Store) OutdatedCharacterIDs(version int, classesVersion string) ([]string, error) {
+	var ids []string
+	err := s.db.Model(&models.Character{}).
+		Where("leveling_version <> ? OR classes_version <> ?", version, classesVersion).
+		Order("id").Pluck("id", &ids).Error
+	return ids, err
+}
+
 // GetCharacterByID retrieves a character by their ID.
 func (s *Store) GetCharacterByID(id string) (*models.Character, error) {
 	var character models.Character
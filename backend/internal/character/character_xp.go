@@ -0,0 +1,116 @@
+package character
+
+import (
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/calendar"
+)
+
+// Limits of the XP history endpoint.
+const (
+	DefaultXPHistoryLimit = 50
+	MaxXPHistoryLimit     = 200
+	DefaultXPHistoryDays  = 30
+	MaxXPHistoryDays      = 366
+)
+
+// XPTotal is the XP granted from one source type.
+type XPTotal struct {
+	Source string `json:"source"`
+	Amount int    `json:"amount"`
+	Count  int    `json:"count"`
+}
+
+// XPDay is the XP granted on one day, in the user's time zone, per source type.
+type XPDay struct {
+	Day      string         `json:"day"` // YYYY-MM-DD
+	Amount   int            `json:"amount"`
+	Count    int            `json:"count"`
+	BySource map[string]int `json:"by_source"`
+}
+
+// XPHistory is a page of a character's XP ledger together with its aggregates.
+type XPHistory struct {
+	Transactions []models.XPTransaction `json:"transactions"`
+	Total        int64                  `json:"total"`
+	Limit        int                    `json:"limit"`
+	Offset       int                    `json:"offset"`
+	// ByDay covers the requested number of days up to today, oldest first; days without XP are included.
+	ByDay []XPDay `json:"by_day"`
+	// BySource covers the whole ledger.
+	BySource []XPTotal `json:"by_source"`
+}
+
+// GetXPHistory returns a page of a character's XP transactions, newest first, with the XP
+// granted per day over the last days days and per source overall.
+func (s *Service) GetXPHistory(characterID string, limit, offset, days int) (*XPHistory, error) {
+	if limit <= 0 {
+		limit = DefaultXPHistoryLimit
+	}
+	if limit > MaxXPHistoryLimit {
+		limit = MaxXPHistoryLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if days <= 0 {
+		days = DefaultXPHistoryDays
+	}
+	if days > MaxXPHistoryDays {
+		days = MaxXPHistoryDays
+	}
+
+	char, err := s.store.GetCharacterByID(characterID)
+	if err != nil {
+		return nil, err
+	}
+	cal, err := calendar.For(s.calendars, char.UserID)
+	if err != nil {
+		return nil, err
+	}
+	transactions, total, err := s.store.ListXPTransactions(characterID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	bySource, err := s.store.XPTotalsBySource(characterID)
+	if err != nil {
+		return nil, err
+	}
+
+	first := cal.StartOfDay(s.now()).AddDate(0, 0, -(days - 1))
+	recent, err := s.store.XPTransactionsSince(characterID, first)
+	if err != nil {
+		return nil, err
+	}
+
+	byDay := make([]XPDay, days)
+	index := map[string]int{}
+	for i := range byDay {
+		day := first.AddDate(0, 0, i).Format("2006-01-02")
+		byDay[i] = XPDay{Day: day, BySource: map[string]int{}}
+		index[day] = i
+	}
+	for _, transaction := range recent {
+		i, ok := index[cal.Format(transaction.CreatedAt)]
+		if !ok {
+			continue
+		}
+		byDay[i].Amount += transaction.Amount
+		byDay[i].Count++
+		byDay[i].BySource[transaction.SourceType] += transaction.Amount
+	}
+
+	if transactions == nil {
+		transactions = []models.XPTransaction{}
+	}
+	if bySource == nil {
+		bySource = []XPTotal{}
+	}
+	return &XPHistory{
+		Transactions: transactions,
+		Total:        total,
+		Limit:        limit,
+		Offset:       offset,
+		ByDay:        byDay,
+		BySource:     bySource,
+	}, nil
+}
@@ -0,0 +1,182 @@
+package character
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+
+	"github.com/adrianvalentim/gamify_journal/internal/auth"
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/calendar"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/database/databasetest"
+)
+
+// newTestCharacter creates a user with a character in a test database.
+func newTestCharacter(t *testing.T) (*Service, *models.Character) {
+	t.Helper()
+	return newTestCharacterIn(t, databasetest.Open(t))
+}
+
+// newTestCharacterIn creates a user with a character in db.
+func newTestCharacterIn(t *testing.T, db *gorm.DB) (*Service, *models.Character) {
+	t.Helper()
+	if err := db.Create(&models.User{ID: "user-1", Username: "hero", Email: "hero@example.com"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	svc := NewService(NewStore(db), XPRules{}, DefaultLeveling(), DefaultClasses(), nil, nil)
+	char, err := svc.CreateCharacter(CreateCharacterInput{UserID: "user-1", Name: "Hero", Class: models.Mage})
+	if err != nil {
+		t.Fatalf("CreateCharacter() error = %v", err)
+	}
+	return svc, char
+}
+
+func TestService_GetXPHistory(t *testing.T) {
+	svc, char := newTestCharacter(t)
+	now := time.Date(2026, 4, 10, 18, 0, 0, 0, time.UTC)
+	grants := []struct {
+		at     time.Time
+		amount int
+		source models.XPSource
+	}{
+		{now.AddDate(0, 0, -40), 100, models.XPSource{Type: models.XPSourceQuest, ID: "quest-1", Reason: "Completed quest: Run"}},
+		{now.AddDate(0, 0, -1), 10, models.XPSource{Type: models.XPSourceAgent, ID: "doc-1", Reason: "Went for a run"}},
+		{now.Add(-2 * time.Hour), 20, models.XPSource{Type: models.XPSourceAgent, ID: "doc-2"}},
+		{now.Add(-time.Hour), 5, models.XPSource{Type: models.XPSourceLocal, ID: "doc-2"}},
+	}
+	for _, grant := range grants {
+		svc.now = func() time.Time { return grant.at }
+		if _, err := svc.GrantXP(char.ID, grant.amount, grant.source); err != nil {
+			t.Fatalf("GrantXP() error = %v", err)
+		}
+	}
+	svc.now = func() time.Time { return now }
+
+	history, err := svc.GetXPHistory(char.ID, 2, 1, 7)
+	if err != nil {
+		t.Fatalf("GetXPHistory() error = %v", err)
+	}
+	if history.Total != 4 || len(history.Transactions) != 2 {
+		t.Fatalf("got %d of %d transactions, want 2 of 4", len(history.Transactions), history.Total)
+	}
+	// Newest first: the page after the local grant holds today's and yesterday's agent grants.
+	if history.Transactions[0].Amount != 20 || history.Transactions[1].Reason != "Went for a run" {
+		t.Errorf("page = %+v", history.Transactions)
+	}
+
+	if len(history.ByDay) != 7 || history.ByDay[6].Day != "2026-04-10" || history.ByDay[0].Day != "2026-04-04" {
+		t.Fatalf("ByDay covers %+v, want the 7 days up to today", history.ByDay)
+	}
+	if today := history.ByDay[6]; today.Amount != 25 || today.Count != 2 || today.BySource[models.XPSourceLocal] != 5 {
+		t.Errorf("today = %+v, want 25 XP from 2 grants", today)
+	}
+	if yesterday := history.ByDay[5]; yesterday.Amount != 10 {
+		t.Errorf("yesterday = %+v, want 10 XP", yesterday)
+	}
+
+	want := map[string]XPTotal{
+		models.XPSourceAgent: {Source: models.XPSourceAgent, Amount: 30, Count: 2},
+		models.XPSourceLocal: {Source: models.XPSourceLocal, Amount: 5, Count: 1},
+		models.XPSourceQuest: {Source: models.XPSourceQuest, Amount: 100, Count: 1},
+	}
+	if len(history.BySource) != len(want) {
+		t.Fatalf("BySource = %+v", history.BySource)
+	}
+	for _, total := range history.BySource {
+		if total != want[total.Source] {
+			t.Errorf("BySource[%s] = %+v, want %+v", total.Source, total, want[total.Source])
+		}
+	}
+}
+
+// fixedCalendar gives every user the same calendar.
+type fixedCalendar calendar.Calendar
+
+func (c fixedCalendar) Calendar(string) (calendar.Calendar, error) { return calendar.Calendar(c), nil }
+
+func TestService_XPDaysInUserZone(t *testing.T) {
+	svc, char := newTestCharacter(t)
+	brt := time.FixedZone("BRT", -3*60*60)
+	svc.calendars = fixedCalendar{Location: brt, WeekStart: time.Sunday}
+	svc.rules = XPRules{DailyCaps: map[string]int{models.XPSourceAgent: 30}}
+
+	svc.now = func() time.Time { return time.Date(2026, 4, 10, 12, 0, 0, 0, brt) }
+	if _, err := svc.GrantXP(char.ID, 20, models.XPSource{Type: models.XPSourceAgent}); err != nil {
+		t.Fatalf("GrantXP() error = %v", err)
+	}
+	// Already the 11th in UTC, but still the 10th in Brazil, so the daily cap still applies.
+	svc.now = func() time.Time { return time.Date(2026, 4, 11, 1, 0, 0, 0, time.UTC) }
+	grant, err := svc.GrantXP(char.ID, 20, models.XPSource{Type: models.XPSourceAgent})
+	if err != nil {
+		t.Fatalf("GrantXP() error = %v", err)
+	}
+	if grant.Granted != 10 {
+		t.Errorf("granted %d XP late in the evening, want 10 left under the daily cap", grant.Granted)
+	}
+
+	history, err := svc.GetXPHistory(char.ID, 10, 0, 2)
+	if err != nil {
+		t.Fatalf("GetXPHistory() error = %v", err)
+	}
+	if today := history.ByDay[1]; today.Day != "2026-04-10" || today.Amount != 30 || today.Count != 2 {
+		t.Errorf("today = %+v, want both grants on the 10th", today)
+	}
+}
+
+func TestHandler_GrantXPIsRecordedInLedger(t *testing.T) {
+	svc, char := newTestCharacter(t)
+	r := chi.NewRouter()
+	NewHandler(svc).RegisterRoutes(r)
+
+	grant, err := auth.IssueServiceGrant("user-1", "doc-7", []string{auth.ScopeXPGrant}, time.Minute)
+	if err != nil {
+		t.Fatalf("IssueServiceGrant() error = %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/users/user-1/character/xp", strings.NewReader(`{"xp_amount":15,"reason":"Finished a workout"}`))
+	req.Header.Set(auth.ServiceGrantHeader, grant)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("grant XP: status %d (%s)", rec.Code, rec.Body.String())
+	}
+
+	token, err := auth.GenerateToken("user-1", "")
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	for target, wantStatus := range map[string]int{
+		"/characters/me/xp-history?limit=10&days=3": http.StatusOK,
+		"/characters/me/xp-history?limit=0":         http.StatusBadRequest,
+		"/characters/me/xp-history?days=1000":       http.StatusBadRequest,
+		"/characters/me/xp-history?offset=-1":       http.StatusBadRequest,
+	} {
+		req = httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec = httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != wantStatus {
+			t.Fatalf("GET %s: status %d, want %d (%s)", target, rec.Code, wantStatus, rec.Body.String())
+		}
+		if wantStatus != http.StatusOK {
+			continue
+		}
+
+		var history XPHistory
+		if err := json.NewDecoder(rec.Body).Decode(&history); err != nil {
+			t.Fatalf("decoding history: %v", err)
+		}
+		if len(history.Transactions) != 1 || len(history.ByDay) != 3 {
+			t.Fatalf("history = %+v, want one transaction and 3 days", history)
+		}
+		got := history.Transactions[0]
+		if got.CharacterID != char.ID || got.Amount != 15 || got.SourceType != models.XPSourceAgent || got.SourceID != "doc-7" || got.Reason != "Finished a workout" {
+			t.Errorf("transaction = %+v, want the agent's grant for doc-7", got)
+		}
+	}
+}
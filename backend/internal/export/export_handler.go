@@ -0,0 +1,80 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/adrianvalentim/gamify_journal/internal/auth"
+	"github.com/go-chi/chi/v5"
+)
+
+// Handler handles HTTP requests for data exports.
+type Handler struct {
+	service Service
+}
+
+// NewHandler creates a new export handler.
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes sets up the routes for data exports.
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Group(func(r chi.Router) {
+		r.Use(auth.AuthMiddleware)
+
+		r.Get("/users/me/export", h.handleExport)
+	})
+}
+
+// handleExport streams the caller's data as a ZIP archive.
+func (h *Handler) handleExport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	cw := &countingWriter{w: w, header: func() {
+		filename := fmt.Sprintf("gamify-journal-export-%s.zip", time.Now().UTC().Format("2006-01-02"))
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		w.WriteHeader(http.StatusOK)
+	}}
+
+	if err := h.service.WriteArchive(userID, cw); err != nil {
+		if cw.started {
+			// The status line is already sent; the truncated archive will fail to open.
+			log.Printf("Error streaming export for user %s: %v", userID, err)
+			return
+		}
+		if errors.Is(err, ErrUserNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		log.Printf("Error exporting data for user %s: %v", userID, err)
+		http.Error(w, "Failed to export data", http.StatusInternalServerError)
+	}
+}
+
+// countingWriter sends the response headers on the first write and counts the bytes written,
+// so errors that happen before anything was streamed can still be reported with a status code.
+type countingWriter struct {
+	w       http.ResponseWriter
+	header  func()
+	started bool
+	written int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if !c.started {
+		c.started = true
+		c.header()
+	}
+	n, err := c.w.Write(p)
+	c.written += int64(n)
+	return n, err
+}
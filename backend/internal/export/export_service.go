@@ -0,0 +1,354 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/richtext"
+	"gorm.io/gorm"
+)
+
+// FormatVersion is bumped whenever the archive layout changes incompatibly.
+const FormatVersion = 1
+
+// journalDir is the archive directory holding the folder tree and entries.
+const journalDir = "journal"
+
+var ErrUserNotFound = errors.New("user not found")
+
+// Manifest is the machine-readable index written to manifest.json.
+type Manifest struct {
+	FormatVersion int              `json:"format_version"`
+	ExportedAt    time.Time        `json:"exported_at"`
+	User          ManifestUser     `json:"user"`
+	Folders       []ManifestFolder `json:"folders"`
+	Entries       []ManifestEntry  `json:"entries"`
+	QuestsFile    string           `json:"quests_file"`
+	CharacterFile string           `json:"character_file,omitempty"`
+	XPHistoryFile string           `json:"xp_history_file,omitempty"`
+}
+
+// ManifestUser describes the account the archive belongs to.
+type ManifestUser struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ManifestFolder maps a folder to its directory in the archive.
+type ManifestFolder struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name"`
+	ParentID *string `json:"parent_id"`
+	Path     string  `json:"path"`
+}
+
+// ManifestEntry maps a journal entry to its files in the archive.
+type ManifestEntry struct {
+	ID           string    `json:"id"`
+	Title        string    `json:"title"`
+	Mood         string    `json:"mood,omitempty"`
+	FolderID     *string   `json:"folder_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	MarkdownPath string    `json:"markdown_path"`
+	HTMLPath     string    `json:"html_path"`
+}
+
+// Service defines the interface for exporting a user's data.
+type Service interface {
+	// WriteArchive streams a ZIP archive of everything the user owns to w.
+	WriteArchive(userID string, w io.Writer) error
+}
+
+type service struct {
+	store Store
+	now   func() time.Time
+}
+
+// NewService creates a new export service.
+func NewService(store Store) Service {
+	return &service{store: store, now: time.Now}
+}
+
+// WriteArchive writes the journal entries (as Markdown and HTML, laid out in the folder tree),
+// quests, character sheet and a manifest. Entries are streamed one at a time, so memory use
+// does not grow with the size of the journal.
+func (s *service) WriteArchive(userID string, w io.Writer) error {
+	user, err := s.store.GetUser(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("could not load user: %w", err)
+	}
+	folders, err := s.store.GetFolders(userID)
+	if err != nil {
+		return fmt.Errorf("could not load folders: %w", err)
+	}
+
+	exportedAt := s.now().UTC()
+	manifest := Manifest{
+		FormatVersion: FormatVersion,
+		ExportedAt:    exportedAt,
+		User: ManifestUser{
+			ID:        user.ID,
+			Username:  user.Username,
+			Email:     user.Email,
+			CreatedAt: user.CreatedAt,
+		},
+		Folders: []ManifestFolder{},
+		Entries: []ManifestEntry{},
+	}
+
+	zw := zip.NewWriter(w)
+	names := newNameSet()
+
+	tree := newFolderTree(folders, names)
+	for _, folder := range folders {
+		dir := tree.path(folder.ID)
+		manifest.Folders = append(manifest.Folders, ManifestFolder{
+			ID:       folder.ID,
+			Name:     folder.Name,
+			ParentID: folder.ParentID,
+			Path:     dir + "/",
+		})
+		// Directory entries keep empty folders in the archive.
+		if _, err := zw.CreateHeader(&zip.FileHeader{Name: dir + "/", Modified: folder.CreatedAt}); err != nil {
+			return err
+		}
+	}
+
+	err = s.store.EachJournalEntry(userID, func(entry *models.JournalEntry) error {
+		dir := journalDir
+		if entry.FolderID != nil {
+			dir = tree.path(*entry.FolderID)
+		}
+		title := entry.Title
+		if strings.TrimSpace(title) == "" {
+			title = "Untitled"
+		}
+		base := names.claim(dir, title, ".md", ".html")
+
+		markdownPath := path.Join(dir, base+".md")
+		if err := writeFile(zw, markdownPath, entry.UpdatedAt, []byte(entryMarkdown(entry))); err != nil {
+			return err
+		}
+		htmlPath := path.Join(dir, base+".html")
+		if err := writeFile(zw, htmlPath, entry.UpdatedAt, []byte(entryHTML(entry))); err != nil {
+			return err
+		}
+
+		manifest.Entries = append(manifest.Entries, ManifestEntry{
+			ID:           entry.ID,
+			Title:        entry.Title,
+			Mood:         entry.Mood,
+			FolderID:     entry.FolderID,
+			CreatedAt:    entry.CreatedAt,
+			UpdatedAt:    entry.UpdatedAt,
+			MarkdownPath: markdownPath,
+			HTMLPath:     htmlPath,
+		})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("could not export journal entries: %w", err)
+	}
+
+	quests, err := s.store.GetQuests(userID)
+	if err != nil {
+		return fmt.Errorf("could not load quests: %w", err)
+	}
+	if quests == nil {
+		quests = []models.Quest{}
+	}
+	manifest.QuestsFile = "quests.json"
+	if err := writeJSON(zw, manifest.QuestsFile, exportedAt, quests); err != nil {
+		return err
+	}
+
+	character, err := s.store.GetCharacter(userID)
+	if err != nil {
+		return fmt.Errorf("could not load character: %w", err)
+	}
+	if character != nil {
+		manifest.CharacterFile = "character.json"
+		if err := writeJSON(zw, manifest.CharacterFile, exportedAt, character); err != nil {
+			return err
+		}
+
+		xpHistory, err := s.store.GetXPTransactions(userID)
+		if err != nil {
+			return fmt.Errorf("could not load XP history: %w", err)
+		}
+		if xpHistory == nil {
+			xpHistory = []models.XPTransaction{}
+		}
+		manifest.XPHistoryFile = "xp_history.json"
+		if err := writeJSON(zw, manifest.XPHistoryFile, exportedAt, xpHistory); err != nil {
+			return err
+		}
+	}
+
+	if err := writeJSON(zw, "manifest.json", exportedAt, manifest); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// entryMarkdown renders an entry as Markdown with YAML front matter holding its metadata.
+func entryMarkdown(entry *models.JournalEntry) string {
+	var b strings.Builder
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "id: %s\n", strconv.Quote(entry.ID))
+	fmt.Fprintf(&b, "title: %s\n", strconv.Quote(entry.Title))
+	if entry.Mood != "" {
+		fmt.Fprintf(&b, "mood: %s\n", strconv.Quote(entry.Mood))
+	}
+	fmt.Fprintf(&b, "created_at: %s\n", entry.CreatedAt.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "updated_at: %s\n", entry.UpdatedAt.UTC().Format(time.RFC3339))
+	b.WriteString("---\n\n")
+	if body := richtext.ToMarkdown(entry.Content); body != "" {
+		b.WriteString(body)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// entryHTML wraps the entry's editor HTML in a standalone document.
+func entryHTML(entry *models.JournalEntry) string {
+	title := html.EscapeString(entry.Title)
+	return "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>" + title + "</title>\n</head>\n<body>\n" +
+		"<h1>" + title + "</h1>\n" + entry.Content + "\n</body>\n</html>\n"
+}
+
+func writeFile(zw *zip.Writer, name string, modified time.Time, data []byte) error {
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+func writeJSON(zw *zip.Writer, name string, modified time.Time, v interface{}) error {
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// folderTree resolves folders to unique directory paths in the archive.
+type folderTree struct {
+	folders map[string]models.Folder
+	paths   map[string]string
+	names   *nameSet
+}
+
+func newFolderTree(folders []models.Folder, names *nameSet) *folderTree {
+	tree := &folderTree{
+		folders: make(map[string]models.Folder, len(folders)),
+		paths:   make(map[string]string, len(folders)),
+		names:   names,
+	}
+	for _, folder := range folders {
+		tree.folders[folder.ID] = folder
+	}
+	return tree
+}
+
+// path returns the directory of a folder. Unknown folders (e.g. deleted ones) map to the journal root.
+func (t *folderTree) path(folderID string) string {
+	return t.resolve(folderID, map[string]bool{})
+}
+
+func (t *folderTree) resolve(folderID string, visiting map[string]bool) string {
+	if p, ok := t.paths[folderID]; ok {
+		return p
+	}
+	folder, ok := t.folders[folderID]
+	if !ok || visiting[folderID] {
+		return journalDir
+	}
+	visiting[folderID] = true
+
+	parent := journalDir
+	if folder.ParentID != nil {
+		parent = t.resolve(*folder.ParentID, visiting)
+	}
+	p := path.Join(parent, t.names.claim(parent, folder.Name, "/"))
+	t.paths[folderID] = p
+	return p
+}
+
+// nameSet hands out file names that are unique (case-insensitively) within a directory.
+type nameSet struct {
+	taken map[string]bool
+}
+
+func newNameSet() *nameSet {
+	return &nameSet{taken: map[string]bool{}}
+}
+
+// claim returns a sanitized version of name that is free in dir for every given suffix,
+// adding " (2)", " (3)", ... if needed, and reserves it.
+func (n *nameSet) claim(dir, name string, suffixes ...string) string {
+	base := sanitizeName(name)
+	candidate := base
+	for i := 2; ; i++ {
+		free := true
+		for _, suffix := range suffixes {
+			if n.taken[strings.ToLower(path.Join(dir, candidate)+suffix)] {
+				free = false
+				break
+			}
+		}
+		if free {
+			break
+		}
+		candidate = fmt.Sprintf("%s (%d)", base, i)
+	}
+	for _, suffix := range suffixes {
+		n.taken[strings.ToLower(path.Join(dir, candidate)+suffix)] = true
+	}
+	return candidate
+}
+
+// maxNameLength keeps generated names well below common file system limits.
+const maxNameLength = 100
+
+// sanitizeName makes a title safe to use as a single path element on any OS.
+func sanitizeName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r < 0x20 || r == 0x7f:
+			continue
+		case strings.ContainsRune(`/\:*?"<>|`, r):
+			b.WriteRune('-')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	clean := strings.Trim(b.String(), " .")
+	if runes := []rune(clean); len(runes) > maxNameLength {
+		clean = strings.TrimRight(string(runes[:maxNameLength]), " .")
+	}
+	if clean == "" {
+		return "Untitled"
+	}
+	return clean
+}
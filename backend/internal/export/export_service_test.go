@@ -0,0 +1,124 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/database/databasetest"
+)
+
+func TestService_WriteArchive(t *testing.T) {
+	db := databasetest.Open(t)
+
+	created := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	parentID, childID := "folder-adventures", "folder-dungeons"
+	records := []interface{}{
+		&models.User{ID: "user-1", Username: "hero", Email: "hero@example.com"},
+		&models.User{ID: "user-2", Username: "other", Email: "other@example.com"},
+		&models.Folder{ID: parentID, Name: "Adventures", UserID: "user-1"},
+		&models.Folder{ID: childID, Name: "Dungeons/Caves", UserID: "user-1", ParentID: &parentID},
+		&models.Folder{ID: "folder-empty", Name: "Empty", UserID: "user-1"},
+		&models.JournalEntry{ID: "entry-1", UserID: "user-1", Title: "Day one", Content: "<p><strong>Hello</strong> world</p>", CreatedAt: created},
+		&models.JournalEntry{ID: "entry-2", UserID: "user-1", Title: "Day one", Content: "<p>Again</p>"},
+		&models.JournalEntry{ID: "entry-3", UserID: "user-1", Title: "Into the dark", Content: "<ul><li><p>torch</p></li></ul>", FolderID: &childID},
+		&models.JournalEntry{ID: "entry-4", UserID: "user-1", Title: "", Content: "<p>No title</p>"},
+		&models.JournalEntry{ID: "entry-other", UserID: "user-2", Title: "Secret", Content: "<p>Not yours</p>"},
+		&models.Quest{UserID: "user-1", Title: "Write daily"},
+		&models.Character{UserID: "user-1", Name: "Hero", Class: string(models.Mage)},
+	}
+	for _, record := range records {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatalf("seeding %T: %v", record, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := NewService(NewStore(db)).WriteArchive("user-1", &buf); err != nil {
+		t.Fatalf("WriteArchive() error = %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("reading archive: %v", err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("opening %s: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+
+	for _, name := range []string{
+		"manifest.json",
+		"quests.json",
+		"character.json",
+		"xp_history.json",
+		"journal/Adventures/",
+		"journal/Adventures/Dungeons-Caves/",
+		"journal/Empty/",
+		"journal/Day one.md",
+		"journal/Day one.html",
+		"journal/Day one (2).md",
+		"journal/Untitled.md",
+		"journal/Adventures/Dungeons-Caves/Into the dark.md",
+		"journal/Adventures/Dungeons-Caves/Into the dark.html",
+	} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("archive is missing %s", name)
+		}
+	}
+	for name, data := range files {
+		if strings.Contains(name, "Secret") || strings.Contains(data, "Not yours") {
+			t.Errorf("archive contains another user's data in %s", name)
+		}
+	}
+
+	wantMarkdown := "---\nid: \"entry-1\"\ntitle: \"Day one\"\ncreated_at: 2024-03-01T09:30:00Z\n"
+	if md := files["journal/Day one.md"]; !strings.HasPrefix(md, wantMarkdown) || !strings.HasSuffix(md, "---\n\n**Hello** world\n") {
+		t.Errorf("Day one.md = %q", md)
+	}
+	if page := files["journal/Day one.html"]; !strings.Contains(page, "<title>Day one</title>") || !strings.Contains(page, "<p><strong>Hello</strong> world</p>") {
+		t.Errorf("Day one.html = %q", page)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal([]byte(files["manifest.json"]), &manifest); err != nil {
+		t.Fatalf("decoding manifest: %v", err)
+	}
+	if manifest.FormatVersion != FormatVersion || manifest.User.ID != "user-1" {
+		t.Errorf("manifest header = %+v", manifest)
+	}
+	if len(manifest.Entries) != 4 || len(manifest.Folders) != 3 {
+		t.Errorf("manifest lists %d entries and %d folders, want 4 and 3", len(manifest.Entries), len(manifest.Folders))
+	}
+	for _, entry := range manifest.Entries {
+		if _, ok := files[entry.MarkdownPath]; !ok {
+			t.Errorf("manifest points at missing file %s", entry.MarkdownPath)
+		}
+	}
+	if manifest.CharacterFile != "character.json" {
+		t.Errorf("CharacterFile = %q, want character.json", manifest.CharacterFile)
+	}
+}
+
+func TestService_WriteArchive_UnknownUser(t *testing.T) {
+	db := databasetest.Open(t)
+
+	var buf bytes.Buffer
+	if err := NewService(NewStore(db)).WriteArchive("nobody", &buf); err != ErrUserNotFound {
+		t.Fatalf("WriteArchive() error = %v, want %v", err, ErrUserNotFound)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("wrote %d bytes for an unknown user", buf.Len())
+	}
+}
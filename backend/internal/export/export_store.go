@@ -0,0 +1,91 @@
+package export
+
+import (
+	"errors"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"gorm.io/gorm"
+)
+
+// entryBatchSize bounds how many journal entries are held in memory at once while exporting.
+const entryBatchSize = 50
+
+// Store defines the read-only queries needed to export a user's data.
+type Store interface {
+	GetUser(userID string) (*models.User, error)
+	GetFolders(userID string) ([]models.Folder, error)
+	// EachJournalEntry calls fn for every journal entry of the user, loading them in batches.
+	EachJournalEntry(userID string, fn func(entry *models.JournalEntry) error) error
+	GetQuests(userID string) ([]models.Quest, error)
+	// GetCharacter returns (nil, nil) if the user has no character.
+	GetCharacter(userID string) (*models.Character, error)
+	// GetXPTransactions retrieves the user's XP ledger, oldest first.
+	GetXPTransactions(userID string) ([]models.XPTransaction, error)
+}
+
+// gormStore is a GORM implementation of the Store interface.
+type gormStore struct {
+	db *gorm.DB
+}
+
+// NewStore creates a new GORM store for exports.
+func NewStore(db *gorm.DB) Store {
+	return &gormStore{db: db}
+}
+
+// GetUser retrieves the user being exported.
+func (s *gormStore) GetUser(userID string) (*models.User, error) {
+	var user models.User
+	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetFolders retrieves every folder of the user.
+func (s *gormStore) GetFolders(userID string) ([]models.Folder, error) {
+	var folders []models.Folder
+	err := s.db.Where("user_id = ?", userID).Order("created_at").Find(&folders).Error
+	return folders, err
+}
+
+// EachJournalEntry streams the user's journal entries in primary key order.
+func (s *gormStore) EachJournalEntry(userID string, fn func(entry *models.JournalEntry) error) error {
+	var batch []models.JournalEntry
+	result := s.db.Where("user_id = ?", userID).
+		FindInBatches(&batch, entryBatchSize, func(tx *gorm.DB, _ int) error {
+			for i := range batch {
+				if err := fn(&batch[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	return result.Error
+}
+
+// GetQuests retrieves every quest of the user.
+func (s *gormStore) GetQuests(userID string) ([]models.Quest, error) {
+	var quests []models.Quest
+	err := s.db.Where("user_id = ?", userID).Order("created_at").Find(&quests).Error
+	return quests, err
+}
+
+// GetCharacter retrieves the user's character, if they have one.
+func (s *gormStore) GetCharacter(userID string) (*models.Character, error) {
+	var character models.Character
+	if err := s.db.Where("user_id = ?", userID).First(&character).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &character, nil
+}
+
+// GetXPTransactions retrieves the user's XP ledger, oldest first.
+func (s *gormStore) GetXPTransactions(userID string) ([]models.XPTransaction, error) {
+	var transactions []models.XPTransaction
+	err := s.db.Where("user_id = ?", userID).Order("created_at, id").Find(&transactions).Error
+	return transactions, err
+}
@@ -2,6 +2,7 @@ package folder
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/adrianvalentim/gamify_journal/internal/auth"
@@ -46,6 +47,10 @@ func (h *Handler) createFolder(w http.ResponseWriter, r *http.Request) {
 
 	folder, err := h.service.CreateFolder(payload.Name, payload.ParentID, userID)
 	if err != nil {
+		if errors.Is(err, ErrFolderNotFound) {
+			http.Error(w, "parent folder not found", http.StatusNotFound)
+			return
+		}
 		http.Error(w, "failed to create folder", http.StatusInternalServerError)
 		return
 	}
@@ -55,6 +60,12 @@ func (h *Handler) createFolder(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) updateFolder(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
 	folderID := chi.URLParam(r, "folderID")
 	var payload struct {
 		Name string `json:"name"`
@@ -65,8 +76,12 @@ func (h *Handler) updateFolder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updatedFolder, err := h.service.UpdateFolder(folderID, payload.Name)
+	updatedFolder, err := h.service.UpdateFolder(folderID, userID, payload.Name)
 	if err != nil {
+		if errors.Is(err, ErrFolderNotFound) {
+			http.Error(w, "folder not found", http.StatusNotFound)
+			return
+		}
 		http.Error(w, "failed to update folder", http.StatusInternalServerError)
 		return
 	}
@@ -76,9 +91,19 @@ func (h *Handler) updateFolder(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) deleteFolder(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
 	folderID := chi.URLParam(r, "folderID")
 
-	if err := h.service.DeleteFolder(folderID); err != nil {
+	if err := h.service.DeleteFolder(folderID, userID); err != nil {
+		if errors.Is(err, ErrFolderNotFound) {
+			http.Error(w, "folder not found", http.StatusNotFound)
+			return
+		}
 		http.Error(w, "failed to delete folder", http.StatusInternalServerError)
 		return
 	}
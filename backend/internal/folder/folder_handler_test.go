@@ -0,0 +1,131 @@
+package folder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+
+	"github.com/adrianvalentim/gamify_journal/internal/auth"
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+)
+
+// memoryStore is an in-memory implementation of the Store interface that mirrors
+// the owner scoping of the GORM store.
+type memoryStore struct {
+	folders map[string]models.Folder
+}
+
+func (m *memoryStore) Create(folder *models.Folder) error {
+	if folder.ID == "" {
+		folder.ID = "folder-new"
+	}
+	m.folders[folder.ID] = *folder
+	return nil
+}
+
+func (m *memoryStore) GetByID(folderID, userID string) (*models.Folder, error) {
+	folder, ok := m.folders[folderID]
+	if !ok || folder.UserID != userID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &folder, nil
+}
+
+func (m *memoryStore) GetFoldersByUserID(userID string) ([]models.Folder, error) {
+	var folders []models.Folder
+	for _, folder := range m.folders {
+		if folder.UserID == userID {
+			folders = append(folders, folder)
+		}
+	}
+	return folders, nil
+}
+
+func (m *memoryStore) Update(folder *models.Folder) error {
+	existing, ok := m.folders[folder.ID]
+	if !ok || existing.UserID != folder.UserID {
+		return gorm.ErrRecordNotFound
+	}
+	existing.Name = folder.Name
+	m.folders[folder.ID] = existing
+	return nil
+}
+
+func (m *memoryStore) Delete(folderID, userID string) error {
+	folder, ok := m.folders[folderID]
+	if !ok || folder.UserID != userID {
+		return gorm.ErrRecordNotFound
+	}
+	delete(m.folders, folderID)
+	return nil
+}
+
+const (
+	ownerID         = "owner-user"
+	intruderID      = "intruder-user"
+	ownedFolderID   = "folder-owned"
+	ownedFolderName = "Private Thoughts"
+)
+
+func authorizedRequest(t *testing.T, method, target, body, userID string) *http.Request {
+	t.Helper()
+	token, err := auth.GenerateToken(userID, "")
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+// TestHandler_Ownership checks every route registered by RegisterRoutes against a folder
+// owned by another user. Foreign folders must look exactly like missing ones.
+func TestHandler_Ownership(t *testing.T) {
+	tests := []struct {
+		name       string
+		userID     string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+	}{
+		{name: "rename foreign folder", userID: intruderID, method: http.MethodPut, target: "/folders/" + ownedFolderID, body: `{"name":"pwned"}`, wantStatus: http.StatusNotFound},
+		{name: "delete foreign folder", userID: intruderID, method: http.MethodDelete, target: "/folders/" + ownedFolderID, wantStatus: http.StatusNotFound},
+		{name: "create inside foreign folder", userID: intruderID, method: http.MethodPost, target: "/folders/", body: `{"name":"child","parent_id":"` + ownedFolderID + `"}`, wantStatus: http.StatusNotFound},
+		{name: "list excludes foreign folders", userID: intruderID, method: http.MethodGet, target: "/folders/me", wantStatus: http.StatusOK},
+		{name: "rename own folder", userID: ownerID, method: http.MethodPut, target: "/folders/" + ownedFolderID, body: `{"name":"Renamed"}`, wantStatus: http.StatusOK},
+		{name: "delete own folder", userID: ownerID, method: http.MethodDelete, target: "/folders/" + ownedFolderID, wantStatus: http.StatusNoContent},
+		{name: "create inside own folder", userID: ownerID, method: http.MethodPost, target: "/folders/", body: `{"name":"child","parent_id":"` + ownedFolderID + `"}`, wantStatus: http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &memoryStore{folders: map[string]models.Folder{
+				ownedFolderID: {ID: ownedFolderID, UserID: ownerID, Name: ownedFolderName},
+			}}
+			r := chi.NewRouter()
+			NewHandler(NewService(store)).RegisterRoutes(r)
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, authorizedRequest(t, tt.method, tt.target, tt.body, tt.userID))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("%s %s: expected status %d, got %d (%s)", tt.method, tt.target, tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if tt.userID == intruderID {
+				if strings.Contains(rec.Body.String(), ownedFolderName) {
+					t.Errorf("%s %s leaked the owner's folder: %s", tt.method, tt.target, rec.Body.String())
+				}
+				owned, ok := store.folders[ownedFolderID]
+				if !ok || owned.Name != ownedFolderName {
+					t.Errorf("%s %s modified the owner's folder: %+v (present: %v)", tt.method, tt.target, owned, ok)
+				}
+			}
+		})
+	}
+}
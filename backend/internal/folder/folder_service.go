@@ -1,15 +1,22 @@
 package folder
 
 import (
+	"errors"
+
 	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"gorm.io/gorm"
 )
 
+// ErrFolderNotFound is returned when a folder does not exist or is owned by another user.
+var ErrFolderNotFound = errors.New("folder not found")
+
 // Service defines the interface for folder business logic.
+// All methods act on behalf of userID and never touch folders owned by someone else.
 type Service interface {
 	CreateFolder(name string, parentID *string, userID string) (*models.Folder, error)
 	GetFoldersByUserID(userID string) ([]models.Folder, error)
-	UpdateFolder(folderID string, newName string) (*models.Folder, error)
-	DeleteFolder(folderID string) error
+	UpdateFolder(folderID, userID string, newName string) (*models.Folder, error)
+	DeleteFolder(folderID, userID string) error
 }
 
 type service struct {
@@ -21,8 +28,30 @@ func NewService(store Store) Service {
 	return &service{store: store}
 }
 
+// authorizeFolder loads a folder on behalf of userID.
+// Folders owned by other users are reported as ErrFolderNotFound.
+func (s *service) authorizeFolder(folderID, userID string) (*models.Folder, error) {
+	if userID == "" {
+		return nil, ErrFolderNotFound
+	}
+	folder, err := s.store.GetByID(folderID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFolderNotFound
+		}
+		return nil, err
+	}
+	return folder, nil
+}
+
 // CreateFolder creates a new folder.
 func (s *service) CreateFolder(name string, parentID *string, userID string) (*models.Folder, error) {
+	if parentID != nil && *parentID != "" {
+		if _, err := s.authorizeFolder(*parentID, userID); err != nil {
+			return nil, err
+		}
+	}
+
 	newFolder := &models.Folder{
 		Name:     name,
 		ParentID: parentID,
@@ -37,25 +66,38 @@ func (s *service) CreateFolder(name string, parentID *string, userID string) (*m
 }
 
 // UpdateFolder updates a folder's name.
-func (s *service) UpdateFolder(folderID string, newName string) (*models.Folder, error) {
-	// For now, we only support renaming. We'd need to fetch the folder first
-	// if we were updating more properties to avoid overwriting them.
-	updatedFolder := &models.Folder{
-		ID:   folderID,
-		Name: newName,
-	}
-	if err := s.store.Update(updatedFolder); err != nil {
+func (s *service) UpdateFolder(folderID, userID string, newName string) (*models.Folder, error) {
+	folder, err := s.authorizeFolder(folderID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// For now, we only support renaming.
+	folder.Name = newName
+	if err := s.store.Update(folder); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFolderNotFound
+		}
 		return nil, err
 	}
-	return updatedFolder, nil
+	return folder, nil
 }
 
-// DeleteFolder deletes a folder by its ID.
-func (s *service) DeleteFolder(folderID string) error {
-	return s.store.Delete(folderID)
+// DeleteFolder deletes a folder owned by userID.
+func (s *service) DeleteFolder(folderID, userID string) error {
+	if _, err := s.authorizeFolder(folderID, userID); err != nil {
+		return err
+	}
+	if err := s.store.Delete(folderID, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrFolderNotFound
+		}
+		return err
+	}
+	return nil
 }
 
 // GetFoldersByUserID retrieves all folders for a given user ID.
 func (s *service) GetFoldersByUserID(userID string) ([]models.Folder, error) {
 	return s.store.GetFoldersByUserID(userID)
-} 
\ No newline at end of file
+}
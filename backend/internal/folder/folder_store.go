@@ -6,11 +6,13 @@ import (
 )
 
 // Store defines the interface for folder data persistence.
+// Lookups and writes are scoped by the owning user.
 type Store interface {
 	Create(folder *models.Folder) error
+	GetByID(folderID, userID string) (*models.Folder, error)
 	GetFoldersByUserID(userID string) ([]models.Folder, error)
 	Update(folder *models.Folder) error
-	Delete(folderID string) error
+	Delete(folderID, userID string) error
 }
 
 // gormStore is a GORM implementation of the Store interface.
@@ -28,24 +30,48 @@ func (s *gormStore) Create(folder *models.Folder) error {
 	return s.db.Create(folder).Error
 }
 
+// GetByID retrieves a folder by its ID for the given owner.
+func (s *gormStore) GetByID(folderID, userID string) (*models.Folder, error) {
+	var folder models.Folder
+	if err := s.db.First(&folder, "id = ? AND user_id = ?", folderID, userID).Error; err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
+
 // Update updates an existing folder's name.
+// Returns gorm.ErrRecordNotFound if the folder does not belong to folder.UserID.
 func (s *gormStore) Update(folder *models.Folder) error {
 	// Using .Model and .Update to only change the specified field.
 	// .Save() would try to update all fields, causing issues with zero-values.
-	return s.db.Model(&models.Folder{}).Where("id = ?", folder.ID).Update("name", folder.Name).Error
+	result := s.db.Model(&models.Folder{}).
+		Where("id = ? AND user_id = ?", folder.ID, folder.UserID).
+		Update("name", folder.Name)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
 
-// Delete removes a folder by its ID.
+// Delete removes a folder by its ID for the given owner.
 // This is a hard delete. It also sets the folder_id of associated documents to NULL.
-func (s *gormStore) Delete(folderID string) error {
+func (s *gormStore) Delete(folderID, userID string) error {
 	return s.db.Transaction(func(tx *gorm.DB) error {
+		var folder models.Folder
+		if err := tx.First(&folder, "id = ? AND user_id = ?", folderID, userID).Error; err != nil {
+			return err
+		}
+
 		// Set folder_id to NULL for documents in the folder being deleted
-		if err := tx.Model(&models.JournalEntry{}).Where("folder_id = ?", folderID).Update("folder_id", nil).Error; err != nil {
+		if err := tx.Model(&models.JournalEntry{}).Where("folder_id = ? AND user_id = ?", folderID, userID).Update("folder_id", nil).Error; err != nil {
 			return err
 		}
 
 		// Delete the folder
-		if err := tx.Where("id = ?", folderID).Delete(&models.Folder{}).Error; err != nil {
+		if err := tx.Where("id = ? AND user_id = ?", folderID, userID).Delete(&models.Folder{}).Error; err != nil {
 			return err
 		}
 
@@ -66,4 +92,4 @@ func (s *gormStore) GetFoldersByUserID(userID string) ([]models.Folder, error) {
 		return nil, err
 	}
 	return folders, nil
-} 
\ No newline at end of file
+}
@@ -0,0 +1,95 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/adrianvalentim/gamify_journal/internal/auth"
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxListLimit caps how many jobs a single list request returns.
+const maxListLimit = 500
+
+// Handler handles the administrative HTTP endpoints of the job queue.
+type Handler struct {
+	service Service
+}
+
+// NewHandler creates a new job queue handler.
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes sets up the admin routes for inspecting and re-running jobs.
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Route("/admin/jobs", func(r chi.Router) {
+		r.Use(auth.AuthMiddleware)
+		r.Use(auth.RequireAdmin)
+
+		r.Get("/", h.handleListJobs)
+		r.Post("/{jobID}/retry", h.handleRetryJob)
+	})
+}
+
+// handleListJobs lists jobs by the "status" query parameter (default: dead), most recently updated first.
+func (h *Handler) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = models.JobDead
+	}
+	limit := 100
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxListLimit {
+			http.Error(w, "invalid value for limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	jobs, err := h.service.List(status, limit)
+	if err != nil {
+		if errors.Is(err, ErrInvalidStatus) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Error listing jobs: %v", err)
+		http.Error(w, "failed to list jobs", http.StatusInternalServerError)
+		return
+	}
+	if jobs == nil {
+		jobs = []models.Job{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jobs)
+}
+
+// handleRetryJob puts a dead job back in the queue.
+func (h *Handler) handleRetryJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "jobID"), 10, 64)
+	if err != nil {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.service.Retry(id); err != nil {
+		switch {
+		case errors.Is(err, ErrJobNotFound):
+			http.Error(w, "job not found", http.StatusNotFound)
+		case errors.Is(err, ErrJobNotDead):
+			http.Error(w, "only dead jobs can be re-run", http.StatusConflict)
+		default:
+			log.Printf("Error re-running job %d: %v", id, err)
+			http.Error(w, "failed to re-run job", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,81 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/adrianvalentim/gamify_journal/internal/auth"
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestHandler_AdminJobs(t *testing.T) {
+	store, svc, pool, _ := testQueue(t)
+	pool.Register("broken", func(ctx context.Context, job *models.Job) error {
+		return Permanent(errors.New("invalid payload"))
+	})
+	svc.Enqueue("broken", "user-1", map[string]string{"content": "Dear diary"})
+	svc.Enqueue("broken", "user-2", nil)
+	pool.RunNext("w")
+
+	auth.SetAdminUserIDs("admin-user")
+	t.Cleanup(func() { auth.SetAdminUserIDs() })
+	r := chi.NewRouter()
+	NewHandler(svc).RegisterRoutes(r)
+
+	do := func(method, target, userID string) *httptest.ResponseRecorder {
+		token, err := auth.GenerateToken(userID, "")
+		if err != nil {
+			t.Fatalf("GenerateToken() error = %v", err)
+		}
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodGet, "/admin/jobs", "user-1"); rec.Code != http.StatusForbidden {
+		t.Errorf("non-admin list: expected status %d, got %d", http.StatusForbidden, rec.Code)
+	}
+	if rec := do(http.MethodPost, "/admin/jobs/1/retry", "user-1"); rec.Code != http.StatusForbidden {
+		t.Errorf("non-admin retry: expected status %d, got %d", http.StatusForbidden, rec.Code)
+	}
+
+	rec := do(http.MethodGet, "/admin/jobs", "admin-user")
+	if strings.Contains(rec.Body.String(), "Dear diary") {
+		t.Errorf("dead jobs expose their payload: %s", rec.Body)
+	}
+	var dead []models.Job
+	if err := json.NewDecoder(rec.Body).Decode(&dead); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("list dead jobs: status %d, error %v", rec.Code, err)
+	}
+	if len(dead) != 1 || dead[0].ID != 1 || dead[0].LastError != "invalid payload" {
+		t.Errorf("dead jobs = %+v, want job 1 with its error", dead)
+	}
+	if rec := do(http.MethodGet, "/admin/jobs?status=bogus", "admin-user"); rec.Code != http.StatusBadRequest {
+		t.Errorf("list with an invalid status: expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	tests := []struct {
+		target     string
+		wantStatus int
+	}{
+		{target: "/admin/jobs/1/retry", wantStatus: http.StatusNoContent},
+		{target: "/admin/jobs/2/retry", wantStatus: http.StatusConflict},
+		{target: "/admin/jobs/99/retry", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if rec := do(http.MethodPost, tt.target, "admin-user"); rec.Code != tt.wantStatus {
+			t.Errorf("POST %s: expected status %d, got %d (%s)", tt.target, tt.wantStatus, rec.Code, rec.Body.String())
+		}
+	}
+	if job, _ := store.Get(1); job.Status != models.JobPending {
+		t.Errorf("re-run job has status %s, want %s", job.Status, models.JobPending)
+	}
+}
@@ -0,0 +1,144 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"gorm.io/gorm"
+)
+
+// Pre-defined errors returned by the job service.
+var (
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobNotDead is returned when re-running a job that has not been dead-lettered.
+	ErrJobNotDead = errors.New("job is not dead")
+	// ErrInvalidStatus is returned when listing jobs by an unknown status.
+	ErrInvalidStatus = errors.New("invalid job status")
+	// ErrLeaseLost is returned when a worker ends a job its lease on expired, so its result is dropped.
+	ErrLeaseLost = errors.New("job lease lost")
+)
+
+// Config tunes the job queue.
+type Config struct {
+	// Workers is the number of jobs processed concurrently.
+	Workers int
+	// MaxAttempts is how often a job is tried before it is dead-lettered.
+	MaxAttempts int
+	// BaseBackoff is the delay before the first retry; it doubles with every further attempt.
+	BaseBackoff time.Duration
+	// MaxBackoff caps the retry delay.
+	MaxBackoff time.Duration
+	// PollInterval is how often idle workers look for new jobs.
+	PollInterval time.Duration
+	// JobTimeout bounds a single attempt.
+	JobTimeout time.Duration
+	// Lease is how long a job may stay claimed before it is considered abandoned and re-queued.
+	// It must be longer than JobTimeout.
+	Lease time.Duration
+	// Retention is how long completed jobs are kept.
+	Retention time.Duration
+}
+
+// DefaultConfig returns the configuration used when no environment overrides are set.
+func DefaultConfig() Config {
+	return Config{
+		Workers:      4,
+		MaxAttempts:  8,
+		BaseBackoff:  10 * time.Second,
+		MaxBackoff:   time.Hour,
+		PollInterval: time.Second,
+		JobTimeout:   2 * time.Minute,
+		Lease:        10 * time.Minute,
+		Retention:    7 * 24 * time.Hour,
+	}
+}
+
+// ConfigFromEnv reads JOB_WORKERS, JOB_MAX_ATTEMPTS, JOB_RETRY_BASE_DELAY and
+// JOB_RETRY_MAX_DELAY on top of DefaultConfig.
+func ConfigFromEnv() Config {
+	cfg := DefaultConfig()
+
+	for key, target := range map[string]*int{"JOB_WORKERS": &cfg.Workers, "JOB_MAX_ATTEMPTS": &cfg.MaxAttempts} {
+		if value := os.Getenv(key); value != "" {
+			if n, err := strconv.Atoi(value); err == nil && n > 0 {
+				*target = n
+			} else {
+				log.Printf("Warning: invalid %s value %q. Defaulting to %d", key, value, *target)
+			}
+		}
+	}
+	for key, target := range map[string]*time.Duration{"JOB_RETRY_BASE_DELAY": &cfg.BaseBackoff, "JOB_RETRY_MAX_DELAY": &cfg.MaxBackoff} {
+		if value := os.Getenv(key); value != "" {
+			if d, err := time.ParseDuration(value); err == nil && d > 0 {
+				*target = d
+			} else {
+				log.Printf("Warning: invalid %s value %q. Defaulting to %s", key, value, *target)
+			}
+		}
+	}
+	return cfg
+}
+
+// Service defines the interface for enqueuing and administering jobs.
+type Service interface {
+	// Enqueue adds a job of the given kind for userID. The payload is stored as JSON.
+	Enqueue(kind, userID string, payload interface{}) error
+	List(status string, limit int) ([]models.Job, error)
+	// Retry re-runs a dead job with a fresh attempt budget.
+	Retry(id uint64) error
+}
+
+type service struct {
+	store Store
+	cfg   Config
+	now   func() time.Time
+}
+
+// NewService creates a new job service.
+func NewService(store Store, cfg Config) Service {
+	return &service{store: store, cfg: cfg, now: time.Now}
+}
+
+func (s *service) Enqueue(kind, userID string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("could not encode payload of %s job: %w", kind, err)
+	}
+	return s.store.Enqueue(&models.Job{
+		Kind:        kind,
+		UserID:      userID,
+		Payload:     string(data),
+		Status:      models.JobPending,
+		MaxAttempts: s.cfg.MaxAttempts,
+		RunAt:       s.now(),
+	})
+}
+
+func (s *service) List(status string, limit int) ([]models.Job, error) {
+	switch status {
+	case models.JobPending, models.JobRunning, models.JobDone, models.JobDead:
+	default:
+		return nil, ErrInvalidStatus
+	}
+	return s.store.List(status, limit)
+}
+
+func (s *service) Retry(id uint64) error {
+	err := s.store.Retry(id, s.now())
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if _, err := s.store.Get(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrJobNotFound
+		}
+		return err
+	}
+	return ErrJobNotDead
+}
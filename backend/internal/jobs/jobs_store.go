@@ -0,0 +1,200 @@
+package jobs
+
+import (
+	"errors"
+	"time"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"gorm.io/gorm"
+)
+
+// Store defines the interface for job queue persistence.
+type Store interface {
+	Enqueue(job *models.Job) error
+	// Claim marks the next runnable job as running on behalf of workerID and returns it,
+	// or returns nil if no job is runnable. A job is runnable once its RunAt has passed and
+	// no other job of the same user is running or was enqueued before it and is still pending.
+	Claim(workerID string, now time.Time) (*models.Job, error)
+	// Complete marks a job workerID is running as done.
+	Complete(id uint64, workerID string, at time.Time) error
+	// Fail records a failed attempt of workerID. The job runs again at retryAt, or is dead-lettered if retryAt is nil.
+	Fail(id uint64, workerID, message string, retryAt *time.Time, at time.Time) error
+	// RequeueStale puts running jobs locked before cutoff back in the queue; their worker presumably died.
+	RequeueStale(cutoff time.Time) (int64, error)
+	Get(id uint64) (*models.Job, error)
+	// List returns jobs with the given status, most recently updated first.
+	List(status string, limit int) ([]models.Job, error)
+	// Retry moves a dead job back to the queue with a fresh attempt budget.
+	// Returns gorm.ErrRecordNotFound if there is no dead job with this ID.
+	Retry(id uint64, at time.Time) error
+	// DeleteFinished removes completed jobs that finished before cutoff.
+	DeleteFinished(cutoff time.Time) (int64, error)
+}
+
+// gormStore is a GORM implementation of the Store interface.
+type gormStore struct {
+	db *gorm.DB
+}
+
+// NewStore creates a new GORM store for the job queue.
+func NewStore(db *gorm.DB) Store {
+	return &gormStore{db: db}
+}
+
+// Enqueue inserts a new job.
+func (s *gormStore) Enqueue(job *models.Job) error {
+	return s.db.Create(job).Error
+}
+
+// claimAttempts bounds how often Claim retries after losing a race for a job to another worker.
+const claimAttempts = 3
+
+// Claim selects the oldest runnable job and locks it with a conditional update,
+// so two workers never run the same job even without row locks.
+func (s *gormStore) Claim(workerID string, now time.Time) (*models.Job, error) {
+	for i := 0; i < claimAttempts; i++ {
+		var job models.Job
+		err := s.db.
+			Where("status = ? AND run_at <= ?", models.JobPending, now).
+			// Per-user ordering: earlier jobs of the user must finish (or die) first...
+			Where("NOT EXISTS (SELECT 1 FROM jobs AS earlier WHERE earlier.user_id = jobs.user_id AND earlier.id < jobs.id AND earlier.status IN ?)",
+				[]string{models.JobPending, models.JobRunning}).
+			// ...and a user never has two jobs running at once, even when a dead job is re-run.
+			Where("NOT EXISTS (SELECT 1 FROM jobs AS busy WHERE busy.user_id = jobs.user_id AND busy.status = ?)", models.JobRunning).
+			Order("id").
+			First(&job).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		result := s.db.Model(&models.Job{}).
+			Where("id = ? AND status = ?", job.ID, models.JobPending).
+			Updates(map[string]interface{}{
+				"status":     models.JobRunning,
+				"attempts":   gorm.Expr("attempts + 1"),
+				"locked_by":  workerID,
+				"locked_at":  now,
+				"updated_at": now,
+			})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			job.Status = models.JobRunning
+			job.Attempts++
+			job.LockedBy = workerID
+			job.LockedAt = &now
+			job.UpdatedAt = now
+			return &job, nil
+		}
+		// Another worker claimed it first; look for the next one.
+	}
+	return nil, nil
+}
+
+// Complete returns ErrLeaseLost if the job is not running on behalf of workerID anymore.
+func (s *gormStore) Complete(id uint64, workerID string, at time.Time) error {
+	return s.finish(id, workerID, map[string]interface{}{
+		"status":      models.JobDone,
+		"locked_by":   "",
+		"locked_at":   nil,
+		"last_error":  "",
+		"finished_at": at,
+		"updated_at":  at,
+	})
+}
+
+// Fail schedules a running job for another attempt or dead-letters it. It returns ErrLeaseLost
+// if the job is not running on behalf of workerID anymore.
+func (s *gormStore) Fail(id uint64, workerID, message string, retryAt *time.Time, at time.Time) error {
+	updates := map[string]interface{}{
+		"locked_by":  "",
+		"locked_at":  nil,
+		"last_error": message,
+		"updated_at": at,
+	}
+	if retryAt != nil {
+		updates["status"] = models.JobPending
+		updates["run_at"] = *retryAt
+	} else {
+		updates["status"] = models.JobDead
+		updates["finished_at"] = at
+	}
+	return s.finish(id, workerID, updates)
+}
+
+// finish applies updates to a job that is still running on behalf of workerID. Once its lease
+// expired, the job may have been re-queued and claimed by another worker, whose run it must not end.
+func (s *gormStore) finish(id uint64, workerID string, updates map[string]interface{}) error {
+	result := s.db.Model(&models.Job{}).
+		Where("id = ? AND status = ? AND locked_by = ?", id, models.JobRunning, workerID).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// RequeueStale releases jobs whose lock has expired.
+func (s *gormStore) RequeueStale(cutoff time.Time) (int64, error) {
+	result := s.db.Model(&models.Job{}).
+		Where("status = ? AND locked_at < ?", models.JobRunning, cutoff).
+		Updates(map[string]interface{}{
+			"status":     models.JobPending,
+			"locked_by":  "",
+			"locked_at":  nil,
+			"last_error": "worker lease expired",
+		})
+	return result.RowsAffected, result.Error
+}
+
+// Get retrieves a job by its ID.
+func (s *gormStore) Get(id uint64) (*models.Job, error) {
+	var job models.Job
+	if err := s.db.First(&job, id).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// List retrieves jobs by status.
+func (s *gormStore) List(status string, limit int) ([]models.Job, error) {
+	var jobs []models.Job
+	err := s.db.Where("status = ?", status).
+		Order("updated_at DESC, id DESC").
+		Limit(limit).
+		Find(&jobs).Error
+	return jobs, err
+}
+
+// Retry re-queues a dead job.
+func (s *gormStore) Retry(id uint64, at time.Time) error {
+	result := s.db.Model(&models.Job{}).
+		Where("id = ? AND status = ?", id, models.JobDead).
+		Updates(map[string]interface{}{
+			"status":      models.JobPending,
+			"attempts":    0,
+			"run_at":      at,
+			"finished_at": nil,
+			"updated_at":  at,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteFinished removes old completed jobs.
+func (s *gormStore) DeleteFinished(cutoff time.Time) (int64, error) {
+	result := s.db.Where("status = ? AND finished_at < ?", models.JobDone, cutoff).Delete(&models.Job{})
+	return result.RowsAffected, result.Error
+}
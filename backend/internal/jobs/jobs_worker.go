@@ -0,0 +1,175 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+)
+
+// HandlerFunc runs a job. Returning an error schedules a retry, unless the error is Permanent.
+type HandlerFunc func(ctx context.Context, job *models.Job) error
+
+// permanentError marks a failure that retrying cannot fix.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the job is dead-lettered right away instead of being retried.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// maintenanceInterval is how often the pool re-queues abandoned jobs and deletes old ones.
+const maintenanceInterval = time.Minute
+
+// Pool runs queued jobs with a fixed number of workers.
+type Pool struct {
+	store    Store
+	cfg      Config
+	handlers map[string]HandlerFunc
+	name     string
+	now      func() time.Time
+}
+
+// NewPool creates a worker pool. Handlers must be registered before Run is called.
+func NewPool(store Store, cfg Config) *Pool {
+	hostname, _ := os.Hostname()
+	return &Pool{
+		store:    store,
+		cfg:      cfg,
+		handlers: map[string]HandlerFunc{},
+		name:     fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		now:      time.Now,
+	}
+}
+
+// Register sets the handler for jobs of the given kind.
+func (p *Pool) Register(kind string, handler HandlerFunc) {
+	p.handlers[kind] = handler
+}
+
+// Run processes jobs until ctx is cancelled. It then stops claiming new jobs and returns
+// once the jobs already running have finished, so nothing is cut off mid-attempt.
+func (p *Pool) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < p.cfg.Workers; i++ {
+		wg.Add(1)
+		go func(workerID string) {
+			defer wg.Done()
+			p.work(ctx, workerID)
+		}(fmt.Sprintf("%s-%d", p.name, i))
+	}
+
+	p.maintain()
+	ticker := time.NewTicker(maintenanceInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-ticker.C:
+			p.maintain()
+		}
+	}
+}
+
+// work is the loop of a single worker.
+func (p *Pool) work(ctx context.Context, workerID string) {
+	for ctx.Err() == nil {
+		ran, err := p.RunNext(workerID)
+		if err != nil {
+			log.Printf("Error running queued job: %v", err)
+		}
+		if ran && err == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(p.cfg.PollInterval):
+		}
+	}
+}
+
+// RunNext claims and runs the next runnable job, and reports whether there was one.
+func (p *Pool) RunNext(workerID string) (bool, error) {
+	job, err := p.store.Claim(workerID, p.now())
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	runErr := p.execute(job)
+	if runErr == nil {
+		if err := p.store.Complete(job.ID, workerID, p.now()); err != nil {
+			return true, fmt.Errorf("could not complete job %d: %w", job.ID, err)
+		}
+		return true, nil
+	}
+
+	var retryAt *time.Time
+	var permanent *permanentError
+	if !errors.As(runErr, &permanent) && job.Attempts < job.MaxAttempts {
+		at := p.now().Add(p.backoff(job.Attempts))
+		retryAt = &at
+		log.Printf("Job %d (%s) failed on attempt %d/%d, retrying at %s: %v", job.ID, job.Kind, job.Attempts, job.MaxAttempts, at.Format(time.RFC3339), runErr)
+	} else {
+		log.Printf("Job %d (%s) failed on attempt %d and was dead-lettered: %v", job.ID, job.Kind, job.Attempts, runErr)
+	}
+	if err := p.store.Fail(job.ID, workerID, runErr.Error(), retryAt, p.now()); err != nil {
+		return true, fmt.Errorf("could not record failure of job %d: %w", job.ID, err)
+	}
+	return true, nil
+}
+
+// execute runs the job's handler, turning panics into errors.
+func (p *Pool) execute(job *models.Job) (err error) {
+	handler, ok := p.handlers[job.Kind]
+	if !ok {
+		return Permanent(fmt.Errorf("no handler registered for job kind %q", job.Kind))
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job handler panicked: %v", r)
+		}
+	}()
+
+	// Jobs get their own context: shutting down lets running attempts finish instead of cancelling them.
+	ctx, cancel := context.WithTimeout(context.Background(), p.cfg.JobTimeout)
+	defer cancel()
+	return handler(ctx, job)
+}
+
+// backoff returns the delay before the next attempt after the given number of attempts.
+func (p *Pool) backoff(attempts int) time.Duration {
+	delay := p.cfg.BaseBackoff
+	for i := 1; i < attempts && delay < p.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > p.cfg.MaxBackoff {
+		delay = p.cfg.MaxBackoff
+	}
+	return delay
+}
+
+// maintain re-queues abandoned jobs and deletes old completed ones.
+func (p *Pool) maintain() {
+	now := p.now()
+	if n, err := p.store.RequeueStale(now.Add(-p.cfg.Lease)); err != nil {
+		log.Printf("Error re-queuing abandoned jobs: %v", err)
+	} else if n > 0 {
+		log.Printf("Re-queued %d abandoned jobs", n)
+	}
+	if _, err := p.store.DeleteFinished(now.Add(-p.cfg.Retention)); err != nil {
+		log.Printf("Error deleting completed jobs: %v", err)
+	}
+}
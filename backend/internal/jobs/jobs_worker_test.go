@@ -0,0 +1,222 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/database/databasetest"
+)
+
+// testQueue returns a store, service and pool over a fresh database, with a clock the test controls.
+func testQueue(t *testing.T) (Store, *service, *Pool, *time.Time) {
+	t.Helper()
+	store := NewStore(databasetest.Open(t))
+
+	cfg := DefaultConfig()
+	cfg.MaxAttempts = 3
+	cfg.BaseBackoff = time.Minute
+	cfg.MaxBackoff = 3 * time.Minute
+	cfg.PollInterval = 5 * time.Millisecond
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	svc := NewService(store, cfg).(*service)
+	svc.now = clock
+	pool := NewPool(store, cfg)
+	pool.now = clock
+	return store, svc, pool, &now
+}
+
+func TestPool_RetriesWithBackoffThenDeadLetters(t *testing.T) {
+	store, svc, pool, now := testQueue(t)
+	attempts := 0
+	pool.Register("flaky", func(ctx context.Context, job *models.Job) error {
+		attempts++
+		return errors.New("agent unavailable")
+	})
+	if err := svc.Enqueue("flaky", "user-1", map[string]string{"entry_id": "doc-1"}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	for _, wantDelay := range []time.Duration{time.Minute, 2 * time.Minute} {
+		if ran, err := pool.RunNext("w"); !ran || err != nil {
+			t.Fatalf("RunNext() = %v, %v", ran, err)
+		}
+		job, _ := store.Get(1)
+		if job.Status != models.JobPending || job.RunAt.Sub(*now) != wantDelay || job.LastError != "agent unavailable" {
+			t.Fatalf("after attempt %d: job = %+v, want pending with a %s backoff", attempts, job, wantDelay)
+		}
+		// Not runnable before the backoff has passed.
+		if ran, _ := pool.RunNext("w"); ran {
+			t.Fatal("RunNext() ran a job before its backoff passed")
+		}
+		*now = job.RunAt
+	}
+
+	if ran, err := pool.RunNext("w"); !ran || err != nil {
+		t.Fatalf("RunNext() = %v, %v", ran, err)
+	}
+	job, _ := store.Get(1)
+	if job.Status != models.JobDead || job.Attempts != 3 || attempts != 3 {
+		t.Fatalf("after the last attempt: job = %+v (handler ran %d times), want dead", job, attempts)
+	}
+	if dead, _ := svc.List(models.JobDead, 10); len(dead) != 1 {
+		t.Errorf("List(dead) returned %d jobs, want 1", len(dead))
+	}
+
+	// An admin re-runs it with a fresh budget.
+	if err := svc.Retry(job.ID); err != nil {
+		t.Fatalf("Retry() error = %v", err)
+	}
+	if err := svc.Retry(job.ID); !errors.Is(err, ErrJobNotDead) {
+		t.Errorf("Retry() of a pending job error = %v, want %v", err, ErrJobNotDead)
+	}
+	if err := svc.Retry(42); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Retry() of a missing job error = %v, want %v", err, ErrJobNotFound)
+	}
+	pool.Register("flaky", func(ctx context.Context, job *models.Job) error { return nil })
+	if ran, err := pool.RunNext("w"); !ran || err != nil {
+		t.Fatalf("RunNext() = %v, %v", ran, err)
+	}
+	if job, _ := store.Get(1); job.Status != models.JobDone || job.Attempts != 1 {
+		t.Errorf("re-run job = %+v, want done after one attempt", job)
+	}
+}
+
+func TestPool_PermanentFailuresAreNotRetried(t *testing.T) {
+	store, svc, pool, _ := testQueue(t)
+	pool.Register("broken", func(ctx context.Context, job *models.Job) error {
+		return Permanent(errors.New("invalid payload"))
+	})
+	pool.Register("panics", func(ctx context.Context, job *models.Job) error {
+		panic("boom")
+	})
+	svc.Enqueue("broken", "user-1", nil)
+	svc.Enqueue("unknown", "user-2", nil)
+	svc.Enqueue("panics", "user-3", nil)
+
+	for i := 0; i < 3; i++ {
+		if ran, err := pool.RunNext("w"); !ran || err != nil {
+			t.Fatalf("RunNext() = %v, %v", ran, err)
+		}
+	}
+	for id, want := range map[uint64]string{1: models.JobDead, 2: models.JobDead, 3: models.JobPending} {
+		if job, _ := store.Get(id); job.Status != want {
+			t.Errorf("job %d (%s) has status %s, want %s", id, job.Kind, job.Status, want)
+		}
+	}
+}
+
+func TestStore_ClaimKeepsPerUserOrder(t *testing.T) {
+	store, svc, _, now := testQueue(t)
+	svc.Enqueue("a", "user-1", nil)
+	svc.Enqueue("b", "user-1", nil)
+	svc.Enqueue("c", "user-2", nil)
+
+	first, _ := store.Claim("w1", *now)
+	second, _ := store.Claim("w2", *now)
+	third, err := store.Claim("w3", *now)
+	if err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	if first == nil || first.Kind != "a" || second == nil || second.Kind != "c" || third != nil {
+		t.Fatalf("claimed %v, %v, %v; want a, c and nothing while user-1 is busy", first, second, third)
+	}
+
+	// A failed job that waits for its retry still blocks the user's later jobs.
+	retryAt := now.Add(time.Minute)
+	store.Fail(first.ID, "w1", "boom", &retryAt, *now)
+	if job, _ := store.Claim("w3", *now); job != nil {
+		t.Fatalf("claimed %s while an earlier job of the user awaits a retry", job.Kind)
+	}
+	*now = retryAt
+	if job, _ := store.Claim("w3", *now); job == nil || job.Kind != "a" || job.Attempts != 2 {
+		t.Fatalf("claimed %+v, want the retry of a", job)
+	}
+	store.Complete(first.ID, "w3", *now)
+	if job, _ := store.Claim("w3", *now); job == nil || job.Kind != "b" {
+		t.Fatalf("claimed %+v, want b once a is done", job)
+	}
+}
+
+func TestStore_LostLeaseCannotEndJob(t *testing.T) {
+	store, svc, _, now := testQueue(t)
+	svc.Enqueue("a", "user-1", nil)
+
+	stale, _ := store.Claim("w1", *now)
+	*now = now.Add(time.Hour)
+	if n, err := store.RequeueStale(*now); err != nil || n != 1 {
+		t.Fatalf("RequeueStale() = %d, %v; want 1", n, err)
+	}
+	current, _ := store.Claim("w2", *now)
+	if stale == nil || current == nil || current.ID != stale.ID {
+		t.Fatalf("claimed %+v, then %+v; want the same job twice", stale, current)
+	}
+
+	if err := store.Complete(stale.ID, "w1", *now); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("Complete() by the stale worker error = %v, want %v", err, ErrLeaseLost)
+	}
+	if err := store.Fail(stale.ID, "w1", "boom", nil, *now); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("Fail() by the stale worker error = %v, want %v", err, ErrLeaseLost)
+	}
+	if job, _ := store.Get(current.ID); job.Status != models.JobRunning || job.LockedBy != "w2" {
+		t.Fatalf("job = %+v, want it still running on w2", job)
+	}
+	if err := store.Complete(current.ID, "w2", *now); err != nil {
+		t.Errorf("Complete() by the current worker error = %v", err)
+	}
+}
+
+func TestPool_RunDrainsRunningJobs(t *testing.T) {
+	store, svc, pool, _ := testQueue(t)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	pool.Register("slow", func(ctx context.Context, job *models.Job) error {
+		close(started)
+		<-release
+		return nil
+	})
+	svc.Enqueue("slow", "user-1", nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		pool.Run(ctx)
+		close(done)
+	}()
+
+	<-started
+	cancel()
+	select {
+	case <-done:
+		t.Fatal("Run() returned while a job was still running")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	<-done
+
+	if job, _ := store.Get(1); job.Status != models.JobDone {
+		t.Errorf("drained job has status %s, want %s", job.Status, models.JobDone)
+	}
+}
+
+func TestStore_RequeueStale(t *testing.T) {
+	store, svc, _, now := testQueue(t)
+	svc.Enqueue("a", "user-1", nil)
+	if job, _ := store.Claim("crashed-worker", *now); job == nil {
+		t.Fatal("Claim() returned no job")
+	}
+
+	if n, err := store.RequeueStale(now.Add(-time.Minute)); err != nil || n != 0 {
+		t.Fatalf("RequeueStale() with a live lease = %d, %v", n, err)
+	}
+	if n, err := store.RequeueStale(now.Add(time.Minute)); err != nil || n != 1 {
+		t.Fatalf("RequeueStale() with an expired lease = %d, %v", n, err)
+	}
+	if job, _ := store.Claim("w", *now); job == nil || job.Attempts != 2 {
+		t.Errorf("claimed %+v after re-queuing, want the abandoned job", job)
+	}
+}
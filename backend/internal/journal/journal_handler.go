@@ -2,9 +2,16 @@ package journal
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/adrianvalentim/gamify_journal/internal/auth"
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/tag"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -21,21 +28,42 @@ func (h *Handler) RegisterRoutes(r chi.Router) {
 		r.Use(auth.AuthMiddleware)
 
 		r.Post("/", h.createJournalEntry)
+		r.Post("/import", h.importJournalEntries)
 		r.Get("/me", h.handleGetMyJournalEntries)
+		r.Get("/search", h.searchJournalEntries)
 		r.Get("/{journalId}", h.getJournalEntry)
 		r.Put("/{journalId}", h.updateJournalEntry)
 		r.Delete("/{journalId}", h.deleteJournalEntry)
+
+		r.Get("/{journalId}/revisions", h.listRevisions)
+		r.Get("/{journalId}/revisions/diff", h.diffRevisions)
+		r.Get("/{journalId}/revisions/{rev}", h.getRevision)
+		r.Post("/{journalId}/revisions/{rev}/restore", h.restoreRevision)
+
+		r.Post("/{journalId}/tags", h.addTags)
+		r.Delete("/{journalId}/tags/{tagId}", h.removeTag)
 	})
 }
 
 func (h *Handler) getJournalEntry(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
 	journalId := chi.URLParam(r, "journalId")
-	entry, err := h.service.GetJournalEntry(journalId)
+	entry, err := h.service.GetJournalEntry(journalId, userID)
 	if err != nil {
-		http.Error(w, "entry not found", http.StatusNotFound)
+		if errors.Is(err, ErrEntryNotFound) {
+			http.Error(w, "entry not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "failed to get entry", http.StatusInternalServerError)
 		return
 	}
 
+	w.Header().Set("ETag", entityTag(entry))
 	json.NewEncoder(w).Encode(entry)
 }
 
@@ -59,21 +87,39 @@ func (h *Handler) createJournalEntry(w http.ResponseWriter, r *http.Request) {
 
 	entry, err := h.service.CreateJournalEntry(payload.Title, payload.Content, userID, payload.FolderID)
 	if err != nil {
+		if errors.Is(err, ErrFolderNotFound) {
+			http.Error(w, "folder not found", http.StatusNotFound)
+			return
+		}
 		http.Error(w, "failed to create entry", http.StatusInternalServerError)
 		return
 	}
 
+	w.Header().Set("ETag", entityTag(entry))
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(entry)
 }
 
+// updateJournalEntry saves an entry. When an If-Match header with the entry's ETag is given, the save
+// only succeeds if nobody else saved the entry since; otherwise 412 is returned with the current copy.
 func (h *Handler) updateJournalEntry(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
 	journalId := chi.URLParam(r, "journalId")
+	version, err := parseIfMatch(r.Header.Get("If-Match"))
+	if err != nil {
+		http.Error(w, "invalid If-Match header", http.StatusBadRequest)
+		return
+	}
+
 	var payload struct {
 		Title    string  `json:"title"`
 		Content  string  `json:"content"`
 		FolderID *string `json:"folder_id"`
-		NewText  string  `json:"new_text"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
@@ -81,15 +127,60 @@ func (h *Handler) updateJournalEntry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	entry, err := h.service.UpdateJournalEntry(journalId, payload.Title, payload.Content, payload.FolderID, payload.NewText)
+	entry, err := h.service.UpdateJournalEntry(journalId, userID, payload.Title, payload.Content, payload.FolderID, version)
 	if err != nil {
+		var conflict *VersionConflictError
+		if errors.As(err, &conflict) {
+			writeConflict(w, conflict)
+			return
+		}
+		if errors.Is(err, ErrEntryNotFound) {
+			http.Error(w, "entry not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, ErrFolderNotFound) {
+			http.Error(w, "folder not found", http.StatusNotFound)
+			return
+		}
 		http.Error(w, "failed to update entry", http.StatusInternalServerError)
 		return
 	}
 
+	w.Header().Set("ETag", entityTag(entry))
 	json.NewEncoder(w).Encode(entry)
 }
 
+// entityTag returns the ETag of an entry, which is derived from its version.
+func entityTag(entry *models.JournalEntry) string {
+	return `"` + strconv.Itoa(entry.Version) + `"`
+}
+
+// parseIfMatch returns the entry version named by an If-Match header.
+// An absent header or "*" returns 0, meaning the save is not conditional.
+func parseIfMatch(header string) (int, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	tag := strings.TrimPrefix(header, "W/")
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, errors.New("malformed entity tag")
+	}
+	version, err := strconv.Atoi(tag[1 : len(tag)-1])
+	if err != nil || version < 1 {
+		return 0, errors.New("unknown entity tag")
+	}
+	return version, nil
+}
+
+// writeConflict responds with 412 and the entry as currently stored, so the client can reconcile its edits.
+func writeConflict(w http.ResponseWriter, conflict *VersionConflictError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", entityTag(conflict.Current))
+	w.WriteHeader(http.StatusPreconditionFailed)
+	json.NewEncoder(w).Encode(conflict.Current)
+}
+
 func (h *Handler) handleGetMyJournalEntries(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(auth.UserIDKey).(string)
 	if !ok {
@@ -97,7 +188,7 @@ func (h *Handler) handleGetMyJournalEntries(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	entries, err := h.service.GetJournalEntriesByUserID(userID)
+	entries, err := h.service.GetJournalEntriesByUserID(userID, r.URL.Query().Get("tag"))
 	if err != nil {
 		http.Error(w, "failed to get entries for user", http.StatusInternalServerError)
 		return
@@ -106,13 +197,312 @@ func (h *Handler) handleGetMyJournalEntries(w http.ResponseWriter, r *http.Reque
 	json.NewEncoder(w).Encode(entries)
 }
 
+// searchJournalEntries runs a full-text search of the user's entries. Query parameters: q is the
+// search; folder_id, tag and mood filter the results; from and to (YYYY-MM-DD) are the first and
+// last days entries were created on; limit and offset page through the results.
+func (h *Handler) searchJournalEntries(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	values := r.URL.Query()
+	query := SearchQuery{Text: values.Get("q"), FolderID: values.Get("folder_id"), Tag: values.Get("tag"), Mood: values.Get("mood")}
+	for name, target := range map[string]*time.Time{"from": &query.From, "to": &query.To} {
+		if value := values.Get(name); value != "" {
+			day, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				http.Error(w, "invalid value for "+name, http.StatusBadRequest)
+				return
+			}
+			*target = day
+		}
+	}
+	for name, target := range map[string]*int{"limit": &query.Limit, "offset": &query.Offset} {
+		value := values.Get(name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 || (name == "limit" && (n == 0 || n > MaxSearchLimit)) {
+			http.Error(w, "invalid value for "+name, http.StatusBadRequest)
+			return
+		}
+		*target = n
+	}
+
+	results, err := h.service.SearchJournalEntries(userID, query)
+	if err != nil {
+		if errors.Is(err, ErrEmptySearch) || errors.Is(err, ErrInvalidDateRange) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "failed to search entries", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(results)
+}
+
 func (h *Handler) deleteJournalEntry(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
 	journalId := chi.URLParam(r, "journalId")
-	err := h.service.DeleteJournalEntry(journalId)
+	err := h.service.DeleteJournalEntry(journalId, userID)
 	if err != nil {
+		if errors.Is(err, ErrEntryNotFound) {
+			http.Error(w, "entry not found", http.StatusNotFound)
+			return
+		}
 		http.Error(w, "failed to delete entry", http.StatusInternalServerError)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// importJournalEntries imports entries from an uploaded ZIP of Markdown/text files or a Day One JSON export.
+// The multipart form takes the upload as "file", plus the optional booleans "dry_run" and "process_with_ai".
+func (h *Handler) importJournalEntries(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, MaxImportSize+1<<20)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		http.Error(w, "invalid multipart form or file too large", http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	var opts ImportOptions
+	for name, target := range map[string]*bool{"dry_run": &opts.DryRun, "process_with_ai": &opts.ProcessWithAI} {
+		value := r.FormValue(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, "invalid value for "+name, http.StatusBadRequest)
+			return
+		}
+		*target = parsed
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "missing file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	entries, err := ParseImport(file, header.Size, header.Filename)
+	if err != nil {
+		if errors.Is(err, ErrInvalidImport) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Error reading import for user %s: %v", userID, err)
+		http.Error(w, "failed to read import file", http.StatusInternalServerError)
+		return
+	}
+
+	report, err := h.service.ImportEntries(userID, entries, opts)
+	if err != nil {
+		log.Printf("Error importing entries for user %s: %v", userID, err)
+		http.Error(w, "failed to import entries", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if opts.DryRun {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// revisionResponse describes a revision in listings, without its content.
+type revisionResponse struct {
+	Number    int       `json:"number"`
+	Title     string    `json:"title"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (h *Handler) listRevisions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	revisions, err := h.service.ListRevisions(chi.URLParam(r, "journalId"), userID)
+	if err != nil {
+		writeRevisionError(w, err)
+		return
+	}
+
+	response := make([]revisionResponse, 0, len(revisions))
+	for _, revision := range revisions {
+		response = append(response, revisionResponse{
+			Number:    revision.Number,
+			Title:     revision.Title,
+			CreatedAt: revision.CreatedAt,
+			UpdatedAt: revision.UpdatedAt,
+		})
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handler) getRevision(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	number, err := strconv.Atoi(chi.URLParam(r, "rev"))
+	if err != nil || number < 1 {
+		http.Error(w, "revision not found", http.StatusNotFound)
+		return
+	}
+
+	revision, err := h.service.GetRevision(chi.URLParam(r, "journalId"), userID, number)
+	if err != nil {
+		writeRevisionError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(revision)
+}
+
+// diffRevisions compares the revisions given by the "from" and "to" query parameters.
+// By default the latest revision is compared with the one before it.
+func (h *Handler) diffRevisions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var from, to int
+	for name, target := range map[string]*int{"from": &from, "to": &to} {
+		value := r.URL.Query().Get(name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid value for "+name, http.StatusBadRequest)
+			return
+		}
+		*target = n
+	}
+
+	diff, err := h.service.DiffRevisions(chi.URLParam(r, "journalId"), userID, from, to)
+	if err != nil {
+		writeRevisionError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(diff)
+}
+
+func (h *Handler) restoreRevision(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	number, err := strconv.Atoi(chi.URLParam(r, "rev"))
+	if err != nil || number < 1 {
+		http.Error(w, "revision not found", http.StatusNotFound)
+		return
+	}
+
+	entry, err := h.service.RestoreRevision(chi.URLParam(r, "journalId"), userID, number)
+	if err != nil {
+		writeRevisionError(w, err)
+		return
+	}
+	w.Header().Set("ETag", entityTag(entry))
+	json.NewEncoder(w).Encode(entry)
+}
+
+// writeRevisionError maps errors of the revision endpoints to responses.
+func writeRevisionError(w http.ResponseWriter, err error) {
+	var conflict *VersionConflictError
+	switch {
+	case errors.As(err, &conflict):
+		writeConflict(w, conflict)
+	case errors.Is(err, ErrEntryNotFound):
+		http.Error(w, "entry not found", http.StatusNotFound)
+	case errors.Is(err, ErrRevisionNotFound):
+		http.Error(w, "revision not found", http.StatusNotFound)
+	default:
+		log.Printf("Error handling journal revisions: %v", err)
+		http.Error(w, "failed to process revisions", http.StatusInternalServerError)
+	}
+}
+
+// addTags attaches the tags named in the request body to an entry, creating missing tags.
+func (h *Handler) addTags(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var payload struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || len(payload.Tags) == 0 {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.service.AddTags(chi.URLParam(r, "journalId"), userID, payload.Tags)
+	if err != nil {
+		writeTagError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", entityTag(entry))
+	json.NewEncoder(w).Encode(entry)
+}
+
+func (h *Handler) removeTag(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	entry, err := h.service.RemoveTag(chi.URLParam(r, "journalId"), userID, chi.URLParam(r, "tagId"))
+	if err != nil {
+		writeTagError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", entityTag(entry))
+	json.NewEncoder(w).Encode(entry)
+}
+
+func writeTagError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrEntryNotFound):
+		http.Error(w, "entry not found", http.StatusNotFound)
+	case errors.Is(err, tag.ErrInvalidName):
+		http.Error(w, "tag names must be between 1 and 50 characters", http.StatusBadRequest)
+	default:
+		log.Printf("Error tagging journal entry: %v", err)
+		http.Error(w, "failed to update tags", http.StatusInternalServerError)
+	}
+}
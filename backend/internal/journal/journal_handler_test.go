@@ -0,0 +1,496 @@
+package journal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+
+	"github.com/adrianvalentim/gamify_journal/internal/auth"
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+)
+
+// memoryStore is an in-memory implementation of the Store interface that mirrors
+// the owner scoping of the GORM store.
+type memoryStore struct {
+	entries   map[string]models.JournalEntry
+	folders   map[string]string // folder ID -> owner ID
+	created   []models.Folder   // folders added through CreateFolder
+	revisions []models.JournalRevision
+	scored    map[string]bool // entry ID + "/" + paragraph hash
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{entries: map[string]models.JournalEntry{}, folders: map[string]string{}}
+}
+
+func (m *memoryStore) GetByID(id, userID string) (*models.JournalEntry, error) {
+	entry, ok := m.entries[id]
+	if !ok || entry.UserID != userID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &entry, nil
+}
+
+func (m *memoryStore) Update(entry *models.JournalEntry) error {
+	existing, ok := m.entries[entry.ID]
+	if !ok || existing.UserID != entry.UserID {
+		return gorm.ErrRecordNotFound
+	}
+	if existing.Version != entry.Version {
+		return errStaleVersion
+	}
+	entry.Version++
+	m.entries[entry.ID] = *entry
+	return nil
+}
+
+func (m *memoryStore) Create(entry *models.JournalEntry) error {
+	if _, ok := m.entries[entry.ID]; ok {
+		return gorm.ErrDuplicatedKey
+	}
+	if entry.Version == 0 {
+		entry.Version = 1
+	}
+	m.entries[entry.ID] = *entry
+	return nil
+}
+
+func (m *memoryStore) GetByUserID(userID, tagName string) ([]models.JournalEntry, error) {
+	var entries []models.JournalEntry
+	for _, entry := range m.entries {
+		if entry.UserID == userID && (tagName == "" || hasTag(entry, tagName)) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func hasTag(entry models.JournalEntry, name string) bool {
+	for _, tag := range entry.Tags {
+		if tag.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *memoryStore) Delete(id, userID string) error {
+	entry, ok := m.entries[id]
+	if !ok || entry.UserID != userID {
+		return gorm.ErrRecordNotFound
+	}
+	delete(m.entries, id)
+	return nil
+}
+
+func (m *memoryStore) FolderExists(folderID, userID string) (bool, error) {
+	return m.folders[folderID] == userID, nil
+}
+
+func (m *memoryStore) FindFolder(userID, name string, parentID *string) (*models.Folder, error) {
+	for _, folder := range m.created {
+		sameParent := (folder.ParentID == nil && parentID == nil) ||
+			(folder.ParentID != nil && parentID != nil && *folder.ParentID == *parentID)
+		if folder.UserID == userID && folder.Name == name && sameParent {
+			return &folder, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryStore) CreateFolder(folder *models.Folder) error {
+	if folder.ID == "" {
+		folder.ID = fmt.Sprintf("folder-%d", len(m.created)+1)
+	}
+	m.created = append(m.created, *folder)
+	m.folders[folder.ID] = folder.UserID
+	return nil
+}
+
+func (m *memoryStore) HasDuplicate(userID, title, content string) (bool, error) {
+	for _, entry := range m.entries {
+		if entry.UserID == userID && entry.Title == title && entry.Content == content {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memoryStore) LatestRevision(entryID, userID string) (*models.JournalRevision, error) {
+	var latest *models.JournalRevision
+	for i, revision := range m.revisions {
+		if revision.EntryID == entryID && revision.UserID == userID && (latest == nil || revision.Number > latest.Number) {
+			latest = &m.revisions[i]
+		}
+	}
+	if latest == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *latest
+	return &copied, nil
+}
+
+func (m *memoryStore) CreateRevision(revision *models.JournalRevision) error {
+	for _, existing := range m.revisions {
+		if existing.EntryID == revision.EntryID && existing.Number == revision.Number {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	m.revisions = append(m.revisions, *revision)
+	return nil
+}
+
+func (m *memoryStore) UpdateRevision(revision *models.JournalRevision) error {
+	for i, existing := range m.revisions {
+		if existing.ID == revision.ID {
+			m.revisions[i].Title = revision.Title
+			m.revisions[i].Content = revision.Content
+			m.revisions[i].UpdatedAt = revision.UpdatedAt
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (m *memoryStore) ListRevisions(entryID, userID string) ([]models.JournalRevision, error) {
+	var revisions []models.JournalRevision
+	for i := len(m.revisions) - 1; i >= 0; i-- {
+		if revision := m.revisions[i]; revision.EntryID == entryID && revision.UserID == userID {
+			revision.Content = ""
+			revisions = append(revisions, revision)
+		}
+	}
+	return revisions, nil
+}
+
+func (m *memoryStore) GetRevision(entryID, userID string, number int) (*models.JournalRevision, error) {
+	for _, revision := range m.revisions {
+		if revision.EntryID == entryID && revision.UserID == userID && revision.Number == number {
+			return &revision, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryStore) PruneRevisions(entryID string, keep int, olderThan time.Time) error {
+	latest := 0
+	for _, revision := range m.revisions {
+		if revision.EntryID == entryID && revision.Number > latest {
+			latest = revision.Number
+		}
+	}
+	kept := m.revisions[:0]
+	for _, revision := range m.revisions {
+		expired := (keep > 0 && revision.Number <= latest-keep) || (!olderThan.IsZero() && revision.CreatedAt.Before(olderThan))
+		if revision.EntryID != entryID || revision.Number == latest || !expired {
+			kept = append(kept, revision)
+		}
+	}
+	m.revisions = kept
+	return nil
+}
+
+func (m *memoryStore) ClaimParagraphs(entryID, userID string, hashes []string) ([]string, error) {
+	if m.scored == nil {
+		m.scored = map[string]bool{}
+	}
+	var claimed []string
+	for _, hash := range hashes {
+		if !m.scored[entryID+"/"+hash] {
+			m.scored[entryID+"/"+hash] = true
+			claimed = append(claimed, hash)
+		}
+	}
+	return claimed, nil
+}
+
+func (m *memoryStore) AttachTags(entry *models.JournalEntry, tags []models.Tag) error {
+	stored := m.entries[entry.ID]
+	for _, tag := range tags {
+		if !hasTag(stored, tag.Name) {
+			stored.Tags = append(stored.Tags, tag)
+		}
+	}
+	m.entries[entry.ID] = stored
+	return nil
+}
+
+func (m *memoryStore) DetachTag(entry *models.JournalEntry, tagID string) error {
+	stored := m.entries[entry.ID]
+	var kept []models.Tag
+	for _, tag := range stored.Tags {
+		if tag.ID != tagID {
+			kept = append(kept, tag)
+		}
+	}
+	stored.Tags = kept
+	m.entries[entry.ID] = stored
+	return nil
+}
+
+func (m *memoryStore) SaveSuggestedTags(entryID, userID string, names []string) error {
+	stored, ok := m.entries[entryID]
+	if !ok || stored.UserID != userID {
+		return nil
+	}
+	stored.SuggestedTags = names
+	m.entries[entryID] = stored
+	return nil
+}
+
+// Search matches the user's entries whose title or content contains the query. Filters and
+// ranking are left to the database tests.
+func (m *memoryStore) Search(userID string, filter SearchFilter, limit, offset int) ([]SearchResult, int64, error) {
+	var results []SearchResult
+	for _, entry := range m.entries {
+		if entry.UserID == userID && (strings.Contains(entry.Title, filter.Text) || strings.Contains(entry.Content, filter.Text)) {
+			results = append(results, SearchResult{ID: entry.ID, Title: entry.Title, SearchText: entry.Content})
+		}
+	}
+	return results, int64(len(results)), nil
+}
+
+func (m *memoryStore) UnindexedEntries(limit int) ([]models.JournalEntry, error) {
+	return nil, nil
+}
+
+func (m *memoryStore) SaveSearchIndex(entry *models.JournalEntry) error {
+	return nil
+}
+
+const (
+	ownerID    = "owner-user"
+	intruderID = "intruder-user"
+	entryID    = "doc-owned"
+)
+
+// recordingQueue is a JobQueue that keeps enqueued jobs in memory.
+type recordingQueue struct {
+	jobs []queuedJob
+}
+
+type queuedJob struct {
+	kind    string
+	userID  string
+	payload interface{}
+}
+
+func (q *recordingQueue) Enqueue(kind, userID string, payload interface{}) error {
+	q.jobs = append(q.jobs, queuedJob{kind: kind, userID: userID, payload: payload})
+	return nil
+}
+
+func newTestRouter(t *testing.T, store Store) http.Handler {
+	t.Helper()
+	return newTestRouterWithQueue(store, &recordingQueue{})
+}
+
+func newTestRouterWithQueue(store Store, queue JobQueue) http.Handler {
+	handler := NewHandler(NewService(store, queue, nil, nil, nil, nil))
+
+	r := chi.NewRouter()
+	handler.RegisterRoutes(r)
+	return r
+}
+
+func authorizedRequest(t *testing.T, method, target, body, userID string) *http.Request {
+	t.Helper()
+	token, err := auth.GenerateToken(userID, "")
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+// TestHandler_CrossUserAccess checks every route registered by RegisterRoutes against a
+// journal entry owned by another user. Foreign entries must look exactly like missing ones.
+func TestHandler_CrossUserAccess(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+	}{
+		{name: "get foreign entry", method: http.MethodGet, target: "/journal/" + entryID, wantStatus: http.StatusNotFound},
+		{name: "update foreign entry", method: http.MethodPut, target: "/journal/" + entryID, body: `{"title":"pwned","content":"<p>pwned</p>"}`, wantStatus: http.StatusNotFound},
+		{name: "delete foreign entry", method: http.MethodDelete, target: "/journal/" + entryID, wantStatus: http.StatusNotFound},
+		{name: "create entry in foreign folder", method: http.MethodPost, target: "/journal/", body: `{"title":"x","content":"x","folder_id":"folder-owned"}`, wantStatus: http.StatusNotFound},
+		{name: "move entry into foreign folder", method: http.MethodPut, target: "/journal/doc-intruder", body: `{"content":"x","folder_id":"folder-owned"}`, wantStatus: http.StatusNotFound},
+		{name: "list excludes foreign entries", method: http.MethodGet, target: "/journal/me", wantStatus: http.StatusOK},
+		{name: "list foreign revisions", method: http.MethodGet, target: "/journal/" + entryID + "/revisions", wantStatus: http.StatusNotFound},
+		{name: "get foreign revision", method: http.MethodGet, target: "/journal/" + entryID + "/revisions/1", wantStatus: http.StatusNotFound},
+		{name: "diff foreign revisions", method: http.MethodGet, target: "/journal/" + entryID + "/revisions/diff", wantStatus: http.StatusNotFound},
+		{name: "restore foreign revision", method: http.MethodPost, target: "/journal/" + entryID + "/revisions/1/restore", wantStatus: http.StatusNotFound},
+		{name: "tag foreign entry", method: http.MethodPost, target: "/journal/" + entryID + "/tags", body: `{"tags":["pwned"]}`, wantStatus: http.StatusNotFound},
+		{name: "search excludes foreign entries", method: http.MethodGet, target: "/journal/search?q=diary", wantStatus: http.StatusOK},
+		{name: "untag foreign entry", method: http.MethodDelete, target: "/journal/" + entryID + "/tags/tag-1", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemoryStore()
+			store.entries[entryID] = models.JournalEntry{ID: entryID, UserID: ownerID, Title: "Secret", Content: "<p>dear diary</p>"}
+			store.entries["doc-intruder"] = models.JournalEntry{ID: "doc-intruder", UserID: intruderID, Title: "Mine"}
+			store.folders["folder-owned"] = ownerID
+			store.revisions = []models.JournalRevision{{ID: "rev-1", EntryID: entryID, UserID: ownerID, Number: 1, Title: "Secret", Content: "<p>dear diary</p>"}}
+			router := newTestRouter(t, store)
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, authorizedRequest(t, tt.method, tt.target, tt.body, intruderID))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("%s %s: expected status %d, got %d (%s)", tt.method, tt.target, tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if strings.Contains(rec.Body.String(), "dear diary") {
+				t.Errorf("%s %s leaked the owner's content: %s", tt.method, tt.target, rec.Body.String())
+			}
+
+			owned := store.entries[entryID]
+			if owned.Title != "Secret" || owned.Content != "<p>dear diary</p>" || owned.UserID != ownerID {
+				t.Errorf("%s %s modified the owner's entry: %+v", tt.method, tt.target, owned)
+			}
+			if moved := store.entries["doc-intruder"]; moved.FolderID != nil {
+				t.Errorf("%s %s moved an entry into a foreign folder", tt.method, tt.target)
+			}
+		})
+	}
+}
+
+// TestHandler_OwnerAccess is the positive control for TestHandler_CrossUserAccess.
+func TestHandler_OwnerAccess(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+	}{
+		{name: "get own entry", method: http.MethodGet, target: "/journal/" + entryID, wantStatus: http.StatusOK},
+		{name: "update own entry", method: http.MethodPut, target: "/journal/" + entryID, body: `{"title":"Renamed"}`, wantStatus: http.StatusOK},
+		{name: "delete own entry", method: http.MethodDelete, target: "/journal/" + entryID, wantStatus: http.StatusNoContent},
+		{name: "create entry in own folder", method: http.MethodPost, target: "/journal/", body: `{"title":"x","content":"x","folder_id":"folder-owned"}`, wantStatus: http.StatusCreated},
+		{name: "list own revisions", method: http.MethodGet, target: "/journal/" + entryID + "/revisions", wantStatus: http.StatusOK},
+		{name: "get own revision", method: http.MethodGet, target: "/journal/" + entryID + "/revisions/1", wantStatus: http.StatusOK},
+		{name: "diff own revisions", method: http.MethodGet, target: "/journal/" + entryID + "/revisions/diff", wantStatus: http.StatusOK},
+		{name: "restore own revision", method: http.MethodPost, target: "/journal/" + entryID + "/revisions/1/restore", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemoryStore()
+			store.entries[entryID] = models.JournalEntry{ID: entryID, UserID: ownerID, Title: "Secret", Content: "<p>dear diary</p>"}
+			store.folders["folder-owned"] = ownerID
+			store.revisions = []models.JournalRevision{{ID: "rev-1", EntryID: entryID, UserID: ownerID, Number: 1, Title: "Secret", Content: "<p>dear diary</p>"}}
+			router := newTestRouter(t, store)
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, authorizedRequest(t, tt.method, tt.target, tt.body, ownerID))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("%s %s: expected status %d, got %d (%s)", tt.method, tt.target, tt.wantStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_OptimisticConcurrency(t *testing.T) {
+	store := newMemoryStore()
+	store.entries[entryID] = models.JournalEntry{ID: entryID, UserID: ownerID, Title: "Day", Content: "<p>draft</p>", Version: 1}
+	router := newTestRouter(t, store)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authorizedRequest(t, http.MethodGet, "/journal/"+entryID, "", ownerID))
+	etag := rec.Header().Get("ETag")
+	if etag != `"1"` {
+		t.Fatalf("GET ETag = %q, want %q", etag, `"1"`)
+	}
+
+	// The first tab saves with the ETag it loaded and gets the next one back.
+	req := authorizedRequest(t, http.MethodPut, "/journal/"+entryID, `{"content":"<p>from tab one</p>"}`, ownerID)
+	req.Header.Set("If-Match", etag)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"2"` {
+		t.Fatalf("first PUT: status %d, ETag %q (%s)", rec.Code, rec.Header().Get("ETag"), rec.Body.String())
+	}
+
+	// The second tab still holds the old ETag and must not clobber the first save.
+	req = authorizedRequest(t, http.MethodPut, "/journal/"+entryID, `{"content":"<p>from tab two</p>"}`, ownerID)
+	req.Header.Set("If-Match", etag)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale PUT: expected status %d, got %d (%s)", http.StatusPreconditionFailed, rec.Code, rec.Body.String())
+	}
+	var current models.JournalEntry
+	if err := json.NewDecoder(rec.Body).Decode(&current); err != nil {
+		t.Fatalf("decode conflict body: %v", err)
+	}
+	if current.Content != "<p>from tab one</p>" || current.Version != 2 || rec.Header().Get("ETag") != `"2"` {
+		t.Errorf("conflict response = %+v with ETag %q, want the server copy at version 2", current, rec.Header().Get("ETag"))
+	}
+	if got := store.entries[entryID].Content; got != "<p>from tab one</p>" {
+		t.Errorf("stored content = %q, the stale save must not be applied", got)
+	}
+
+	for _, header := range []string{"2", `"abc"`} {
+		req = authorizedRequest(t, http.MethodPut, "/journal/"+entryID, `{"content":"x"}`, ownerID)
+		req.Header.Set("If-Match", header)
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("If-Match %s: expected status %d, got %d", header, http.StatusBadRequest, rec.Code)
+		}
+	}
+
+	// A conditional save never creates a missing entry.
+	req = authorizedRequest(t, http.MethodPut, "/journal/doc-new", `{"content":"x"}`, ownerID)
+	req.Header.Set("If-Match", `"1"`)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("conditional PUT of a missing entry: expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestHandler_UpdateSendsOnlyNewParagraphs(t *testing.T) {
+	store := newMemoryStore()
+	store.entries[entryID] = models.JournalEntry{ID: entryID, UserID: ownerID, Title: "Day", Content: "<p>Woke up early.</p>", Version: 1}
+	queue := &recordingQueue{}
+	router := newTestRouterWithQueue(store, queue)
+
+	// A client-supplied new_text is ignored; the server works out what is new.
+	body := `{"content":"<p>Woke up <em>early</em>.</p><p>Ran 5km.</p>","new_text":"I slayed a dragon"}`
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authorizedRequest(t, http.MethodPut, "/journal/"+entryID, body, ownerID))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT: expected status %d, got %d (%s)", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if len(queue.jobs) != 2 || queue.jobs[0].kind != JobProcessXP || queue.jobs[1].kind != JobProcessQuests {
+		t.Fatalf("enqueued jobs = %+v, want an XP and a quest job", queue.jobs)
+	}
+	for _, job := range queue.jobs {
+		payload := job.payload.(aiJobPayload)
+		if job.userID != ownerID || payload.EntryID != entryID || payload.Text != "Ran 5km." {
+			t.Errorf("%s job = %+v, want only the new paragraph of the entry", job.kind, job)
+		}
+	}
+
+	// Saving the same content again has nothing new to score.
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authorizedRequest(t, http.MethodPut, "/journal/"+entryID, body, ownerID))
+	if len(queue.jobs) != 2 {
+		t.Errorf("a save without new paragraphs enqueued %d more jobs", len(queue.jobs)-2)
+	}
+}
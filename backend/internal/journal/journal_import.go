@@ -0,0 +1,311 @@
+package journal
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/adrianvalentim/gamify_journal/internal/platform/richtext"
+)
+
+// Limits that keep a single import from exhausting memory.
+const (
+	MaxImportSize        = 64 << 20 // Size of the uploaded file
+	maxImportFileSize    = 8 << 20  // Uncompressed size of one file inside an archive
+	maxImportEntries     = 5000
+	maxImportFolderDepth = 16
+)
+
+// ErrInvalidImport is returned when an uploaded file is not a supported import format.
+var ErrInvalidImport = errors.New("unsupported or malformed import file")
+
+// ImportedEntry is a journal entry parsed from an import file, before it is saved.
+type ImportedEntry struct {
+	Source     string     // File (and entry index, for JSON exports) it was read from
+	FolderPath []string   // Folder names from the root, empty for the root
+	Title      string     // Never empty
+	Content    string     // Editor HTML
+	Mood       string     // Optional
+	CreatedAt  *time.Time // From front matter or the source app, if known
+	// LocalTime is set when CreatedAt had no UTC offset. It is then the wall clock time of the
+	// user, and ImportEntries moves it to their time zone.
+	LocalTime bool
+}
+
+// ParseImport reads the entries from an uploaded file. Supported formats are:
+//   - a ZIP of Markdown (.md, .markdown) and plain-text (.txt) files, where directories
+//     become folders and YAML front matter can set title, date and mood;
+//   - a Day One JSON export, on its own or inside a ZIP.
+//
+// Archives produced by GET /users/me/export are recognized by their manifest.json,
+// and the "journal" directory holding their folder tree is not turned into a folder.
+func ParseImport(r io.ReaderAt, size int64, filename string) ([]ImportedEntry, error) {
+	if strings.EqualFold(path.Ext(filename), ".json") {
+		data, err := io.ReadAll(io.LimitReader(io.NewSectionReader(r, 0, size), MaxImportSize))
+		if err != nil {
+			return nil, err
+		}
+		return parseDayOne(data, path.Base(filename))
+	}
+
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: expected a .zip or .json file", ErrInvalidImport)
+	}
+
+	prefix := ""
+	var files []*zip.File
+	for _, f := range zr.File {
+		if f.Name == "manifest.json" {
+			prefix = "journal/"
+		}
+		if f.FileInfo().IsDir() || isHiddenPath(f.Name) {
+			continue
+		}
+		switch strings.ToLower(path.Ext(f.Name)) {
+		case ".md", ".markdown", ".txt", ".json":
+			files = append(files, f)
+		}
+	}
+
+	var entries []ImportedEntry
+	for _, f := range files {
+		data, err := readZipFile(f)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimPrefix(f.Name, prefix)
+
+		if strings.EqualFold(path.Ext(name), ".json") {
+			// Other JSON files (like an export manifest) are not journals and are skipped.
+			parsed, err := parseDayOne(data, name)
+			if err != nil {
+				continue
+			}
+			entries = append(entries, parsed...)
+		} else {
+			entries = append(entries, parseTextFile(name, data))
+		}
+		if len(entries) > maxImportEntries {
+			return nil, fmt.Errorf("%w: more than %d entries", ErrInvalidImport, maxImportEntries)
+		}
+	}
+	return entries, nil
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	if f.UncompressedSize64 > maxImportFileSize {
+		return nil, fmt.Errorf("%w: %s is larger than %d bytes", ErrInvalidImport, f.Name, maxImportFileSize)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+	defer rc.Close()
+	// The header size can lie, so the read is bounded as well.
+	data, err := io.ReadAll(io.LimitReader(rc, maxImportFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+	if len(data) > maxImportFileSize {
+		return nil, fmt.Errorf("%w: %s is larger than %d bytes", ErrInvalidImport, f.Name, maxImportFileSize)
+	}
+	return data, nil
+}
+
+// isHiddenPath reports whether any element of a path is hidden or OS metadata (e.g. __MACOSX).
+func isHiddenPath(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") || part == "__MACOSX" {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTextFile converts a Markdown or plain-text file to an entry.
+func parseTextFile(name string, data []byte) ImportedEntry {
+	text := strings.TrimPrefix(toValidUTF8(data), "\ufeff")
+	entry := ImportedEntry{Source: name}
+
+	dir := path.Dir(name)
+	if dir != "." {
+		for _, part := range strings.Split(dir, "/") {
+			if part != "" && len(entry.FolderPath) < maxImportFolderDepth {
+				entry.FolderPath = append(entry.FolderPath, part)
+			}
+		}
+	}
+
+	meta, body := splitFrontMatter(text)
+	entry.Title = meta["title"]
+	entry.Mood = meta["mood"]
+	for _, key := range []string{"created_at", "created", "date"} {
+		if t, local, ok := parseImportDate(meta[key]); ok {
+			entry.CreatedAt, entry.LocalTime = &t, local
+			break
+		}
+	}
+
+	if strings.EqualFold(path.Ext(name), ".txt") {
+		entry.Content = richtext.FromPlainText(body)
+	} else {
+		if entry.Title == "" {
+			entry.Title, body = takeHeadingTitle(body)
+		}
+		entry.Content = richtext.FromMarkdown(body)
+	}
+
+	if entry.Title == "" {
+		base := path.Base(name)
+		entry.Title = strings.TrimSuffix(base, path.Ext(base))
+	}
+	return entry
+}
+
+// splitFrontMatter separates a leading "---" delimited block of "key: value" lines from the body.
+// Only flat string values are understood, which covers the fields used for import.
+func splitFrontMatter(text string) (map[string]string, string) {
+	meta := map[string]string{}
+	if !strings.HasPrefix(text, "---\n") && !strings.HasPrefix(text, "---\r\n") {
+		return meta, text
+	}
+	lines := strings.SplitAfter(text, "\n")
+	for i := 1; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\r\n")
+		if line == "---" || line == "..." {
+			return meta, strings.Join(lines[i+1:], "")
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		meta[strings.ToLower(strings.TrimSpace(key))] = unquoteYAML(strings.TrimSpace(value))
+	}
+	// No closing delimiter: it was not front matter after all.
+	return map[string]string{}, text
+}
+
+func unquoteYAML(value string) string {
+	if len(value) >= 2 {
+		switch {
+		case value[0] == '"' && value[len(value)-1] == '"':
+			if s, err := strconv.Unquote(value); err == nil {
+				return s
+			}
+			return value[1 : len(value)-1]
+		case value[0] == '\'' && value[len(value)-1] == '\'':
+			return strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+		}
+	}
+	return value
+}
+
+// takeHeadingTitle uses a first-line "# Heading" as the title and removes it from the body.
+func takeHeadingTitle(body string) (string, string) {
+	trimmed := strings.TrimLeft(body, "\r\n")
+	line, rest, _ := strings.Cut(trimmed, "\n")
+	line = strings.TrimRight(line, "\r")
+	if strings.HasPrefix(line, "# ") {
+		return strings.TrimSpace(strings.TrimPrefix(line, "# ")), rest
+	}
+	return "", body
+}
+
+var importDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// parseImportDate parses a date in one of importDateLayouts. Dates without a UTC offset are
+// returned in UTC and reported as local.
+func parseImportDate(value string) (t time.Time, local, ok bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false, false
+	}
+	for _, layout := range importDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, layout != time.RFC3339, true
+		}
+	}
+	return time.Time{}, false, false
+}
+
+// dayOneExport is the subset of the Day One JSON export format used for import.
+type dayOneExport struct {
+	Metadata *struct {
+		Version string `json:"version"`
+	} `json:"metadata"`
+	Entries []struct {
+		UUID         string `json:"uuid"`
+		CreationDate string `json:"creationDate"`
+		Text         string `json:"text"`
+	} `json:"entries"`
+}
+
+// parseDayOne converts a Day One JSON export. The first line of each entry's Markdown is its title.
+func parseDayOne(data []byte, name string) ([]ImportedEntry, error) {
+	var export dayOneExport
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(&export); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+	if export.Metadata == nil {
+		return nil, fmt.Errorf("%w: %s is not a Day One export", ErrInvalidImport, name)
+	}
+
+	entries := make([]ImportedEntry, 0, len(export.Entries))
+	for i, e := range export.Entries {
+		text := strings.TrimSpace(e.Text)
+		if text == "" {
+			continue
+		}
+		entry := ImportedEntry{Source: fmt.Sprintf("%s#%d", name, i+1)}
+
+		first, rest, _ := strings.Cut(text, "\n")
+		// Day One backslash-escapes Markdown punctuation, which is noise in a plain title.
+		entry.Title = strings.TrimSpace(strings.TrimLeft(first, "#"))
+		entry.Title = strings.ReplaceAll(entry.Title, `\`, "")
+		if len(entry.Title) > 120 {
+			// A long first line is a paragraph rather than a title.
+			entry.Title = truncateTitle(entry.Title)
+			rest = text
+		}
+		if entry.Title == "" {
+			entry.Title = "Untitled"
+		}
+		entry.Content = richtext.FromMarkdown(rest)
+
+		if t, local, ok := parseImportDate(e.CreationDate); ok {
+			entry.CreatedAt, entry.LocalTime = &t, local
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func truncateTitle(title string) string {
+	runes := []rune(title)
+	if len(runes) <= 60 {
+		return title
+	}
+	return strings.TrimSpace(string(runes[:60])) + "…"
+}
+
+func toValidUTF8(data []byte) string {
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	return strings.ToValidUTF8(string(data), "�")
+}
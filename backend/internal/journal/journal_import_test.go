@@ -0,0 +1,250 @@
+package journal
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/calendar"
+)
+
+// buildZip creates an in-memory ZIP archive from file names and contents.
+func buildZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("creating %s: %v", name, err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatalf("writing %s: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("closing zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func parseZip(t *testing.T, files map[string]string) map[string]ImportedEntry {
+	t.Helper()
+	data := buildZip(t, files)
+	entries, err := ParseImport(bytes.NewReader(data), int64(len(data)), "notes.zip")
+	if err != nil {
+		t.Fatalf("ParseImport() error = %v", err)
+	}
+	bySource := map[string]ImportedEntry{}
+	for _, entry := range entries {
+		bySource[entry.Source] = entry
+	}
+	return bySource
+}
+
+func TestParseImport_MarkdownArchive(t *testing.T) {
+	entries := parseZip(t, map[string]string{
+		"journal/Adventures/Dungeons/dark.md": "---\ntitle: \"Into the dark\"\ncreated_at: 2024-03-01T09:30:00Z\nmood: brave\n---\n\n**Torch** lit.\n",
+		"journal/heading.md":                  "# From heading\n\nBody text",
+		"journal/plain.txt":                   "Line one\nLine <two>",
+		"journal/Day one.html":                "<p>ignored, the Markdown copy is imported</p>",
+		"manifest.json":                       `{"format_version":1,"entries":[]}`,
+		".DS_Store":                           "junk",
+	})
+
+	if len(entries) != 3 {
+		t.Fatalf("parsed %d entries, want 3: %+v", len(entries), entries)
+	}
+
+	dark := entries["Adventures/Dungeons/dark.md"]
+	wantDate := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	if dark.Title != "Into the dark" || dark.Mood != "brave" || dark.CreatedAt == nil || !dark.CreatedAt.Equal(wantDate) {
+		t.Errorf("front matter not applied: %+v", dark)
+	}
+	if got := len(dark.FolderPath); got != 2 || dark.FolderPath[0] != "Adventures" {
+		t.Errorf("FolderPath = %v, want [Adventures Dungeons]", dark.FolderPath)
+	}
+	if dark.Content != "<p><strong>Torch</strong> lit.</p>" {
+		t.Errorf("Content = %q", dark.Content)
+	}
+
+	if heading := entries["heading.md"]; heading.Title != "From heading" || heading.Content != "<p>Body text</p>" {
+		t.Errorf("heading entry = %+v", heading)
+	}
+	if plain := entries["plain.txt"]; plain.Title != "plain" || plain.Content != "<p>Line one<br>Line &lt;two&gt;</p>" {
+		t.Errorf("plain entry = %+v", plain)
+	}
+}
+
+func TestParseImport_DayOne(t *testing.T) {
+	data := []byte(`{
+		"metadata": {"version": "1.0"},
+		"entries": [
+			{"uuid": "A1", "creationDate": "2023-07-04T18:00:00Z", "text": "# Fireworks\n\nSaw the *fireworks*\\."},
+			{"uuid": "A2", "creationDate": "2023-07-05T08:00:00Z", "text": "   "}
+		]
+	}`)
+	entries, err := ParseImport(bytes.NewReader(data), int64(len(data)), "Journal.json")
+	if err != nil {
+		t.Fatalf("ParseImport() error = %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("parsed %d entries, want 1 (empty entries are skipped)", len(entries))
+	}
+	entry := entries[0]
+	if entry.Title != "Fireworks" || entry.Content != "<p>Saw the <em>fireworks</em>.</p>" {
+		t.Errorf("entry = %+v", entry)
+	}
+	if entry.CreatedAt == nil || !entry.CreatedAt.Equal(time.Date(2023, 7, 4, 18, 0, 0, 0, time.UTC)) {
+		t.Errorf("CreatedAt = %v", entry.CreatedAt)
+	}
+}
+
+func TestParseImport_RejectsUnknownFormats(t *testing.T) {
+	for name, data := range map[string]string{
+		"notes.zip":    "definitely not a zip",
+		"Journal.json": `{"entries": []}`,
+	} {
+		if _, err := ParseImport(bytes.NewReader([]byte(data)), int64(len(data)), name); err == nil {
+			t.Errorf("ParseImport(%s) succeeded, want ErrInvalidImport", name)
+		}
+	}
+}
+
+func TestService_ImportEntries(t *testing.T) {
+	created := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	imported := []ImportedEntry{
+		{Source: "a.md", Title: "Existing", Content: "<p>same</p>"},
+		{Source: "b.md", Title: "Nested", Content: "<p>b</p>", FolderPath: []string{"Adventures", "Dungeons"}, CreatedAt: &created},
+		{Source: "c.md", Title: "Sibling", Content: "<p>c</p>", FolderPath: []string{"Adventures"}},
+		{Source: "d.md", Title: "Nested", Content: "<p>b</p>", FolderPath: []string{"Adventures", "Dungeons"}},
+	}
+
+	newStore := func() *memoryStore {
+		store := newMemoryStore()
+		store.entries["doc-existing"] = models.JournalEntry{ID: "doc-existing", UserID: ownerID, Title: "Existing", Content: "<p>same</p>"}
+		store.created = append(store.created, models.Folder{ID: "folder-adventures", Name: "Adventures", UserID: ownerID})
+		store.folders["folder-adventures"] = ownerID
+		return store
+	}
+
+	t.Run("dry run writes nothing", func(t *testing.T) {
+		store := newStore()
+		report, err := NewService(store, nil, nil, nil, nil, nil).ImportEntries(ownerID, imported, ImportOptions{DryRun: true})
+		if err != nil {
+			t.Fatalf("ImportEntries() error = %v", err)
+		}
+		if report.Created != 2 || report.Duplicates != 2 || report.FoldersCreated != 1 {
+			t.Errorf("report = %+v, want 2 created, 2 duplicates, 1 folder", report)
+		}
+		if report.Entries[1].Status != ImportStatusWouldCreate {
+			t.Errorf("status = %q, want %q", report.Entries[1].Status, ImportStatusWouldCreate)
+		}
+		if len(store.entries) != 1 || len(store.created) != 1 {
+			t.Errorf("dry run wrote to the store: %d entries, %d folders", len(store.entries), len(store.created))
+		}
+	})
+
+	t.Run("import creates entries and reuses folders", func(t *testing.T) {
+		store := newStore()
+		report, err := NewService(store, nil, nil, nil, nil, nil).ImportEntries(ownerID, imported, ImportOptions{})
+		if err != nil {
+			t.Fatalf("ImportEntries() error = %v", err)
+		}
+		if report.Created != 2 || report.Duplicates != 2 || report.FoldersCreated != 1 {
+			t.Errorf("report = %+v, want 2 created, 2 duplicates, 1 folder", report)
+		}
+
+		nested := store.entries[report.Entries[1].EntryID]
+		if !nested.CreatedAt.Equal(created) {
+			t.Errorf("CreatedAt = %v, want %v", nested.CreatedAt, created)
+		}
+		dungeons, err := store.FindFolder(ownerID, "Dungeons", strPtr("folder-adventures"))
+		if err != nil || nested.FolderID == nil || *nested.FolderID != dungeons.ID {
+			t.Errorf("nested entry is in folder %v, want Dungeons inside the existing Adventures", nested.FolderID)
+		}
+		if sibling := store.entries[report.Entries[2].EntryID]; sibling.FolderID == nil || *sibling.FolderID != "folder-adventures" {
+			t.Errorf("sibling entry is in folder %v, want folder-adventures", sibling.FolderID)
+		}
+
+		again, err := NewService(store, nil, nil, nil, nil, nil).ImportEntries(ownerID, imported, ImportOptions{})
+		if err != nil {
+			t.Fatalf("second ImportEntries() error = %v", err)
+		}
+		if again.Created != 0 || again.Duplicates != len(imported) {
+			t.Errorf("re-import report = %+v, want everything reported as duplicate", again)
+		}
+	})
+}
+
+// fixedCalendar gives every user the same calendar.
+type fixedCalendar calendar.Calendar
+
+func (c fixedCalendar) Calendar(string) (calendar.Calendar, error) { return calendar.Calendar(c), nil }
+
+func TestService_ImportEntries_LocalDates(t *testing.T) {
+	brt := time.FixedZone("BRT", -3*60*60)
+	wallClock := time.Date(2024, 3, 1, 22, 0, 0, 0, time.UTC)
+	exact := time.Date(2024, 3, 2, 1, 0, 0, 0, time.UTC)
+	imported := []ImportedEntry{
+		{Source: "local.md", Title: "Late night", Content: "<p>a</p>", CreatedAt: &wallClock, LocalTime: true},
+		{Source: "exact.md", Title: "Exact", Content: "<p>b</p>", CreatedAt: &exact},
+	}
+
+	store := newMemoryStore()
+	report, err := NewService(store, nil, nil, nil, fixedCalendar{Location: brt}, nil).ImportEntries(ownerID, imported, ImportOptions{})
+	if err != nil {
+		t.Fatalf("ImportEntries() error = %v", err)
+	}
+	// 22:00 on the 1st in Brazil, not in UTC.
+	if got := store.entries[report.Entries[0].EntryID].CreatedAt; !got.Equal(time.Date(2024, 3, 1, 22, 0, 0, 0, brt)) {
+		t.Errorf("local date imported as %v, want 22:00 in the user's zone", got)
+	}
+	if got := store.entries[report.Entries[1].EntryID].CreatedAt; !got.Equal(exact) {
+		t.Errorf("date with an offset imported as %v, want it unchanged", got)
+	}
+}
+
+func TestHandler_Import(t *testing.T) {
+	store := newMemoryStore()
+	router := newTestRouter(t, store)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", "notes.zip")
+	if err != nil {
+		t.Fatalf("CreateFormFile() error = %v", err)
+	}
+	part.Write(buildZip(t, map[string]string{"Trips/rome.md": "# Rome\n\nPasta."}))
+	mw.WriteField("dry_run", "false")
+	mw.Close()
+
+	req := authorizedRequest(t, http.MethodPost, "/journal/import", body.String(), ownerID)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d (%s)", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var report ImportReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("decoding report: %v", err)
+	}
+	if report.Created != 1 || report.FoldersCreated != 1 {
+		t.Errorf("report = %+v", report)
+	}
+	for _, entry := range store.entries {
+		if entry.UserID != ownerID || entry.Title != "Rome" {
+			t.Errorf("imported entry = %+v", entry)
+		}
+	}
+}
+
+func strPtr(s string) *string { return &s }
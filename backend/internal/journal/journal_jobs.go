@@ -0,0 +1,67 @@
+package journal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/adrianvalentim/gamify_journal/internal/ai"
+	"github.com/adrianvalentim/gamify_journal/internal/jobs"
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+)
+
+// Kinds of the background jobs enqueued by the journal service.
+const (
+	// JobProcessXP scores newly written text for XP.
+	JobProcessXP = "journal.process_xp"
+	// JobProcessQuests matches newly written text against the user's quests.
+	JobProcessQuests = "journal.process_quests"
+)
+
+// JobQueue enqueues background jobs; it is implemented by jobs.Service.
+type JobQueue interface {
+	Enqueue(kind, userID string, payload interface{}) error
+}
+
+// aiJobPayload is the payload of JobProcessXP and JobProcessQuests jobs.
+type aiJobPayload struct {
+	EntryID string `json:"entry_id"`
+	Text    string `json:"text"`
+}
+
+// RegisterJobHandlers registers the handlers of the journal's job kinds with a worker pool.
+// Tags suggested by the XP agent are saved on the entry through service.
+func RegisterJobHandlers(pool *jobs.Pool, client ai.Client, service Service) {
+	pool.Register(JobProcessXP, func(ctx context.Context, job *models.Job) error {
+		payload, err := decodeAIJob(job)
+		if err != nil {
+			return err
+		}
+		resp, err := client.ProcessText(ctx, payload.Text, job.UserID, payload.EntryID)
+		if err != nil || resp == nil || len(resp.SuggestedTags) == 0 {
+			return err
+		}
+		// The XP is already granted, so a failure to keep the suggestions must not retry the job.
+		if err := service.SuggestTags(payload.EntryID, job.UserID, resp.SuggestedTags); err != nil {
+			log.Printf("Failed to save suggested tags of entry %s: %v", payload.EntryID, err)
+		}
+		return nil
+	})
+	pool.Register(JobProcessQuests, func(ctx context.Context, job *models.Job) error {
+		payload, err := decodeAIJob(job)
+		if err != nil {
+			return err
+		}
+		return client.ProcessTextForQuests(ctx, payload.Text, job.UserID, payload.EntryID)
+	})
+}
+
+// decodeAIJob reads the payload of an AI job. A malformed payload will never succeed, so it is not retried.
+func decodeAIJob(job *models.Job) (*aiJobPayload, error) {
+	var payload aiJobPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return nil, jobs.Permanent(fmt.Errorf("invalid payload: %w", err))
+	}
+	return &payload, nil
+}
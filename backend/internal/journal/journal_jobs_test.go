@@ -0,0 +1,79 @@
+package journal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/adrianvalentim/gamify_journal/internal/ai"
+	"github.com/adrianvalentim/gamify_journal/internal/ai/aitest"
+	"github.com/adrianvalentim/gamify_journal/internal/jobs"
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/database/databasetest"
+)
+
+func TestJobs_ProcessEntryChangesWithRetries(t *testing.T) {
+	db := databasetest.Open(t)
+	if err := db.Create(&models.User{ID: ownerID, Username: "owner", Email: "owner@example.com"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	agents := &aitest.Recorder{Failures: 2, Response: &ai.AIResponse{SuggestedTags: []string{"#Running", "running", "Morning  Routine"}}}
+
+	cfg := jobs.DefaultConfig()
+	cfg.Workers = 2
+	cfg.MaxAttempts = 5
+	cfg.BaseBackoff = time.Millisecond
+	cfg.PollInterval = 5 * time.Millisecond
+	jobStore := jobs.NewStore(db)
+	svc := NewService(NewStore(db), jobs.NewService(jobStore, cfg), nil, nil, nil, nil)
+
+	if _, err := svc.UpdateJournalEntry(entryID, ownerID, "Day", "<p>Woke up.</p>", nil, 0); err != nil {
+		t.Fatalf("UpdateJournalEntry() error = %v", err)
+	}
+	if _, err := svc.UpdateJournalEntry(entryID, ownerID, "Day", "<p>Woke up.</p><p>Ran 5km.</p>", nil, 0); err != nil {
+		t.Fatalf("UpdateJournalEntry() error = %v", err)
+	}
+
+	pool := jobs.NewPool(jobStore, cfg)
+	RegisterJobHandlers(pool, agents, svc)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		pool.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		finished, err := jobStore.List(models.JobDone, 10)
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		if len(finished) == 4 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("only %d of 4 jobs finished", len(finished))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	// The user's jobs ran in order, so the agents saw each save once, oldest first.
+	for _, method := range []string{aitest.ProcessText, aitest.ProcessTextForQuests} {
+		got := agents.Texts(method)
+		if len(got) != 2 || got[0] != "Woke up." || got[1] != "Ran 5km." {
+			t.Errorf("%s received %q, want each new paragraph once, in order", method, got)
+		}
+	}
+
+	entry, err := svc.GetJournalEntry(entryID, ownerID)
+	if err != nil {
+		t.Fatalf("GetJournalEntry() error = %v", err)
+	}
+	if got := entry.SuggestedTags; len(got) != 2 || got[0] != "running" || got[1] != "morning routine" {
+		t.Errorf("suggested tags = %q, want the agent's tags normalized and deduplicated", got)
+	}
+}
@@ -0,0 +1,236 @@
+package journal
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/richtext"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/textdiff"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrRevisionNotFound is returned when a revision does not exist for an entry the user owns.
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// RevisionPolicy controls how revisions are recorded and how long they are kept.
+type RevisionPolicy struct {
+	// CoalesceWindow is how long after a revision is opened further saves update it
+	// instead of creating a new revision. Zero records every save.
+	CoalesceWindow time.Duration
+	// MaxRevisions is how many revisions are kept per entry. Zero keeps all of them.
+	MaxRevisions int
+	// MaxAge is how long revisions are kept. Zero keeps them forever.
+	// The newest revision of an entry is never removed.
+	MaxAge time.Duration
+}
+
+// revisionPolicyFromEnv reads JOURNAL_REVISION_COALESCE_WINDOW (default 2m),
+// JOURNAL_REVISION_LIMIT (default 100) and JOURNAL_REVISION_MAX_AGE (default: forever).
+func revisionPolicyFromEnv() RevisionPolicy {
+	policy := RevisionPolicy{CoalesceWindow: 2 * time.Minute, MaxRevisions: 100}
+
+	if value := os.Getenv("JOURNAL_REVISION_COALESCE_WINDOW"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+			policy.CoalesceWindow = d
+		} else {
+			log.Printf("Warning: invalid JOURNAL_REVISION_COALESCE_WINDOW value %q. Defaulting to %s", value, policy.CoalesceWindow)
+		}
+	}
+	if value := os.Getenv("JOURNAL_REVISION_LIMIT"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			policy.MaxRevisions = n
+		} else {
+			log.Printf("Warning: invalid JOURNAL_REVISION_LIMIT value %q. Defaulting to %d", value, policy.MaxRevisions)
+		}
+	}
+	if value := os.Getenv("JOURNAL_REVISION_MAX_AGE"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+			policy.MaxAge = d
+		} else {
+			log.Printf("Warning: invalid JOURNAL_REVISION_MAX_AGE value %q. Keeping revisions forever.", value)
+		}
+	}
+	return policy
+}
+
+// RevisionDiff is the difference between two revisions of an entry.
+// The content is compared line by line in its Markdown form.
+type RevisionDiff struct {
+	From       int           `json:"from"`
+	To         int           `json:"to"`
+	FromTitle  string        `json:"from_title"`
+	ToTitle    string        `json:"to_title"`
+	Insertions int           `json:"insertions"`
+	Deletions  int           `json:"deletions"`
+	Changes    []textdiff.Op `json:"changes"`
+}
+
+// recordRevision snapshots an entry after a save. Unless forceNew is set, a save within the
+// coalescing window of the latest revision updates that revision instead of adding one.
+func (s *service) recordRevision(entry *models.JournalEntry, forceNew bool) error {
+	err := s.writeRevision(entry, forceNew)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		// A concurrent save took the next revision number; try again on top of it.
+		err = s.writeRevision(entry, forceNew)
+	}
+	return err
+}
+
+func (s *service) writeRevision(entry *models.JournalEntry, forceNew bool) error {
+	now := s.now()
+
+	number := 1
+	latest, err := s.store.LatestRevision(entry.ID, entry.UserID)
+	switch {
+	case err == nil:
+		if latest.Title == entry.Title && latest.Content == entry.Content {
+			return nil
+		}
+		if !forceNew && now.Sub(latest.CreatedAt) < s.revisions.CoalesceWindow {
+			latest.Title = entry.Title
+			latest.Content = entry.Content
+			latest.UpdatedAt = now
+			return s.store.UpdateRevision(latest)
+		}
+		number = latest.Number + 1
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return err
+	}
+
+	revision := &models.JournalRevision{
+		ID:        uuid.NewString(),
+		EntryID:   entry.ID,
+		UserID:    entry.UserID,
+		Number:    number,
+		Title:     entry.Title,
+		Content:   entry.Content,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.store.CreateRevision(revision); err != nil {
+		return err
+	}
+
+	var olderThan time.Time
+	if s.revisions.MaxAge > 0 {
+		olderThan = now.Add(-s.revisions.MaxAge)
+	}
+	return s.store.PruneRevisions(entry.ID, s.revisions.MaxRevisions, olderThan)
+}
+
+// ListRevisions returns the revisions of an entry owned by userID, newest first, without content.
+func (s *service) ListRevisions(entryID, userID string) ([]models.JournalRevision, error) {
+	if _, err := s.authorizeEntry(entryID, userID); err != nil {
+		return nil, err
+	}
+	return s.store.ListRevisions(entryID, userID)
+}
+
+// GetRevision returns one revision of an entry owned by userID.
+func (s *service) GetRevision(entryID, userID string, number int) (*models.JournalRevision, error) {
+	if _, err := s.authorizeEntry(entryID, userID); err != nil {
+		return nil, err
+	}
+	revision, err := s.store.GetRevision(entryID, userID, number)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRevisionNotFound
+		}
+		return nil, err
+	}
+	return revision, nil
+}
+
+// DiffRevisions compares two revisions of an entry owned by userID.
+// A zero to means the latest revision, and a zero from means the revision before to.
+func (s *service) DiffRevisions(entryID, userID string, from, to int) (*RevisionDiff, error) {
+	if _, err := s.authorizeEntry(entryID, userID); err != nil {
+		return nil, err
+	}
+
+	if to == 0 {
+		latest, err := s.store.LatestRevision(entryID, userID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrRevisionNotFound
+			}
+			return nil, err
+		}
+		to = latest.Number
+	}
+	if from == 0 {
+		from = to - 1
+	}
+
+	newer, err := s.GetRevision(entryID, userID, to)
+	if err != nil {
+		return nil, err
+	}
+	// Diffing the first revision against "nothing" shows it as fully inserted.
+	older := &models.JournalRevision{Number: from}
+	if from > 0 {
+		if older, err = s.GetRevision(entryID, userID, from); err != nil {
+			return nil, err
+		}
+	}
+
+	diff := &RevisionDiff{
+		From:      older.Number,
+		To:        newer.Number,
+		FromTitle: older.Title,
+		ToTitle:   newer.Title,
+		Changes:   textdiff.Diff(diffLines(older.Content), diffLines(newer.Content)),
+	}
+	for _, op := range diff.Changes {
+		switch op.Kind {
+		case textdiff.Insert:
+			diff.Insertions++
+		case textdiff.Delete:
+			diff.Deletions++
+		}
+	}
+	return diff, nil
+}
+
+// diffLines renders editor HTML as Markdown and splits it into its non-blank lines.
+func diffLines(content string) []string {
+	var lines []string
+	for _, line := range strings.Split(richtext.ToMarkdown(content), "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// RestoreRevision makes an old revision the current content of an entry owned by userID.
+// The restore itself is recorded as a new revision, so it can be undone as well.
+func (s *service) RestoreRevision(entryID, userID string, number int) (*models.JournalEntry, error) {
+	entry, err := s.authorizeEntry(entryID, userID)
+	if err != nil {
+		return nil, err
+	}
+	revision, err := s.GetRevision(entryID, userID, number)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.Title = revision.Title
+	entry.Content = revision.Content
+	if err := s.saveEntry(entry); err != nil {
+		return nil, err
+	}
+	if err := s.recordRevision(entry, true); err != nil {
+		return nil, fmt.Errorf("could not record revision: %w", err)
+	}
+	// Restored text is not new writing.
+	s.publishSaved(entry, "")
+	return entry, nil
+}
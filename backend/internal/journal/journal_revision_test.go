@@ -0,0 +1,184 @@
+package journal
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/database/databasetest"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/textdiff"
+)
+
+// newRevisionTestService returns a service with a controllable clock and an entry owned by ownerID.
+func newRevisionTestService(policy RevisionPolicy) (*service, *memoryStore, *time.Time) {
+	store := newMemoryStore()
+	store.entries[entryID] = models.JournalEntry{ID: entryID, UserID: ownerID, Title: "Day", Content: "<p>one</p>"}
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	svc := NewService(store, nil, nil, nil, nil, nil).(*service)
+	svc.revisions = policy
+	svc.now = func() time.Time { return now }
+	return svc, store, &now
+}
+
+// save records a revision for the entry with new content, as UpdateJournalEntry does.
+func save(t *testing.T, svc *service, store *memoryStore, content string) {
+	t.Helper()
+	entry := store.entries[entryID]
+	entry.Content = content
+	store.entries[entryID] = entry
+	if err := svc.recordRevision(&entry, false); err != nil {
+		t.Fatalf("recordRevision() error = %v", err)
+	}
+}
+
+func TestRecordRevision_CoalescesRapidSaves(t *testing.T) {
+	svc, store, now := newRevisionTestService(RevisionPolicy{CoalesceWindow: time.Minute})
+
+	save(t, svc, store, "<p>one</p>")
+	*now = now.Add(10 * time.Second)
+	save(t, svc, store, "<p>one two</p>")
+	*now = now.Add(10 * time.Second)
+	save(t, svc, store, "<p>one two three</p>")
+
+	if len(store.revisions) != 1 {
+		t.Fatalf("got %d revisions, want the autosaves coalesced into 1", len(store.revisions))
+	}
+	if got := store.revisions[0].Content; got != "<p>one two three</p>" {
+		t.Errorf("coalesced revision content = %q, want the last save", got)
+	}
+
+	// Once the window since the revision was opened has passed, the next save starts a new revision.
+	*now = now.Add(time.Minute)
+	save(t, svc, store, "<p>one two three four</p>")
+	if len(store.revisions) != 2 || store.revisions[1].Number != 2 {
+		t.Fatalf("revisions = %+v, want a second revision", store.revisions)
+	}
+	if store.revisions[0].Content != "<p>one two three</p>" {
+		t.Error("closed revision was modified")
+	}
+
+	// Saving unchanged content records nothing.
+	*now = now.Add(time.Hour)
+	save(t, svc, store, "<p>one two three four</p>")
+	if len(store.revisions) != 2 {
+		t.Errorf("got %d revisions after a no-op save, want 2", len(store.revisions))
+	}
+}
+
+func TestRecordRevision_Retention(t *testing.T) {
+	svc, store, now := newRevisionTestService(RevisionPolicy{MaxRevisions: 3, MaxAge: 48 * time.Hour})
+
+	for i, content := range []string{"<p>a</p>", "<p>b</p>", "<p>c</p>", "<p>d</p>", "<p>e</p>"} {
+		if i > 0 {
+			*now = now.Add(time.Hour)
+		}
+		save(t, svc, store, content)
+	}
+	var numbers []int
+	for _, revision := range store.revisions {
+		numbers = append(numbers, revision.Number)
+	}
+	if len(numbers) != 3 || numbers[0] != 3 || numbers[2] != 5 {
+		t.Fatalf("kept revisions %v, want [3 4 5]", numbers)
+	}
+
+	// Age-based pruning never removes the newest revision.
+	*now = now.Add(72 * time.Hour)
+	save(t, svc, store, "<p>f</p>")
+	if len(store.revisions) != 1 || store.revisions[0].Number != 6 {
+		t.Errorf("revisions after expiry = %+v, want only revision 6", store.revisions)
+	}
+}
+
+func TestService_DiffAndRestoreRevisions(t *testing.T) {
+	svc, store, now := newRevisionTestService(RevisionPolicy{})
+
+	save(t, svc, store, "<p>one</p><p>two</p>")
+	*now = now.Add(time.Minute)
+	save(t, svc, store, "<p>one</p><p>2</p><p>three</p>")
+
+	diff, err := svc.DiffRevisions(entryID, ownerID, 0, 0)
+	if err != nil {
+		t.Fatalf("DiffRevisions() error = %v", err)
+	}
+	want := []textdiff.Op{{Kind: textdiff.Equal, Text: "one"}, {Kind: textdiff.Delete, Text: "two"}, {Kind: textdiff.Insert, Text: "2"}, {Kind: textdiff.Insert, Text: "three"}}
+	if diff.From != 1 || diff.To != 2 || diff.Insertions != 2 || diff.Deletions != 1 || len(diff.Changes) != len(want) {
+		t.Fatalf("diff = %+v", diff)
+	}
+	for i, op := range want {
+		if diff.Changes[i] != op {
+			t.Errorf("change %d = %v, want %v", i, diff.Changes[i], op)
+		}
+	}
+
+	if _, err := svc.DiffRevisions(entryID, ownerID, 1, 7); err != ErrRevisionNotFound {
+		t.Errorf("DiffRevisions(missing) error = %v, want %v", err, ErrRevisionNotFound)
+	}
+
+	*now = now.Add(time.Minute)
+	entry, err := svc.RestoreRevision(entryID, ownerID, 1)
+	if err != nil {
+		t.Fatalf("RestoreRevision() error = %v", err)
+	}
+	if entry.Content != "<p>one</p><p>two</p>" || store.entries[entryID].Content != entry.Content {
+		t.Errorf("restored content = %q", store.entries[entryID].Content)
+	}
+	if len(store.revisions) != 3 || store.revisions[2].Content != entry.Content {
+		t.Errorf("restore was not recorded as revision 3: %+v", store.revisions)
+	}
+}
+
+func TestGormStore_PruneRevisions(t *testing.T) {
+	db := databasetest.Open(t)
+	store := NewStore(db)
+	if err := db.Create(&models.User{ID: ownerID, Username: "owner", Email: "owner@example.com"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.JournalEntry{ID: entryID, UserID: ownerID, Title: "Day"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	for number := 1; number <= 5; number++ {
+		revision := &models.JournalRevision{ID: fmt.Sprintf("rev-%d", number), EntryID: entryID, UserID: ownerID, Number: number, Content: "x", CreatedAt: start.Add(time.Duration(number) * time.Hour)}
+		if err := store.CreateRevision(revision); err != nil {
+			t.Fatalf("CreateRevision(%d) error = %v", number, err)
+		}
+	}
+	if err := store.CreateRevision(&models.JournalRevision{ID: "rev-dup", EntryID: entryID, UserID: ownerID, Number: 5}); !errors.Is(err, gorm.ErrDuplicatedKey) {
+		t.Errorf("CreateRevision(duplicate number) error = %v, want %v", err, gorm.ErrDuplicatedKey)
+	}
+
+	// Keep three, and drop anything created before revision 4 even though it is within the limit.
+	if err := store.PruneRevisions(entryID, 3, start.Add(4*time.Hour)); err != nil {
+		t.Fatalf("PruneRevisions() error = %v", err)
+	}
+	revisions, err := store.ListRevisions(entryID, ownerID)
+	if err != nil {
+		t.Fatalf("ListRevisions() error = %v", err)
+	}
+	if len(revisions) != 2 || revisions[0].Number != 5 || revisions[1].Number != 4 {
+		t.Fatalf("ListRevisions() = %+v, want revisions 5 and 4", revisions)
+	}
+	if revisions[0].Content != "" {
+		t.Error("ListRevisions() should not load content")
+	}
+
+	if err := store.PruneRevisions(entryID, 0, start.Add(100*time.Hour)); err != nil {
+		t.Fatalf("PruneRevisions() error = %v", err)
+	}
+	if latest, err := store.LatestRevision(entryID, ownerID); err != nil || latest.Number != 5 {
+		t.Errorf("LatestRevision() = %+v, %v; the newest revision must survive pruning", latest, err)
+	}
+
+	if err := store.Delete(entryID, ownerID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.LatestRevision(entryID, ownerID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("LatestRevision() after deleting the entry error = %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+}
@@ -0,0 +1,323 @@
+package journal
+
+import (
+	"errors"
+	"html"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/calendar"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/richtext"
+	"github.com/adrianvalentim/gamify_journal/internal/tag"
+)
+
+// Text search configurations entries are indexed with. PostgreSQL stems the words of an entry
+// in its language, so "correndo" finds "correr" and "runs" finds "running".
+const (
+	searchEnglish    = "english"
+	searchPortuguese = "portuguese"
+	// searchSimple is used when the language of an entry cannot be told; words match as written.
+	searchSimple = "simple"
+)
+
+// searchLanguages are the text search configurations entries can be indexed with.
+var searchLanguages = []string{searchEnglish, searchPortuguese, searchSimple}
+
+// Page sizes of SearchJournalEntries.
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+const (
+	// snippetWords is how many words a search snippet shows.
+	snippetWords = 30
+	// snippetLead is how many words a snippet shows before the first match.
+	snippetLead = 8
+	// indexBatchSize is how many entries IndexEntries reads at a time.
+	indexBatchSize = 100
+)
+
+var (
+	ErrEmptySearch      = errors.New("search query is empty")
+	ErrInvalidDateRange = errors.New("search date range ends before it starts")
+)
+
+// SearchQuery is a full-text search of a user's entries.
+type SearchQuery struct {
+	// Text uses web search syntax: "quoted phrases", -excluded words and OR.
+	Text string
+	// FolderID, Tag and Mood, when set, only keep entries directly in that folder, with that
+	// tag or with that mood.
+	FolderID string
+	Tag      string
+	Mood     string
+	// From and To are the first and last days, as dates in UTC, on which matching entries were
+	// created. The days are counted in the user's calendar; a zero value leaves the range open.
+	From, To      time.Time
+	Limit, Offset int
+}
+
+// SearchFilter is a SearchQuery as the store runs it.
+type SearchFilter struct {
+	Text     string
+	FolderID string
+	Tag      string
+	Mood     string
+	// CreatedAfter and CreatedBefore bound the creation time of entries, when not zero. The
+	// bound of CreatedBefore is excluded.
+	CreatedAfter, CreatedBefore time.Time
+}
+
+// SearchResult is an entry matched by a search.
+type SearchResult struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	FolderID  *string   `json:"folder_id"`
+	Mood      string    `json:"mood,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Rank orders results by relevance; it is only comparable within one search.
+	Rank float64 `json:"rank"`
+	// Snippet is the text around the first match, HTML-escaped, with matched words in <mark>.
+	Snippet    string `json:"snippet"`
+	SearchText string `json:"-"`
+}
+
+// SearchResults is a page of search results, best first.
+type SearchResults struct {
+	Results []SearchResult `json:"results"`
+	Total   int64          `json:"total"`
+	Limit   int            `json:"limit"`
+	Offset  int            `json:"offset"`
+}
+
+// SearchJournalEntries searches the text and titles of the user's entries.
+func (s *service) SearchJournalEntries(userID string, query SearchQuery) (*SearchResults, error) {
+	text := strings.TrimSpace(query.Text)
+	if text == "" {
+		return nil, ErrEmptySearch
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && query.To.Before(query.From) {
+		return nil, ErrInvalidDateRange
+	}
+	cal, err := calendar.For(s.calendars, userID)
+	if err != nil {
+		return nil, err
+	}
+	filter := SearchFilter{
+		Text:     text,
+		FolderID: query.FolderID,
+		Tag:      tag.NormalizeName(query.Tag),
+		Mood:     strings.TrimSpace(query.Mood),
+	}
+	if !query.From.IsZero() {
+		filter.CreatedAfter = cal.Start(query.From)
+	}
+	if !query.To.IsZero() {
+		filter.CreatedBefore = cal.Start(query.To.AddDate(0, 0, 1))
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	if limit > MaxSearchLimit {
+		limit = MaxSearchLimit
+	}
+	results, total, err := s.store.Search(userID, filter, limit, query.Offset)
+	if err != nil {
+		return nil, err
+	}
+	terms := searchTerms(text)
+	for i := range results {
+		results[i].Snippet = snippet(results[i].SearchText, terms)
+	}
+	if results == nil {
+		results = []SearchResult{}
+	}
+	return &SearchResults{Results: results, Total: total, Limit: limit, Offset: query.Offset}, nil
+}
+
+// IndexEntries indexes the entries saved before search existed, and returns how many it indexed.
+// Entries saved since are indexed by the store as they are saved.
+func (s *service) IndexEntries() (int, error) {
+	indexed := 0
+	for {
+		entries, err := s.store.UnindexedEntries(indexBatchSize)
+		if err != nil || len(entries) == 0 {
+			return indexed, err
+		}
+		for i := range entries {
+			indexForSearch(&entries[i])
+			if err := s.store.SaveSearchIndex(&entries[i]); err != nil {
+				return indexed, err
+			}
+			indexed++
+		}
+	}
+}
+
+// indexForSearch sets the search text and language of an entry from its title and content.
+func indexForSearch(entry *models.JournalEntry) {
+	entry.SearchText = strings.Join(richtext.Paragraphs(entry.Content), "\n")
+	entry.SearchLanguage = detectLanguage(entry.Title + "\n" + entry.SearchText)
+}
+
+// stopWords are common words that tell the languages of entries apart, by language.
+var stopWords = map[string]string{}
+
+func init() {
+	for language, list := range map[string][]string{
+		searchEnglish: {"the", "and", "is", "was", "were", "i", "my", "me", "to", "of", "it", "in", "that",
+			"with", "for", "on", "today", "this", "have", "had", "but", "not", "went", "we"},
+		searchPortuguese: {"o", "os", "de", "da", "dos", "das", "que", "e", "é", "não", "um", "uma", "com",
+			"para", "eu", "meu", "minha", "hoje", "foi", "na", "em", "mas", "muito", "também", "fui", "nós"},
+	} {
+		for _, word := range list {
+			stopWords[word] = language
+		}
+	}
+}
+
+// detectLanguage returns the text search configuration for text: the language most of its
+// stop words belong to, or searchSimple if it has none or as many of each.
+func detectLanguage(text string) string {
+	counts := map[string]int{}
+	for _, word := range words(text) {
+		if language, ok := stopWords[strings.ToLower(text[word[0]:word[1]])]; ok {
+			counts[language]++
+		}
+	}
+	switch {
+	case counts[searchEnglish] > counts[searchPortuguese]:
+		return searchEnglish
+	case counts[searchPortuguese] > counts[searchEnglish]:
+		return searchPortuguese
+	}
+	return searchSimple
+}
+
+// words returns the start and end offsets of the words in text.
+func words(text string) [][2]int {
+	var spans [][2]int
+	start := -1
+	for i, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			spans = append(spans, [2]int{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, [2]int{start, len(text)})
+	}
+	return spans
+}
+
+// searchTerms returns the lowercased words of a search query to highlight, leaving out
+// excluded words and the OR operator.
+func searchTerms(query string) []string {
+	var terms []string
+	seen := map[string]bool{}
+	for _, field := range strings.Fields(query) {
+		field = strings.TrimLeft(field, `"`)
+		if strings.HasPrefix(field, "-") || strings.EqualFold(field, "or") {
+			continue
+		}
+		for _, word := range words(field) {
+			term := strings.ToLower(field[word[0]:word[1]])
+			if !seen[term] {
+				seen[term] = true
+				terms = append(terms, term)
+			}
+		}
+	}
+	return terms
+}
+
+// matchesTerm reports whether a lowercased word of an entry matches a search term. Words match
+// terms they start with and terms that start with them, so "run" marks "running" much like
+// stemming finds it; words and terms under three letters must be equal.
+func matchesTerm(word, term string) bool {
+	if len([]rune(word)) < 3 || len([]rune(term)) < 3 {
+		return word == term
+	}
+	return strings.HasPrefix(word, term) || strings.HasPrefix(term, word)
+}
+
+// snippet returns up to snippetWords words of text around the first word matching one of
+// terms, or from the start if none does. The text is HTML-escaped and every matching word is
+// wrapped in <mark>, so clients can render snippets as HTML.
+func snippet(text string, terms []string) string {
+	spans := words(text)
+	if len(spans) == 0 {
+		return ""
+	}
+	matches := make([]bool, len(spans))
+	first := -1
+	for i, span := range spans {
+		word := strings.ToLower(text[span[0]:span[1]])
+		for _, term := range terms {
+			if matchesTerm(word, term) {
+				matches[i] = true
+				break
+			}
+		}
+		if matches[i] && first < 0 {
+			first = i
+		}
+	}
+	from := 0
+	if first > snippetLead {
+		from = first - snippetLead
+	}
+	to := from + snippetWords
+	if to > len(spans) {
+		to = len(spans)
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("… ")
+	}
+	pos := spans[from][0]
+	for i := from; i < to; i++ {
+		b.WriteString(html.EscapeString(collapseSpace(text[pos:spans[i][0]])))
+		word := html.EscapeString(text[spans[i][0]:spans[i][1]])
+		if matches[i] {
+			word = "<mark>" + word + "</mark>"
+		}
+		b.WriteString(word)
+		pos = spans[i][1]
+	}
+	if to < len(spans) {
+		b.WriteString(" …")
+	} else {
+		b.WriteString(html.EscapeString(collapseSpace(strings.TrimRightFunc(text[pos:], unicode.IsSpace))))
+	}
+	return b.String()
+}
+
+// collapseSpace replaces every run of whitespace in s with a single space.
+func collapseSpace(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			if !space {
+				b.WriteByte(' ')
+			}
+			space = true
+			continue
+		}
+		space = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
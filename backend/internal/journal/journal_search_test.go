@@ -0,0 +1,305 @@
+package journal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/database/databasetest"
+	"github.com/adrianvalentim/gamify_journal/internal/tag"
+)
+
+// likeSearchStore searches with LIKE, since the SQLite of the tests has no text search. Every
+// word of the query must appear in the title or text of an entry, and the most recently updated
+// entries come first; TestGormStore_SearchPostgres covers stemming and ranking.
+type likeSearchStore struct {
+	*gormStore
+}
+
+func (s likeSearchStore) Search(userID string, filter SearchFilter, limit, offset int) ([]SearchResult, int64, error) {
+	query := s.filterEntries(userID, filter)
+	escape := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	for _, term := range searchTerms(filter.Text) {
+		pattern := "%" + escape.Replace(term) + "%"
+		query = query.Where(`(title LIKE ? ESCAPE '\' OR search_text LIKE ? ESCAPE '\')`, pattern, pattern)
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil || total == 0 {
+		return nil, total, err
+	}
+	var results []SearchResult
+	err := query.Select(searchColumns).Order("updated_at DESC").Limit(limit).Offset(offset).Scan(&results).Error
+	return results, total, err
+}
+
+func TestGormStore_SearchSQL(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt := db.Model(&models.JournalEntry{}).
+		Select("id, ? AS rank", searchRank("run")).
+		Where(searchMatch("run")).
+		Find(&[]SearchResult{}).Statement
+	got := db.Dialector.Explain(stmt.SQL.String(), stmt.Vars...)
+	want := `SELECT id, ts_rank_cd(search_vector, CASE search_language ` +
+		`WHEN 'english' THEN websearch_to_tsquery('english', 'run') ` +
+		`WHEN 'portuguese' THEN websearch_to_tsquery('portuguese', 'run') ` +
+		`WHEN 'simple' THEN websearch_to_tsquery('simple', 'run') END, 1) AS rank FROM "journal_entries" WHERE ` +
+		`((search_language = 'english' AND search_vector @@ websearch_to_tsquery('english', 'run')) OR ` +
+		`(search_language = 'portuguese' AND search_vector @@ websearch_to_tsquery('portuguese', 'run')) OR ` +
+		`(search_language = 'simple' AND search_vector @@ websearch_to_tsquery('simple', 'run')))`
+	if got != want {
+		t.Errorf("search SQL =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// TestGormStore_SearchPostgres runs the full-text search of gormStore on PostgreSQL, when
+// TEST_DB_DSN is set.
+func TestGormStore_SearchPostgres(t *testing.T) {
+	db := databasetest.OpenPostgres(t)
+	for _, id := range []string{ownerID, intruderID} {
+		if err := db.Create(&models.User{ID: id, Username: id, Email: id + "@example.com"}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Create(&models.Folder{ID: "folder-trips", UserID: ownerID, Name: "Trips"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	brt := time.FixedZone("BRT", -3*60*60)
+	svc := NewService(NewStore(db), &recordingQueue{}, nil, nil, fixedCalendar{Location: brt}, tag.NewService(tag.NewStore(db))).(*service)
+
+	trips := "folder-trips"
+	write := func(id, userID, title, content string, folderID *string, createdAt time.Time) {
+		t.Helper()
+		entry := &models.JournalEntry{ID: id, UserID: userID, Title: title, Content: content, FolderID: folderID, CreatedAt: createdAt, UpdatedAt: createdAt}
+		if err := svc.store.Create(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Late on March 1st in Brazil is already March 2nd in UTC.
+	write("doc-1", ownerID, "Beach run", "<p>I went running on the beach and swam.</p>", &trips, time.Date(2026, 3, 2, 1, 0, 0, 0, time.UTC))
+	write("doc-2", ownerID, "Office", "<p>Long meeting at the office, then a run home.</p>", nil, time.Date(2026, 3, 5, 12, 0, 0, 0, time.UTC))
+	write("doc-3", ownerID, "Corrida", "<p>Hoje eu corri na praia.</p>", nil, time.Date(2026, 3, 9, 12, 0, 0, 0, time.UTC))
+	write("doc-4", ownerID, "Run", "<p>Run, run, run: the run of the year.</p>", nil, time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	write("doc-foreign", intruderID, "Run run", "<p>Someone else's run, run and run.</p>", nil, time.Date(2026, 3, 5, 12, 0, 0, 0, time.UTC))
+	if _, err := svc.AddTags("doc-2", ownerID, []string{"work"}); err != nil {
+		t.Fatal(err)
+	}
+
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name  string
+		query SearchQuery
+		want  []string // best first
+	}{
+		// Ranked by matches, with titles weighing more, rather than by date; other users'
+		// entries never match.
+		{"ranking", SearchQuery{Text: "run"}, []string{"doc-4", "doc-1", "doc-2"}},
+		{"english stemming", SearchQuery{Text: "runs"}, []string{"doc-4", "doc-1", "doc-2"}},
+		{"portuguese stemming", SearchQuery{Text: "correndo"}, []string{"doc-3"}},
+		{"every word must match", SearchQuery{Text: "beaches running"}, []string{"doc-1"}},
+		{"excluded word", SearchQuery{Text: "run -beach"}, []string{"doc-4", "doc-2"}},
+		{"folder", SearchQuery{Text: "run", FolderID: "folder-trips"}, []string{"doc-1"}},
+		{"tag", SearchQuery{Text: "run", Tag: "#Work"}, []string{"doc-2"}},
+		{"days in the user's zone", SearchQuery{Text: "run", From: day(1), To: day(1)}, []string{"doc-4", "doc-1"}},
+		{"open range", SearchQuery{Text: "run", From: day(2)}, []string{"doc-2"}},
+		{"filters combined", SearchQuery{Text: "run", Tag: "work", From: day(6)}, nil},
+		{"page", SearchQuery{Text: "run", Limit: 1, Offset: 1}, []string{"doc-1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := svc.SearchJournalEntries(ownerID, tt.query)
+			if err != nil {
+				t.Fatalf("SearchJournalEntries() error = %v", err)
+			}
+			var got []string
+			for _, result := range results.Results {
+				got = append(got, result.ID)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("search %+v = %v, want %v", tt.query, got, tt.want)
+			}
+			for i := 1; i < len(results.Results); i++ {
+				if results.Results[i].Rank > results.Results[i-1].Rank {
+					t.Errorf("results %v are not ordered by rank", results.Results)
+				}
+			}
+		})
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"Today I went to the gym and it was great.", searchEnglish},
+		{"Hoje eu fui à academia e foi ótimo, não parei.", searchPortuguese},
+		{"Academia 5km", searchSimple},
+	}
+	for _, tt := range tests {
+		if got := detectLanguage(tt.text); got != tt.want {
+			t.Errorf("detectLanguage(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	tests := []struct {
+		name, text, query, want string
+	}{
+		{"marks stems and escapes", "I ran <fast> and kept running.", "running", "I ran &lt;fast&gt; and kept <mark>running</mark>."},
+		{"skips excluded words", "Walked, then ran.", `walk -ran`, "<mark>Walked</mark>, then ran."},
+		{"starts near the match", "one two three four five six seven eight nine ten eleven twelve\nmatch", "match",
+			"… five six seven eight nine ten eleven twelve <mark>match</mark>"},
+		{"no match shows the start", "Nothing here.", "else", "Nothing here."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := snippet(tt.text, searchTerms(tt.query)); got != tt.want {
+				t.Errorf("snippet() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler_SearchEntries(t *testing.T) {
+	db := databasetest.Open(t)
+	for _, id := range []string{ownerID, intruderID} {
+		if err := db.Create(&models.User{ID: id, Username: id, Email: id + "@example.com"}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Create(&models.Folder{ID: "folder-trips", UserID: ownerID, Name: "Trips"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	brt := time.FixedZone("BRT", -3*60*60)
+	store := likeSearchStore{NewStore(db).(*gormStore)}
+	svc := NewService(store, &recordingQueue{}, nil, nil, fixedCalendar{Location: brt}, tag.NewService(tag.NewStore(db))).(*service)
+
+	trips := "folder-trips"
+	write := func(id, userID, title, content, mood string, folderID *string, createdAt time.Time) {
+		t.Helper()
+		entry := &models.JournalEntry{ID: id, UserID: userID, Title: title, Content: content, Mood: mood, FolderID: folderID, CreatedAt: createdAt, UpdatedAt: createdAt}
+		if err := svc.store.Create(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Late on March 1st in Brazil is already March 2nd in UTC.
+	write("doc-1", ownerID, "Beach run", "<p>I went running on the beach &amp; swam.</p>", "happy", &trips, time.Date(2026, 3, 2, 1, 0, 0, 0, time.UTC))
+	write("doc-2", ownerID, "Office", "<p>Long meeting, then a <b>run</b> home.</p>", "Tired", nil, time.Date(2026, 3, 5, 12, 0, 0, 0, time.UTC))
+	write("doc-3", ownerID, "Corrida", "<p>Hoje eu corri na praia.</p>", "", nil, time.Date(2026, 3, 9, 12, 0, 0, 0, time.UTC))
+	write("doc-foreign", intruderID, "Run", "<p>Someone else's run.</p>", "happy", nil, time.Date(2026, 3, 5, 12, 0, 0, 0, time.UTC))
+	if _, err := svc.AddTags("doc-2", ownerID, []string{"work"}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := chi.NewRouter()
+	NewHandler(svc).RegisterRoutes(r)
+	search := func(query string, wantStatus int) *SearchResults {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, authorizedRequest(t, http.MethodGet, "/journal/search?"+query, "", ownerID))
+		if rec.Code != wantStatus {
+			t.Fatalf("search %q status = %d, want %d; body: %s", query, rec.Code, wantStatus, rec.Body)
+		}
+		if wantStatus != http.StatusOK {
+			return nil
+		}
+		var results SearchResults
+		if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+			t.Fatal(err)
+		}
+		return &results
+	}
+	ids := func(results *SearchResults) []string {
+		var ids []string
+		for _, result := range results.Results {
+			ids = append(ids, result.ID)
+		}
+		return ids
+	}
+
+	all := search("q=run", http.StatusOK)
+	if got := ids(all); all.Total != 2 || len(got) != 2 || got[0] != "doc-2" || got[1] != "doc-1" {
+		t.Fatalf("search run = %v (total %d), want the owner's two entries, newest first", got, all.Total)
+	}
+	if want := "I went <mark>running</mark> on the beach &amp; swam."; all.Results[1].Snippet != want {
+		t.Errorf("snippet = %q, want %q", all.Results[1].Snippet, want)
+	}
+
+	tests := []struct {
+		name, query string
+		want        []string
+	}{
+		{"folder", "q=run&folder_id=folder-trips", []string{"doc-1"}},
+		{"tag", "q=run&tag=%23Work", []string{"doc-2"}},
+		{"mood", "q=run&mood=tired", []string{"doc-2"}},
+		{"days in the user's zone", "q=run&from=2026-03-01&to=2026-03-01", []string{"doc-1"}},
+		{"open range", "q=run&from=2026-03-02", []string{"doc-2"}},
+		{"every word must match", "q=beach+swam", []string{"doc-1"}},
+		{"page", "q=run&limit=1&offset=1", []string{"doc-1"}},
+		{"portuguese", "q=praia", []string{"doc-3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ids(search(tt.query, http.StatusOK))
+			if len(got) != len(tt.want) || (len(got) > 0 && got[0] != tt.want[0]) {
+				t.Errorf("search %q = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+	if page := search("q=run&limit=1", http.StatusOK); page.Total != 2 || page.Limit != 1 || len(page.Results) != 1 {
+		t.Errorf("first page = %+v, want 1 of 2 results", page)
+	}
+
+	for _, query := range []string{"q=+", "q=run&from=2026-03-05&to=2026-03-01", "q=run&from=yesterday", "q=run&limit=0", "q=run&limit=1000"} {
+		search(query, http.StatusBadRequest)
+	}
+}
+
+func TestService_IndexEntries(t *testing.T) {
+	db := databasetest.Open(t)
+	if err := db.Create(&models.User{ID: ownerID, Username: "owner", Email: "owner@example.com"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	// Entries saved before search existed have no search text or language.
+	updated := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := db.Exec("INSERT INTO journal_entries (id, user_id, title, content, version, created_at, updated_at) VALUES (?, ?, ?, ?, 1, ?, ?)",
+		entryID, ownerID, "Old", "<p>Hoje eu corri na praia.</p>", updated, updated).Error; err != nil {
+		t.Fatal(err)
+	}
+	svc := NewService(NewStore(db), &recordingQueue{}, nil, nil, nil, nil).(*service)
+
+	for _, want := range []int{1, 0} {
+		if indexed, err := svc.IndexEntries(); err != nil || indexed != want {
+			t.Fatalf("IndexEntries() = %d, %v; want %d", indexed, err, want)
+		}
+	}
+	entry, err := svc.GetJournalEntry(entryID, ownerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.SearchText != "Hoje eu corri na praia." || entry.SearchLanguage != searchPortuguese || !entry.UpdatedAt.Equal(updated) || entry.Version != 1 {
+		t.Errorf("indexed entry = %+v, want the text indexed in Portuguese and the entry otherwise unchanged", entry)
+	}
+
+	// An entry saved while it was being indexed keeps the index of the save.
+	stale := &models.JournalEntry{ID: entryID, SearchText: "Old text", SearchLanguage: searchEnglish}
+	if err := svc.store.SaveSearchIndex(stale); err != nil {
+		t.Fatal(err)
+	}
+	if entry, err := svc.GetJournalEntry(entryID, ownerID); err != nil || entry.SearchText != "Hoje eu corri na praia." {
+		t.Errorf("entry after a stale index = %+v, %v; want its index kept", entry, err)
+	}
+}
@@ -4,68 +4,229 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
-	"github.com/adrianvalentim/gamify_journal/internal/ai"
 	"github.com/adrianvalentim/gamify_journal/internal/character"
 	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/calendar"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/events"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/richtext"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/textdiff"
+	"github.com/adrianvalentim/gamify_journal/internal/tag"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// Pre-defined errors returned by the journal service.
+var (
+	// ErrEntryNotFound is returned when an entry does not exist or is owned by another user.
+	// The two cases are deliberately indistinguishable so entry IDs cannot be probed.
+	ErrEntryNotFound = errors.New("journal entry not found")
+	// ErrFolderNotFound is returned when a referenced folder does not exist or is owned by another user.
+	ErrFolderNotFound = errors.New("folder not found")
+	// ErrVersionConflict is returned when an entry was modified since the version the caller edited.
+	// The returned error is a *VersionConflictError carrying the current entry.
+	ErrVersionConflict = errors.New("journal entry was modified concurrently")
+)
+
+// VersionConflictError reports a failed optimistic concurrency check.
+// Current is the entry as stored on the server, so the client can merge or overwrite it.
+type VersionConflictError struct {
+	Current *models.JournalEntry
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("%v: current version is %d", ErrVersionConflict, e.Current.Version)
+}
+
+func (e *VersionConflictError) Unwrap() error {
+	return ErrVersionConflict
+}
+
 // Service defines the interface for journal business logic.
+// All methods act on behalf of userID and never touch entries owned by someone else.
 type Service interface {
-	GetJournalEntry(id string) (*models.JournalEntry, error)
-	UpdateJournalEntry(id, title, content string, folderID *string, newText string) (*models.JournalEntry, error)
+	GetJournalEntry(id, userID string) (*models.JournalEntry, error)
+	UpdateJournalEntry(id, userID, title, content string, folderID *string, version int) (*models.JournalEntry, error)
 	CreateJournalEntry(title, content, userID string, folderID *string) (*models.JournalEntry, error)
-	GetJournalEntriesByUserID(userID string) ([]models.JournalEntry, error)
-	DeleteJournalEntry(id string) error
+	// GetJournalEntriesByUserID lists the user's entries, only those with the named tag unless tagName is empty.
+	GetJournalEntriesByUserID(userID, tagName string) ([]models.JournalEntry, error)
+	DeleteJournalEntry(id, userID string) error
+	ImportEntries(userID string, entries []ImportedEntry, opts ImportOptions) (*ImportReport, error)
+	ListRevisions(entryID, userID string) ([]models.JournalRevision, error)
+	GetRevision(entryID, userID string, number int) (*models.JournalRevision, error)
+	DiffRevisions(entryID, userID string, from, to int) (*RevisionDiff, error)
+	RestoreRevision(entryID, userID string, number int) (*models.JournalEntry, error)
+	AddTags(entryID, userID string, names []string) (*models.JournalEntry, error)
+	RemoveTag(entryID, userID, tagID string) (*models.JournalEntry, error)
+	SuggestTags(entryID, userID string, names []string) error
+	SearchJournalEntries(userID string, query SearchQuery) (*SearchResults, error)
+	// IndexEntries indexes for search the entries saved before search existed.
+	IndexEntries() (int, error)
+}
+
+// ImportOptions controls how ImportEntries saves entries.
+type ImportOptions struct {
+	// DryRun reports what would be created without writing anything.
+	DryRun bool
+	// ProcessWithAI sends every imported entry to the XP and quest agents, as if it had just been written.
+	ProcessWithAI bool
+}
+
+// Statuses of an entry in an ImportReport.
+const (
+	ImportStatusCreated     = "created"
+	ImportStatusWouldCreate = "would_create"
+	ImportStatusDuplicate   = "duplicate"
+)
+
+// ImportReport describes the outcome of an import, entry by entry.
+type ImportReport struct {
+	DryRun         bool           `json:"dry_run"`
+	Created        int            `json:"created"`
+	Duplicates     int            `json:"duplicates"`
+	FoldersCreated int            `json:"folders_created"`
+	Entries        []ImportResult `json:"entries"`
+}
+
+// ImportResult is the outcome for a single imported entry.
+type ImportResult struct {
+	Source    string     `json:"source"`
+	Title     string     `json:"title"`
+	Folder    string     `json:"folder,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	Status    string     `json:"status"`
+	EntryID   string     `json:"entry_id,omitempty"`
 }
 
 type service struct {
 	store            Store
-	aiService        *ai.AIService
+	queue            JobQueue
 	characterService *character.Service
+	bus              *events.Bus
+	calendars        calendar.Source
+	tags             *tag.Service
+	revisions        RevisionPolicy
+	now              func() time.Time
 }
 
 // NewService creates a new journal service.
-func NewService(store Store, aiService *ai.AIService, characterService *character.Service) Service {
-	return &service{store: store, aiService: aiService, characterService: characterService}
+// AI processing of new text is enqueued on queue; see RegisterJobHandlers. Saved entries are
+// published on bus. Dates are read in the time zone of each user from calendars, or in UTC
+// when calendars is nil. Tags attached by name are found or created with tags.
+// The revision retention policy is read from the environment.
+func NewService(store Store, queue JobQueue, characterService *character.Service, bus *events.Bus, calendars calendar.Source, tags *tag.Service) Service {
+	return &service{
+		store:            store,
+		queue:            queue,
+		characterService: characterService,
+		bus:              bus,
+		calendars:        calendars,
+		tags:             tags,
+		revisions:        revisionPolicyFromEnv(),
+		now:              time.Now,
+	}
 }
 
-// GetJournalEntry retrieves a journal entry, creating it if it doesn't exist.
-func (s *service) GetJournalEntry(id string) (*models.JournalEntry, error) {
-	entry, err := s.store.GetByID(id)
+// authorizeEntry loads an entry on behalf of userID.
+// Entries owned by other users are reported as ErrEntryNotFound.
+func (s *service) authorizeEntry(id, userID string) (*models.JournalEntry, error) {
+	if userID == "" {
+		return nil, ErrEntryNotFound
+	}
+	entry, err := s.store.GetByID(id, userID)
 	if err != nil {
-		// If not found, create a new one
-		newEntry := &models.JournalEntry{
-			ID:      id,
-			Title:   "Untitled",
-			Content: "<h1>Untitled</h1><p>Start writing your new entry!</p>",
-		}
-		if createErr := s.store.Create(newEntry); createErr != nil {
-			return nil, createErr
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEntryNotFound
 		}
-		return newEntry, nil
+		return nil, err
 	}
 	return entry, nil
 }
 
+// authorizeFolder checks that a folder referenced by an entry belongs to userID.
+// A nil folderID (the root) is always allowed.
+func (s *service) authorizeFolder(folderID *string, userID string) error {
+	if folderID == nil || *folderID == "" {
+		return nil
+	}
+	ok, err := s.store.FolderExists(*folderID, userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrFolderNotFound
+	}
+	return nil
+}
+
+// saveEntry writes the changes to an entry read at entry.Version.
+// If someone else saved it in the meantime, a *VersionConflictError with their copy is returned.
+func (s *service) saveEntry(entry *models.JournalEntry) error {
+	err := s.store.Update(entry)
+	switch {
+	case errors.Is(err, errStaleVersion):
+		current, err := s.authorizeEntry(entry.ID, entry.UserID)
+		if err != nil {
+			return err
+		}
+		return &VersionConflictError{Current: current}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrEntryNotFound
+	}
+	return err
+}
+
+// GetJournalEntry retrieves a journal entry owned by userID.
+func (s *service) GetJournalEntry(id, userID string) (*models.JournalEntry, error) {
+	return s.authorizeEntry(id, userID)
+}
+
 // UpdateJournalEntry updates the title and content of a journal entry.
-func (s *service) UpdateJournalEntry(id, title, content string, folderID *string, newText string) (*models.JournalEntry, error) {
-	entry, err := s.store.GetByID(id)
+// If no entry with this ID exists yet it is created for userID, which lets the editor
+// save documents whose IDs were generated client-side.
+// A non-zero version is the version the caller edited (from If-Match); the update fails with a
+// *VersionConflictError if the entry no longer has that version, or ErrEntryNotFound if it does not exist.
+//
+// Paragraphs that are new or changed compared to the stored content are sent to the AI agents,
+// unless they were already scored for this entry before.
+func (s *service) UpdateJournalEntry(id, userID, title, content string, folderID *string, version int) (*models.JournalEntry, error) {
+	if err := s.authorizeFolder(folderID, userID); err != nil {
+		return nil, err
+	}
+
+	entry, err := s.authorizeEntry(id, userID)
 	if err != nil {
 		// If the entry does not exist, create it.
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+		if errors.Is(err, ErrEntryNotFound) && userID != "" && version == 0 {
 			newEntry := &models.JournalEntry{
 				ID:       id,
+				UserID:   userID,
 				Title:    title,
 				Content:  content,
 				FolderID: folderID,
 			}
-			return newEntry, s.store.Create(newEntry)
+			if err := s.store.Create(newEntry); err != nil {
+				// The ID is taken by an entry owned by someone else.
+				if errors.Is(err, gorm.ErrDuplicatedKey) {
+					return nil, ErrEntryNotFound
+				}
+				return nil, err
+			}
+			if err := s.recordRevision(newEntry, false); err != nil {
+				return nil, fmt.Errorf("could not record revision: %w", err)
+			}
+			s.publishSaved(newEntry, s.processChanges(newEntry, ""))
+			return newEntry, nil
 		}
 		return nil, err
 	}
+	if version != 0 && entry.Version != version {
+		return nil, &VersionConflictError{Current: entry}
+	}
+
+	previous := entry.Content
 
 	// Only update fields that are not empty
 	if title != "" {
@@ -78,41 +239,83 @@ func (s *service) UpdateJournalEntry(id, title, content string, folderID *string
 		entry.FolderID = folderID
 	}
 
-	if err := s.store.Update(entry); err != nil {
+	if err := s.saveEntry(entry); err != nil {
 		return nil, err
 	}
+	if err := s.recordRevision(entry, false); err != nil {
+		return nil, fmt.Errorf("could not record revision: %w", err)
+	}
 
-	// Determine what text to send to the AI
-	textToProcess := newText
-	if textToProcess == "" {
-		textToProcess = content
+	// After successfully updating, send the newly written text to the AI services
+	text := s.processChanges(entry, previous)
+	if entry.Content != previous {
+		s.publishSaved(entry, text)
 	}
 
-	// After successfully updating, send content to the AI services
-	// Process for XP
-	go func() {
-		_, err := s.aiService.ProcessText(textToProcess, entry.UserID)
-		if err != nil {
-			log.Printf("Failed to process text with XP agent: %v", err)
-			return
+	return entry, nil
+}
+
+// publishSaved reports that the content of entry was saved, with text newly written in it.
+func (s *service) publishSaved(entry *models.JournalEntry, text string) {
+	s.bus.Publish(events.Event{Type: events.EntrySaved, UserID: entry.UserID, SubjectID: entry.ID, Words: len(strings.Fields(text))})
+}
+
+// processChanges enqueues the paragraphs written since previous for the XP and quest agents,
+// and returns them.
+func (s *service) processChanges(entry *models.JournalEntry, previous string) string {
+	text, err := s.unscoredText(entry.ID, entry.UserID, previous, entry.Content)
+	if err != nil {
+		log.Printf("Failed to determine new text of entry %s: %v", entry.ID, err)
+		return ""
+	}
+	if text == "" {
+		return ""
+	}
+	payload := aiJobPayload{EntryID: entry.ID, Text: text}
+	for _, kind := range []string{JobProcessXP, JobProcessQuests} {
+		if err := s.queue.Enqueue(kind, entry.UserID, payload); err != nil {
+			log.Printf("Failed to enqueue %s job for entry %s: %v", kind, entry.ID, err)
 		}
-		log.Printf("XP agent processed entry %s successfully.", entry.ID)
-	}()
+	}
+	return text
+}
 
-	// Process for Quests
-	go func() {
-		err := s.aiService.ProcessTextForQuests(textToProcess, entry.UserID)
-		if err != nil {
-			log.Printf("Failed to process text with Quest agent: %v", err)
-			return
+// unscoredText returns the paragraphs of content that were added or changed compared to previous
+// and have not been scored for the entry yet, joined by blank lines. The returned paragraphs are
+// marked as scored, so no paragraph is ever sent to the agents twice.
+func (s *service) unscoredText(entryID, userID, previous, content string) (string, error) {
+	added := map[string]string{} // paragraph hash -> text
+	var hashes []string
+	for _, op := range textdiff.Diff(richtext.Paragraphs(previous), richtext.Paragraphs(content)) {
+		if op.Kind != textdiff.Insert {
+			continue
+		}
+		hash := richtext.ParagraphHash(op.Text)
+		if _, ok := added[hash]; !ok {
+			added[hash] = op.Text
+			hashes = append(hashes, hash)
 		}
-		log.Printf("Quest agent processed entry %s successfully.", entry.ID)
-	}()
+	}
+	if len(hashes) == 0 {
+		return "", nil
+	}
 
-	return entry, nil
+	claimed, err := s.store.ClaimParagraphs(entryID, userID, hashes)
+	if err != nil {
+		return "", err
+	}
+	paragraphs := make([]string, 0, len(claimed))
+	for _, hash := range claimed {
+		paragraphs = append(paragraphs, added[hash])
+	}
+	return strings.Join(paragraphs, "\n\n"), nil
 }
 
 func (s *service) CreateJournalEntry(title, content, userID string, folderID *string) (*models.JournalEntry, error) {
+	if err := s.authorizeFolder(folderID, userID); err != nil {
+		return nil, err
+	}
+
 	newEntry := &models.JournalEntry{
 		ID:       fmt.Sprintf("doc-%d", time.Now().UnixNano()),
 		Title:    title,
@@ -124,15 +327,174 @@ func (s *service) CreateJournalEntry(title, content, userID string, folderID *st
 	if err := s.store.Create(newEntry); err != nil {
 		return nil, err
 	}
+	if err := s.recordRevision(newEntry, false); err != nil {
+		return nil, fmt.Errorf("could not record revision: %w", err)
+	}
+	s.publishSaved(newEntry, s.processChanges(newEntry, ""))
 
 	return newEntry, nil
 }
 
-func (s *service) GetJournalEntriesByUserID(userID string) ([]models.JournalEntry, error) {
-	return s.store.GetByUserID(userID)
+func (s *service) GetJournalEntriesByUserID(userID, tagName string) ([]models.JournalEntry, error) {
+	return s.store.GetByUserID(userID, tag.NormalizeName(tagName))
+}
+
+// DeleteJournalEntry deletes a journal entry owned by userID.
+func (s *service) DeleteJournalEntry(id, userID string) error {
+	if userID == "" {
+		return ErrEntryNotFound
+	}
+	if err := s.store.Delete(id, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrEntryNotFound
+		}
+		return err
+	}
+	return nil
 }
 
-// DeleteJournalEntry deletes a journal entry by its ID.
-func (s *service) DeleteJournalEntry(id string) error {
-	return s.store.Delete(id)
+// ImportEntries saves parsed import entries for userID, recreating their folder paths.
+// Folders are reused when a folder with the same name already exists at that place in the tree,
+// and entries whose title and content match an existing (or earlier imported) entry are skipped.
+// Dates imported without a UTC offset are taken as the user's local time.
+func (s *service) ImportEntries(userID string, entries []ImportedEntry, opts ImportOptions) (*ImportReport, error) {
+	cal, err := calendar.For(s.calendars, userID)
+	if err != nil {
+		return nil, err
+	}
+	report := &ImportReport{DryRun: opts.DryRun, Entries: make([]ImportResult, 0, len(entries))}
+	folders := &importFolders{service: s, userID: userID, dryRun: opts.DryRun, ids: map[string]*string{}}
+	seen := map[string]bool{}
+	var created []*models.JournalEntry
+
+	for _, imported := range entries {
+		if imported.CreatedAt != nil && imported.LocalTime {
+			t := *imported.CreatedAt
+			local := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), cal.Location)
+			imported.CreatedAt = &local
+		}
+		result := ImportResult{
+			Source:    imported.Source,
+			Title:     imported.Title,
+			Folder:    strings.Join(imported.FolderPath, "/"),
+			CreatedAt: imported.CreatedAt,
+		}
+
+		key := imported.Title + "\x00" + imported.Content
+		duplicate := seen[key]
+		if !duplicate {
+			var err error
+			if duplicate, err = s.store.HasDuplicate(userID, imported.Title, imported.Content); err != nil {
+				return nil, err
+			}
+		}
+		seen[key] = true
+		if duplicate {
+			result.Status = ImportStatusDuplicate
+			report.Duplicates++
+			report.Entries = append(report.Entries, result)
+			continue
+		}
+
+		folderID, err := folders.resolve(imported.FolderPath)
+		if err != nil {
+			return nil, err
+		}
+
+		if opts.DryRun {
+			result.Status = ImportStatusWouldCreate
+			report.Created++
+			report.Entries = append(report.Entries, result)
+			continue
+		}
+
+		entry := &models.JournalEntry{
+			ID:       "doc-" + uuid.NewString(),
+			UserID:   userID,
+			Title:    imported.Title,
+			Content:  imported.Content,
+			Mood:     imported.Mood,
+			FolderID: folderID,
+		}
+		if imported.CreatedAt != nil {
+			entry.CreatedAt = *imported.CreatedAt
+			entry.UpdatedAt = *imported.CreatedAt
+		}
+		if err := s.store.Create(entry); err != nil {
+			return nil, err
+		}
+		if err := s.recordRevision(entry, true); err != nil {
+			return nil, fmt.Errorf("could not record revision: %w", err)
+		}
+
+		result.Status = ImportStatusCreated
+		result.EntryID = entry.ID
+		report.Created++
+		report.Entries = append(report.Entries, result)
+		created = append(created, entry)
+	}
+	report.FoldersCreated = folders.created
+
+	if opts.ProcessWithAI {
+		// The user's jobs run one at a time, so a large import does not flood the agents.
+		for _, entry := range created {
+			s.processChanges(entry, "")
+		}
+	}
+	// One event covers the whole import; subscribers look at all of the user's entries anyway.
+	// Imported text was written in the past, so it is not reported as new.
+	if len(created) > 0 {
+		s.publishSaved(created[len(created)-1], "")
+	}
+	return report, nil
+}
+
+// importFolders finds or creates the folders of imported entries, caching them by path.
+type importFolders struct {
+	service *service
+	userID  string
+	dryRun  bool
+	ids     map[string]*string // path -> folder ID; nil for folders a dry run would create
+	created int
+}
+
+// resolve returns the ID of the folder at path, creating missing folders along the way.
+// In a dry run missing folders are only counted, and nil is returned for them.
+func (f *importFolders) resolve(path []string) (*string, error) {
+	var parentID *string
+	for i, name := range path {
+		key := strings.Join(path[:i+1], "/")
+		if id, ok := f.ids[key]; ok {
+			parentID = id
+			continue
+		}
+
+		// A folder that a dry run would create has no existing children to look up.
+		parentPending := i > 0 && parentID == nil
+		var folder *models.Folder
+		if !parentPending {
+			found, err := f.service.store.FindFolder(f.userID, name, parentID)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, err
+			}
+			folder = found
+		}
+
+		if folder == nil {
+			f.created++
+			if f.dryRun {
+				f.ids[key] = nil
+				parentID = nil
+				continue
+			}
+			folder = &models.Folder{Name: name, UserID: f.userID, ParentID: parentID}
+			if err := f.service.store.CreateFolder(folder); err != nil {
+				return nil, err
+			}
+		}
+		id := folder.ID
+		f.ids[key] = &id
+		parentID = &id
+	}
+	return parentID, nil
 }
@@ -0,0 +1,32 @@
+package journal
+
+import "testing"
+
+func TestService_UnscoredText(t *testing.T) {
+	svc := NewService(newMemoryStore(), nil, nil, nil, nil, nil).(*service)
+
+	steps := []struct {
+		name     string
+		entryID  string
+		previous string
+		content  string
+		want     string
+	}{
+		{name: "first save", entryID: entryID, content: "<h1>Day</h1><p>Woke up.</p>", want: "Day\n\nWoke up."},
+		{name: "new and changed paragraphs", entryID: entryID, previous: "<h1>Day</h1><p>Woke up.</p>", content: "<h1>Day</h1><p>Woke up early.</p><p>Ran.</p>", want: "Woke up early.\n\nRan."},
+		{name: "formatting only", entryID: entryID, previous: "<p>Ran.</p>", content: "<p><strong>Ran.</strong></p>"},
+		{name: "reverting to scored text", entryID: entryID, previous: "<p>Woke up early.</p>", content: "<p>Woke up.</p>"},
+		{name: "repeated paragraph", entryID: entryID, previous: "<p>Ran.</p>", content: "<p>Ran.</p><p>Ate.</p><p>Ate.</p>", want: "Ate."},
+		{name: "deletions", entryID: entryID, previous: "<p>Ran.</p><p>Ate.</p>", content: "<p>Ate.</p>"},
+		{name: "other entry", entryID: "doc-other", content: "<p>Woke up.</p>", want: "Woke up."},
+	}
+	for _, step := range steps {
+		got, err := svc.unscoredText(step.entryID, ownerID, step.previous, step.content)
+		if err != nil {
+			t.Fatalf("%s: unscoredText() error = %v", step.name, err)
+		}
+		if got != step.want {
+			t.Errorf("%s: unscoredText() = %q, want %q", step.name, got, step.want)
+		}
+	}
+}
@@ -1,17 +1,62 @@
 package journal
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/google/uuid"
 )
 
 // Store defines the interface for journal data persistence.
+// Every lookup is scoped by the owning user so that entries belonging to
+// someone else are indistinguishable from entries that do not exist.
 type Store interface {
-	GetByID(id string) (*models.JournalEntry, error)
+	GetByID(id, userID string) (*models.JournalEntry, error)
+	// Update saves the editable fields of an entry if its stored version still equals
+	// entry.Version, and increments the version.
 	Update(entry *models.JournalEntry) error
 	Create(entry *models.JournalEntry) error
-	GetByUserID(userID string) ([]models.JournalEntry, error)
-	Delete(id string) error
+	// GetByUserID returns the user's entries, only those with the named tag unless tagName is empty.
+	GetByUserID(userID, tagName string) ([]models.JournalEntry, error)
+	Delete(id, userID string) error
+	FolderExists(folderID, userID string) (bool, error)
+	// FindFolder looks up a folder of the user by name within a parent (nil for the root).
+	FindFolder(userID, name string, parentID *string) (*models.Folder, error)
+	CreateFolder(folder *models.Folder) error
+	// HasDuplicate reports whether the user already has an entry with this title and content.
+	HasDuplicate(userID, title, content string) (bool, error)
+
+	// LatestRevision returns the newest revision of an entry, or gorm.ErrRecordNotFound if it has none.
+	LatestRevision(entryID, userID string) (*models.JournalRevision, error)
+	CreateRevision(revision *models.JournalRevision) error
+	// UpdateRevision overwrites the title, content and update time of a revision being coalesced.
+	UpdateRevision(revision *models.JournalRevision) error
+	// ListRevisions returns the revisions of an entry, newest first, without their content.
+	ListRevisions(entryID, userID string) ([]models.JournalRevision, error)
+	GetRevision(entryID, userID string, number int) (*models.JournalRevision, error)
+	// PruneRevisions deletes all but the newest keep revisions (if keep > 0) and revisions created
+	// before olderThan (if it is not zero). The newest revision is always kept.
+	PruneRevisions(entryID string, keep int, olderThan time.Time) error
+	// ClaimParagraphs marks paragraph hashes of an entry as scored and returns those that were
+	// not scored before. Concurrent claims of the same hash succeed for exactly one caller.
+	ClaimParagraphs(entryID, userID string, hashes []string) ([]string, error)
+
+	// AttachTags attaches tags to an entry, skipping those it already has.
+	AttachTags(entry *models.JournalEntry, tags []models.Tag) error
+	DetachTag(entry *models.JournalEntry, tagID string) error
+	SaveSuggestedTags(entryID, userID string, names []string) error
+
+	// Search returns a page of the user's entries matching filter, best first, and how many
+	// entries match in all.
+	Search(userID string, filter SearchFilter, limit, offset int) ([]SearchResult, int64, error)
+	// UnindexedEntries returns up to limit entries that were saved before search existed.
+	UnindexedEntries(limit int) ([]models.JournalEntry, error)
+	SaveSearchIndex(entry *models.JournalEntry) error
 }
 
 // gormStore is a GORM implementation of the Store interface.
@@ -24,36 +69,342 @@ func NewStore(db *gorm.DB) Store {
 	return &gormStore{db: db}
 }
 
-// GetByID retrieves a journal entry by its ID.
-func (s *gormStore) GetByID(id string) (*models.JournalEntry, error) {
+// preloadTags loads the tags of entries, sorted by name.
+func preloadTags(db *gorm.DB) *gorm.DB {
+	return db.Preload("Tags", func(db *gorm.DB) *gorm.DB {
+		return db.Order("tags.name")
+	})
+}
+
+// GetByID retrieves a journal entry by its ID for the given owner, with its tags.
+func (s *gormStore) GetByID(id, userID string) (*models.JournalEntry, error) {
 	var entry models.JournalEntry
-	if err := s.db.First(&entry, "id = ?", id).Error; err != nil {
+	if err := preloadTags(s.db).First(&entry, "id = ? AND user_id = ?", id, userID).Error; err != nil {
 		return nil, err
 	}
 	return &entry, nil
 }
 
-// Update saves the changes to a journal entry.
+// errStaleVersion is returned by Update when the entry was modified since it was read.
+var errStaleVersion = errors.New("journal entry version is stale")
+
+// Update saves the editable fields of a journal entry, reindexes it for search and increments
+// its version. The write is restricted to the entry's owner and only succeeds if the stored version still
+// equals entry.Version; errStaleVersion is returned otherwise, and gorm.ErrRecordNotFound if no
+// such entry exists.
 func (s *gormStore) Update(entry *models.JournalEntry) error {
-	return s.db.Save(entry).Error
+	// A blind Save would fall back to an upsert when no row matches, which could
+	// overwrite another user's entry. Updating with an explicit owner filter avoids that.
+	// The version check is part of the same statement, so concurrent saves cannot both win.
+	indexForSearch(entry)
+	expected := entry.Version
+	entry.Version = expected + 1
+	result := s.db.Model(entry).
+		Where("user_id = ? AND version = ?", entry.UserID, expected).
+		Select("title", "content", "mood", "folder_id", "search_text", "search_language", "version").
+		Updates(entry)
+	if result.Error != nil || result.RowsAffected == 0 {
+		entry.Version = expected
+	}
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		var count int64
+		if err := s.db.Model(&models.JournalEntry{}).Where("id = ? AND user_id = ?", entry.ID, entry.UserID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errStaleVersion
+		}
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
 
-// Create creates a new journal entry.
+// Create creates a new journal entry, indexed for search.
 func (s *gormStore) Create(entry *models.JournalEntry) error {
+	indexForSearch(entry)
 	return s.db.Create(entry).Error
 }
 
-// GetByUserID retrieves all journal entries for a given user ID.
-func (s *gormStore) GetByUserID(userID string) ([]models.JournalEntry, error) {
+// GetByUserID retrieves the journal entries of a given user ID with their tags, optionally
+// only those tagged tagName.
+func (s *gormStore) GetByUserID(userID, tagName string) ([]models.JournalEntry, error) {
 	var entries []models.JournalEntry
-	if err := s.db.Where("user_id = ?", userID).Find(&entries).Error; err != nil {
+	query := preloadTags(s.db).Where("user_id = ?", userID)
+	if tagName != "" {
+		query = query.Where("id IN (?)", s.taggedWith(userID, tagName))
+	}
+	if err := query.Find(&entries).Error; err != nil {
 		return nil, err
 	}
 	return entries, nil
 }
 
-// Delete removes a journal entry by its ID.
-func (s *gormStore) Delete(id string) error {
-	// GORM's delete function requires a model to delete, so we create a dummy one with the ID.
-	return s.db.Delete(&models.JournalEntry{}, "id = ?", id).Error
-} 
\ No newline at end of file
+// taggedWith selects the IDs of the user's entries with the named tag.
+func (s *gormStore) taggedWith(userID, tagName string) *gorm.DB {
+	return s.db.Table("journal_entry_tags").
+		Select("journal_entry_tags.journal_entry_id").
+		Joins("JOIN tags ON tags.id = journal_entry_tags.tag_id").
+		Where("tags.user_id = ? AND tags.name = ?", userID, tagName)
+}
+
+// Delete removes a journal entry, its revisions, its scored paragraphs and its tag links by its ID
+// for the given owner. Returns gorm.ErrRecordNotFound if the entry does not exist or is owned by someone else.
+func (s *gormStore) Delete(id, userID string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		owned := tx.Model(&models.JournalEntry{}).Select("id").Where("id = ? AND user_id = ?", id, userID)
+		if err := tx.Table("journal_entry_tags").Where("journal_entry_id IN (?)", owned).Delete(nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("entry_id = ? AND user_id = ?", id, userID).Delete(&models.JournalRevision{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("entry_id = ? AND user_id = ?", id, userID).Delete(&models.ScoredParagraph{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&models.JournalEntry{}, "id = ? AND user_id = ?", id, userID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// FolderExists reports whether the folder exists and is owned by the given user.
+func (s *gormStore) FolderExists(folderID, userID string) (bool, error) {
+	var count int64
+	if err := s.db.Model(&models.Folder{}).Where("id = ? AND user_id = ?", folderID, userID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// FindFolder retrieves a folder by name and parent for the given owner.
+// Returns gorm.ErrRecordNotFound if there is none.
+func (s *gormStore) FindFolder(userID, name string, parentID *string) (*models.Folder, error) {
+	query := s.db.Where("user_id = ? AND name = ?", userID, name)
+	if parentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+	var folder models.Folder
+	if err := query.First(&folder).Error; err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// CreateFolder creates a new folder.
+func (s *gormStore) CreateFolder(folder *models.Folder) error {
+	return s.db.Create(folder).Error
+}
+
+// HasDuplicate reports whether an entry with the same title and content exists for the user.
+func (s *gormStore) HasDuplicate(userID, title, content string) (bool, error) {
+	var count int64
+	err := s.db.Model(&models.JournalEntry{}).
+		Where("user_id = ? AND title = ? AND content = ?", userID, title, content).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// LatestRevision retrieves the revision with the highest number for an entry of the given owner.
+func (s *gormStore) LatestRevision(entryID, userID string) (*models.JournalRevision, error) {
+	var revision models.JournalRevision
+	err := s.db.Where("entry_id = ? AND user_id = ?", entryID, userID).
+		Order("number DESC").
+		First(&revision).Error
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// CreateRevision inserts a new revision.
+// Returns gorm.ErrDuplicatedKey if a concurrent save already took the revision number.
+func (s *gormStore) CreateRevision(revision *models.JournalRevision) error {
+	return s.db.Create(revision).Error
+}
+
+// UpdateRevision saves the coalesced title and content of a revision.
+func (s *gormStore) UpdateRevision(revision *models.JournalRevision) error {
+	return s.db.Model(revision).
+		Select("title", "content", "updated_at").
+		Updates(revision).Error
+}
+
+// ListRevisions retrieves the revision metadata of an entry, newest first.
+func (s *gormStore) ListRevisions(entryID, userID string) ([]models.JournalRevision, error) {
+	var revisions []models.JournalRevision
+	err := s.db.Omit("content").
+		Where("entry_id = ? AND user_id = ?", entryID, userID).
+		Order("number DESC").
+		Find(&revisions).Error
+	return revisions, err
+}
+
+// GetRevision retrieves a single revision of an entry by its number.
+func (s *gormStore) GetRevision(entryID, userID string, number int) (*models.JournalRevision, error) {
+	var revision models.JournalRevision
+	err := s.db.Where("entry_id = ? AND user_id = ? AND number = ?", entryID, userID, number).
+		First(&revision).Error
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// PruneRevisions applies the retention policy to an entry's revisions.
+func (s *gormStore) PruneRevisions(entryID string, keep int, olderThan time.Time) error {
+	var latest int
+	if err := s.db.Model(&models.JournalRevision{}).
+		Where("entry_id = ?", entryID).
+		Select("COALESCE(MAX(number), 0)").
+		Scan(&latest).Error; err != nil {
+		return err
+	}
+
+	query := s.db.Where("entry_id = ? AND number < ?", entryID, latest)
+	switch {
+	case keep > 0 && !olderThan.IsZero():
+		query = query.Where("number <= ? OR created_at < ?", latest-keep, olderThan)
+	case keep > 0:
+		query = query.Where("number <= ?", latest-keep)
+	case !olderThan.IsZero():
+		query = query.Where("created_at < ?", olderThan)
+	default:
+		return nil
+	}
+	return query.Delete(&models.JournalRevision{}).Error
+}
+
+// ClaimParagraphs inserts a scored paragraph per hash, skipping hashes the entry already has.
+func (s *gormStore) ClaimParagraphs(entryID, userID string, hashes []string) ([]string, error) {
+	var claimed []string
+	for _, hash := range hashes {
+		result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.ScoredParagraph{
+			ID:      uuid.NewString(),
+			EntryID: entryID,
+			UserID:  userID,
+			Hash:    hash,
+		})
+		if result.Error != nil {
+			return claimed, result.Error
+		}
+		if result.RowsAffected > 0 {
+			claimed = append(claimed, hash)
+		}
+	}
+	return claimed, nil
+}
+
+// AttachTags only writes the links; the tags themselves already exist.
+func (s *gormStore) AttachTags(entry *models.JournalEntry, tags []models.Tag) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	return s.db.Model(entry).Omit("Tags.*").Association("Tags").Append(tags)
+}
+
+func (s *gormStore) DetachTag(entry *models.JournalEntry, tagID string) error {
+	return s.db.Model(entry).Association("Tags").Delete(&models.Tag{ID: tagID})
+}
+
+// SaveSuggestedTags leaves the version and update time of the entry alone, since its
+// content did not change.
+func (s *gormStore) SaveSuggestedTags(entryID, userID string, names []string) error {
+	return s.db.Model(&models.JournalEntry{ID: entryID}).
+		Where("user_id = ?", userID).
+		Select("suggested_tags").
+		UpdateColumns(&models.JournalEntry{SuggestedTags: names}).Error
+}
+
+// Search matches the search_vector of entries against the query, parsed in the language of
+// each entry, and ranks entries by cover density.
+func (s *gormStore) Search(userID string, filter SearchFilter, limit, offset int) ([]SearchResult, int64, error) {
+	query := s.filterEntries(userID, filter).Where(searchMatch(filter.Text)).Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil || total == 0 {
+		return nil, total, err
+	}
+	var results []SearchResult
+	err := query.Select(searchColumns+", ? AS rank", searchRank(filter.Text)).
+		Order("rank DESC, updated_at DESC").
+		Limit(limit).Offset(offset).
+		Scan(&results).Error
+	return results, total, err
+}
+
+// searchColumns are the columns of entries read into a SearchResult.
+const searchColumns = "id, title, folder_id, mood, created_at, updated_at, search_text"
+
+// filterEntries selects the user's entries kept by the folder, tag, mood and dates of filter.
+func (s *gormStore) filterEntries(userID string, filter SearchFilter) *gorm.DB {
+	query := s.db.Model(&models.JournalEntry{}).Where("user_id = ?", userID)
+	if filter.FolderID != "" {
+		query = query.Where("folder_id = ?", filter.FolderID)
+	}
+	if filter.Tag != "" {
+		query = query.Where("id IN (?)", s.taggedWith(userID, filter.Tag))
+	}
+	if filter.Mood != "" {
+		query = query.Where("LOWER(mood) = LOWER(?)", filter.Mood)
+	}
+	// Bounds are passed in UTC, like times are saved, so databases that compare times as text agree.
+	if !filter.CreatedAfter.IsZero() {
+		query = query.Where("created_at >= ?", filter.CreatedAfter.UTC())
+	}
+	if !filter.CreatedBefore.IsZero() {
+		query = query.Where("created_at < ?", filter.CreatedBefore.UTC())
+	}
+	return query
+}
+
+// searchMatch matches the search_vector of entries against text, parsed with the text search
+// configuration of each entry. The configurations are named rather than read from the entries,
+// so the GIN index on search_vector can serve the match.
+func searchMatch(text string) clause.Expr {
+	var conditions []string
+	var vars []interface{}
+	for _, language := range searchLanguages {
+		conditions = append(conditions, fmt.Sprintf("(search_language = '%[1]s' AND search_vector @@ websearch_to_tsquery('%[1]s', ?))", language))
+		vars = append(vars, text)
+	}
+	return gorm.Expr("("+strings.Join(conditions, " OR ")+")", vars...)
+}
+
+// searchRank ranks an entry matched by searchMatch by how close together the words of text are
+// in it, normalized by the length of the entry.
+func searchRank(text string) clause.Expr {
+	var cases []string
+	var vars []interface{}
+	for _, language := range searchLanguages {
+		cases = append(cases, fmt.Sprintf("WHEN '%[1]s' THEN websearch_to_tsquery('%[1]s', ?)", language))
+		vars = append(vars, text)
+	}
+	return gorm.Expr("ts_rank_cd(search_vector, CASE search_language "+strings.Join(cases, " ")+" END, 1)", vars...)
+}
+
+// UnindexedEntries finds the entries without a search language, which every save sets.
+func (s *gormStore) UnindexedEntries(limit int) ([]models.JournalEntry, error) {
+	var entries []models.JournalEntry
+	err := s.db.Where("search_language IS NULL").Limit(limit).Find(&entries).Error
+	return entries, err
+}
+
+// SaveSearchIndex leaves the version and update time of the entry alone, since its content
+// did not change. Entries saved since they were read are indexed already and left alone, so
+// their new text is not replaced by the old one.
+func (s *gormStore) SaveSearchIndex(entry *models.JournalEntry) error {
+	return s.db.Model(&models.JournalEntry{ID: entry.ID}).
+		Where("search_language IS NULL").
+		Select("search_text", "search_language").
+		UpdateColumns(entry).Error
+}
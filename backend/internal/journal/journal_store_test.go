@@ -0,0 +1,91 @@
+package journal
+
+import (
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/database/databasetest"
+)
+
+func TestGormStore_UpdateChecksVersion(t *testing.T) {
+	db := databasetest.Open(t)
+	store := NewStore(db)
+	if err := db.Create(&models.User{ID: ownerID, Username: "owner", Email: "owner@example.com"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Create(&models.JournalEntry{ID: entryID, UserID: ownerID, Title: "Day"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// Two tabs load the entry at the same version.
+	first, err := store.GetByID(entryID, ownerID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	second := *first
+	if first.Version != 1 {
+		t.Fatalf("new entry has version %d, want 1", first.Version)
+	}
+
+	first.Content = "<p>first</p>"
+	if err := store.Update(first); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if first.Version != 2 {
+		t.Errorf("version after update = %d, want 2", first.Version)
+	}
+
+	second.Content = "<p>second</p>"
+	if err := store.Update(&second); !errors.Is(err, errStaleVersion) {
+		t.Fatalf("Update() with a stale version error = %v, want %v", err, errStaleVersion)
+	}
+	if second.Version != 1 {
+		t.Errorf("failed update changed the caller's version to %d", second.Version)
+	}
+
+	stored, err := store.GetByID(entryID, ownerID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if stored.Content != "<p>first</p>" || stored.Version != 2 {
+		t.Errorf("stored entry = %+v, want the first update at version 2", stored)
+	}
+
+	foreign := *stored
+	foreign.UserID = intruderID
+	if err := store.Update(&foreign); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Update() of a foreign entry error = %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+}
+
+func TestGormStore_ClaimParagraphs(t *testing.T) {
+	db := databasetest.Open(t)
+	store := NewStore(db)
+	if err := db.Create(&models.User{ID: ownerID, Username: "owner", Email: "owner@example.com"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Create(&models.JournalEntry{ID: entryID, UserID: ownerID, Title: "Day"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	claimed, err := store.ClaimParagraphs(entryID, ownerID, []string{"a", "b"})
+	if err != nil || len(claimed) != 2 {
+		t.Fatalf("ClaimParagraphs() = %v, %v; want both hashes", claimed, err)
+	}
+	claimed, err = store.ClaimParagraphs(entryID, ownerID, []string{"b", "c"})
+	if err != nil || len(claimed) != 1 || claimed[0] != "c" {
+		t.Fatalf("ClaimParagraphs() = %v, %v; want only the unscored hash", claimed, err)
+	}
+
+	if err := store.Delete(entryID, ownerID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	var count int64
+	db.Model(&models.ScoredParagraph{}).Count(&count)
+	if count != 0 {
+		t.Errorf("%d scored paragraphs left after deleting the entry", count)
+	}
+}
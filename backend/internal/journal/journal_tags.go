@@ -0,0 +1,86 @@
+package journal
+
+import (
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/tag"
+)
+
+// maxSuggestedTags is how many of the tags the XP agent suggests are kept for an entry.
+const maxSuggestedTags = 5
+
+// AddTags attaches tags to an entry by name, creating the tags the user does not have yet.
+// Tags the entry already has are left as they are, and attached tags are no longer suggested.
+func (s *service) AddTags(entryID, userID string, names []string) (*models.JournalEntry, error) {
+	entry, err := s.authorizeEntry(entryID, userID)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := s.tags.ResolveTags(userID, names)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.AttachTags(entry, tags); err != nil {
+		return nil, err
+	}
+
+	attached := map[string]bool{}
+	for _, t := range tags {
+		attached[t.Name] = true
+	}
+	suggested := withoutNames(entry.SuggestedTags, attached)
+	if len(suggested) != len(entry.SuggestedTags) {
+		if err := s.store.SaveSuggestedTags(entryID, userID, suggested); err != nil {
+			return nil, err
+		}
+	}
+	return s.authorizeEntry(entryID, userID)
+}
+
+// RemoveTag detaches a tag from an entry. The tag itself is kept.
+func (s *service) RemoveTag(entryID, userID, tagID string) (*models.JournalEntry, error) {
+	entry, err := s.authorizeEntry(entryID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.DetachTag(entry, tagID); err != nil {
+		return nil, err
+	}
+	return s.authorizeEntry(entryID, userID)
+}
+
+// SuggestTags saves the tags an agent suggested for an entry, replacing earlier suggestions.
+// Invalid names and tags the entry already has are dropped.
+func (s *service) SuggestTags(entryID, userID string, names []string) error {
+	entry, err := s.authorizeEntry(entryID, userID)
+	if err != nil {
+		return err
+	}
+	skip := map[string]bool{}
+	for _, t := range entry.Tags {
+		skip[t.Name] = true
+	}
+	var suggested []string
+	for _, name := range names {
+		name = tag.NormalizeName(name)
+		if name == "" || len([]rune(name)) > tag.MaxNameLength || skip[name] {
+			continue
+		}
+		skip[name] = true
+		suggested = append(suggested, name)
+		if len(suggested) == maxSuggestedTags {
+			break
+		}
+	}
+	return s.store.SaveSuggestedTags(entryID, userID, suggested)
+}
+
+// withoutNames returns names except those in drop.
+func withoutNames(names []string, drop map[string]bool) []string {
+	var kept []string
+	for _, name := range names {
+		if !drop[name] {
+			kept = append(kept, name)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,90 @@
+package journal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/database/databasetest"
+	"github.com/adrianvalentim/gamify_journal/internal/tag"
+)
+
+func TestHandler_TagEntries(t *testing.T) {
+	db := databasetest.Open(t)
+	if err := db.Create(&models.User{ID: ownerID, Username: "owner", Email: "owner@example.com"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	svc := NewService(NewStore(db), &recordingQueue{}, nil, nil, nil, tag.NewService(tag.NewStore(db)))
+	for _, id := range []string{"doc-1", "doc-2"} {
+		if _, err := svc.UpdateJournalEntry(id, ownerID, id, "<p>Went running.</p>", nil, 0); err != nil {
+			t.Fatalf("UpdateJournalEntry(%s) error = %v", id, err)
+		}
+	}
+	if err := svc.SuggestTags("doc-1", ownerID, []string{"Running", "health"}); err != nil {
+		t.Fatalf("SuggestTags() error = %v", err)
+	}
+	r := chi.NewRouter()
+	NewHandler(svc).RegisterRoutes(r)
+
+	do := func(method, target, body string, wantStatus int, out interface{}) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, authorizedRequest(t, method, target, body, ownerID))
+		if rec.Code != wantStatus {
+			t.Fatalf("%s %s status = %d, want %d; body: %s", method, target, rec.Code, wantStatus, rec.Body)
+		}
+		if out != nil {
+			if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+				t.Fatalf("decoding %s %s: %v", method, target, err)
+			}
+		}
+	}
+
+	var entry models.JournalEntry
+	do(http.MethodPost, "/journal/doc-1/tags", `{"tags":["#Running","Morning Routine"]}`, http.StatusOK, &entry)
+	if len(entry.Tags) != 2 || entry.Tags[0].Name != "morning routine" || entry.Tags[1].Name != "running" {
+		t.Fatalf("tags = %+v, want morning routine and running", entry.Tags)
+	}
+	if len(entry.SuggestedTags) != 1 || entry.SuggestedTags[0] != "health" {
+		t.Errorf("suggested tags = %q, want the attached suggestion dropped", entry.SuggestedTags)
+	}
+	// Adding a tag the entry has is a no-op.
+	do(http.MethodPost, "/journal/doc-2/tags", `{"tags":["running"]}`, http.StatusOK, &entry)
+	do(http.MethodPost, "/journal/doc-2/tags", `{"tags":["running"]}`, http.StatusOK, &entry)
+	if len(entry.Tags) != 1 {
+		t.Errorf("tags after adding twice = %+v, want one", entry.Tags)
+	}
+	do(http.MethodPost, "/journal/doc-2/tags", `{"tags":[""]}`, http.StatusBadRequest, nil)
+
+	var listed []models.JournalEntry
+	do(http.MethodGet, "/journal/me?tag=%23Morning%20Routine", "", http.StatusOK, &listed)
+	if len(listed) != 1 || listed[0].ID != "doc-1" {
+		t.Errorf("entries tagged morning routine = %+v, want doc-1", listed)
+	}
+	do(http.MethodGet, "/journal/me?tag=running", "", http.StatusOK, &listed)
+	if len(listed) != 2 {
+		t.Errorf("entries tagged running = %d, want 2", len(listed))
+	}
+
+	running := entry.Tags[0].ID
+	var untagged models.JournalEntry
+	do(http.MethodDelete, "/journal/doc-2/tags/"+running, "", http.StatusOK, &untagged)
+	if len(untagged.Tags) != 0 {
+		t.Errorf("tags after removal = %+v, want none", untagged.Tags)
+	}
+	do(http.MethodGet, "/journal/me?tag=running", "", http.StatusOK, &listed)
+	if len(listed) != 1 || listed[0].ID != "doc-1" {
+		t.Errorf("entries tagged running after removal = %+v, want doc-1", listed)
+	}
+
+	// Deleting an entry leaves its tags to the user.
+	do(http.MethodDelete, "/journal/doc-1", "", http.StatusNoContent, nil)
+	usages, err := tag.NewService(tag.NewStore(db)).ListTags(ownerID)
+	if err != nil || len(usages) != 2 || usages[0].EntryCount != 0 || usages[1].EntryCount != 0 {
+		t.Errorf("ListTags() = %+v, %v; want both tags kept, unused", usages, err)
+	}
+}
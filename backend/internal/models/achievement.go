@@ -12,4 +12,17 @@ type Achievement struct {
 	// This could be a simple string description, a JSON object stored as string/JSONB,
 	// or map to more complex logic in the gamification service.
 	CriteriaDescription string `json:"criteria_description,omitempty"`
-} 
\ No newline at end of file
+	// Criteria is the statistic the achievement is awarded for (see the Criteria constants),
+	// and Threshold the value of it that unlocks the achievement.
+	Criteria  string `json:"criteria"`
+	Threshold int    `json:"threshold"`
+}
+
+// Statistics achievements can be awarded for.
+const (
+	CriteriaEntries         = "entries"          // Journal entries written
+	CriteriaWords           = "words"            // Words of new text written in journal entries
+	CriteriaStreak          = "streak"           // Longest daily writing streak
+	CriteriaQuestsCompleted = "quests_completed" // Quests completed
+	CriteriaLevel           = "level"            // Level of the user's character
+) 
\ No newline at end of file
@@ -14,13 +14,15 @@ const (
 	Warrior CharacterClass = "Warrior"
 	Mage    CharacterClass = "Mage"
 	Rogue   CharacterClass = "Rogue"
-	// Add more classes as needed
+	// More classes can be added in the classes config; see character.ClassRegistry
 )
 
 // Character represents a user's game character.
+// ID and UserID are text like the keys they are linked to (the user's ID and the character IDs
+// of rewards and XP transactions); PostgreSQL cannot link uuid columns to text ones.
 type Character struct {
-	ID        string `gorm:"type:uuid;primary_key;" json:"id"`
-	UserID    string `gorm:"type:uuid;not null" json:"user_id"`
+	ID        string `gorm:"primary_key;" json:"id"`
+	UserID    string `gorm:"not null" json:"user_id"`
 	User      User   `gorm:"foreignkey:UserID"`
 	Name      string `gorm:"not null" json:"name"`
 	Class     string `gorm:"not null" json:"class"`
@@ -37,6 +39,18 @@ type Character struct {
 	// Points to be spent on level up
 	AttributePoints int `gorm:"not null;default:0" json:"attribute_points"`
 
+	// StartingAttributes sums the attributes the character was created with, so the points spent
+	// on them can be counted again when levels are recomputed (0 before it was recorded).
+	StartingAttributes int `gorm:"not null;default:0" json:"-"`
+
+	// Leveling state: the title of the highest level reached that has one, all XP ever credited,
+	// and the version of the leveling config Level and XP were computed with (0 before it was tracked)
+	// together with the version of the classes config the class rewards came from.
+	Title           string `json:"title"`
+	TotalXP         int    `gorm:"not null;default:0" json:"total_xp"`
+	LevelingVersion int    `gorm:"not null;default:0" json:"-"`
+	ClassesVersion  string `gorm:"not null;default:''" json:"-"`
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Kinds of level-up rewards that are kept after they are granted.
+const (
+	RewardTitle   = "title"
+	RewardItem    = "item"
+	RewardFeature = "feature"
+)
+
+// CharacterReward is a title, item or feature a character unlocked by reaching a level.
+// Rewards are kept when a change of the leveling curve lowers the character's level.
+type CharacterReward struct {
+	ID          string    `json:"id" gorm:"primaryKey"`
+	CharacterID string    `json:"character_id" gorm:"not null;uniqueIndex:idx_character_rewards_unique"`
+	UserID      string    `json:"user_id" gorm:"not null;index"`
+	Level       int       `json:"level" gorm:"not null"`
+	Kind        string    `json:"kind" gorm:"not null;uniqueIndex:idx_character_rewards_unique"`
+	Value       string    `json:"value" gorm:"not null;uniqueIndex:idx_character_rewards_unique"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	// Associations
+	Character Character `gorm:"foreignKey:CharacterID" json:"-"`
+}
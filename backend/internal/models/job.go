@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Statuses of a Job.
+const (
+	JobPending = "pending" // Waiting to run, possibly after a failed attempt
+	JobRunning = "running" // Claimed by a worker
+	JobDone    = "done"
+	JobDead    = "dead" // Failed too often; needs an admin to re-run it
+)
+
+// Job is a unit of background work in the durable job queue.
+// Jobs of the same user run one at a time, in the order they were enqueued.
+type Job struct {
+	ID          uint64     `json:"id" gorm:"primaryKey;autoIncrement"`
+	Kind        string     `json:"kind" gorm:"not null;index"`
+	UserID      string     `json:"user_id" gorm:"not null;index"`
+	Payload     string     `json:"-" gorm:"type:text"` // JSON arguments, interpreted by the handler of Kind; may hold entry text, so never listed
+	Status      string     `json:"status" gorm:"not null;index:idx_jobs_status_run_at"`
+	Attempts    int        `json:"attempts"`
+	MaxAttempts int        `json:"max_attempts"`
+	RunAt       time.Time  `json:"run_at" gorm:"index:idx_jobs_status_run_at"` // The job is not claimed before this time
+	LockedBy    string     `json:"locked_by,omitempty"`
+	LockedAt    *time.Time `json:"locked_at,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
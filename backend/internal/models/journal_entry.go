@@ -1,6 +1,11 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
 
 // JournalEntry represents a single journal entry made by a user.
 // It corresponds to the JournalEntry class in Class.md.
@@ -11,14 +16,31 @@ type JournalEntry struct {
 	Content   string    `json:"content"`
 	Mood      string    `json:"mood,omitempty"` // omitempty if mood is optional
 	FolderID  *string   `gorm:"index" json:"folder_id"` // Nullable for documents in root
+	Version   int       `json:"version" gorm:"not null;default:1"` // Incremented on every update, used for optimistic locking
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	Tags      []Tag     `json:"tags,omitempty" gorm:"many2many:journal_entry_tags;"` // Relationship with Tags
+	// SuggestedTags are tag names the XP agent proposed for the entry's latest text. They are
+	// only attached when the user picks them.
+	SuggestedTags []string `json:"suggested_tags,omitempty" gorm:"type:jsonb;serializer:json"`
+	// SearchText is the plain text of Content, and SearchLanguage the text search configuration
+	// ("english", "portuguese" or "simple") it is indexed with. The journal store sets both on
+	// every save; in PostgreSQL they feed the generated search_vector column.
+	SearchText     string `json:"-"`
+	SearchLanguage string `json:"-" gorm:"type:regconfig"`
 	// TagIDs    []string `json:"tag_ids,omitempty" gorm:"-"` // Placeholder for tag association - REMOVED
 
 	// Associations
 	User User `gorm:"foreignKey:UserID" json:"-"`
 }
 
-// BeforeCreate will set a UUID for the journal entry if it's not set.
-// ... existing code ... 
\ No newline at end of file
+// BeforeCreate will set a UUID for the journal entry if it's not set, and start it at version 1.
+func (entry *JournalEntry) BeforeCreate(tx *gorm.DB) (err error) {
+	if entry.ID == "" {
+		entry.ID = "doc-" + uuid.New().String()
+	}
+	if entry.Version == 0 {
+		entry.Version = 1
+	}
+	return
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// JournalRevision is a snapshot of a journal entry's title and content after a save.
+// Rapid autosaves are coalesced: saves made shortly after a revision was opened update
+// that revision, after which it becomes immutable.
+type JournalRevision struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	EntryID   string    `json:"entry_id" gorm:"not null;uniqueIndex:idx_journal_revisions_entry_number"`
+	UserID    string    `json:"user_id" gorm:"not null;index"`
+	Number    int       `json:"number" gorm:"not null;uniqueIndex:idx_journal_revisions_entry_number"` // 1, 2, 3... per entry
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"` // Last save coalesced into this revision
+
+	// Associations
+	Entry JournalEntry `gorm:"foreignKey:EntryID" json:"-"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// PasswordResetToken is a single-use token emailed to a user who forgot their password.
+// Only a hash of the token is stored.
+type PasswordResetToken struct {
+	ID        string     `json:"id" gorm:"primaryKey"`
+	UserID    string     `json:"user_id" gorm:"index;not null"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	// Associations
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// ScoredParagraph records that a paragraph of a journal entry was already sent to the AI agents.
+// Only a hash of the paragraph's text is kept, so later saves can skip it without storing it twice.
+type ScoredParagraph struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	EntryID   string    `json:"entry_id" gorm:"not null;uniqueIndex:idx_scored_paragraphs_entry_hash"`
+	UserID    string    `json:"user_id" gorm:"not null;index"`
+	Hash      string    `json:"hash" gorm:"not null;uniqueIndex:idx_scored_paragraphs_entry_hash"` // SHA-256 of the paragraph text
+	CreatedAt time.Time `json:"created_at"`
+
+	// Associations
+	Entry JournalEntry `gorm:"foreignKey:EntryID" json:"-"`
+}
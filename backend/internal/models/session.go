@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Session represents a logged-in device.
+// A session is created on login and lives on through refresh token rotation, so every refresh
+// token issued for it belongs to the same family; revoking the session kills the whole family.
+type Session struct {
+	ID         string     `json:"id" gorm:"primaryKey"`
+	UserID     string     `json:"-" gorm:"index;not null"`
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+
+	// Associations
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// RefreshToken is a single-use token that can be exchanged for a new access token.
+// Only a hash of the token is stored; the plain value is handed to the client once.
+type RefreshToken struct {
+	ID        string     `json:"id" gorm:"primaryKey"`
+	SessionID string     `json:"session_id" gorm:"index;not null"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"` // Set once the token has been rotated
+	CreatedAt time.Time  `json:"created_at"`
+
+	// Associations
+	Session Session `gorm:"foreignKey:SessionID" json:"-"`
+}
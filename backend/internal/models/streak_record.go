@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Streak types tracked in UserProgress.
+const (
+	// StreakDailyWriting counts the consecutive days, in the user's time zone, on which the user
+	// wrote new text in their journal.
+	StreakDailyWriting = "daily_writing"
+)
+
+// StreakRecord is a streak that was broken, kept for the user's streak history.
+type StreakRecord struct {
+	ID     string `json:"id" gorm:"primaryKey"`
+	UserID string `json:"-" gorm:"not null;index"`
+	Kind   string `json:"kind" gorm:"not null"`
+	Length int    `json:"length" gorm:"not null"`
+	// StartedAt and LastUpdateAt are the first and the last write of the streak.
+	StartedAt    time.Time `json:"started_at"`
+	LastUpdateAt time.Time `json:"last_update_at"`
+	// BrokenAt is when the break was noticed: by the next write or by the streak job.
+	BrokenAt time.Time `json:"broken_at" gorm:"index"`
+}
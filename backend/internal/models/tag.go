@@ -1,15 +1,25 @@
 package models
 
-// Tag represents a keyword or label that can be associated with journal entries.
-// It corresponds to the Tag class in Class.md.
-type Tag struct {
-	ID   string `json:"id" gorm:"primaryKey"`
-	Name string `json:"name" gorm:"uniqueIndex"` // Assuming tags are global and names unique
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
 
-	// If tags are user-specific, we might add:
-	// UserID    string `json:"user_id,omitempty" gorm:"index"`
-	// And adjust uniqueness to be a composite key of UserID and Name.
+// Tag represents a keyword or label that a user attaches to their journal entries.
+// It corresponds to the Tag class in Class.md. Names are unique per user.
+type Tag struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	UserID    string    `json:"-" gorm:"not null;uniqueIndex:idx_tags_user_name"`
+	Name      string    `json:"name" gorm:"not null;uniqueIndex:idx_tags_user_name"`
+	CreatedAt time.Time `json:"created_at"`
+}
 
-	// For GORM many2many relationship with JournalEntry:
-	// Entries []JournalEntry `gorm:"many2many:journal_entry_tags;"`
-} 
\ No newline at end of file
+// BeforeCreate will set a UUID for the tag.
+func (tag *Tag) BeforeCreate(tx *gorm.DB) (err error) {
+	if tag.ID == "" {
+		tag.ID = "tag-" + uuid.New().String()
+	}
+	return
+}
@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 // User represents a user in the system.
 // It corresponds to the User class defined in Class.md.
@@ -11,4 +15,7 @@ type User struct {
 	HashedPassword string    `json:"-"` // Excluded from JSON responses.
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
+	// DeletedAt is set when the user deletes their account and a grace period applies.
+	// The account and all its data are purged once the grace period is over.
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 } 
\ No newline at end of file
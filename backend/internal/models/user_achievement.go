@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// UserAchievement records that a user unlocked an achievement, and when.
+type UserAchievement struct {
+	UserID        string    `json:"-" gorm:"primaryKey"`
+	AchievementID string    `json:"achievement_id" gorm:"primaryKey"`
+	UnlockedAt    time.Time `json:"unlocked_at" gorm:"not null"`
+
+	// Associations
+	Achievement Achievement `gorm:"foreignKey:AchievementID" json:"-"`
+}
@@ -17,13 +17,25 @@ type UserProgress struct {
 	UnlockedAchievementIDs []string `json:"unlocked_achievement_ids,omitempty" gorm:"type:text[]"`
 
 	// CurrentStreaks tracks active streaks, e.g., {"daily_writing": 5}.
-	// `gorm:"type:jsonb"` is suitable for PostgreSQL JSONB type.
-	// For other databases, this might be stored as a serialized string or in separate tables.
-	CurrentStreaks map[string]int `json:"current_streaks,omitempty" gorm:"type:jsonb"`
+	// `gorm:"type:jsonb"` is suitable for PostgreSQL JSONB type; the JSON serializer
+	// lets other databases store the map as text.
+	CurrentStreaks map[string]int `json:"current_streaks,omitempty" gorm:"type:jsonb;serializer:json"`
 
 	// LastStreakUpdate tracks the timestamp of the last update for each streak type.
 	// This helps in determining if a streak is continuous or broken.
-	LastStreakUpdate map[string]time.Time `json:"last_streak_update,omitempty" gorm:"type:jsonb"`
+	LastStreakUpdate map[string]time.Time `json:"last_streak_update,omitempty" gorm:"type:jsonb;serializer:json"`
+
+	// StreakStartedAt is when each active streak began.
+	StreakStartedAt map[string]time.Time `json:"streak_started_at,omitempty" gorm:"type:jsonb;serializer:json"`
+
+	// LongestStreaks holds the longest length each streak type ever reached.
+	LongestStreaks map[string]int `json:"longest_streaks,omitempty" gorm:"type:jsonb;serializer:json"`
+
+	// StreakFreezes is how many missed days the user can skip without breaking a streak.
+	StreakFreezes int `json:"streak_freezes"`
+
+	// WordsWritten is how many words of new text the user wrote in their journal entries.
+	WordsWritten int `json:"words_written" gorm:"not null;default:0"`
 
 	UpdatedAt time.Time `json:"updated_at"`
 }
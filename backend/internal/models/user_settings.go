@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Locales the app is translated to.
+const (
+	LocaleEnglish             = "en"
+	LocalePortugueseBrazilian = "pt-BR"
+)
+
+// UserSettings are the preferences of a user. Users who never changed them have no row and
+// get DefaultUserSettings.
+type UserSettings struct {
+	UserID string `json:"-" gorm:"primaryKey"`
+	// Timezone is the IANA name of the zone in which the user's days begin and end,
+	// such as America/Sao_Paulo.
+	Timezone string `json:"timezone" gorm:"not null"`
+	Locale   string `json:"locale" gorm:"not null"`
+	// WeekStart is the lowercase name of the first day of the user's weeks, such as monday.
+	WeekStart     string               `json:"week_start" gorm:"not null"`
+	Notifications NotificationSettings `json:"notifications" gorm:"embedded;embeddedPrefix:notify_"`
+	UpdatedAt     time.Time            `json:"updated_at"`
+}
+
+// NotificationSettings tell which notifications a user wants to receive.
+type NotificationSettings struct {
+	StreakReminders bool `json:"streak_reminders"`
+	WeeklySummary   bool `json:"weekly_summary"`
+	Achievements    bool `json:"achievements"`
+}
+
+// DefaultUserSettings returns the settings of a user who never changed them.
+func DefaultUserSettings(userID string) UserSettings {
+	return UserSettings{
+		UserID:    userID,
+		Timezone:  "UTC",
+		Locale:    LocaleEnglish,
+		WeekStart: "monday",
+		Notifications: NotificationSettings{
+			StreakReminders: true,
+			Achievements:    true,
+		},
+	}
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Sources of XP transactions.
+const (
+	// XPSourceAgent is XP awarded by the AI agent for a journal entry.
+	XPSourceAgent = "agent"
+	// XPSourceLocal is XP awarded by the built-in rules for a journal entry.
+	XPSourceLocal = "local"
+	// XPSourceQuest is the reward for completing a quest.
+	XPSourceQuest = "quest"
+)
+
+// Writing categories an XP grant can be reported for. Character classes earn more XP in some of them.
+const (
+	CategoryFitness      = "fitness"
+	CategoryDiscipline   = "discipline"
+	CategoryLearning     = "learning"
+	CategoryReflection   = "reflection"
+	CategoryCreativity   = "creativity"
+	CategoryProductivity = "productivity"
+	CategoryWellbeing    = "wellbeing"
+	CategorySocial       = "social"
+)
+
+// WritingCategories lists every writing category.
+var WritingCategories = []string{
+	CategoryFitness, CategoryDiscipline, CategoryLearning, CategoryReflection,
+	CategoryCreativity, CategoryProductivity, CategoryWellbeing, CategorySocial,
+}
+
+// XPSource describes why XP is granted.
+type XPSource struct {
+	Type   string
+	ID     string // The journal entry or quest the XP was granted for, if any
+	Reason string
+	// Categories are the writing categories the text was classified in.
+	Categories []string
+	// Text is the journal text the XP was awarded for, if any. It is checked by the
+	// content quality rules but not stored.
+	Text string
+}
+
+// XPAdjustment is a rule that changed an XP grant, such as a cap or a class bonus.
+type XPAdjustment struct {
+	Rule    string `json:"rule"`
+	Added   int    `json:"added,omitempty"` // XP added to the grant by this rule, such as a bonus
+	Removed int    `json:"removed"`         // XP taken off the grant by this rule
+	Reason  string `json:"reason"`
+}
+
+// UnmarshalJSON also reads the adjustments of older ledger entries, which recorded bonuses as
+// negative removed XP.
+func (a *XPAdjustment) UnmarshalJSON(data []byte) error {
+	type adjustment XPAdjustment
+	if err := json.Unmarshal(data, (*adjustment)(a)); err != nil {
+		return err
+	}
+	if a.Removed < 0 {
+		a.Added, a.Removed = a.Added-a.Removed, 0
+	}
+	return nil
+}
+
+// XPTransaction is an append-only record of XP granted to a character.
+// It is written in the same transaction as the character it credits.
+type XPTransaction struct {
+	ID          string    `json:"id" gorm:"primaryKey"`
+	CharacterID string    `json:"character_id" gorm:"not null;index"`
+	UserID      string    `json:"user_id" gorm:"not null;index:idx_xp_transactions_user_created"`
+	Amount      int       `json:"amount" gorm:"not null"` // XP credited after the XP rules
+	SourceType  string    `json:"source_type" gorm:"not null"`
+	SourceID    string    `json:"source_id,omitempty"`
+	Reason      string    `json:"reason,omitempty"`
+	CreatedAt   time.Time `json:"created_at" gorm:"index:idx_xp_transactions_user_created"`
+
+	// RequestedAmount is the XP asked for; Adjustments explain any difference to Amount.
+	RequestedAmount int            `json:"requested_amount" gorm:"not null;default:0"`
+	Adjustments     []XPAdjustment `json:"adjustments,omitempty" gorm:"type:text;serializer:json"`
+	Categories      []string       `json:"categories,omitempty" gorm:"type:text;serializer:json"`
+
+	// Associations
+	Character Character `gorm:"foreignKey:CharacterID" json:"-"`
+}
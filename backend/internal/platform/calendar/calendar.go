@@ -0,0 +1,64 @@
+// Package calendar buckets times into the days and weeks of a user, which begin and end in
+// the user's time zone rather than the server's.
+package calendar
+
+import "time"
+
+// Calendar is how the days and weeks of a user are counted.
+type Calendar struct {
+	Location  *time.Location
+	WeekStart time.Weekday
+}
+
+// UTC is the calendar of users who did not choose one: days in UTC and weeks starting on Monday.
+var UTC = Calendar{Location: time.UTC, WeekStart: time.Monday}
+
+// Source provides the calendars of users.
+type Source interface {
+	Calendar(userID string) (Calendar, error)
+}
+
+// For returns the calendar of a user, or UTC when source is nil.
+func For(source Source, userID string) (Calendar, error) {
+	if source == nil {
+		return UTC, nil
+	}
+	return source.Calendar(userID)
+}
+
+// Day returns the calendar day of t as midnight UTC, so days can be compared and subtracted
+// regardless of daylight saving time.
+func (c Calendar) Day(t time.Time) time.Time {
+	y, m, d := t.In(c.Location).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// Start returns when a calendar day, given as midnight UTC like Day returns it, began.
+func (c Calendar) Start(day time.Time) time.Time {
+	y, m, d := day.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, c.Location)
+}
+
+// DaysBetween returns how many calendar days after the day of from the day of to is.
+func (c Calendar) DaysBetween(from, to time.Time) int {
+	return int(c.Day(to).Sub(c.Day(from)).Hours() / 24)
+}
+
+// StartOfDay returns when the day of t began.
+func (c Calendar) StartOfDay(t time.Time) time.Time {
+	y, m, d := t.In(c.Location).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, c.Location)
+}
+
+// StartOfWeek returns when the week of t began.
+func (c Calendar) StartOfWeek(t time.Time) time.Time {
+	day := c.StartOfDay(t)
+	since := (int(day.Weekday()) - int(c.WeekStart) + 7) % 7
+	y, m, d := day.Date()
+	return time.Date(y, m, d-since, 0, 0, 0, 0, c.Location)
+}
+
+// Format returns the day of t as YYYY-MM-DD.
+func (c Calendar) Format(t time.Time) string {
+	return t.In(c.Location).Format("2006-01-02")
+}
@@ -0,0 +1,44 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCalendar(t *testing.T) {
+	brt := time.FixedZone("BRT", -3*60*60)
+	// Sunday, March 8th 2026, 01:30 UTC is still Saturday the 7th in Brazil.
+	at := time.Date(2026, 3, 8, 1, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		cal       Calendar
+		day, week time.Time
+	}{
+		{"utc", UTC, time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)},
+		{"brazil, weeks from monday", Calendar{Location: brt, WeekStart: time.Monday}, time.Date(2026, 3, 7, 0, 0, 0, 0, brt), time.Date(2026, 3, 2, 0, 0, 0, 0, brt)},
+		{"brazil, weeks from sunday", Calendar{Location: brt, WeekStart: time.Sunday}, time.Date(2026, 3, 7, 0, 0, 0, 0, brt), time.Date(2026, 3, 1, 0, 0, 0, 0, brt)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cal.StartOfDay(at); !got.Equal(tt.day) {
+				t.Errorf("StartOfDay() = %s, want %s", got, tt.day)
+			}
+			if got := tt.cal.StartOfWeek(at); !got.Equal(tt.week) {
+				t.Errorf("StartOfWeek() = %s, want %s", got, tt.week)
+			}
+			if got := tt.cal.Format(at); got != tt.day.Format("2006-01-02") {
+				t.Errorf("Format() = %s, want the day of %s", got, tt.day)
+			}
+			if got := tt.cal.Start(tt.cal.Day(at)); !got.Equal(tt.day) {
+				t.Errorf("Start(Day()) = %s, want %s", got, tt.day)
+			}
+		})
+	}
+
+	// A day in São Paulo runs from 03:00 to 03:00 UTC.
+	cal := Calendar{Location: brt}
+	if got := cal.DaysBetween(time.Date(2026, 3, 1, 2, 59, 0, 0, time.UTC), time.Date(2026, 3, 1, 3, 0, 0, 0, time.UTC)); got != 1 {
+		t.Errorf("DaysBetween() across local midnight = %d, want 1", got)
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"log"
 	"os"
 
+	"github.com/google/uuid"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 
 	"github.com/adrianvalentim/gamify_journal/internal/models" // Updated import path
@@ -28,6 +30,8 @@ func Connect() error {
 	var err error
 	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Warn), // Changed to Warn to reduce log verbosity
+		// Map driver-specific errors (e.g., unique violations) to gorm.ErrDuplicatedKey and friends.
+		TranslateError: true,
 	})
 
 	if err != nil {
@@ -38,34 +42,147 @@ func Connect() error {
 	return nil
 }
 
-// MigrateAll performs auto-migration for all defined models in internal/models.
-// This should be called once, usually at application startup.
-func MigrateAll() error {
-	if DB == nil {
-		return fmt.Errorf("database connection is not initialized, call database.Connect() first")
-	}
-	log.Println("Starting database auto-migrations...")
-
-	// Add all your models here to be migrated
-	migrationErr := DB.AutoMigrate(
+// Models lists every model managed by MigrateAll.
+// Add all your models here to be migrated.
+func Models() []interface{} {
+	return []interface{}{
 		&models.User{},
+		&models.UserSettings{},
 		&models.JournalEntry{},
+		&models.JournalRevision{},
+		&models.ScoredParagraph{},
 		&models.Tag{},
 		&models.Achievement{},
+		&models.UserAchievement{},
 		&models.UserProgress{},
+		&models.StreakRecord{},
 		&models.Quest{},     // Added Quest model for user-specific quests
 		&models.Character{}, // Added Character model
+		&models.XPTransaction{},
+		&models.CharacterReward{},
 		&models.Folder{},
-	)
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.PasswordResetToken{},
+		&models.Job{},
+	}
+}
 
-	if migrationErr != nil {
-		return fmt.Errorf("failed to auto-migrate database schemas: %w", migrationErr)
+// MigrateAll performs auto-migration for all defined models in internal/models.
+// This should be called once, usually at application startup.
+func MigrateAll() error {
+	if DB == nil {
+		return fmt.Errorf("database connection is not initialized, call database.Connect() first")
+	}
+	log.Println("Starting database auto-migrations...")
+
+	if err := Migrate(DB); err != nil {
+		return err
 	}
 
 	log.Println("Database auto-migrations completed successfully.")
 	return nil
 }
 
+// Migrate brings the schema of db up to date with every model from Models.
+func Migrate(db *gorm.DB) error {
+	if err := migrateGlobalTags(db); err != nil {
+		return fmt.Errorf("failed to migrate global tags: %w", err)
+	}
+	migrationErr := db.AutoMigrate(Models()...)
+
+	if migrationErr != nil {
+		return fmt.Errorf("failed to auto-migrate database schemas: %w", migrationErr)
+	}
+	if err := addSearchVector(db); err != nil {
+		return fmt.Errorf("failed to migrate journal search: %w", err)
+	}
+	return nil
+}
+
+// migrateGlobalTags moves the tags of the schema in which tag names were global to their users.
+// Every user whose entries used a tag gets a copy of it, and the entries are linked to the copy.
+// Tags no entry used have no owner and are dropped.
+func migrateGlobalTags(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&models.Tag{}) || migrator.HasColumn(&models.Tag{}, "user_id") {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		// The unique index on the name alone would keep two users from using the same name.
+		if tx.Migrator().HasIndex(&models.Tag{}, "idx_tags_name") {
+			if err := tx.Migrator().DropIndex(&models.Tag{}, "idx_tags_name"); err != nil {
+				return err
+			}
+		}
+		if err := tx.Exec("ALTER TABLE tags ADD COLUMN user_id text").Error; err != nil {
+			return err
+		}
+
+		var uses []struct {
+			TagID  string
+			UserID string
+			Name   string
+		}
+		if tx.Migrator().HasTable("journal_entry_tags") {
+			err := tx.Raw(`SELECT DISTINCT tags.id AS tag_id, journal_entries.user_id, tags.name FROM tags
+				JOIN journal_entry_tags ON journal_entry_tags.tag_id = tags.id
+				JOIN journal_entries ON journal_entries.id = journal_entry_tags.journal_entry_id
+				ORDER BY tags.id, journal_entries.user_id`).Scan(&uses).Error
+			if err != nil {
+				return err
+			}
+		}
+		for _, use := range uses {
+			id := "tag-" + uuid.NewString()
+			if err := tx.Exec("INSERT INTO tags (id, user_id, name) VALUES (?, ?, ?)", id, use.UserID, use.Name).Error; err != nil {
+				return err
+			}
+			err := tx.Exec(`UPDATE journal_entry_tags SET tag_id = ? WHERE tag_id = ?
+				AND journal_entry_id IN (SELECT id FROM journal_entries WHERE user_id = ?)`, id, use.TagID, use.UserID).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		result := tx.Exec("DELETE FROM tags WHERE user_id IS NULL")
+		if result.Error != nil {
+			return result.Error
+		}
+		used := map[string]bool{}
+		for _, use := range uses {
+			used[use.TagID] = true
+		}
+		log.Printf("Info: Moved %d global tags to %d user tags; dropped %d tags no entry used.",
+			len(used), len(uses), result.RowsAffected-int64(len(used)))
+		return nil
+	})
+}
+
+// addSearchVector adds the full-text search vector of journal entries and its index. The vector
+// is a generated column, so PostgreSQL rebuilds it from the title and search text of an entry
+// whenever they are saved. Titles weigh more than the text when results are ranked.
+func addSearchVector(db *gorm.DB) error {
+	if db.Dialector.Name() != "postgres" {
+		return nil
+	}
+	if err := db.Exec(`ALTER TABLE journal_entries ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (
+			setweight(to_tsvector(COALESCE(search_language, 'simple'::regconfig), COALESCE(title, '')), 'A') ||
+			setweight(to_tsvector(COALESCE(search_language, 'simple'::regconfig), COALESCE(search_text, '')), 'B')
+		) STORED`).Error; err != nil {
+		return err
+	}
+	return db.Exec("CREATE INDEX IF NOT EXISTS idx_journal_entries_search_vector ON journal_entries USING GIN (search_vector)").Error
+}
+
+// ForUpdate locks the rows db reads until its transaction ends (SELECT ... FOR UPDATE), so
+// read-modify-write updates of concurrent transactions are applied one after the other.
+// SQLite has no row locks and ignores the clause.
+func ForUpdate(db *gorm.DB) *gorm.DB {
+	return db.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate})
+}
+
 // GetDB returns the global GORM DB instance.
 // Ensure Connect() has been called successfully before using this.
 func GetDB() *gorm.DB {
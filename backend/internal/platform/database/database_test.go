@@ -0,0 +1,78 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+)
+
+func TestMigrateGlobalTags(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:TestMigrateGlobalTags?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	// The schema in which tag names were global, with a tag used by two users and one used by none.
+	for _, statement := range []string{
+		"CREATE TABLE journal_entries (id text PRIMARY KEY, user_id text)",
+		"CREATE TABLE tags (id text PRIMARY KEY, name text)",
+		"CREATE UNIQUE INDEX idx_tags_name ON tags (name)",
+		"CREATE TABLE journal_entry_tags (journal_entry_id text, tag_id text, PRIMARY KEY (journal_entry_id, tag_id))",
+		"INSERT INTO journal_entries VALUES ('entry-1', 'alice'), ('entry-2', 'alice'), ('entry-3', 'bob')",
+		"INSERT INTO tags VALUES ('run', 'running'), ('work', 'work'), ('unused', 'unused')",
+		"INSERT INTO journal_entry_tags VALUES ('entry-1', 'run'), ('entry-1', 'work'), ('entry-2', 'run'), ('entry-3', 'run')",
+	} {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := migrateGlobalTags(db); err != nil {
+		t.Fatalf("migrateGlobalTags() error = %v", err)
+	}
+	if err := db.AutoMigrate(&models.Tag{}); err != nil {
+		t.Fatalf("AutoMigrate() after migrateGlobalTags error = %v", err)
+	}
+
+	var links []struct {
+		EntryID string
+		Owner   string
+		TagUser string
+		Name    string
+	}
+	err = db.Raw(`SELECT journal_entries.id AS entry_id, journal_entries.user_id AS owner, tags.user_id AS tag_user, tags.name
+		FROM journal_entry_tags
+		JOIN journal_entries ON journal_entries.id = journal_entry_tags.journal_entry_id
+		JOIN tags ON tags.id = journal_entry_tags.tag_id
+		ORDER BY journal_entries.id, tags.name`).Scan(&links).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"entry-1 running", "entry-1 work", "entry-2 running", "entry-3 running"}
+	if len(links) != len(want) {
+		t.Fatalf("entries have tags %+v, want %v", links, want)
+	}
+	for i, link := range links {
+		if link.EntryID+" "+link.Name != want[i] || link.TagUser != link.Owner {
+			t.Errorf("entry %s has tag %q of user %q, want %q of its owner %q", link.EntryID, link.Name, link.TagUser, want[i], link.Owner)
+		}
+	}
+
+	var tags []models.Tag
+	if err := db.Order("user_id, name").Find(&tags).Error; err != nil {
+		t.Fatal(err)
+	}
+	// Alice's entries share one copy of "running"; the unused tag had no owner to go to.
+	if len(tags) != 3 || tags[0].UserID != "alice" || tags[0].Name != "running" || tags[1].Name != "work" || tags[2].UserID != "bob" {
+		t.Errorf("tags = %+v, want running and work for alice and running for bob", tags)
+	}
+}
@@ -0,0 +1,51 @@
+// Package databasetest provides an embedded SQLite database for store tests,
+// so they run without a PostgreSQL server, and a PostgreSQL database for the tests
+// that depend on its locks and full-text search.
+package databasetest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/adrianvalentim/gamify_journal/internal/platform/database"
+)
+
+// Open returns a fresh in-memory database with every model from database.Models migrated.
+// The database is closed when the test ends.
+func Open(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	// A named shared-cache database lets every pooled connection see the same data.
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared&_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)",
+		strings.NewReplacer("/", "_", " ", "_").Replace(t.Name()))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		t.Fatalf("opening test database: %v", err)
+	}
+	// SQLite ignores SELECT ... FOR UPDATE. With a single connection, transactions run one after
+	// the other instead, so concurrent tests pass here whether or not a store locks what it
+	// reads; OpenPostgres runs them against real row locks.
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("opening test database: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(database.Models()...); err != nil {
+		t.Fatalf("migrating test database: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
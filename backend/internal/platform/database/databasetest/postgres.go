@@ -0,0 +1,79 @@
+package databasetest
+
+import (
+	"database/sql"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/adrianvalentim/gamify_journal/internal/platform/database"
+)
+
+// PostgresDSNEnv names the environment variable with the DSN of the PostgreSQL server used by
+// OpenPostgres, e.g. "host=localhost user=postgres password=postgres dbname=postgres sslmode=disable".
+const PostgresDSNEnv = "TEST_DB_DSN"
+
+// OpenPostgres returns a database migrated like production in a new schema of the PostgreSQL
+// server named by TEST_DB_DSN, and skips the test when the variable is not set. Unlike Open,
+// connections are not limited, so concurrent transactions really run side by side. The schema
+// is dropped when the test ends.
+func OpenPostgres(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv(PostgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set; skipping PostgreSQL test", PostgresDSNEnv)
+	}
+	config, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		t.Fatalf("parsing %s: %v", PostgresDSNEnv, err)
+	}
+
+	admin, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("opening test database: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+	schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("creating test schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+			t.Errorf("dropping test schema: %v", err)
+		}
+	})
+
+	// Every connection of the pool starts in the test schema.
+	config.RuntimeParams["search_path"] = schema
+	sqlDB := stdlib.OpenDB(*config)
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		sqlDB.Close()
+		t.Fatalf("opening test database: %v", err)
+	}
+	// Registered after the schema cleanup, so the pool is closed before the schema is dropped.
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("migrating test database: %v", err)
+	}
+	return db
+}
+
+// Run runs test against a database from Open and, when TEST_DB_DSN is set, one from
+// OpenPostgres. Tests of concurrent updates only prove their locking on PostgreSQL; on SQLite
+// they check that every update lands.
+func Run(t *testing.T, test func(t *testing.T, db *gorm.DB)) {
+	t.Run("sqlite", func(t *testing.T) { test(t, Open(t)) })
+	t.Run("postgres", func(t *testing.T) { test(t, OpenPostgres(t)) })
+}
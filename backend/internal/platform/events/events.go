@@ -0,0 +1,77 @@
+// Package events lets features react to what happens elsewhere in the game (an entry was saved,
+// a quest completed, a character leveled up) without the publishing package depending on them.
+package events
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Types of events published by the services.
+const (
+	// EntrySaved is published when a journal entry is created or its content saved; SubjectID is the entry.
+	EntrySaved = "journal.entry_saved"
+	// QuestCompleted is published when a quest is completed; SubjectID is the quest.
+	QuestCompleted = "quest.completed"
+	// LevelUp is published when a character reaches a new level; SubjectID is the character.
+	LevelUp = "character.level_up"
+	// StreakExtended is published when a streak grows by a day; SubjectID is the streak type.
+	StreakExtended = "streak.extended"
+)
+
+// Event is something that happened to a user.
+type Event struct {
+	Type      string
+	UserID    string
+	SubjectID string
+	// Level is the level reached, for LevelUp events.
+	Level int
+	// Words is the number of words of new text, for EntrySaved events.
+	Words int
+	At    time.Time
+}
+
+// Handler reacts to an event. An error is logged and does not affect the publisher.
+type Handler func(event Event) error
+
+// Bus delivers published events to their subscribers.
+// Handlers run synchronously, in the order they subscribed, once the publisher's work is committed.
+// A nil *Bus is valid and drops every event, so services can be used without one.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+// NewBus creates a bus without subscribers.
+func NewBus() *Bus {
+	return &Bus{handlers: map[string][]Handler{}}
+}
+
+// Subscribe registers handler for the given event types.
+func (b *Bus) Subscribe(handler Handler, types ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, eventType := range types {
+		b.handlers[eventType] = append(b.handlers[eventType], handler)
+	}
+}
+
+// Publish delivers event to the handlers subscribed to its type.
+// The time of the event defaults to now.
+func (b *Bus) Publish(event Event) {
+	if b == nil {
+		return
+	}
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+	b.mu.RLock()
+	handlers := b.handlers[event.Type]
+	b.mu.RUnlock()
+	for _, handler := range handlers {
+		if err := handler(event); err != nil {
+			log.Printf("Failed to handle %s event for user %s: %v", event.Type, event.UserID, err)
+		}
+	}
+}
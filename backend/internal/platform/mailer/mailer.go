@@ -0,0 +1,79 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Message is an outgoing plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers email messages.
+// Implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// NewFromEnv returns the mailer selected by the MAILER environment variable.
+//   - "outbox" writes every message to a file in MAIL_OUTBOX_DIR (default "./outbox").
+//   - anything else (the default) logs messages to stdout.
+//
+// Both are meant for local development; production deployments plug in a real provider.
+func NewFromEnv() Mailer {
+	switch os.Getenv("MAILER") {
+	case "outbox":
+		dir := os.Getenv("MAIL_OUTBOX_DIR")
+		if dir == "" {
+			dir = "outbox"
+		}
+		log.Printf("Info: Emails will be written to the %s directory.", dir)
+		return &OutboxMailer{Dir: dir}
+	default:
+		log.Println("Info: MAILER not set. Emails will be written to the log.")
+		return &LogMailer{}
+	}
+}
+
+// LogMailer writes messages to the standard logger instead of sending them.
+type LogMailer struct{}
+
+// Send logs the message.
+func (m *LogMailer) Send(msg Message) error {
+	log.Printf("Mailer: To: %s | Subject: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// OutboxMailer writes each message as an .eml file in Dir, so it can be opened by a mail client.
+type OutboxMailer struct {
+	Dir string
+}
+
+// Send writes the message to a new file in the outbox directory.
+func (m *OutboxMailer) Send(msg Message) error {
+	if err := os.MkdirAll(m.Dir, 0o700); err != nil {
+		return fmt.Errorf("could not create outbox directory: %w", err)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(msg.Body)
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405"), uuid.NewString())
+	if err := os.WriteFile(filepath.Join(m.Dir, name), []byte(b.String()), 0o600); err != nil {
+		return fmt.Errorf("could not write message to outbox: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,434 @@
+package richtext
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FromPlainText converts plain text into editor HTML.
+// Blank lines separate paragraphs and single newlines become line breaks.
+func FromPlainText(text string) string {
+	text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+
+	var b strings.Builder
+	for _, para := range blankLinePattern.Split(text, -1) {
+		para = strings.Trim(para, "\n")
+		if strings.TrimSpace(para) == "" {
+			continue
+		}
+		lines := strings.Split(para, "\n")
+		for i, line := range lines {
+			lines[i] = html.EscapeString(line)
+		}
+		b.WriteString("<p>" + strings.Join(lines, "<br>") + "</p>")
+	}
+	return b.String()
+}
+
+var (
+	blankLinePattern = regexp.MustCompile(`\n[ \t]*\n`)
+	headingPattern   = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	rulePattern      = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	fencePattern     = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})[ \t]*([^`\\s]*)")
+	listItemPattern  = regexp.MustCompile(`^( *)([-*+]|\d{1,9}[.)])(?:[ \t]+|$)(.*)$`)
+	taskPattern      = regexp.MustCompile(`^\[([ xX])\](?:[ \t]+|$)`)
+)
+
+// FromMarkdown converts Markdown into editor HTML.
+// It supports the constructs ToMarkdown produces: headings, paragraphs, emphasis, links,
+// images, code, block quotes, rules and bullet, ordered and task lists.
+func FromMarkdown(src string) string {
+	src = strings.ReplaceAll(strings.ReplaceAll(src, "\r\n", "\n"), "\r", "\n")
+	src = strings.ReplaceAll(src, "\t", "    ")
+	return renderMarkdownBlocks(strings.Split(src, "\n"))
+}
+
+func renderMarkdownBlocks(lines []string) string {
+	var b strings.Builder
+	var para []string
+
+	flush := func() {
+		if len(para) > 0 {
+			b.WriteString("<p>" + renderParagraph(para) + "</p>")
+			para = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		switch {
+		case strings.TrimSpace(line) == "":
+			flush()
+
+		case fencePattern.MatchString(line):
+			flush()
+			m := fencePattern.FindStringSubmatch(line)
+			fence := m[2]
+			var code []string
+			for i++; i < len(lines); i++ {
+				if strings.HasPrefix(strings.TrimLeft(lines[i], " "), fence) {
+					break
+				}
+				code = append(code, strings.TrimPrefix(lines[i], m[1]))
+			}
+			class := ""
+			if m[3] != "" {
+				class = ` class="language-` + html.EscapeString(m[3]) + `"`
+			}
+			b.WriteString("<pre><code" + class + ">" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>")
+
+		case headingPattern.MatchString(line):
+			flush()
+			m := headingPattern.FindStringSubmatch(line)
+			level := strconv.Itoa(len(m[1]))
+			b.WriteString("<h" + level + ">" + renderInlineMarkdown(m[2]) + "</h" + level + ">")
+
+		case rulePattern.MatchString(line):
+			flush()
+			b.WriteString("<hr>")
+
+		case strings.HasPrefix(strings.TrimLeft(line, " "), ">"):
+			flush()
+			var quoted []string
+			for ; i < len(lines); i++ {
+				trimmed := strings.TrimLeft(lines[i], " ")
+				if !strings.HasPrefix(trimmed, ">") {
+					break
+				}
+				trimmed = strings.TrimPrefix(trimmed, ">")
+				quoted = append(quoted, strings.TrimPrefix(trimmed, " "))
+			}
+			i--
+			b.WriteString("<blockquote>" + renderMarkdownBlocks(quoted) + "</blockquote>")
+
+		case listItemPattern.MatchString(line) && (len(para) == 0 || canInterruptParagraph(line)):
+			flush()
+			end := listEnd(lines, i)
+			b.WriteString(renderMarkdownList(lines[i:end]))
+			i = end - 1
+
+		default:
+			para = append(para, line)
+		}
+	}
+	flush()
+	return b.String()
+}
+
+// canInterruptParagraph reports whether a list item line starts a list right after paragraph text.
+// As in CommonMark, bullets can, but ordered lists only when they start at 1, so that
+// sentences wrapped before a number ("... in\n2024. We") are not turned into lists.
+func canInterruptParagraph(line string) bool {
+	marker := listItemPattern.FindStringSubmatch(line)[2]
+	switch marker {
+	case "-", "*", "+":
+		return true
+	}
+	return strings.TrimRight(marker, ".)") == "1"
+}
+
+// listEnd returns the index of the first line after the list starting at lines[start].
+// The list continues through its items, their indented content and single blank lines
+// followed by more list content.
+func listEnd(lines []string, start int) int {
+	indent := len(listItemPattern.FindStringSubmatch(lines[start])[1])
+	i := start + 1
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" {
+			if i+1 < len(lines) && continuesList(lines[i+1], indent) {
+				continue
+			}
+			return i
+		}
+		if continuesList(line, indent) {
+			continue
+		}
+		// A lazy continuation line belongs to the previous item's paragraph,
+		// unless it starts a different kind of block.
+		if headingPattern.MatchString(line) || rulePattern.MatchString(line) || fencePattern.MatchString(line) ||
+			strings.HasPrefix(strings.TrimLeft(line, " "), ">") || strings.TrimSpace(lines[i-1]) == "" {
+			return i
+		}
+	}
+	return i
+}
+
+func continuesList(line string, indent int) bool {
+	if m := listItemPattern.FindStringSubmatch(line); m != nil && len(m[1]) >= indent {
+		return true
+	}
+	return leadingSpaces(line) > indent
+}
+
+func leadingSpaces(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// renderMarkdownList renders the lines of one list, splitting them into items at the list's indentation.
+func renderMarkdownList(lines []string) string {
+	first := listItemPattern.FindStringSubmatch(lines[0])
+	indent := len(first[1])
+	ordered := first[2] != "-" && first[2] != "*" && first[2] != "+"
+	// Content of an item is indented past its marker and one space.
+	width := indent + len(first[2]) + 1
+
+	type item struct {
+		lines   []string
+		checked *bool
+	}
+	var items []item
+	isTaskList := true
+
+	for _, line := range lines {
+		m := listItemPattern.FindStringSubmatch(line)
+		if m != nil && len(m[1]) == indent {
+			text := m[3]
+			it := item{}
+			if t := taskPattern.FindStringSubmatch(text); t != nil {
+				checked := t[1] != " "
+				it.checked = &checked
+				text = text[len(t[0]):]
+			} else {
+				isTaskList = false
+			}
+			it.lines = []string{text}
+			items = append(items, it)
+			continue
+		}
+		// Continuation lines are de-indented so nested blocks are parsed relative to the item.
+		last := &items[len(items)-1]
+		if leadingSpaces(line) >= width {
+			line = line[width:]
+		} else {
+			line = strings.TrimLeft(line, " ")
+		}
+		last.lines = append(last.lines, line)
+	}
+
+	var b strings.Builder
+	switch {
+	case ordered:
+		start, _ := strconv.Atoi(strings.TrimRight(first[2], ".)"))
+		if start != 1 {
+			b.WriteString(`<ol start="` + strconv.Itoa(start) + `">`)
+		} else {
+			b.WriteString("<ol>")
+		}
+	case isTaskList:
+		b.WriteString(`<ul data-type="taskList">`)
+	default:
+		b.WriteString("<ul>")
+	}
+
+	for _, it := range items {
+		body := renderMarkdownBlocks(it.lines)
+		if isTaskList && !ordered {
+			b.WriteString(`<li data-type="taskItem" data-checked="` + strconv.FormatBool(*it.checked) + `">` + body + "</li>")
+		} else {
+			b.WriteString("<li>" + body + "</li>")
+		}
+	}
+
+	if ordered {
+		b.WriteString("</ol>")
+	} else {
+		b.WriteString("</ul>")
+	}
+	return b.String()
+}
+
+// renderParagraph joins paragraph lines, turning hard breaks (two trailing spaces or a backslash) into <br>.
+func renderParagraph(lines []string) string {
+	var b strings.Builder
+	for i, line := range lines {
+		line = strings.TrimLeft(line, " ")
+		hardBreak := false
+		if i < len(lines)-1 {
+			if strings.HasSuffix(line, "  ") {
+				hardBreak = true
+			} else if strings.HasSuffix(line, `\`) && !strings.HasSuffix(line, `\\`) {
+				hardBreak = true
+				line = strings.TrimSuffix(line, `\`)
+			}
+		}
+		b.WriteString(renderInlineMarkdown(strings.TrimRight(line, " ")))
+		if i < len(lines)-1 {
+			if hardBreak {
+				b.WriteString("<br>")
+			} else {
+				b.WriteString(" ")
+			}
+		}
+	}
+	return b.String()
+}
+
+// renderInlineMarkdown converts inline Markdown syntax to HTML, escaping everything else.
+func renderInlineMarkdown(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && strings.IndexByte("\\`*_{}[]()#+-.!<>~|", text[i+1]) >= 0:
+			b.WriteString(html.EscapeString(text[i+1 : i+2]))
+			i += 2
+			continue
+
+		case c == '`':
+			run := len(text[i:]) - len(strings.TrimLeft(text[i:], "`"))
+			fence := text[i : i+run]
+			if end := strings.Index(text[i+run:], fence); end >= 0 {
+				code := text[i+run : i+run+end]
+				if len(code) > 1 && code[0] == ' ' && code[len(code)-1] == ' ' {
+					code = code[1 : len(code)-1]
+				}
+				b.WriteString("<code>" + html.EscapeString(code) + "</code>")
+				i += run + end + run
+				continue
+			}
+			b.WriteString(fence)
+			i += run
+			continue
+
+		case c == '!' && strings.HasPrefix(text[i:], "!["):
+			if label, url, n := parseLink(text[i+1:]); n > 0 {
+				b.WriteString(`<img src="` + html.EscapeString(url) + `" alt="` + html.EscapeString(unescapeMarkdown(label)) + `">`)
+				i += 1 + n
+				continue
+			}
+
+		case c == '[':
+			if label, url, n := parseLink(text[i:]); n > 0 {
+				b.WriteString(`<a href="` + html.EscapeString(url) + `">` + renderInlineMarkdown(label) + "</a>")
+				i += n
+				continue
+			}
+
+		case c == '*' || c == '_' || c == '~':
+			if out, n := renderEmphasis(text, i); n > 0 {
+				b.WriteString(out)
+				i += n
+				continue
+			}
+		}
+
+		b.WriteString(html.EscapeString(text[i : i+1]))
+		i++
+	}
+	return b.String()
+}
+
+// renderEmphasis handles **strong**, *em*, _em_, __strong__ and ~~strike~~ starting at text[i].
+// It returns the HTML and the number of bytes consumed, or 0 if there is no matching closer.
+func renderEmphasis(text string, i int) (string, int) {
+	c := text[i]
+	delim := string(c)
+	if strings.HasPrefix(text[i:], delim+delim) {
+		delim += delim
+	}
+	if c == '~' && len(delim) != 2 {
+		return "", 0
+	}
+
+	rest := text[i+len(delim):]
+	if rest == "" || rest[0] == ' ' {
+		return "", 0
+	}
+	// Intra-word underscores (snake_case) are not emphasis.
+	if c == '_' && i > 0 && isWordByte(text[i-1]) {
+		return "", 0
+	}
+
+	end := -1
+	for j := 0; j+len(delim) <= len(rest); j++ {
+		if rest[j] == '\\' {
+			j++
+			continue
+		}
+		if strings.HasPrefix(rest[j:], delim) && j > 0 && rest[j-1] != ' ' {
+			after := j + len(delim)
+			// For single delimiters, skip over a double one that belongs to nested strong emphasis.
+			if len(delim) == 1 && after < len(rest) && rest[after] == c {
+				j++
+				continue
+			}
+			if c == '_' && after < len(rest) && isWordByte(rest[after]) {
+				continue
+			}
+			end = j
+			break
+		}
+	}
+	if end < 0 {
+		return "", 0
+	}
+
+	tag := "em"
+	switch {
+	case c == '~':
+		tag = "s"
+	case len(delim) == 2:
+		tag = "strong"
+	}
+	inner := renderInlineMarkdown(rest[:end])
+	return "<" + tag + ">" + inner + "</" + tag + ">", len(delim)*2 + end
+}
+
+// parseLink parses "[label](url)" at the start of text and returns its parts
+// and the number of bytes consumed, or 0 if text does not start with a link.
+func parseLink(text string) (label, url string, n int) {
+	depth := 0
+	closeLabel := -1
+	for j := 0; j < len(text); j++ {
+		switch text[j] {
+		case '\\':
+			j++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				closeLabel = j
+			}
+		}
+		if closeLabel >= 0 {
+			break
+		}
+	}
+	if closeLabel < 0 || closeLabel+1 >= len(text) || text[closeLabel+1] != '(' {
+		return "", "", 0
+	}
+	closeURL := strings.IndexByte(text[closeLabel+2:], ')')
+	if closeURL < 0 {
+		return "", "", 0
+	}
+	target := strings.TrimSpace(text[closeLabel+2 : closeLabel+2+closeURL])
+	// Drop an optional title: [label](url "title").
+	if sp := strings.IndexAny(target, " \t"); sp >= 0 {
+		target = target[:sp]
+	}
+	target = strings.TrimSuffix(strings.TrimPrefix(target, "<"), ">")
+	if strings.HasPrefix(strings.ToLower(strings.TrimSpace(target)), "javascript:") {
+		target = ""
+	}
+	return text[1:closeLabel], target, closeLabel + 2 + closeURL + 1
+}
+
+func unescapeMarkdown(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' && i+1 < len(text) {
+			i++
+		}
+		b.WriteByte(text[i])
+	}
+	return b.String()
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
@@ -0,0 +1,65 @@
+package richtext
+
+import "testing"
+
+func TestFromMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		md   string
+		want string
+	}{
+		{"empty", "", ""},
+		{"paragraphs", "First line\nsame paragraph\n\nSecond", "<p>First line same paragraph</p><p>Second</p>"},
+		{"html is escaped", "a <b> & c", "<p>a &lt;b&gt; &amp; c</p>"},
+		{"headings", "# Title\n### Sub ###", "<h1>Title</h1><h3>Sub</h3>"},
+		{"emphasis", "**bold**, *it*, _it_, ~~gone~~ and `x*y`",
+			"<p><strong>bold</strong>, <em>it</em>, <em>it</em>, <s>gone</s> and <code>x*y</code></p>"},
+		{"nested emphasis", "**bold _and it_**", "<p><strong>bold <em>and it</em></strong></p>"},
+		{"snake_case is literal", "a snake_case_name", "<p>a snake_case_name</p>"},
+		{"escapes", `2\*3 \_x\_`, "<p>2*3 _x_</p>"},
+		{"link and image", "[site](https://example.com) ![map](/a.png)",
+			`<p><a href="https://example.com">site</a> <img src="/a.png" alt="map"></p>`},
+		{"script links are dropped", "[x](javascript:alert(1))", `<p><a href="">x</a>)</p>`},
+		{"hard break", "one  \ntwo\\\nthree", "<p>one<br>two<br>three</p>"},
+		{"bullet list", "- a\n- b", "<ul><li><p>a</p></li><li><p>b</p></li></ul>"},
+		{"ordered list", "3. a\n4. b", `<ol start="3"><li><p>a</p></li><li><p>b</p></li></ol>`},
+		{"nested list", "- a\n  - b\n- c", "<ul><li><p>a</p><ul><li><p>b</p></li></ul></li><li><p>c</p></li></ul>"},
+		{"task list", "- [x] done\n- [ ] todo",
+			`<ul data-type="taskList"><li data-type="taskItem" data-checked="true"><p>done</p></li><li data-type="taskItem" data-checked="false"><p>todo</p></li></ul>`},
+		{"list then paragraph", "- a\n\nafter", "<ul><li><p>a</p></li></ul><p>after</p>"},
+		{"blockquote", "> a\n>\n> b", "<blockquote><p>a</p><p>b</p></blockquote>"},
+		{"code block", "```go\nif a < b {\n}\n```", `<pre><code class="language-go">if a &lt; b {
+}</code></pre>`},
+		{"rule", "a\n\n---\n\nb", "<p>a</p><hr><p>b</p>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FromMarkdown(tt.md); got != tt.want {
+				t.Errorf("FromMarkdown(%q)\n got: %q\nwant: %q", tt.md, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromMarkdown_RoundTrip(t *testing.T) {
+	docs := []string{
+		"<h2>Quest log</h2><p><strong>Slay</strong> the <em>dragon</em> at <a href=\"https://example.com\">the keep</a></p>",
+		"<ul><li><p>sword</p></li><li><p>shield</p><ul><li><p>wooden</p></li></ul></li></ul>",
+		`<ul data-type="taskList"><li data-type="taskItem" data-checked="true"><p>done</p></li></ul>`,
+		"<blockquote><p>wise words</p></blockquote><hr><p>2*3_4</p>",
+	}
+	for _, doc := range docs {
+		if got := FromMarkdown(ToMarkdown(doc)); got != doc {
+			t.Errorf("round trip of %q\n got: %q", doc, got)
+		}
+	}
+}
+
+func TestFromPlainText(t *testing.T) {
+	got := FromPlainText("Dear diary,\r\nI <3 Go.\n\n\nBye")
+	want := "<p>Dear diary,<br>I &lt;3 Go.</p><p>Bye</p>"
+	if got != want {
+		t.Errorf("FromPlainText() = %q, want %q", got, want)
+	}
+}
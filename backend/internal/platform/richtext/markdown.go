@@ -0,0 +1,231 @@
+package richtext
+
+import (
+	"strconv"
+	"strings"
+)
+
+// blockElements start a new Markdown block; everything else is rendered inline.
+var blockElements = map[string]bool{
+	"p": true, "div": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "li": true, "blockquote": true, "pre": true, "hr": true, "table": true,
+}
+
+// ToMarkdown converts editor HTML into CommonMark-flavoured Markdown.
+func ToMarkdown(content string) string {
+	return strings.TrimSpace(renderBlocks(parse(content).children, "\n\n"))
+}
+
+// renderBlocks renders a sequence of nodes as Markdown blocks joined by sep.
+// Runs of inline nodes between blocks form their own paragraph.
+func renderBlocks(nodes []*node, sep string) string {
+	var blocks []string
+	var inline []*node
+
+	flush := func() {
+		if text := strings.TrimSpace(renderInline(inline)); text != "" {
+			blocks = append(blocks, text)
+		}
+		inline = nil
+	}
+
+	for _, n := range nodes {
+		if n.tag == "" || !blockElements[n.tag] {
+			inline = append(inline, n)
+			continue
+		}
+		flush()
+		if block := renderBlock(n); block != "" {
+			blocks = append(blocks, block)
+		}
+	}
+	flush()
+	return strings.Join(blocks, sep)
+}
+
+func renderBlock(n *node) string {
+	switch n.tag {
+	case "p":
+		return strings.TrimSpace(renderInline(n.children))
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		text := strings.TrimSpace(renderInline(n.children))
+		if text == "" {
+			return ""
+		}
+		level, _ := strconv.Atoi(n.tag[1:])
+		return strings.Repeat("#", level) + " " + strings.ReplaceAll(text, "  \n", " ")
+	case "blockquote":
+		return prefixLines(renderBlocks(n.children, "\n\n"), "> ", "> ")
+	case "ul", "ol":
+		return renderList(n)
+	case "li":
+		return renderBlocks(n.children, "\n")
+	case "pre":
+		lang := ""
+		for _, c := range n.children {
+			if c.tag == "code" {
+				lang = strings.TrimPrefix(c.attr("class"), "language-")
+			}
+		}
+		code := strings.TrimRight(textContent(n), "\n")
+		fence := "```"
+		for strings.Contains(code, fence) {
+			fence += "`"
+		}
+		return fence + lang + "\n" + code + "\n" + fence
+	case "hr":
+		return "---"
+	default:
+		return renderBlocks(n.children, "\n\n")
+	}
+}
+
+func renderList(list *node) string {
+	start := 1
+	if s, err := strconv.Atoi(list.attr("start")); err == nil {
+		start = s
+	}
+	isTaskList := list.attr("data-type") == "taskList"
+
+	var items []string
+	for _, item := range list.children {
+		if item.tag != "li" {
+			continue
+		}
+		marker := "- "
+		if list.tag == "ol" {
+			marker = strconv.Itoa(start+len(items)) + ". "
+		}
+		if isTaskList {
+			if item.attr("data-checked") == "true" {
+				marker += "[x] "
+			} else {
+				marker += "[ ] "
+			}
+		}
+		body := renderBlocks(item.children, "\n")
+		items = append(items, prefixLines(body, marker, strings.Repeat(" ", len(marker))))
+	}
+	return strings.Join(items, "\n")
+}
+
+// prefixLines puts first before the first line of text and rest before every other non-empty line.
+func prefixLines(text, first, rest string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		switch {
+		case i == 0:
+			lines[i] = first + line
+		case line == "":
+			lines[i] = strings.TrimRight(rest, " ")
+		default:
+			lines[i] = rest + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func renderInline(nodes []*node) string {
+	var b strings.Builder
+	for _, n := range nodes {
+		if n.tag == "" {
+			b.WriteString(escapeMarkdown(collapseSpace(n.text)))
+			continue
+		}
+
+		switch n.tag {
+		case "br":
+			b.WriteString("  \n")
+		case "img":
+			b.WriteString("![" + escapeMarkdown(n.attr("alt")) + "](" + n.attr("src") + ")")
+		case "code":
+			code := textContent(n)
+			fence := "`"
+			for strings.Contains(code, fence) {
+				fence += "`"
+			}
+			b.WriteString(fence + code + fence)
+		case "strong", "b":
+			b.WriteString(wrapInline(renderInline(n.children), "**"))
+		case "em", "i":
+			b.WriteString(wrapInline(renderInline(n.children), "_"))
+		case "s", "del", "strike":
+			b.WriteString(wrapInline(renderInline(n.children), "~~"))
+		case "a":
+			text := renderInline(n.children)
+			if href := n.attr("href"); href != "" {
+				b.WriteString("[" + text + "](" + href + ")")
+			} else {
+				b.WriteString(text)
+			}
+		default:
+			if blockElements[n.tag] {
+				// Block content nested in inline markup, e.g. a paragraph inside a span.
+				b.WriteString(renderBlock(n))
+			} else {
+				b.WriteString(renderInline(n.children))
+			}
+		}
+	}
+	return b.String()
+}
+
+// wrapInline surrounds text with a Markdown delimiter, keeping surrounding spaces outside of it
+// because "** bold**" is not valid emphasis.
+func wrapInline(text, delim string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	lead := text[:strings.Index(text, trimmed)]
+	trail := text[len(lead)+len(trimmed):]
+	return lead + delim + trimmed + delim + trail
+}
+
+// textContent returns the raw text of a node and its descendants, with <br> as newlines.
+func textContent(n *node) string {
+	var b strings.Builder
+	var walk func(*node)
+	walk = func(n *node) {
+		if n.tag == "" {
+			b.WriteString(n.text)
+			return
+		}
+		if n.tag == "br" {
+			b.WriteString("\n")
+			return
+		}
+		for _, c := range n.children {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+// collapseSpace replaces runs of whitespace with a single space, as HTML rendering does.
+func collapseSpace(text string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range text {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f' {
+			if !space {
+				b.WriteByte(' ')
+			}
+			space = true
+			continue
+		}
+		space = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`, "<", `\<`,
+)
+
+// escapeMarkdown escapes characters that would otherwise be read as inline Markdown syntax.
+func escapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
@@ -0,0 +1,45 @@
+package richtext
+
+import "testing"
+
+func TestToMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"empty", "", ""},
+		{"plain text", "Just words", "Just words"},
+		{"paragraphs", "<p>First</p><p>Second</p>", "First\n\nSecond"},
+		{"entities", "<p>Fish &amp; chips &lt;3</p>", "Fish & chips \\<3"},
+		{"markdown characters are escaped", "<p>2*3 = 6_a</p>", `2\*3 = 6\_a`},
+		{"whitespace collapses", "<p>one\n   two</p>", "one two"},
+		{"headings", "<h1>Title</h1><h3>Sub</h3>", "# Title\n\n### Sub"},
+		{"inline marks", "<p><strong>bold</strong>, <em>it</em>, <s>gone</s> and <code>x*y</code></p>",
+			"**bold**, _it_, ~~gone~~ and `x*y`"},
+		{"spaces stay outside marks", "<p>a<strong> b </strong>c</p>", "a **b** c"},
+		{"link", `<p><a href="https://example.com" target="_blank">site</a></p>`, "[site](https://example.com)"},
+		{"image", `<img src="/a.png" alt="map">`, "![map](/a.png)"},
+		{"line break", "<p>one<br>two</p>", "one  \ntwo"},
+		{"bullet list", "<ul><li><p>a</p></li><li><p>b</p></li></ul>", "- a\n- b"},
+		{"ordered list with start", `<ol start="3"><li><p>a</p></li><li><p>b</p></li></ol>`, "3. a\n4. b"},
+		{"nested list", "<ul><li><p>a</p><ul><li><p>b</p></li></ul></li></ul>", "- a\n  - b"},
+		{"task list", `<ul data-type="taskList"><li data-checked="true"><p>done</p></li><li data-checked="false"><p>todo</p></li></ul>`,
+			"- [x] done\n- [ ] todo"},
+		{"blockquote", "<blockquote><p>a</p><p>b</p></blockquote>", "> a\n>\n> b"},
+		{"code block", `<pre><code class="language-go">if a &lt; b {
+}</code></pre>`, "```go\nif a < b {\n}\n```"},
+		{"horizontal rule", "<p>a</p><hr><p>b</p>", "a\n\n---\n\nb"},
+		{"unknown tags are transparent", `<p><span style="color: red">red</span> <u>under</u></p>`, "red under"},
+		{"unclosed tags", "<p><strong>bold", "**bold**"},
+		{"stray angle bracket", "<p>a < b</p>", `a \< b`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToMarkdown(tt.html); got != tt.want {
+				t.Errorf("ToMarkdown(%q)\n got: %q\nwant: %q", tt.html, got, tt.want)
+			}
+		})
+	}
+}
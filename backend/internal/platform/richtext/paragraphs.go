@@ -0,0 +1,66 @@
+package richtext
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// ParagraphHash identifies the text of a paragraph returned by Paragraphs (hex-encoded SHA-256),
+// so paragraphs can be compared without storing their text.
+func ParagraphHash(paragraph string) string {
+	sum := sha256.Sum256([]byte(paragraph))
+	return hex.EncodeToString(sum[:])
+}
+
+// Paragraphs returns the plain text of every paragraph-level block of editor HTML, in document order.
+// Headings, list items, table cells and code blocks count as paragraphs; text directly inside a
+// container is grouped into the paragraph it visually forms. Whitespace is collapsed and empty
+// paragraphs are dropped, so formatting-only edits do not change the result.
+func Paragraphs(content string) []string {
+	var paragraphs []string
+	var inline strings.Builder
+
+	flush := func() {
+		if text := strings.TrimSpace(collapseSpace(inline.String())); text != "" {
+			paragraphs = append(paragraphs, text)
+		}
+		inline.Reset()
+	}
+
+	var walk func(nodes []*node)
+	walk = func(nodes []*node) {
+		for _, n := range nodes {
+			switch {
+			case n.tag == "script" || n.tag == "style":
+			case n.tag == "pre":
+				flush()
+				if text := strings.TrimSpace(textContent(n)); text != "" {
+					paragraphs = append(paragraphs, text)
+				}
+			case n.tag == "td" || n.tag == "th" || blockElements[n.tag]:
+				flush()
+				walk(n.children)
+				flush()
+			case n.tag == "":
+				inline.WriteString(n.text)
+			case n.tag == "br":
+				inline.WriteString("\n")
+			default:
+				walk(n.children)
+			}
+		}
+	}
+	walk(parse(content).children)
+	flush()
+	return paragraphs
+}
+
+// WordCount returns the number of words in the paragraphs of editor HTML.
+func WordCount(content string) int {
+	n := 0
+	for _, paragraph := range Paragraphs(content) {
+		n += len(strings.Fields(paragraph))
+	}
+	return n
+}
@@ -0,0 +1,51 @@
+package richtext
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParagraphs(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{name: "empty", content: "", want: nil},
+		{name: "plain text", content: "just text", want: []string{"just text"}},
+		{
+			name:    "blocks",
+			content: "<h1>Day one</h1><p>Went <strong>running</strong>\n  today.</p><p></p><p>   </p><hr><p>Tired<br>but happy</p>",
+			want:    []string{"Day one", "Went running today.", "Tired but happy"},
+		},
+		{
+			name:    "nested lists",
+			content: "<ul><li><p>milk</p><ul><li>oat</li></ul></li><li>eggs</li></ul>",
+			want:    []string{"milk", "oat", "eggs"},
+		},
+		{
+			name:    "inline text around blocks",
+			content: "<div>intro<p>body</p>outro</div>",
+			want:    []string{"intro", "body", "outro"},
+		},
+		{
+			name:    "code keeps its lines",
+			content: "<pre><code>a := 1\nb := 2</code></pre><script>alert(1)</script>",
+			want:    []string{"a := 1\nb := 2"},
+		},
+		{name: "entities", content: "<p>Fish &amp; chips</p>", want: []string{"Fish & chips"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Paragraphs(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Paragraphs(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWordCount(t *testing.T) {
+	if got := WordCount("<h1>Day one</h1><p>Went <strong>run</strong>ning today.</p><script>var x = 1</script>"); got != 5 {
+		t.Errorf("WordCount() = %d, want 5", got)
+	}
+}
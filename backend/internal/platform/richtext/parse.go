@@ -0,0 +1,198 @@
+// Package richtext converts the HTML produced by the TipTap editor into other formats.
+//
+// TipTap emits a small, well-formed subset of HTML, so a lenient hand-written parser
+// is enough; unknown tags are kept transparent and only their content is used.
+package richtext
+
+import (
+	"html"
+	"strings"
+)
+
+// node is an element or text node of a parsed document.
+type node struct {
+	tag      string // lower-case tag name, empty for text nodes
+	attrs    map[string]string
+	text     string // unescaped text, only for text nodes
+	children []*node
+	parent   *node
+}
+
+func (n *node) attr(name string) string {
+	return n.attrs[name]
+}
+
+// voidElements never have content or a closing tag.
+var voidElements = map[string]bool{
+	"br": true, "hr": true, "img": true, "input": true, "meta": true, "link": true, "wbr": true,
+}
+
+// parse builds a node tree from an HTML fragment.
+// Unmatched closing tags are ignored and unclosed elements end with the document.
+func parse(src string) *node {
+	root := &node{tag: "#root"}
+	current := root
+
+	for len(src) > 0 {
+		lt := strings.IndexByte(src, '<')
+		if lt < 0 {
+			appendText(current, src)
+			break
+		}
+		if lt > 0 {
+			appendText(current, src[:lt])
+			src = src[lt:]
+		}
+
+		// Comments and doctype declarations carry no content.
+		if strings.HasPrefix(src, "<!--") {
+			end := strings.Index(src, "-->")
+			if end < 0 {
+				break
+			}
+			src = src[end+3:]
+			continue
+		}
+		if strings.HasPrefix(src, "<!") || strings.HasPrefix(src, "<?") {
+			end := strings.IndexByte(src, '>')
+			if end < 0 {
+				break
+			}
+			src = src[end+1:]
+			continue
+		}
+
+		end := tagEnd(src)
+		if end < 0 {
+			// A lone '<' is literal text.
+			appendText(current, src[:1])
+			src = src[1:]
+			continue
+		}
+		raw := src[1:end]
+		src = src[end+1:]
+
+		if strings.HasPrefix(raw, "/") {
+			name := strings.ToLower(strings.TrimSpace(raw[1:]))
+			for n := current; n != root; n = n.parent {
+				if n.tag == name {
+					current = n.parent
+					break
+				}
+			}
+			continue
+		}
+
+		selfClosing := strings.HasSuffix(raw, "/")
+		raw = strings.TrimSuffix(raw, "/")
+		name, attrs := parseTag(raw)
+		if name == "" {
+			appendText(current, "<"+raw+">")
+			continue
+		}
+
+		el := &node{tag: name, attrs: attrs, parent: current}
+		current.children = append(current.children, el)
+		if !selfClosing && !voidElements[name] {
+			current = el
+		}
+	}
+	return root
+}
+
+// tagEnd returns the index of the '>' closing the tag at the start of src,
+// skipping over quoted attribute values, or -1 if there is none.
+func tagEnd(src string) int {
+	var quote byte
+	for i := 1; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return i
+		case c == '<':
+			return -1
+		}
+	}
+	return -1
+}
+
+// parseTag splits the inside of a start tag into its name and attributes.
+func parseTag(raw string) (string, map[string]string) {
+	raw = strings.TrimSpace(raw)
+	i := strings.IndexAny(raw, " \t\n\r")
+	if i < 0 {
+		return validName(raw), nil
+	}
+	name := validName(raw[:i])
+	if name == "" {
+		return "", nil
+	}
+
+	attrs := map[string]string{}
+	rest := raw[i:]
+	for {
+		rest = strings.TrimLeft(rest, " \t\n\r")
+		if rest == "" {
+			break
+		}
+		j := strings.IndexAny(rest, "= \t\n\r")
+		if j < 0 {
+			attrs[strings.ToLower(rest)] = ""
+			break
+		}
+		key := strings.ToLower(rest[:j])
+		rest = strings.TrimLeft(rest[j:], " \t\n\r")
+		if !strings.HasPrefix(rest, "=") {
+			attrs[key] = ""
+			continue
+		}
+		rest = strings.TrimLeft(rest[1:], " \t\n\r")
+
+		var value string
+		if rest != "" && (rest[0] == '"' || rest[0] == '\'') {
+			q := rest[0]
+			k := strings.IndexByte(rest[1:], q)
+			if k < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:k+1], rest[k+2:]
+			}
+		} else {
+			k := strings.IndexAny(rest, " \t\n\r")
+			if k < 0 {
+				value, rest = rest, ""
+			} else {
+				value, rest = rest[:k], rest[k:]
+			}
+		}
+		attrs[key] = html.UnescapeString(value)
+	}
+	return name, attrs
+}
+
+// validName lower-cases a tag name, returning "" if it is not a plausible element name.
+func validName(name string) string {
+	if name == "" {
+		return ""
+	}
+	for i, r := range name {
+		isLetter := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		if i == 0 && !isLetter {
+			return ""
+		}
+		if !isLetter && !(r >= '0' && r <= '9') && r != '-' {
+			return ""
+		}
+	}
+	return strings.ToLower(name)
+}
+
+func appendText(parent *node, raw string) {
+	parent.children = append(parent.children, &node{text: html.UnescapeString(raw), parent: parent})
+}
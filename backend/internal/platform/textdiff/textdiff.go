@@ -0,0 +1,93 @@
+// Package textdiff computes line- or paragraph-level differences between two texts.
+package textdiff
+
+// Kinds of diff operations.
+const (
+	Equal  = "equal"
+	Insert = "insert"
+	Delete = "delete"
+)
+
+// maxCells bounds the size of the LCS table. Larger inputs fall back to
+// replacing everything between the common prefix and suffix.
+const maxCells = 4_000_000
+
+// Op is one line of a diff.
+type Op struct {
+	Kind string `json:"op"`
+	Text string `json:"text"`
+}
+
+// Diff returns the operations turning a into b, based on a longest common subsequence.
+func Diff(a, b []string) []Op {
+	// Common prefix and suffix are cheap to strip and keep the table small for typical edits.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]Op, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		ops = append(ops, Op{Kind: Equal, Text: line})
+	}
+	ops = append(ops, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, Op{Kind: Equal, Text: line})
+	}
+	return ops
+}
+
+func diffMiddle(a, b []string) []Op {
+	var ops []Op
+	if len(a)*len(b) > maxCells {
+		for _, line := range a {
+			ops = append(ops, Op{Kind: Delete, Text: line})
+		}
+		for _, line := range b {
+			ops = append(ops, Op{Kind: Insert, Text: line})
+		}
+		return ops
+	}
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, Op{Kind: Equal, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, Op{Kind: Delete, Text: a[i]})
+			i++
+		default:
+			ops = append(ops, Op{Kind: Insert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, Op{Kind: Delete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, Op{Kind: Insert, Text: b[j]})
+	}
+	return ops
+}
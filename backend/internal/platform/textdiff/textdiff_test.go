@@ -0,0 +1,30 @@
+package textdiff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []string
+		want []Op
+	}{
+		{"identical", []string{"a", "b"}, []string{"a", "b"}, []Op{{Equal, "a"}, {Equal, "b"}}},
+		{"append", []string{"a"}, []string{"a", "b"}, []Op{{Equal, "a"}, {Insert, "b"}}},
+		{"remove", []string{"a", "b", "c"}, []string{"a", "c"}, []Op{{Equal, "a"}, {Delete, "b"}, {Equal, "c"}}},
+		{"replace", []string{"a", "b", "c"}, []string{"a", "x", "c"}, []Op{{Equal, "a"}, {Delete, "b"}, {Insert, "x"}, {Equal, "c"}}},
+		{"move", []string{"a", "b", "c"}, []string{"b", "c", "a"}, []Op{{Delete, "a"}, {Equal, "b"}, {Equal, "c"}, {Insert, "a"}}},
+		{"from empty", nil, []string{"a"}, []Op{{Insert, "a"}}},
+		{"to empty", []string{"a"}, nil, []Op{{Delete, "a"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
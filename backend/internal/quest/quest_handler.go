@@ -16,7 +16,7 @@ type IQuestService interface {
 	CreateQuest(input CreateQuestInput) (*models.Quest, error)
 	GetUserQuests(userID string) ([]models.Quest, error)
 	UpdateQuest(id string, input UpdateQuestInput) (*models.Quest, error)
-	CompleteQuest(id string) (*models.Quest, error)
+	CompleteQuest(id, userID string) (*models.Quest, error)
 }
 
 // Handler handles HTTP requests for quests.
@@ -38,21 +38,30 @@ func (h *Handler) RegisterRoutes(r chi.Router) {
 			r.Get("/me", h.handleGetMyQuests)
 		})
 
-		// AI Service routes (unauthenticated)
-		r.Post("/", h.handleCreateQuest)
-		r.Route("/{questID}", func(r chi.Router) {
-			r.Put("/", h.handleUpdateQuest)
-			r.Post("/complete", h.handleCompleteQuest)
+		// AI Service routes, authorized by a grant bound to the user whose entry is being processed
+		r.Group(func(r chi.Router) {
+			r.Use(auth.ServiceMiddleware)
+
+			r.With(auth.RequireScope(auth.ScopeQuestsWrite)).Post("/", h.handleCreateQuest)
+			r.Route("/{questID}", func(r chi.Router) {
+				r.Use(auth.RequireScope(auth.ScopeQuestsWrite))
+				r.Put("/", h.handleUpdateQuest)
+				r.Post("/complete", h.handleCompleteQuest)
+			})
+			r.With(auth.RequireScope(auth.ScopeQuestsRead)).Get("/user/{userID}", h.handleGetUserQuests)
 		})
-
-		// Unauthenticated route for fetching quests by user ID
-		r.Get("/user/{userID}", h.handleGetUserQuests)
 	})
 }
 
 // handleCreateQuest handles the creation of a new quest.
 // This endpoint is expected to be called by the AI service.
 func (h *Handler) handleCreateQuest(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
 	var input CreateQuestInput
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
@@ -60,6 +69,9 @@ func (h *Handler) handleCreateQuest(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
+	// The quest always belongs to the user the grant was issued for.
+	input.UserID = userID
+
 	quest, err := h.service.CreateQuest(input)
 	if err != nil {
 		http.Error(w, "Failed to create quest", http.StatusInternalServerError)
@@ -72,6 +84,7 @@ func (h *Handler) handleCreateQuest(w http.ResponseWriter, r *http.Request) {
 }
 
 // handleGetUserQuests handles fetching all quests for a user.
+// The caller's grant must have been issued for that same user.
 func (h *Handler) handleGetUserQuests(w http.ResponseWriter, r *http.Request) {
 	userID := chi.URLParam(r, "userID")
 	if userID == "" {
@@ -79,6 +92,12 @@ func (h *Handler) handleGetUserQuests(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	grantedUserID, _ := r.Context().Value(auth.UserIDKey).(string)
+	if userID != grantedUserID {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
 	quests, err := h.service.GetUserQuests(userID)
 	if err != nil {
 		http.Error(w, "Failed to retrieve quests", http.StatusInternalServerError)
@@ -91,6 +110,12 @@ func (h *Handler) handleGetUserQuests(w http.ResponseWriter, r *http.Request) {
 
 // handleUpdateQuest handles updating a quest's details.
 func (h *Handler) handleUpdateQuest(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
 	questID := chi.URLParam(r, "questID")
 	if questID == "" {
 		http.Error(w, "Quest ID is required", http.StatusBadRequest)
@@ -104,6 +129,7 @@ func (h *Handler) handleUpdateQuest(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
+	input.UserID = userID
 	quest, err := h.service.UpdateQuest(questID, input)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -120,13 +146,19 @@ func (h *Handler) handleUpdateQuest(w http.ResponseWriter, r *http.Request) {
 
 // handleCompleteQuest handles marking a quest as completed.
 func (h *Handler) handleCompleteQuest(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
 	questID := chi.URLParam(r, "questID")
 	if questID == "" {
 		http.Error(w, "Quest ID is required", http.StatusBadRequest)
 		return
 	}
 
-	quest, err := h.service.CompleteQuest(questID)
+	quest, err := h.service.CompleteQuest(questID, userID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			http.Error(w, "Quest not found", http.StatusNotFound)
@@ -0,0 +1,144 @@
+package quest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+
+	"github.com/adrianvalentim/gamify_journal/internal/auth"
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+)
+
+// memoryStore is an in-memory implementation of IQuestStore that mirrors
+// the owner scoping of the GORM store.
+type memoryStore struct {
+	quests map[string]models.Quest
+}
+
+func (m *memoryStore) CreateQuest(quest *models.Quest) error {
+	quest.ID = "quest-new"
+	m.quests[quest.ID] = *quest
+	return nil
+}
+
+func (m *memoryStore) GetQuestByID(id, userID string) (*models.Quest, error) {
+	quest, ok := m.quests[id]
+	if !ok || quest.UserID != userID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &quest, nil
+}
+
+func (m *memoryStore) GetQuestsByUserID(userID string) ([]models.Quest, error) {
+	var quests []models.Quest
+	for _, quest := range m.quests {
+		if quest.UserID == userID {
+			quests = append(quests, quest)
+		}
+	}
+	return quests, nil
+}
+
+func (m *memoryStore) UpdateQuest(quest *models.Quest) error {
+	m.quests[quest.ID] = *quest
+	return nil
+}
+
+func (m *memoryStore) MarkCompleted(id, userID string) (bool, error) {
+	quest, ok := m.quests[id]
+	if !ok || quest.UserID != userID || quest.Status == models.QuestStatusCompleted {
+		return false, nil
+	}
+	quest.Status = models.QuestStatusCompleted
+	m.quests[id] = quest
+	return true, nil
+}
+
+func (m *memoryStore) Transaction(fn func(store IQuestStore, tx *gorm.DB) error) error {
+	return fn(m, nil)
+}
+
+const (
+	ownerID      = "owner-user"
+	intruderID   = "intruder-user"
+	ownedQuestID = "quest-owned"
+	questSecret  = "Confront my fear of heights"
+)
+
+// TestHandler_Ownership checks the quest routes registered by RegisterRoutes against a quest
+// owned by another user. Foreign quests must look exactly like missing ones.
+func TestHandler_Ownership(t *testing.T) {
+	tests := []struct {
+		name       string
+		userID     string // end-user token, if set
+		grantFor   string // service grant bound to this user, if set
+		scopes     []string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+	}{
+		{name: "list my quests excludes foreign quests", userID: intruderID, method: http.MethodGet, target: "/quests/me", wantStatus: http.StatusOK},
+		{name: "update foreign quest", grantFor: intruderID, scopes: []string{auth.ScopeQuestsWrite}, method: http.MethodPut, target: "/quests/" + ownedQuestID, body: `{"title":"pwned"}`, wantStatus: http.StatusNotFound},
+		{name: "complete foreign quest", grantFor: intruderID, scopes: []string{auth.ScopeQuestsWrite}, method: http.MethodPost, target: "/quests/" + ownedQuestID + "/complete", wantStatus: http.StatusNotFound},
+		{name: "list quests of another user", grantFor: intruderID, scopes: []string{auth.ScopeQuestsRead}, method: http.MethodGet, target: "/quests/user/" + ownerID, wantStatus: http.StatusNotFound},
+		{name: "create quest for another user", grantFor: intruderID, scopes: []string{auth.ScopeQuestsWrite}, method: http.MethodPost, target: "/quests/", body: `{"user_id":"` + ownerID + `","title":"t"}`, wantStatus: http.StatusCreated},
+		{name: "update own quest", grantFor: ownerID, scopes: []string{auth.ScopeQuestsWrite}, method: http.MethodPut, target: "/quests/" + ownedQuestID, body: `{"description":"Climbed a ladder"}`, wantStatus: http.StatusOK},
+		{name: "update without grant", method: http.MethodPut, target: "/quests/" + ownedQuestID, body: `{"title":"pwned"}`, wantStatus: http.StatusUnauthorized},
+		{name: "update with user token", userID: ownerID, method: http.MethodPut, target: "/quests/" + ownedQuestID, body: `{"title":"pwned"}`, wantStatus: http.StatusUnauthorized},
+		{name: "update with read-only grant", grantFor: ownerID, scopes: []string{auth.ScopeQuestsRead}, method: http.MethodPut, target: "/quests/" + ownedQuestID, body: `{"title":"pwned"}`, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &memoryStore{quests: map[string]models.Quest{
+				ownedQuestID: {ID: ownedQuestID, UserID: ownerID, Title: questSecret, Status: models.QuestStatusInProgress},
+			}}
+			r := chi.NewRouter()
+			NewHandler(NewService(store, nil, nil)).RegisterRoutes(r)
+
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.userID != "" {
+				token, err := auth.GenerateToken(tt.userID, "")
+				if err != nil {
+					t.Fatalf("GenerateToken() error = %v", err)
+				}
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			if tt.grantFor != "" {
+				grant, err := auth.IssueServiceGrant(tt.grantFor, "", tt.scopes, time.Minute)
+				if err != nil {
+					t.Fatalf("IssueServiceGrant() error = %v", err)
+				}
+				req.Header.Set(auth.ServiceGrantHeader, grant)
+			}
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("%s %s: expected status %d, got %d (%s)", tt.method, tt.target, tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if tt.userID == intruderID || tt.grantFor == intruderID || rec.Code >= 400 {
+				if strings.Contains(rec.Body.String(), questSecret) {
+					t.Errorf("%s %s leaked the owner's quest: %s", tt.method, tt.target, rec.Body.String())
+				}
+				owned := store.quests[ownedQuestID]
+				if owned.Title != questSecret || owned.Status != models.QuestStatusInProgress {
+					t.Errorf("%s %s modified the owner's quest: %+v", tt.method, tt.target, owned)
+				}
+			}
+			for _, quest := range store.quests {
+				if quest.UserID == ownerID && quest.ID != ownedQuestID {
+					t.Errorf("%s %s created a quest for another user: %+v", tt.method, tt.target, quest)
+				}
+			}
+		})
+	}
+}
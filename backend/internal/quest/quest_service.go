@@ -1,27 +1,38 @@
 package quest
 
 import (
+	"gorm.io/gorm"
+
 	"github.com/adrianvalentim/gamify_journal/internal/character"
 	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/events"
 )
 
 // IQuestStore defines the interface for quest data storage.
+// Lookups by quest ID are scoped by the owning user.
 type IQuestStore interface {
 	CreateQuest(quest *models.Quest) error
-	GetQuestByID(id string) (*models.Quest, error)
+	GetQuestByID(id, userID string) (*models.Quest, error)
 	GetQuestsByUserID(userID string) ([]models.Quest, error)
 	UpdateQuest(quest *models.Quest) error
+	// MarkCompleted completes a quest of userID that is not completed yet. It reports false
+	// when the quest was already completed.
+	MarkCompleted(id, userID string) (bool, error)
+	// Transaction runs fn with a store bound to a single database transaction. The
+	// transaction is passed along so other services can take part in it.
+	Transaction(fn func(store IQuestStore, tx *gorm.DB) error) error
 }
 
 // Service provides quest-related business logic.
 type Service struct {
 	store            IQuestStore
 	characterService *character.Service
+	bus              *events.Bus
 }
 
-// NewService creates a new quest service.
-func NewService(store IQuestStore, characterService *character.Service) *Service {
-	return &Service{store: store, characterService: characterService}
+// NewService creates a new quest service. Completed quests are published on bus.
+func NewService(store IQuestStore, characterService *character.Service, bus *events.Bus) *Service {
+	return &Service{store: store, characterService: characterService, bus: bus}
 }
 
 // CreateQuestInput defines the input for creating a new quest.
@@ -55,15 +66,26 @@ func (s *Service) GetUserQuests(userID string) ([]models.Quest, error) {
 	return s.store.GetQuestsByUserID(userID)
 }
 
+// authorizeQuest loads a quest on behalf of userID.
+// Quests owned by other users are reported as gorm.ErrRecordNotFound.
+func (s *Service) authorizeQuest(id, userID string) (*models.Quest, error) {
+	if userID == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return s.store.GetQuestByID(id, userID)
+}
+
 // UpdateQuestInput defines the input for updating a quest.
 type UpdateQuestInput struct {
+	UserID      string  `json:"-"` // Set from the authenticated caller, never from the payload
 	Title       *string `json:"title"`
 	Description *string `json:"description"`
 }
 
 // UpdateQuest handles updating a quest's details.
+// Only quests owned by input.UserID can be updated.
 func (s *Service) UpdateQuest(id string, input UpdateQuestInput) (*models.Quest, error) {
-	quest, err := s.store.GetQuestByID(id)
+	quest, err := s.authorizeQuest(id, input.UserID)
 	if err != nil {
 		return nil, err
 	}
@@ -83,36 +105,52 @@ func (s *Service) UpdateQuest(id string, input UpdateQuestInput) (*models.Quest,
 	return quest, nil
 }
 
-// CompleteQuest marks a quest as completed and grants experience to the user's character.
-func (s *Service) CompleteQuest(questID string) (*models.Quest, error) {
-	quest, err := s.store.GetQuestByID(questID)
+// CompleteQuest marks a quest owned by userID as completed and grants experience to the user's character.
+// The quest is completed and the XP granted in one transaction, so a quest pays out once even
+// when it is completed by concurrent requests, and not at all if either step fails.
+func (s *Service) CompleteQuest(questID, userID string) (*models.Quest, error) {
+	quest, err := s.authorizeQuest(questID, userID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Avoid re-completing a quest
 	if quest.Status == models.QuestStatusCompleted {
-		// Or return an error, depending on desired behavior
 		return quest, nil
 	}
 
-	// Grant XP to the character
-	// We need the character to grant XP to. Let's assume the character service can find a character by userID.
 	char, err := s.characterService.GetCharacterByUserID(quest.UserID)
 	if err != nil {
 		// Handle case where character is not found for the user
 		return nil, err
 	}
+	// Resolved before the transaction, which must not wait for a second connection.
+	cal, err := s.characterService.Calendar(quest.UserID)
+	if err != nil {
+		return nil, err
+	}
 
-	if _, _, err := s.characterService.GrantXP(char.ID, quest.ExperienceReward); err != nil {
-		// Decide how to handle this error. Should we still mark the quest as complete?
-		// For now, let's return the error and not complete the quest.
+	var grant *character.XPGrant
+	err = s.store.Transaction(func(store IQuestStore, tx *gorm.DB) error {
+		completed, err := store.MarkCompleted(quest.ID, quest.UserID)
+		if err != nil || !completed {
+			return err // !completed: another request completed the quest first
+		}
+		grant, err = s.characterService.GrantXPTx(tx, cal, char.ID, quest.ExperienceReward, models.XPSource{
+			Type:   models.XPSourceQuest,
+			ID:     quest.ID,
+			Reason: "Completed quest: " + quest.Title,
+		})
+		return err
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	quest.Status = models.QuestStatusCompleted
-	if err := s.store.UpdateQuest(quest); err != nil {
-		return nil, err
+	if grant != nil {
+		s.characterService.PublishLevelUp(grant)
+		s.bus.Publish(events.Event{Type: events.QuestCompleted, UserID: quest.UserID, SubjectID: quest.ID})
 	}
 
 	return quest, nil
@@ -0,0 +1,150 @@
+package quest
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/adrianvalentim/gamify_journal/internal/character"
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/database/databasetest"
+)
+
+// staleStore reads every quest as still in progress, as a request does that loaded the
+// quest just before a concurrent request completed it.
+type staleStore struct {
+	IQuestStore
+}
+
+func (s staleStore) GetQuestByID(id, userID string) (*models.Quest, error) {
+	quest, err := s.IQuestStore.GetQuestByID(id, userID)
+	if quest != nil {
+		quest.Status = models.QuestStatusInProgress
+	}
+	return quest, err
+}
+
+var errLedger = errors.New("ledger unavailable")
+
+// brokenLedger fails to record XP transactions.
+type brokenLedger struct {
+	character.ICharacterStore
+}
+
+func (s brokenLedger) CreateXPTransaction(*models.XPTransaction) error {
+	return errLedger
+}
+
+func (s brokenLedger) WithTx(tx *gorm.DB) character.ICharacterStore {
+	return brokenLedger{s.ICharacterStore.WithTx(tx)}
+}
+
+// newTestQuest returns a quest worth 50 XP and a service completing it for the character of its user.
+func newTestQuest(t *testing.T) (*Service, *gorm.DB, *models.Quest) {
+	t.Helper()
+	return newTestQuestIn(t, databasetest.Open(t))
+}
+
+// newTestQuestIn creates the quest of newTestQuest in db.
+func newTestQuestIn(t *testing.T, db *gorm.DB) (*Service, *gorm.DB, *models.Quest) {
+	t.Helper()
+	if err := db.Create(&models.User{ID: "user-1", Username: "hero", Email: "hero@example.com"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	characterService := character.NewService(character.NewStore(db), character.XPRules{}, character.DefaultLeveling(), character.DefaultClasses(), nil, nil)
+	if _, err := characterService.CreateCharacter(character.CreateCharacterInput{UserID: "user-1", Name: "Hero", Class: models.Warrior}); err != nil {
+		t.Fatalf("CreateCharacter() error = %v", err)
+	}
+	svc := NewService(NewStore(db), characterService, nil)
+	quest, err := svc.CreateQuest(CreateQuestInput{UserID: "user-1", Title: "Run", ExperienceReward: 50})
+	if err != nil {
+		t.Fatalf("CreateQuest() error = %v", err)
+	}
+	return svc, db, quest
+}
+
+func characterXP(t *testing.T, db *gorm.DB) int {
+	t.Helper()
+	var char models.Character
+	if err := db.Where("user_id = ?", "user-1").First(&char).Error; err != nil {
+		t.Fatal(err)
+	}
+	return char.TotalXP
+}
+
+func TestService_CompleteQuestGrantsXPOnce(t *testing.T) {
+	svc, db, quest := newTestQuest(t)
+	svc.store = staleStore{svc.store}
+
+	for i := 0; i < 3; i++ {
+		completed, err := svc.CompleteQuest(quest.ID, "user-1")
+		if err != nil {
+			t.Fatalf("CompleteQuest() error = %v", err)
+		}
+		if completed.Status != models.QuestStatusCompleted {
+			t.Errorf("CompleteQuest() status = %q, want %q", completed.Status, models.QuestStatusCompleted)
+		}
+	}
+	if got := characterXP(t, db); got != 50 {
+		t.Errorf("character has %d XP after completing the quest three times, want 50", got)
+	}
+}
+
+func TestService_CompleteQuestRollsBackWhenGrantFails(t *testing.T) {
+	svc, db, quest := newTestQuest(t)
+	svc.characterService = character.NewService(brokenLedger{character.NewStore(db)}, character.XPRules{}, character.DefaultLeveling(), character.DefaultClasses(), nil, nil)
+
+	if _, err := svc.CompleteQuest(quest.ID, "user-1"); !errors.Is(err, errLedger) {
+		t.Fatalf("CompleteQuest() error = %v, want %v", err, errLedger)
+	}
+	stored, err := svc.store.GetQuestByID(quest.ID, "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != models.QuestStatusInProgress {
+		t.Errorf("quest status = %q after the grant failed, want %q", stored.Status, models.QuestStatusInProgress)
+	}
+	if got := characterXP(t, db); got != 0 {
+		t.Errorf("character has %d XP after the grant failed, want 0", got)
+	}
+}
+
+// TestService_CompleteQuestConcurrently completes a quest from concurrent requests. SQLite runs
+// their transactions one after the other; with TEST_DB_DSN set, the test also runs them side
+// by side on PostgreSQL.
+func TestService_CompleteQuestConcurrently(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *gorm.DB) {
+		svc, db, quest := newTestQuestIn(t, db)
+
+		const requests = 20
+		var wg sync.WaitGroup
+		errs := make(chan error, requests)
+		for i := 0; i < requests; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := svc.CompleteQuest(quest.ID, "user-1"); err != nil {
+					errs <- err
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Fatalf("CompleteQuest() error = %v", err)
+		}
+
+		if got := characterXP(t, db); got != 50 {
+			t.Errorf("character has %d XP after %d concurrent completions, want 50", got, requests)
+		}
+		var ledger int64
+		if err := db.Model(&models.XPTransaction{}).Where("source_id = ?", quest.ID).Count(&ledger).Error; err != nil {
+			t.Fatal(err)
+		}
+		if ledger != 1 {
+			t.Errorf("ledger has %d transactions for the quest, want 1", ledger)
+		}
+	})
+}
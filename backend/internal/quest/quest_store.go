@@ -23,10 +23,10 @@ func (s *Store) CreateQuest(quest *models.Quest) error {
 	return s.db.Create(quest).Error
 }
 
-// GetQuestByID retrieves a quest by its ID.
-func (s *Store) GetQuestByID(id string) (*models.Quest, error) {
+// GetQuestByID retrieves a quest by its ID for the given owner.
+func (s *Store) GetQuestByID(id, userID string) (*models.Quest, error) {
 	var quest models.Quest
-	err := s.db.First(&quest, "id = ?", id).Error
+	err := s.db.First(&quest, "id = ? AND user_id = ?", id, userID).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, err
@@ -50,3 +50,23 @@ func (s *Store) GetQuestsByUserID(userID string) ([]models.Quest, error) {
 func (s *Store) UpdateQuest(quest *models.Quest) error {
 	return s.db.Save(quest).Error
 }
+
+// MarkCompleted sets the status of a quest to completed unless it already is, so of
+// concurrent calls only one reports true.
+func (s *Store) MarkCompleted(id, userID string) (bool, error) {
+	result := s.db.Model(&models.Quest{}).
+		Where("id = ? AND user_id = ? AND status <> ?", id, userID, models.QuestStatusCompleted).
+		Update("status", models.QuestStatusCompleted)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Transaction runs fn with a store whose operations all belong to one database transaction.
+// The transaction is committed if fn returns nil and rolled back otherwise.
+func (s *Store) Transaction(fn func(store IQuestStore, tx *gorm.DB) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&Store{db: tx}, tx)
+	})
+}
@@ -0,0 +1,146 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/adrianvalentim/gamify_journal/internal/auth"
+	"github.com/go-chi/chi/v5"
+)
+
+// Handler handles HTTP requests for sessions and token renewal.
+type Handler struct {
+	service Service
+}
+
+// NewHandler creates a new session handler.
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes sets up the routes for session operations.
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Post("/token/refresh", h.handleRefresh)
+
+	r.Group(func(r chi.Router) {
+		r.Use(auth.AuthMiddleware)
+
+		r.Post("/logout", h.handleLogout)
+		r.Get("/users/me/sessions", h.handleListMySessions)
+		r.Delete("/users/me/sessions/{sessionID}", h.handleRevokeMySession)
+	})
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// sessionResponse describes a logged-in device.
+type sessionResponse struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"` // True for the session of the calling access token
+}
+
+// handleRefresh exchanges a refresh token for a new token pair.
+func (h *Handler) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	var req refreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	tokens, err := h.service.Refresh(req.RefreshToken, r.UserAgent(), r.RemoteAddr)
+	if err != nil {
+		if errors.Is(err, ErrInvalidRefreshToken) || errors.Is(err, ErrRefreshTokenReused) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "Failed to refresh token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// handleLogout revokes the session of the calling access token.
+func (h *Handler) handleLogout(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	sessionID, _ := r.Context().Value(auth.SessionIDKey).(string)
+	if sessionID == "" {
+		http.Error(w, "Token is not bound to a session", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Revoke(sessionID, userID); err != nil && !errors.Is(err, ErrSessionNotFound) {
+		http.Error(w, "Failed to log out", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleListMySessions lists the devices the authenticated user is logged in on.
+func (h *Handler) handleListMySessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	currentID, _ := r.Context().Value(auth.SessionIDKey).(string)
+
+	sessions, err := h.service.ListActive(userID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve sessions", http.StatusInternalServerError)
+		return
+	}
+
+	resp := make([]sessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		resp = append(resp, sessionResponse{
+			ID:         s.ID,
+			UserAgent:  s.UserAgent,
+			IPAddress:  s.IPAddress,
+			CreatedAt:  s.CreatedAt,
+			LastUsedAt: s.LastUsedAt,
+			ExpiresAt:  s.ExpiresAt,
+			Current:    s.ID == currentID,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleRevokeMySession logs the authenticated user out of one of their devices.
+func (h *Handler) handleRevokeMySession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID := chi.URLParam(r, "sessionID")
+	if err := h.service.Revoke(sessionID, userID); err != nil {
+		if errors.Is(err, ErrSessionNotFound) {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to revoke session", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
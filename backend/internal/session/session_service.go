@@ -0,0 +1,223 @@
+package session
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/adrianvalentim/gamify_journal/internal/auth"
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+)
+
+// Pre-defined errors returned by the session service.
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	// ErrRefreshTokenReused is returned when an already rotated refresh token is presented again.
+	// This indicates the token was stolen, so the whole session is revoked.
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected; session revoked")
+	ErrSessionNotFound    = errors.New("session not found")
+)
+
+// refreshTokenTTL is how long a session can stay idle before its refresh token expires.
+// Every refresh extends the session by this amount.
+var refreshTokenTTL = refreshTTLFromEnv()
+
+// Tokens is the pair of credentials handed to a client for a session.
+type Tokens struct {
+	SessionID            string    `json:"session_id"`
+	AccessToken          string    `json:"token"`
+	AccessTokenExpiresAt time.Time `json:"token_expires_at"`
+	RefreshToken         string    `json:"refresh_token"`
+}
+
+// Service defines the interface for session business logic.
+type Service interface {
+	Start(userID, userAgent, ipAddress string) (*Tokens, error)
+	Refresh(refreshToken, userAgent, ipAddress string) (*Tokens, error)
+	ListActive(userID string) ([]models.Session, error)
+	Revoke(sessionID, userID string) error
+	RevokeAllForUser(userID, exceptSessionID string) error
+	IsSessionActive(sessionID string) (bool, error)
+}
+
+type service struct {
+	store Store
+	now   func() time.Time
+}
+
+// NewService creates a new session service.
+func NewService(store Store) Service {
+	return &service{store: store, now: time.Now}
+}
+
+// Start opens a new session for a user who just authenticated and issues its first tokens.
+func (s *service) Start(userID, userAgent, ipAddress string) (*Tokens, error) {
+	now := s.now()
+	session := &models.Session{
+		ID:         uuid.NewString(),
+		UserID:     userID,
+		UserAgent:  userAgent,
+		IPAddress:  ipAddress,
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(refreshTokenTTL),
+	}
+
+	plain, token, err := newRefreshToken(session.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.CreateSession(session, token); err != nil {
+		return nil, fmt.Errorf("could not create session: %w", err)
+	}
+
+	return s.issue(session, plain, now)
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh token.
+// Each refresh token can only be used once; presenting a used one revokes the session.
+func (s *service) Refresh(refreshToken, userAgent, ipAddress string) (*Tokens, error) {
+	if refreshToken == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+	now := s.now()
+
+	current, err := s.store.GetRefreshToken(hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	session := &current.Session
+	if current.UsedAt != nil {
+		return nil, s.revokeReusedSession(session, now)
+	}
+	if session.RevokedAt != nil || !now.Before(session.ExpiresAt) || !now.Before(current.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	plain, next, err := newRefreshToken(session.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	session.LastUsedAt = now
+	session.ExpiresAt = now.Add(refreshTokenTTL)
+	session.UserAgent = userAgent
+	session.IPAddress = ipAddress
+
+	if err := s.store.ConsumeRefreshToken(current.ID, next, session); err != nil {
+		if errors.Is(err, errTokenAlreadyUsed) {
+			return nil, s.revokeReusedSession(session, now)
+		}
+		return nil, fmt.Errorf("could not rotate refresh token: %w", err)
+	}
+
+	return s.issue(session, plain, now)
+}
+
+// revokeReusedSession kills the session a reused refresh token belongs to.
+func (s *service) revokeReusedSession(session *models.Session, now time.Time) error {
+	log.Printf("Warning: refresh token reuse detected for session %s of user %s. Revoking session.", session.ID, session.UserID)
+	if err := s.store.RevokeSession(session.ID, session.UserID, now); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("could not revoke session after refresh token reuse: %w", err)
+	}
+	return ErrRefreshTokenReused
+}
+
+// ListActive returns the active sessions (logged-in devices) of a user.
+func (s *service) ListActive(userID string) ([]models.Session, error) {
+	return s.store.ListActiveSessions(userID, s.now())
+}
+
+// Revoke ends a session owned by userID.
+func (s *service) Revoke(sessionID, userID string) error {
+	if err := s.store.RevokeSession(sessionID, userID, s.now()); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrSessionNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// RevokeAllForUser ends every session of a user, e.g. after a password change.
+// A non-empty exceptSessionID keeps that session (the caller's own) alive.
+func (s *service) RevokeAllForUser(userID, exceptSessionID string) error {
+	return s.store.RevokeAllForUser(userID, exceptSessionID, s.now())
+}
+
+// IsSessionActive reports whether a session exists and is neither revoked nor expired.
+// It implements auth.SessionChecker.
+func (s *service) IsSessionActive(sessionID string) (bool, error) {
+	session, err := s.store.GetSession(sessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return session.RevokedAt == nil && s.now().Before(session.ExpiresAt), nil
+}
+
+// issue builds the tokens handed to the client for a session.
+func (s *service) issue(session *models.Session, refreshToken string, now time.Time) (*Tokens, error) {
+	accessToken, err := auth.GenerateToken(session.UserID, session.ID)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate access token: %w", err)
+	}
+	return &Tokens{
+		SessionID:            session.ID,
+		AccessToken:          accessToken,
+		AccessTokenExpiresAt: now.Add(auth.AccessTokenTTL),
+		RefreshToken:         refreshToken,
+	}, nil
+}
+
+// newRefreshToken generates a random refresh token and the record storing its hash.
+func newRefreshToken(sessionID string, now time.Time) (string, *models.RefreshToken, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", nil, fmt.Errorf("could not generate refresh token: %w", err)
+	}
+	plain := base64.RawURLEncoding.EncodeToString(raw)
+	return plain, &models.RefreshToken{
+		ID:        uuid.NewString(),
+		SessionID: sessionID,
+		TokenHash: hashToken(plain),
+		ExpiresAt: now.Add(refreshTokenTTL),
+		CreatedAt: now,
+	}, nil
+}
+
+// hashToken returns the hex-encoded SHA-256 of a token.
+// Refresh tokens are high-entropy random values, so a fast hash is sufficient.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// refreshTTLFromEnv reads REFRESH_TOKEN_TTL (e.g., "720h"), defaulting to 30 days.
+func refreshTTLFromEnv() time.Duration {
+	const def = 30 * 24 * time.Hour
+	value := os.Getenv("REFRESH_TOKEN_TTL")
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("Warning: invalid REFRESH_TOKEN_TTL value %q. Defaulting to %s", value, def)
+		return def
+	}
+	return d
+}
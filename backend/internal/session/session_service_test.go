@@ -0,0 +1,171 @@
+package session
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+)
+
+// memoryStore is an in-memory implementation of the Store interface for testing the session service.
+type memoryStore struct {
+	sessions map[string]models.Session
+	tokens   map[string]models.RefreshToken // keyed by token hash
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{sessions: map[string]models.Session{}, tokens: map[string]models.RefreshToken{}}
+}
+
+func (m *memoryStore) CreateSession(session *models.Session, token *models.RefreshToken) error {
+	m.sessions[session.ID] = *session
+	m.tokens[token.TokenHash] = *token
+	return nil
+}
+
+func (m *memoryStore) GetSession(sessionID string) (*models.Session, error) {
+	session, ok := m.sessions[sessionID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &session, nil
+}
+
+func (m *memoryStore) GetRefreshToken(tokenHash string) (*models.RefreshToken, error) {
+	token, ok := m.tokens[tokenHash]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	token.Session = m.sessions[token.SessionID]
+	return &token, nil
+}
+
+func (m *memoryStore) ConsumeRefreshToken(tokenID string, next *models.RefreshToken, session *models.Session) error {
+	for hash, token := range m.tokens {
+		if token.ID != tokenID {
+			continue
+		}
+		if token.UsedAt != nil {
+			return errTokenAlreadyUsed
+		}
+		usedAt := next.CreatedAt
+		token.UsedAt = &usedAt
+		m.tokens[hash] = token
+		m.tokens[next.TokenHash] = *next
+		m.sessions[session.ID] = *session
+		return nil
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (m *memoryStore) ListActiveSessions(userID string, now time.Time) ([]models.Session, error) {
+	var sessions []models.Session
+	for _, session := range m.sessions {
+		if session.UserID == userID && session.RevokedAt == nil && session.ExpiresAt.After(now) {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+func (m *memoryStore) RevokeSession(sessionID, userID string, at time.Time) error {
+	session, ok := m.sessions[sessionID]
+	if !ok || session.UserID != userID || session.RevokedAt != nil {
+		return gorm.ErrRecordNotFound
+	}
+	session.RevokedAt = &at
+	m.sessions[sessionID] = session
+	return nil
+}
+
+func (m *memoryStore) RevokeAllForUser(userID, exceptSessionID string, at time.Time) error {
+	for id, session := range m.sessions {
+		if session.UserID == userID && session.RevokedAt == nil && id != exceptSessionID {
+			session.RevokedAt = &at
+			m.sessions[id] = session
+		}
+	}
+	return nil
+}
+
+func TestService_RefreshRotatesTokens(t *testing.T) {
+	svc := NewService(newMemoryStore())
+
+	first, err := svc.Start("user-1", "test-agent", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	second, err := svc.Refresh(first.RefreshToken, "test-agent", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Error("Refresh() must rotate the refresh token")
+	}
+	if second.SessionID != first.SessionID {
+		t.Errorf("Refresh() moved to session %s, expected %s", second.SessionID, first.SessionID)
+	}
+	if active, _ := svc.IsSessionActive(first.SessionID); !active {
+		t.Error("session should stay active after a normal refresh")
+	}
+}
+
+func TestService_RefreshTokenReuseRevokesSession(t *testing.T) {
+	svc := NewService(newMemoryStore())
+
+	first, err := svc.Start("user-1", "test-agent", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	second, err := svc.Refresh(first.RefreshToken, "test-agent", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	// An attacker replays the first token after the legitimate client already rotated it.
+	if _, err := svc.Refresh(first.RefreshToken, "attacker", "10.0.0.1"); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("Refresh() with a used token: expected %v, got %v", ErrRefreshTokenReused, err)
+	}
+	if active, _ := svc.IsSessionActive(first.SessionID); active {
+		t.Error("session must be revoked after refresh token reuse")
+	}
+	// The latest token of the family is dead too.
+	if _, err := svc.Refresh(second.RefreshToken, "test-agent", "127.0.0.1"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("Refresh() with the newest token of a revoked family: expected %v, got %v", ErrInvalidRefreshToken, err)
+	}
+}
+
+func TestService_Revoke(t *testing.T) {
+	svc := NewService(newMemoryStore())
+
+	mine, err := svc.Start("user-1", "phone", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		sessionID string
+		userID    string
+		wantErr   error
+	}{
+		{name: "other user's session", sessionID: mine.SessionID, userID: "user-2", wantErr: ErrSessionNotFound},
+		{name: "unknown session", sessionID: "nope", userID: "user-1", wantErr: ErrSessionNotFound},
+		{name: "own session", sessionID: mine.SessionID, userID: "user-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := svc.Revoke(tt.sessionID, tt.userID); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Revoke() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := svc.Refresh(mine.RefreshToken, "phone", "127.0.0.1"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("Refresh() after logout: expected %v, got %v", ErrInvalidRefreshToken, err)
+	}
+}
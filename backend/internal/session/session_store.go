@@ -0,0 +1,124 @@
+package session
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+)
+
+// errTokenAlreadyUsed is returned by ConsumeRefreshToken when the token was rotated concurrently.
+var errTokenAlreadyUsed = errors.New("refresh token already used")
+
+// Store defines the interface for session data persistence.
+type Store interface {
+	CreateSession(session *models.Session, token *models.RefreshToken) error
+	GetSession(sessionID string) (*models.Session, error)
+	GetRefreshToken(tokenHash string) (*models.RefreshToken, error)
+	ConsumeRefreshToken(tokenID string, next *models.RefreshToken, session *models.Session) error
+	ListActiveSessions(userID string, now time.Time) ([]models.Session, error)
+	RevokeSession(sessionID, userID string, at time.Time) error
+	RevokeAllForUser(userID, exceptSessionID string, at time.Time) error
+}
+
+// gormStore is a GORM implementation of the Store interface.
+type gormStore struct {
+	db *gorm.DB
+}
+
+// NewStore creates a new GORM store for sessions.
+func NewStore(db *gorm.DB) Store {
+	return &gormStore{db: db}
+}
+
+// CreateSession inserts a session together with its first refresh token.
+func (s *gormStore) CreateSession(session *models.Session, token *models.RefreshToken) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(session).Error; err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	})
+}
+
+// GetSession retrieves a session by its ID.
+func (s *gormStore) GetSession(sessionID string) (*models.Session, error) {
+	var session models.Session
+	if err := s.db.First(&session, "id = ?", sessionID).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetRefreshToken retrieves a refresh token and its session by the token hash.
+func (s *gormStore) GetRefreshToken(tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	if err := s.db.Preload("Session").First(&token, "token_hash = ?", tokenHash).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// ConsumeRefreshToken marks a refresh token as used and stores its successor in one transaction.
+// The used_at check is done in SQL so two concurrent refreshes cannot both succeed;
+// the loser gets errTokenAlreadyUsed.
+func (s *gormStore) ConsumeRefreshToken(tokenID string, next *models.RefreshToken, session *models.Session) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL", tokenID).
+			Update("used_at", next.CreatedAt)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errTokenAlreadyUsed
+		}
+		if err := tx.Create(next).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Session{}).Where("id = ?", session.ID).Updates(map[string]interface{}{
+			"last_used_at": session.LastUsedAt,
+			"expires_at":   session.ExpiresAt,
+			"user_agent":   session.UserAgent,
+			"ip_address":   session.IPAddress,
+		}).Error
+	})
+}
+
+// ListActiveSessions returns the sessions of a user that are neither revoked nor expired.
+func (s *gormStore) ListActiveSessions(userID string, now time.Time) ([]models.Session, error) {
+	var sessions []models.Session
+	if err := s.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_used_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// RevokeSession revokes a session owned by userID.
+// Returns gorm.ErrRecordNotFound if no active session matched.
+func (s *gormStore) RevokeSession(sessionID, userID string, at time.Time) error {
+	result := s.db.Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		Update("revoked_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RevokeAllForUser revokes every active session of a user except exceptSessionID, if set.
+func (s *gormStore) RevokeAllForUser(userID, exceptSessionID string, at time.Time) error {
+	query := s.db.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID)
+	if exceptSessionID != "" {
+		query = query.Where("id <> ?", exceptSessionID)
+	}
+	return query.Update("revoked_at", at).Error
+}
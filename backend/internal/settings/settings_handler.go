@@ -0,0 +1,85 @@
+package settings
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/adrianvalentim/gamify_journal/internal/auth"
+)
+
+// Handler handles the HTTP endpoints of user settings.
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new settings handler.
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes sets up the routes for reading and changing the user's settings.
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Group(func(r chi.Router) {
+		r.Use(auth.AuthMiddleware)
+		r.Get("/users/me/settings", h.handleGetMySettings)
+		r.Patch("/users/me/settings", h.handleUpdateMySettings)
+	})
+}
+
+// handleGetMySettings returns the authenticated user's settings.
+func (h *Handler) handleGetMySettings(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	settings, err := h.service.GetSettings(userID)
+	if err != nil {
+		log.Printf("Error getting settings of user %s: %v", userID, err)
+		http.Error(w, `{"error": "Failed to get settings"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+// handleUpdateMySettings changes the fields of the authenticated user's settings present in
+// the request body.
+func (h *Handler) handleUpdateMySettings(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var input UpdateSettingsInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.service.UpdateSettings(userID, input)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidTimezone):
+			http.Error(w, `{"error": "Unknown time zone"}`, http.StatusBadRequest)
+		case errors.Is(err, ErrUnsupportedLocale):
+			http.Error(w, `{"error": "Unsupported locale"}`, http.StatusBadRequest)
+		case errors.Is(err, ErrInvalidWeekStart):
+			http.Error(w, `{"error": "Week start must be the name of a day"}`, http.StatusBadRequest)
+		default:
+			log.Printf("Error updating settings of user %s: %v", userID, err)
+			http.Error(w, `{"error": "Failed to update settings"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
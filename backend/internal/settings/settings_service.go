@@ -0,0 +1,157 @@
+package settings
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	// The zone database is embedded so time zones resolve on hosts without one.
+	_ "time/tzdata"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/calendar"
+)
+
+var (
+	ErrInvalidTimezone   = errors.New("unknown time zone")
+	ErrUnsupportedLocale = errors.New("unsupported locale")
+	ErrInvalidWeekStart  = errors.New("week start must be the name of a day")
+)
+
+// locales maps the lowercase form of every supported locale to its canonical form.
+var locales = map[string]string{
+	"en":    models.LocaleEnglish,
+	"en-us": models.LocaleEnglish,
+	"pt":    models.LocalePortugueseBrazilian,
+	"pt-br": models.LocalePortugueseBrazilian,
+}
+
+// Store defines the data access the settings service needs.
+type Store interface {
+	// GetSettings returns the user's settings, or nil if the user never changed them.
+	GetSettings(userID string) (*models.UserSettings, error)
+	// SaveSettings creates or replaces the user's settings.
+	SaveSettings(settings *models.UserSettings) error
+}
+
+// Service manages the settings of users. It is also the calendar.Source of the app, so that
+// days are counted in each user's time zone.
+type Service struct {
+	store Store
+}
+
+// NewService creates a settings service.
+func NewService(store Store) *Service {
+	return &Service{store: store}
+}
+
+// GetSettings returns the user's settings, or the defaults if the user never changed them.
+func (s *Service) GetSettings(userID string) (*models.UserSettings, error) {
+	settings, err := s.store.GetSettings(userID)
+	if err != nil || settings != nil {
+		return settings, err
+	}
+	defaults := models.DefaultUserSettings(userID)
+	return &defaults, nil
+}
+
+// UpdateSettingsInput defines the input for updating settings. Fields left nil are unchanged.
+type UpdateSettingsInput struct {
+	Timezone      *string                   `json:"timezone"`
+	Locale        *string                   `json:"locale"`
+	WeekStart     *string                   `json:"week_start"`
+	Notifications *UpdateNotificationsInput `json:"notifications"`
+}
+
+// UpdateNotificationsInput defines the notification preferences to change.
+type UpdateNotificationsInput struct {
+	StreakReminders *bool `json:"streak_reminders"`
+	WeeklySummary   *bool `json:"weekly_summary"`
+	Achievements    *bool `json:"achievements"`
+}
+
+// UpdateSettings changes the user's settings. Locales and week starts are accepted in any case
+// and saved in their canonical form.
+func (s *Service) UpdateSettings(userID string, input UpdateSettingsInput) (*models.UserSettings, error) {
+	settings, err := s.GetSettings(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Timezone != nil {
+		if _, err := loadLocation(*input.Timezone); err != nil {
+			return nil, err
+		}
+		settings.Timezone = *input.Timezone
+	}
+	if input.Locale != nil {
+		locale, ok := locales[strings.ToLower(*input.Locale)]
+		if !ok {
+			return nil, ErrUnsupportedLocale
+		}
+		settings.Locale = locale
+	}
+	if input.WeekStart != nil {
+		weekday, err := parseWeekday(*input.WeekStart)
+		if err != nil {
+			return nil, err
+		}
+		settings.WeekStart = strings.ToLower(weekday.String())
+	}
+	if n := input.Notifications; n != nil {
+		if n.StreakReminders != nil {
+			settings.Notifications.StreakReminders = *n.StreakReminders
+		}
+		if n.WeeklySummary != nil {
+			settings.Notifications.WeeklySummary = *n.WeeklySummary
+		}
+		if n.Achievements != nil {
+			settings.Notifications.Achievements = *n.Achievements
+		}
+	}
+
+	settings.UpdatedAt = time.Now()
+	if err := s.store.SaveSettings(settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// Calendar returns the calendar of the user's days and weeks.
+func (s *Service) Calendar(userID string) (calendar.Calendar, error) {
+	settings, err := s.GetSettings(userID)
+	if err != nil {
+		return calendar.Calendar{}, err
+	}
+	location, err := loadLocation(settings.Timezone)
+	if err != nil {
+		return calendar.Calendar{}, err
+	}
+	weekStart, err := parseWeekday(settings.WeekStart)
+	if err != nil {
+		return calendar.Calendar{}, err
+	}
+	return calendar.Calendar{Location: location, WeekStart: weekStart}, nil
+}
+
+// loadLocation loads an IANA time zone. The server's own zone, "Local", is refused since it
+// means nothing to the user.
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, ErrInvalidTimezone
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, ErrInvalidTimezone
+	}
+	return location, nil
+}
+
+func parseWeekday(name string) (time.Weekday, error) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(name, day.String()) {
+			return day, nil
+		}
+	}
+	return 0, ErrInvalidWeekStart
+}
@@ -0,0 +1,74 @@
+package settings
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/database/databasetest"
+)
+
+func TestService_UpdateSettings(t *testing.T) {
+	svc := NewService(NewStore(databasetest.Open(t)))
+
+	got, err := svc.GetSettings("user-1")
+	if err != nil {
+		t.Fatalf("GetSettings() error = %v", err)
+	}
+	if *got != models.DefaultUserSettings("user-1") {
+		t.Errorf("GetSettings() = %+v, want the defaults", got)
+	}
+
+	zone, locale, weekStart, summary := "America/Sao_Paulo", "PT-br", "Sunday", true
+	got, err = svc.UpdateSettings("user-1", UpdateSettingsInput{
+		Timezone:      &zone,
+		Locale:        &locale,
+		WeekStart:     &weekStart,
+		Notifications: &UpdateNotificationsInput{WeeklySummary: &summary},
+	})
+	if err != nil {
+		t.Fatalf("UpdateSettings() error = %v", err)
+	}
+	if got.Timezone != zone || got.Locale != models.LocalePortugueseBrazilian || got.WeekStart != "sunday" ||
+		!got.Notifications.WeeklySummary || !got.Notifications.StreakReminders {
+		t.Errorf("UpdateSettings() = %+v, want the changes on top of the defaults", got)
+	}
+
+	// Fields left out are kept.
+	reminders := false
+	if _, err := svc.UpdateSettings("user-1", UpdateSettingsInput{Notifications: &UpdateNotificationsInput{StreakReminders: &reminders}}); err != nil {
+		t.Fatalf("UpdateSettings() error = %v", err)
+	}
+	if got, _ = svc.GetSettings("user-1"); got.Timezone != zone || got.Notifications.StreakReminders || !got.Notifications.WeeklySummary {
+		t.Errorf("GetSettings() = %+v, want only the streak reminders turned off", got)
+	}
+
+	cal, err := svc.Calendar("user-1")
+	if err != nil {
+		t.Fatalf("Calendar() error = %v", err)
+	}
+	if cal.Location.String() != zone || cal.WeekStart != time.Sunday {
+		t.Errorf("Calendar() = %v, %v; want São Paulo weeks from Sunday", cal.Location, cal.WeekStart)
+	}
+
+	invalid := []struct {
+		name    string
+		input   UpdateSettingsInput
+		wantErr error
+	}{
+		{"unknown zone", UpdateSettingsInput{Timezone: ptr("Mars/Olympus_Mons")}, ErrInvalidTimezone},
+		{"server zone", UpdateSettingsInput{Timezone: ptr("Local")}, ErrInvalidTimezone},
+		{"unsupported locale", UpdateSettingsInput{Locale: ptr("fr")}, ErrUnsupportedLocale},
+		{"invalid week start", UpdateSettingsInput{WeekStart: ptr("someday")}, ErrInvalidWeekStart},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.UpdateSettings("user-1", tt.input); !errors.Is(err, tt.wantErr) {
+				t.Errorf("UpdateSettings() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func ptr(s string) *string { return &s }
@@ -0,0 +1,39 @@
+package settings
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+)
+
+// gormStore is a GORM implementation of the Store interface.
+type gormStore struct {
+	db *gorm.DB
+}
+
+// NewStore creates a new GORM store for user settings.
+func NewStore(db *gorm.DB) Store {
+	return &gormStore{db: db}
+}
+
+func (s *gormStore) GetSettings(userID string) (*models.UserSettings, error) {
+	var settings models.UserSettings
+	err := s.db.Where("user_id = ?", userID).Take(&settings).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (s *gormStore) SaveSettings(settings *models.UserSettings) error {
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		UpdateAll: true,
+	}).Create(settings).Error
+}
@@ -0,0 +1,62 @@
+package streak
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/adrianvalentim/gamify_journal/internal/auth"
+)
+
+// Bounds of the number of broken streaks returned by GET /users/me/streaks.
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 500
+)
+
+// Handler handles the HTTP endpoints of streaks.
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new streak handler.
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes sets up the routes for reading the user's streaks.
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.With(auth.AuthMiddleware).Get("/users/me/streaks", h.handleGetMyStreaks)
+}
+
+// handleGetMyStreaks returns the authenticated user's current streaks and streak history.
+// The query parameter limit sets how many broken streaks are listed.
+func (h *Handler) handleGetMyStreaks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	limit := defaultHistoryLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxHistoryLimit {
+			http.Error(w, `{"error": "Invalid value for limit"}`, http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	streaks, err := h.service.GetStreaks(userID, limit)
+	if err != nil {
+		log.Printf("Error getting streaks of user %s: %v", userID, err)
+		http.Error(w, `{"error": "Failed to get streaks"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(streaks)
+}
@@ -0,0 +1,330 @@
+package streak
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/calendar"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/events"
+)
+
+// Config controls what counts toward a streak and how streak freezes are earned.
+type Config struct {
+	// MinWords is how many new words a save needs to count as a day of writing.
+	MinWords int
+	// FreezeEvery is how many days of a streak earn a streak freeze.
+	FreezeEvery int
+	// MaxFreezes caps the freezes a user can hold.
+	MaxFreezes int
+}
+
+// DefaultConfig returns the configuration used when no environment overrides are set.
+func DefaultConfig() Config {
+	return Config{MinWords: 20, FreezeEvery: 7, MaxFreezes: 3}
+}
+
+// ConfigFromEnv reads STREAK_MIN_WORDS, STREAK_FREEZE_EVERY and STREAK_MAX_FREEZES on top of DefaultConfig.
+func ConfigFromEnv() Config {
+	cfg := DefaultConfig()
+	for key, target := range map[string]*int{"STREAK_MIN_WORDS": &cfg.MinWords, "STREAK_FREEZE_EVERY": &cfg.FreezeEvery, "STREAK_MAX_FREEZES": &cfg.MaxFreezes} {
+		if value := os.Getenv(key); value != "" {
+			if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+				*target = n
+			} else {
+				log.Printf("Warning: invalid %s value %q. Defaulting to %d", key, value, *target)
+			}
+		}
+	}
+	return cfg
+}
+
+// Store defines the data access the streak service needs.
+type Store interface {
+	// GetProgress returns the user's progress, or nil if the user has none yet.
+	GetProgress(userID string) (*models.UserProgress, error)
+	// LockProgress returns the user's progress, created if needed, and locks it until the
+	// surrounding transaction ends.
+	LockProgress(userID string) (*models.UserProgress, error)
+	// SaveStreaks saves the streak fields of a progress.
+	SaveStreaks(progress *models.UserProgress) error
+	// EachActiveProgress calls fn for the progress of every user with an active streak.
+	EachActiveProgress(fn func(progress *models.UserProgress) error) error
+	CreateStreakRecord(record *models.StreakRecord) error
+	// ListStreakRecords returns the user's broken streaks, most recently broken first.
+	ListStreakRecords(userID string, limit int) ([]models.StreakRecord, error)
+	// Transaction runs fn with a store bound to a single database transaction.
+	Transaction(fn func(store Store) error) error
+}
+
+// Service tracks the writing streaks of users.
+type Service struct {
+	store     Store
+	cfg       Config
+	calendars calendar.Source
+	now       func() time.Time
+}
+
+// NewService creates a streak service. Days are counted in the calendar of each user from
+// calendars, or in UTC when calendars is nil.
+func NewService(store Store, cfg Config, calendars calendar.Source) *Service {
+	return &Service{store: store, cfg: cfg, calendars: calendars, now: time.Now}
+}
+
+// Subscribe extends streaks when bus reports a save with enough new text, and awards a streak
+// freeze for every level-up. Extended streaks are published on bus.
+func (s *Service) Subscribe(bus *events.Bus) {
+	bus.Subscribe(func(event events.Event) error {
+		if event.Words < s.cfg.MinWords {
+			return nil
+		}
+		extended, err := s.RecordWriting(event.UserID, event.At)
+		if err != nil || !extended {
+			return err
+		}
+		bus.Publish(events.Event{Type: events.StreakExtended, UserID: event.UserID, SubjectID: models.StreakDailyWriting})
+		return nil
+	}, events.EntrySaved)
+	bus.Subscribe(func(event events.Event) error {
+		return s.AwardFreeze(event.UserID)
+	}, events.LevelUp)
+}
+
+// missedDays returns how many days since the last update of a streak passed without writing,
+// not counting the current day, which is not over yet.
+func missedDays(progress *models.UserProgress, kind string, cal calendar.Calendar, now time.Time) int {
+	last, ok := progress.LastStreakUpdate[kind]
+	if !ok {
+		return 0
+	}
+	missed := cal.DaysBetween(last, now) - 1
+	if missed < 0 {
+		return 0
+	}
+	return missed
+}
+
+// broken reports whether an active streak was broken: more days were missed than freezes can cover.
+func broken(progress *models.UserProgress, kind string, cal calendar.Calendar, now time.Time) bool {
+	return progress.CurrentStreaks[kind] > 0 && missedDays(progress, kind, cal, now) > progress.StreakFreezes
+}
+
+// endStreak moves an active streak to the user's streak history.
+func endStreak(store Store, progress *models.UserProgress, kind string, at time.Time) error {
+	record := &models.StreakRecord{
+		ID:           uuid.NewString(),
+		UserID:       progress.UserID,
+		Kind:         kind,
+		Length:       progress.CurrentStreaks[kind],
+		StartedAt:    progress.StreakStartedAt[kind],
+		LastUpdateAt: progress.LastStreakUpdate[kind],
+		BrokenAt:     at,
+	}
+	progress.CurrentStreaks[kind] = 0
+	delete(progress.StreakStartedAt, kind)
+	return store.CreateStreakRecord(record)
+}
+
+// RecordWriting counts writing done at the given time toward the user's daily writing streak
+// and reports whether the streak grew. Days missed since the last write are bridged with
+// streak freezes, as long as the user has enough of them; otherwise a new streak starts.
+func (s *Service) RecordWriting(userID string, at time.Time) (bool, error) {
+	cal, err := calendar.For(s.calendars, userID)
+	if err != nil {
+		return false, err
+	}
+	const kind = models.StreakDailyWriting
+	extended := false
+	err = s.store.Transaction(func(store Store) error {
+		progress, err := store.LockProgress(userID)
+		if err != nil {
+			return err
+		}
+		if progress.CurrentStreaks[kind] > 0 {
+			if cal.DaysBetween(progress.LastStreakUpdate[kind], at) <= 0 {
+				return nil // Already written that day
+			}
+			if broken(progress, kind, cal, at) {
+				if err := endStreak(store, progress, kind, at); err != nil {
+					return err
+				}
+			} else {
+				progress.StreakFreezes -= missedDays(progress, kind, cal, at)
+			}
+		}
+
+		if progress.CurrentStreaks[kind] == 0 {
+			progress.StreakStartedAt[kind] = at
+		}
+		progress.CurrentStreaks[kind]++
+		progress.LastStreakUpdate[kind] = at
+		current := progress.CurrentStreaks[kind]
+		if current > progress.LongestStreaks[kind] {
+			progress.LongestStreaks[kind] = current
+		}
+		if s.cfg.FreezeEvery > 0 && current%s.cfg.FreezeEvery == 0 && progress.StreakFreezes < s.cfg.MaxFreezes {
+			progress.StreakFreezes++
+		}
+		extended = true
+		return store.SaveStreaks(progress)
+	})
+	return extended, err
+}
+
+// AwardFreeze gives the user a streak freeze, unless they already hold the most they can.
+func (s *Service) AwardFreeze(userID string) error {
+	return s.store.Transaction(func(store Store) error {
+		progress, err := store.LockProgress(userID)
+		if err != nil || progress.StreakFreezes >= s.cfg.MaxFreezes {
+			return err
+		}
+		progress.StreakFreezes++
+		return store.SaveStreaks(progress)
+	})
+}
+
+// BreakStreaks ends the streaks that were broken: those of users who missed more days than
+// their freezes cover. It returns how many streaks were ended.
+func (s *Service) BreakStreaks() (int, error) {
+	now := s.now()
+	var userIDs []string
+	err := s.store.EachActiveProgress(func(progress *models.UserProgress) error {
+		cal, err := calendar.For(s.calendars, progress.UserID)
+		if err != nil {
+			return err
+		}
+		if broken(progress, models.StreakDailyWriting, cal, now) {
+			userIDs = append(userIDs, progress.UserID)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	ended := 0
+	for _, userID := range userIDs {
+		cal, err := calendar.For(s.calendars, userID)
+		if err != nil {
+			return ended, err
+		}
+		// The user may have written since the progress was read.
+		err = s.store.Transaction(func(store Store) error {
+			progress, err := store.LockProgress(userID)
+			if err != nil || !broken(progress, models.StreakDailyWriting, cal, now) {
+				return err
+			}
+			if err := endStreak(store, progress, models.StreakDailyWriting, now); err != nil {
+				return err
+			}
+			ended++
+			return store.SaveStreaks(progress)
+		})
+		if err != nil {
+			return ended, err
+		}
+	}
+	return ended, nil
+}
+
+// RunBreakJob calls BreakStreaks every interval until ctx is cancelled.
+func RunBreakJob(ctx context.Context, service *Service, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ended, err := service.BreakStreaks()
+			if err != nil {
+				log.Printf("Error breaking streaks: %v", err)
+			}
+			if ended > 0 {
+				log.Printf("Ended %d broken streak(s).", ended)
+			}
+		}
+	}
+}
+
+// Streak is the state of one of a user's streaks.
+type Streak struct {
+	Kind    string `json:"kind"`
+	Current int    `json:"current"`
+	Longest int    `json:"longest"`
+	// StartedAt and LastUpdateAt are the first and the last write of the current streak.
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	LastUpdateAt *time.Time `json:"last_update_at,omitempty"`
+	// WrittenToday tells whether the streak already counts the current day.
+	WrittenToday bool `json:"written_today"`
+}
+
+// Streaks are a user's current streaks and the streaks they broke.
+type Streaks struct {
+	Timezone string                `json:"timezone"`
+	Freezes  int                   `json:"freezes"`
+	Streaks  []Streak              `json:"streaks"`
+	History  []models.StreakRecord `json:"history"`
+}
+
+// GetStreaks returns the user's current streaks and up to limit of the streaks they broke.
+// A streak broken since the streak job last ran is shown as broken already.
+func (s *Service) GetStreaks(userID string, limit int) (*Streaks, error) {
+	cal, err := calendar.For(s.calendars, userID)
+	if err != nil {
+		return nil, err
+	}
+	progress, err := s.store.GetProgress(userID)
+	if err != nil {
+		return nil, err
+	}
+	history, err := s.store.ListStreakRecords(userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	if history == nil {
+		history = []models.StreakRecord{}
+	}
+
+	result := &Streaks{Timezone: cal.Location.String(), Streaks: []Streak{dailyWriting(progress, cal, s.now())}, History: history}
+	if progress != nil {
+		result.Freezes = progress.StreakFreezes
+	}
+	return result, nil
+}
+
+// DailyWriting returns the state of the user's daily writing streak.
+func (s *Service) DailyWriting(userID string) (Streak, error) {
+	cal, err := calendar.For(s.calendars, userID)
+	if err != nil {
+		return Streak{}, err
+	}
+	progress, err := s.store.GetProgress(userID)
+	if err != nil {
+		return Streak{}, err
+	}
+	return dailyWriting(progress, cal, s.now()), nil
+}
+
+// dailyWriting returns the state of the daily writing streak in progress, which may be nil.
+// A streak broken since the streak job last ran is not current anymore.
+func dailyWriting(progress *models.UserProgress, cal calendar.Calendar, now time.Time) Streak {
+	const kind = models.StreakDailyWriting
+	streak := Streak{Kind: kind}
+	if progress == nil {
+		return streak
+	}
+	streak.Longest = progress.LongestStreaks[kind]
+	if progress.CurrentStreaks[kind] > 0 && !broken(progress, kind, cal, now) {
+		started, last := progress.StreakStartedAt[kind], progress.LastStreakUpdate[kind]
+		streak.Current = progress.CurrentStreaks[kind]
+		streak.StartedAt, streak.LastUpdateAt = &started, &last
+		streak.WrittenToday = cal.DaysBetween(last, now) == 0
+	}
+	return streak
+}
@@ -0,0 +1,87 @@
+package streak
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/calendar"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/database/databasetest"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/events"
+)
+
+// fixedCalendar gives every user the same calendar.
+type fixedCalendar calendar.Calendar
+
+func (c fixedCalendar) Calendar(string) (calendar.Calendar, error) { return calendar.Calendar(c), nil }
+
+func TestService_TracksStreaksInUserZone(t *testing.T) {
+	brt := time.FixedZone("BRT", -3*60*60)
+	svc := NewService(NewStore(databasetest.Open(t)), Config{MinWords: 10, FreezeEvery: 2, MaxFreezes: 1}, fixedCalendar{Location: brt})
+	local := func(day, hour int) time.Time { return time.Date(2026, 3, day, hour, 0, 0, 0, brt) }
+
+	write := func(at time.Time, wantExtended bool) {
+		t.Helper()
+		extended, err := svc.RecordWriting("user-1", at)
+		if err != nil {
+			t.Fatalf("RecordWriting(%s) error = %v", at, err)
+		}
+		if extended != wantExtended {
+			t.Errorf("RecordWriting(%s) = %v, want %v", at, extended, wantExtended)
+		}
+	}
+	write(local(1, 10), true)
+	// Already the 2nd in UTC, but still the 1st for the user.
+	write(local(1, 23), false)
+	// The second day earns a freeze, which bridges the 3rd.
+	write(local(2, 9), true)
+	write(local(4, 20), true)
+
+	svc.now = func() time.Time { return local(5, 23) }
+	if ended, err := svc.BreakStreaks(); err != nil || ended != 0 {
+		t.Fatalf("BreakStreaks() on the 5th = %d, %v; want the streak kept until the day is over", ended, err)
+	}
+	svc.now = func() time.Time { return local(6, 1) }
+	if ended, err := svc.BreakStreaks(); err != nil || ended != 1 {
+		t.Fatalf("BreakStreaks() on the 6th = %d, %v; want the streak ended", ended, err)
+	}
+	if ended, err := svc.BreakStreaks(); err != nil || ended != 0 {
+		t.Fatalf("second BreakStreaks() = %d, %v; want nothing left to end", ended, err)
+	}
+	write(local(7, 9), true)
+
+	svc.now = func() time.Time { return local(7, 10) }
+	got, err := svc.GetStreaks("user-1", 10)
+	if err != nil {
+		t.Fatalf("GetStreaks() error = %v", err)
+	}
+	streak := got.Streaks[0]
+	if got.Timezone != "BRT" || got.Freezes != 0 || streak.Current != 1 || streak.Longest != 3 || !streak.WrittenToday {
+		t.Errorf("streaks = %+v, %+v; want a new streak of 1 after a longest of 3", got, streak)
+	}
+	if len(got.History) != 1 || got.History[0].Length != 3 || !got.History[0].StartedAt.Equal(local(1, 10)) || !got.History[0].LastUpdateAt.Equal(local(4, 20)) {
+		t.Errorf("history = %+v, want the streak from the 1st to the 4th", got.History)
+	}
+
+	// Short saves do not count; level-ups earn a freeze.
+	bus := events.NewBus()
+	svc.Subscribe(bus)
+	var extended []events.Event
+	bus.Subscribe(func(event events.Event) error {
+		extended = append(extended, event)
+		return nil
+	}, events.StreakExtended)
+	bus.Publish(events.Event{Type: events.EntrySaved, UserID: "user-1", Words: 9, At: local(8, 9)})
+	bus.Publish(events.Event{Type: events.LevelUp, UserID: "user-1", Level: 2})
+	bus.Publish(events.Event{Type: events.EntrySaved, UserID: "user-1", Words: 10, At: local(9, 9)})
+	if got, err = svc.GetStreaks("user-1", 10); err != nil {
+		t.Fatalf("GetStreaks() error = %v", err)
+	}
+	// The second day of the new streak earns the freeze back.
+	if len(extended) != 1 || got.Streaks[0].Current != 2 || got.Freezes != 1 {
+		t.Errorf("after events: %d extension(s), streak %+v, %d freezes; want the 8th bridged by the level-up freeze", len(extended), got.Streaks[0], got.Freezes)
+	}
+	if got.Streaks[0].Kind != models.StreakDailyWriting {
+		t.Errorf("streak kind = %q", got.Streaks[0].Kind)
+	}
+}
@@ -0,0 +1,112 @@
+package streak
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/database"
+)
+
+// progressColumns are the columns of UserProgress the streak store reads and writes.
+// The PostgreSQL array of unlocked achievements is left alone.
+var progressColumns = []string{"id", "user_id", "points", "level", "current_streaks", "last_streak_update", "streak_started_at", "longest_streaks", "streak_freezes", "updated_at"}
+
+// streakColumns are the columns SaveStreaks writes.
+var streakColumns = []string{"current_streaks", "last_streak_update", "streak_started_at", "longest_streaks", "streak_freezes", "updated_at"}
+
+// gormStore is a GORM implementation of the Store interface.
+type gormStore struct {
+	db *gorm.DB
+}
+
+// NewStore creates a new GORM store for streaks.
+func NewStore(db *gorm.DB) Store {
+	return &gormStore{db: db}
+}
+
+func (s *gormStore) Transaction(fn func(store Store) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&gormStore{db: tx})
+	})
+}
+
+func (s *gormStore) GetProgress(userID string) (*models.UserProgress, error) {
+	var progress models.UserProgress
+	err := s.db.Select(progressColumns).Where("user_id = ?", userID).Take(&progress).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	initMaps(&progress)
+	return &progress, nil
+}
+
+// LockProgress returns the user's progress, created if the user has none yet, with none of its
+// streak maps nil. The progress stays locked until the transaction ends.
+func (s *gormStore) LockProgress(userID string) (*models.UserProgress, error) {
+	// Only the scalar columns are written; the array and JSON ones keep their defaults.
+	progress := map[string]interface{}{"id": uuid.NewString(), "user_id": userID, "points": 0, "updated_at": time.Now()}
+	if err := s.db.Model(&models.UserProgress{}).Clauses(clause.OnConflict{DoNothing: true}).Create(progress).Error; err != nil {
+		return nil, err
+	}
+	var locked models.UserProgress
+	if err := database.ForUpdate(s.db).Select(progressColumns).Where("user_id = ?", userID).Take(&locked).Error; err != nil {
+		return nil, err
+	}
+	initMaps(&locked)
+	return &locked, nil
+}
+
+func (s *gormStore) SaveStreaks(progress *models.UserProgress) error {
+	progress.UpdatedAt = time.Now()
+	return s.db.Model(progress).Select(streakColumns).Updates(progress).Error
+}
+
+// EachActiveProgress reads the progress of all users in batches; which streaks are active is
+// decided by fn, since the streak maps are JSON.
+func (s *gormStore) EachActiveProgress(fn func(progress *models.UserProgress) error) error {
+	var batch []models.UserProgress
+	return s.db.Select(progressColumns).Where("current_streaks IS NOT NULL").
+		FindInBatches(&batch, 100, func(tx *gorm.DB, _ int) error {
+			for i := range batch {
+				initMaps(&batch[i])
+				if err := fn(&batch[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+func (s *gormStore) CreateStreakRecord(record *models.StreakRecord) error {
+	return s.db.Create(record).Error
+}
+
+func (s *gormStore) ListStreakRecords(userID string, limit int) ([]models.StreakRecord, error) {
+	var records []models.StreakRecord
+	err := s.db.Where("user_id = ?", userID).Order("broken_at DESC").Limit(limit).Find(&records).Error
+	return records, err
+}
+
+// initMaps replaces the nil streak maps of a progress with empty ones.
+func initMaps(progress *models.UserProgress) {
+	if progress.CurrentStreaks == nil {
+		progress.CurrentStreaks = map[string]int{}
+	}
+	if progress.LastStreakUpdate == nil {
+		progress.LastStreakUpdate = map[string]time.Time{}
+	}
+	if progress.StreakStartedAt == nil {
+		progress.StreakStartedAt = map[string]time.Time{}
+	}
+	if progress.LongestStreaks == nil {
+		progress.LongestStreaks = map[string]int{}
+	}
+}
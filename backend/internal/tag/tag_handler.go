@@ -0,0 +1,164 @@
+package tag
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/adrianvalentim/gamify_journal/internal/auth"
+)
+
+// Handler handles the HTTP endpoints of tags.
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new tag handler.
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes sets up the routes for managing the user's tags.
+// Tags are attached to entries through the journal routes.
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Route("/tags", func(r chi.Router) {
+		r.Use(auth.AuthMiddleware)
+
+		r.Get("/", h.handleListTags)
+		r.Post("/", h.handleCreateTag)
+		r.Patch("/{tagId}", h.handleRenameTag)
+		r.Delete("/{tagId}", h.handleDeleteTag)
+		r.Post("/{tagId}/merge", h.handleMergeTag)
+	})
+}
+
+// handleListTags returns the authenticated user's tags with their usage counts.
+func (h *Handler) handleListTags(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	tags, err := h.service.ListTags(userID)
+	if err != nil {
+		log.Printf("Error listing tags of user %s: %v", userID, err)
+		http.Error(w, `{"error": "Failed to list tags"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tags)
+}
+
+func (h *Handler) handleCreateTag(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var input struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	tag, err := h.service.CreateTag(userID, input.Name)
+	if err != nil {
+		writeError(w, err, "Failed to create tag")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(tag)
+}
+
+// handleRenameTag renames a tag. A name the user already uses merges the two tags.
+func (h *Handler) handleRenameTag(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var input struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	tag, err := h.service.RenameTag(chi.URLParam(r, "tagId"), userID, input.Name)
+	if err != nil {
+		writeError(w, err, "Failed to rename tag")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tag)
+}
+
+func (h *Handler) handleDeleteTag(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.service.DeleteTag(chi.URLParam(r, "tagId"), userID); err != nil {
+		writeError(w, err, "Failed to delete tag")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleMergeTag moves the entries of a tag to the tag given as "into" and deletes it.
+func (h *Handler) handleMergeTag(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var input struct {
+		Into string `json:"into"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.Into == "" {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	tag, err := h.service.MergeTags(chi.URLParam(r, "tagId"), input.Into, userID)
+	if err != nil {
+		writeError(w, err, "Failed to merge tags")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tag)
+}
+
+// writeError maps service errors to HTTP responses; other errors are logged and reported with message.
+func writeError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, ErrTagNotFound):
+		http.Error(w, `{"error": "Tag not found"}`, http.StatusNotFound)
+	case errors.Is(err, ErrInvalidName):
+		http.Error(w, `{"error": "Tag names must be between 1 and 50 characters"}`, http.StatusBadRequest)
+	case errors.Is(err, ErrMergeSelf):
+		http.Error(w, `{"error": "A tag cannot be merged into itself"}`, http.StatusBadRequest)
+	case errors.Is(err, ErrTagExists):
+		http.Error(w, `{"error": "A tag with this name already exists"}`, http.StatusConflict)
+	default:
+		log.Printf("%s: %v", message, err)
+		http.Error(w, `{"error": "`+message+`"}`, http.StatusInternalServerError)
+	}
+}
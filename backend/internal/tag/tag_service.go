@@ -0,0 +1,209 @@
+package tag
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+)
+
+// MaxNameLength is the longest tag name, in characters.
+const MaxNameLength = 50
+
+var (
+	ErrTagNotFound = errors.New("tag not found")
+	ErrInvalidName = errors.New("tag names must be between 1 and 50 characters")
+	ErrTagExists   = errors.New("a tag with this name already exists")
+	ErrMergeSelf   = errors.New("a tag cannot be merged into itself")
+)
+
+// Usage is a tag with the number of journal entries it is attached to.
+type Usage struct {
+	models.Tag
+	EntryCount int `json:"entry_count"`
+}
+
+// Store defines the data access the tag service needs. Lookups are scoped by the owning user.
+type Store interface {
+	// ListTags returns the user's tags by name, with how many entries each is attached to.
+	ListTags(userID string) ([]Usage, error)
+	GetTag(id, userID string) (*models.Tag, error)
+	FindTagByName(userID, name string) (*models.Tag, error)
+	FindTags(userID string, names []string) ([]models.Tag, error)
+	// CreateTag returns gorm.ErrDuplicatedKey if the user already has a tag with that name.
+	CreateTag(tag *models.Tag) error
+	// CreateMissingTags creates the tags the user does not have yet.
+	CreateMissingTags(tags []models.Tag) error
+	RenameTag(tag *models.Tag) error
+	// DeleteTag removes a tag from all entries and deletes it.
+	DeleteTag(id, userID string) error
+	// MergeTags moves the entries of the source tag to the target tag and deletes the source.
+	MergeTags(sourceID, targetID string) error
+}
+
+// Service manages the tags of users.
+type Service struct {
+	store Store
+}
+
+// NewService creates a tag service.
+func NewService(store Store) *Service {
+	return &Service{store: store}
+}
+
+// NormalizeName returns the form in which a tag name is saved: lowercase, without a leading #,
+// and with runs of whitespace collapsed, so "#Road  Trip" and "road trip" are the same tag.
+func NormalizeName(name string) string {
+	name = strings.TrimPrefix(strings.TrimSpace(name), "#")
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// validName normalizes a tag name and checks its length.
+func validName(name string) (string, error) {
+	name = NormalizeName(name)
+	if name == "" || utf8.RuneCountInString(name) > MaxNameLength {
+		return "", ErrInvalidName
+	}
+	return name, nil
+}
+
+// ListTags returns the user's tags and how often each is used.
+func (s *Service) ListTags(userID string) ([]Usage, error) {
+	usages, err := s.store.ListTags(userID)
+	if usages == nil && err == nil {
+		usages = []Usage{}
+	}
+	return usages, err
+}
+
+// getTag loads a tag on behalf of userID. Tags of other users are reported as ErrTagNotFound.
+func (s *Service) getTag(id, userID string) (*models.Tag, error) {
+	tag, err := s.store.GetTag(id, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTagNotFound
+	}
+	return tag, err
+}
+
+// CreateTag creates a tag for the user.
+func (s *Service) CreateTag(userID, name string) (*models.Tag, error) {
+	name, err := validName(name)
+	if err != nil {
+		return nil, err
+	}
+	tag := &models.Tag{UserID: userID, Name: name}
+	if err := s.store.CreateTag(tag); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrTagExists
+		}
+		return nil, err
+	}
+	return tag, nil
+}
+
+// ResolveTags returns the user's tags with the given names, in the order of names, creating
+// those that do not exist yet. Repeated names are returned once.
+func (s *Service) ResolveTags(userID string, names []string) ([]models.Tag, error) {
+	var wanted []string
+	seen := map[string]bool{}
+	for _, name := range names {
+		name, err := validName(name)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[name] {
+			seen[name] = true
+			wanted = append(wanted, name)
+		}
+	}
+	if len(wanted) == 0 {
+		return []models.Tag{}, nil
+	}
+
+	missing := make([]models.Tag, len(wanted))
+	for i, name := range wanted {
+		missing[i] = models.Tag{UserID: userID, Name: name}
+	}
+	if err := s.store.CreateMissingTags(missing); err != nil {
+		return nil, err
+	}
+	found, err := s.store.FindTags(userID, wanted)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]models.Tag, len(found))
+	for _, tag := range found {
+		byName[tag.Name] = tag
+	}
+	tags := make([]models.Tag, 0, len(wanted))
+	for _, name := range wanted {
+		if tag, ok := byName[name]; ok {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+// RenameTag renames a tag of the user. Entries keep the tag under its new name. Renaming a tag
+// to the name of another of the user's tags merges it into that tag, which is returned.
+func (s *Service) RenameTag(id, userID, name string) (*models.Tag, error) {
+	name, err := validName(name)
+	if err != nil {
+		return nil, err
+	}
+	tag, err := s.getTag(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if tag.Name == name {
+		return tag, nil
+	}
+
+	existing, err := s.store.FindTagByName(userID, name)
+	switch {
+	case err == nil:
+		return s.MergeTags(id, existing.ID, userID)
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+
+	tag.Name = name
+	if err := s.store.RenameTag(tag); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrTagExists
+		}
+		return nil, err
+	}
+	return tag, nil
+}
+
+// MergeTags moves every entry tagged with the source tag to the target tag and deletes the
+// source tag. It returns the target tag.
+func (s *Service) MergeTags(sourceID, targetID, userID string) (*models.Tag, error) {
+	if sourceID == targetID {
+		return nil, ErrMergeSelf
+	}
+	if _, err := s.getTag(sourceID, userID); err != nil {
+		return nil, err
+	}
+	target, err := s.getTag(targetID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.MergeTags(sourceID, targetID); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// DeleteTag removes a tag of the user from all their entries and deletes it.
+func (s *Service) DeleteTag(id, userID string) error {
+	err := s.store.DeleteTag(id, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrTagNotFound
+	}
+	return err
+}
@@ -0,0 +1,94 @@
+package tag
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/database/databasetest"
+)
+
+func TestService_ManagesTags(t *testing.T) {
+	db := databasetest.Open(t)
+	for _, id := range []string{"user-1", "user-2"} {
+		if err := db.Create(&models.User{ID: id, Username: id, Email: id + "@example.com"}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	svc := NewService(NewStore(db))
+
+	trip, err := svc.CreateTag("user-1", "  #Road   Trip ")
+	if err != nil || trip.Name != "road trip" {
+		t.Fatalf("CreateTag() = %+v, %v; want the name normalized", trip, err)
+	}
+	if _, err := svc.CreateTag("user-1", "road trip"); !errors.Is(err, ErrTagExists) {
+		t.Errorf("CreateTag() of a taken name error = %v, want ErrTagExists", err)
+	}
+	if _, err := svc.CreateTag("user-2", "road trip"); err != nil {
+		t.Errorf("CreateTag() of another user's name error = %v", err)
+	}
+	if _, err := svc.CreateTag("user-1", "#"); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("CreateTag() of an empty name error = %v, want ErrInvalidName", err)
+	}
+
+	tags, err := svc.ResolveTags("user-1", []string{"Family", "road trip", "family"})
+	if err != nil {
+		t.Fatalf("ResolveTags() error = %v", err)
+	}
+	if len(tags) != 2 || tags[0].Name != "family" || tags[1].ID != trip.ID {
+		t.Fatalf("ResolveTags() = %+v, want a new family tag and the existing road trip tag", tags)
+	}
+	family := tags[0]
+
+	entries := []models.JournalEntry{
+		{ID: "doc-1", UserID: "user-1", Title: "Beach", Tags: []models.Tag{*trip, family}},
+		{ID: "doc-2", UserID: "user-1", Title: "Mountains", Tags: []models.Tag{*trip}},
+		{ID: "doc-3", UserID: "user-1", Title: "Dinner", Tags: []models.Tag{family}},
+	}
+	if err := db.Omit("Tags.*").Create(&entries).Error; err != nil {
+		t.Fatal(err)
+	}
+	usage := func() map[string]int {
+		t.Helper()
+		usages, err := svc.ListTags("user-1")
+		if err != nil {
+			t.Fatalf("ListTags() error = %v", err)
+		}
+		counts := map[string]int{}
+		for _, u := range usages {
+			counts[u.Name] = u.EntryCount
+		}
+		return counts
+	}
+	if got := usage(); len(got) != 2 || got["family"] != 2 || got["road trip"] != 2 {
+		t.Errorf("usage = %v, want both tags on two entries", got)
+	}
+
+	// Renaming onto an existing name merges, keeping doc-1 tagged once.
+	merged, err := svc.RenameTag(trip.ID, "user-1", "Family")
+	if err != nil || merged.ID != family.ID {
+		t.Fatalf("RenameTag() onto an existing name = %+v, %v; want the family tag", merged, err)
+	}
+	if got := usage(); len(got) != 1 || got["family"] != 3 {
+		t.Errorf("usage after the merge = %v, want family on three entries", got)
+	}
+	if _, err := svc.MergeTags(family.ID, family.ID, "user-1"); !errors.Is(err, ErrMergeSelf) {
+		t.Errorf("MergeTags() into itself error = %v, want ErrMergeSelf", err)
+	}
+
+	renamed, err := svc.RenameTag(family.ID, "user-1", "Home")
+	if err != nil || renamed.Name != "home" {
+		t.Fatalf("RenameTag() = %+v, %v", renamed, err)
+	}
+	if err := svc.DeleteTag(family.ID, "user-2"); !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("DeleteTag() by another user error = %v, want ErrTagNotFound", err)
+	}
+	if err := svc.DeleteTag(family.ID, "user-1"); err != nil {
+		t.Fatalf("DeleteTag() error = %v", err)
+	}
+	var links int64
+	db.Table("journal_entry_tags").Count(&links)
+	if got := usage(); len(got) != 0 || links != 0 {
+		t.Errorf("after DeleteTag(): usage %v and %d link(s), want none", got, links)
+	}
+}
@@ -0,0 +1,105 @@
+package tag
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+)
+
+// gormStore is a GORM implementation of the Store interface.
+type gormStore struct {
+	db *gorm.DB
+}
+
+// NewStore creates a new GORM store for tags.
+func NewStore(db *gorm.DB) Store {
+	return &gormStore{db: db}
+}
+
+func (s *gormStore) ListTags(userID string) ([]Usage, error) {
+	var usages []Usage
+	err := s.db.Model(&models.Tag{}).
+		Select("tags.*, COUNT(journal_entry_tags.journal_entry_id) AS entry_count").
+		Joins("LEFT JOIN journal_entry_tags ON journal_entry_tags.tag_id = tags.id").
+		Where("tags.user_id = ?", userID).
+		Group("tags.id").
+		Order("tags.name").
+		Scan(&usages).Error
+	return usages, err
+}
+
+func (s *gormStore) GetTag(id, userID string) (*models.Tag, error) {
+	var tag models.Tag
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).Take(&tag).Error; err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (s *gormStore) FindTagByName(userID, name string) (*models.Tag, error) {
+	var tag models.Tag
+	if err := s.db.Where("user_id = ? AND name = ?", userID, name).Take(&tag).Error; err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (s *gormStore) FindTags(userID string, names []string) ([]models.Tag, error) {
+	var tags []models.Tag
+	err := s.db.Where("user_id = ? AND name IN ?", userID, names).Find(&tags).Error
+	return tags, err
+}
+
+func (s *gormStore) CreateTag(tag *models.Tag) error {
+	return s.db.Create(tag).Error
+}
+
+// CreateMissingTags skips the tags whose name the user already has, so concurrent
+// requests creating the same tag both succeed.
+func (s *gormStore) CreateMissingTags(tags []models.Tag) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "name"}},
+		DoNothing: true,
+	}).Create(&tags).Error
+}
+
+func (s *gormStore) RenameTag(tag *models.Tag) error {
+	return s.db.Model(tag).Where("user_id = ?", tag.UserID).Update("name", tag.Name).Error
+}
+
+func (s *gormStore) DeleteTag(id, userID string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("journal_entry_tags").Where("tag_id = ?", id).Delete(nil).Error; err != nil {
+			return err
+		}
+		result := tx.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Tag{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// MergeTags attaches the target tag to every entry carrying the source tag, then deletes the source.
+func (s *gormStore) MergeTags(sourceID, targetID string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`INSERT INTO journal_entry_tags (journal_entry_id, tag_id)
+			SELECT journal_entry_id, ? FROM journal_entry_tags
+			WHERE tag_id = ? AND journal_entry_id NOT IN (SELECT journal_entry_id FROM journal_entry_tags WHERE tag_id = ?)`,
+			targetID, sourceID, targetID).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Table("journal_entry_tags").Where("tag_id = ?", sourceID).Delete(nil).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", sourceID).Delete(&models.Tag{}).Error
+	})
+}
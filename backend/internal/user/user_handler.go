@@ -3,11 +3,13 @@ package user
 import (
 	"encoding/json"
 	"errors"
+	"log"
 	"net/http"
 	"time" // Added for time formatting
 
 	"github.com/adrianvalentim/gamify_journal/internal/auth"
 	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/session"
 )
 
 // --- Request/Response Structs ---
@@ -25,6 +27,34 @@ type LoginUserRequest struct {
 	Password string `json:"password"`
 }
 
+// ChangePasswordRequest defines the expected payload for changing the password of the current user.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// DeleteAccountRequest defines the expected payload for deleting the current user's account.
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+// DeleteAccountResponse is sent when the account deletion is scheduled rather than immediate.
+type DeleteAccountResponse struct {
+	Status  string `json:"status"`
+	PurgeAt string `json:"purge_at"`
+}
+
+// ForgotPasswordRequest defines the expected payload for requesting a password reset email.
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest defines the expected payload for redeeming a password reset token.
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
 // UserResponse is the sanitized user data sent back to clients.
 // It omits sensitive information like the password hash.
 type UserResponse struct {
@@ -35,22 +65,29 @@ type UserResponse struct {
 	UpdatedAt string `json:"updated_at"` // Using string for consistent time format (RFC3339)
 }
 
-// AuthResponse is sent back on successful login, including user details and a token.
+// AuthResponse is sent back on successful login, including user details and the session tokens.
 type AuthResponse struct {
-	User  UserResponse `json:"user"`
-	Token string       `json:"token,omitempty"` // JWT token will be placed here
+	User UserResponse `json:"user"`
+	// Short-lived access token ("token") plus the refresh token to renew it.
+	*session.Tokens
 }
 
 // --- Handler ---
 
+// SessionStarter opens a server-side session for an authenticated user and issues its tokens.
+type SessionStarter interface {
+	Start(userID, userAgent, ipAddress string) (*session.Tokens, error)
+}
+
 // Handler holds dependencies for user HTTP handlers, like the user service.
 type Handler struct {
-	service Service // The user service interface
+	service  Service        // The user service interface
+	sessions SessionStarter // Issues tokens on login and registration
 }
 
 // NewHandler creates a new user Handler with the given service.
-func NewHandler(service Service) *Handler {
-	return &Handler{service: service}
+func NewHandler(service Service, sessions SessionStarter) *Handler {
+	return &Handler{service: service, sessions: sessions}
 }
 
 // --- Handler Functions ---
@@ -91,14 +128,14 @@ func (h *Handler) HandleRegisterUser(w http.ResponseWriter, r *http.Request) {
 
 	userResp := toUserResponse(user)
 
-	token, err := auth.GenerateToken(user.ID)
+	tokens, err := h.sessions.Start(user.ID, r.UserAgent(), r.RemoteAddr)
 	if err != nil {
 		// log.Printf("Failed to generate token for user %s: %v", user.ID, err) // Requires logger
 		respondWithError(w, http.StatusInternalServerError, "Failed to generate authentication token.")
 		return
 	}
 
-	respondWithJSON(w, http.StatusCreated, AuthResponse{User: userResp, Token: token})
+	respondWithJSON(w, http.StatusCreated, AuthResponse{User: userResp, Tokens: tokens})
 }
 
 func (h *Handler) HandleLoginUser(w http.ResponseWriter, r *http.Request) {
@@ -122,16 +159,15 @@ func (h *Handler) HandleLoginUser(w http.ResponseWriter, r *http.Request) {
 
 	userResp := toUserResponse(user)
 
-	// TODO: Implement JWT generation and return it in the AuthResponse
-	// For now, a placeholder token is used.
-	token, err := auth.GenerateToken(user.ID)
+	// Every login is a new session (device) with its own refresh token family.
+	tokens, err := h.sessions.Start(user.ID, r.UserAgent(), r.RemoteAddr)
 	if err != nil {
 		// log.Printf("Failed to generate token for user %s: %v", user.ID, err) // Requires logger
 		respondWithError(w, http.StatusInternalServerError, "Failed to generate authentication token.")
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, AuthResponse{User: userResp, Token: token})
+	respondWithJSON(w, http.StatusOK, AuthResponse{User: userResp, Tokens: tokens})
 }
 
 func (h *Handler) HandleGetMe(w http.ResponseWriter, r *http.Request) {
@@ -156,6 +192,134 @@ func (h *Handler) HandleGetMe(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusOK, userResp)
 }
 
+func (h *Handler) HandleChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User ID not found in context.")
+		return
+	}
+	sessionID, _ := r.Context().Value(auth.SessionIDKey).(string)
+
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		respondWithError(w, http.StatusBadRequest, "Missing required fields: current_password and new_password are required")
+		return
+	}
+
+	if err := h.service.ChangePassword(userID, sessionID, req.CurrentPassword, req.NewPassword); err != nil {
+		switch {
+		case errors.Is(err, ErrIncorrectPassword):
+			respondWithError(w, http.StatusForbidden, err.Error())
+		case errors.Is(err, ErrPasswordTooShort), errors.Is(err, ErrPasswordComplexity):
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, ErrUserNotFound):
+			respondWithError(w, http.StatusNotFound, err.Error())
+		default:
+			log.Printf("Error changing password for user %s: %v", userID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to change password.")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) HandleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	if req.Email == "" {
+		respondWithError(w, http.StatusBadRequest, "Missing required field: email")
+		return
+	}
+
+	// Always answer the same way, so the response does not reveal whether the account exists.
+	if err := h.service.RequestPasswordReset(req.Email); err != nil {
+		log.Printf("Error requesting password reset: %v", err)
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *Handler) HandleResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	if req.Token == "" || req.NewPassword == "" {
+		respondWithError(w, http.StatusBadRequest, "Missing required fields: token and new_password are required")
+		return
+	}
+
+	if err := h.service.ResetPassword(req.Token, req.NewPassword); err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidResetToken), errors.Is(err, ErrPasswordTooShort), errors.Is(err, ErrPasswordComplexity):
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		default:
+			log.Printf("Error resetting password: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to reset password.")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) HandleDeleteMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User ID not found in context.")
+		return
+	}
+
+	var req DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	if req.Password == "" {
+		respondWithError(w, http.StatusBadRequest, "Missing required field: password")
+		return
+	}
+
+	purgeAt, err := h.service.DeleteAccount(userID, req.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrIncorrectPassword):
+			respondWithError(w, http.StatusForbidden, err.Error())
+		case errors.Is(err, ErrUserNotFound):
+			respondWithError(w, http.StatusNotFound, err.Error())
+		default:
+			log.Printf("Error deleting account of user %s: %v", userID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to delete account.")
+		}
+		return
+	}
+
+	if purgeAt != nil {
+		respondWithJSON(w, http.StatusAccepted, DeleteAccountResponse{
+			Status:  "scheduled",
+			PurgeAt: purgeAt.UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // toUserResponse converts a models.User to a UserResponse, ensuring consistent formatting.
 func toUserResponse(user *models.User) UserResponse {
 	return UserResponse{
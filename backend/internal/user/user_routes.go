@@ -8,10 +8,14 @@ import (
 func (h *Handler) RegisterRoutes(r chi.Router) {
 	r.Post("/register", h.HandleRegisterUser)
 	r.Post("/login", h.HandleLoginUser)
+	r.Post("/password/forgot", h.HandleForgotPassword)
+	r.Post("/password/reset", h.HandleResetPassword)
 
 	r.Group(func(r chi.Router) {
 		r.Use(auth.AuthMiddleware)
 
 		r.Get("/users/me", h.HandleGetMe)
+		r.Delete("/users/me", h.HandleDeleteMe)
+		r.Post("/users/me/password", h.HandleChangePassword)
 	})
 }
@@ -1,15 +1,27 @@
 package user
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"log"
+	"net/url"
+	"os"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
 
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/mailer"
 )
 
 // Pre-defined error variables for common user service issues.
@@ -21,6 +33,8 @@ var (
 	ErrPasswordTooShort   = errors.New("password must be at least 8 characters long")
 	ErrPasswordComplexity = errors.New("password must contain at least one uppercase letter, one lowercase letter, and one digit")
 	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrIncorrectPassword  = errors.New("current password is incorrect")
+	ErrInvalidResetToken  = errors.New("password reset token is invalid or has expired")
 	ErrValidation         = errors.New("validation failed") // Generic validation error
 )
 
@@ -31,22 +45,55 @@ type Service interface {
 	GetUserByEmail(email string) (*models.User, error)
 	AuthenticateUser(email, password string) (*models.User, error)
 	// UpdateUserProfile(userID string, updates map[string]interface{}) (*models.User, error)
-	// ChangePassword(userID, oldPassword, newPassword string) error
+	ChangePassword(userID, sessionID, currentPassword, newPassword string) error
+	RequestPasswordReset(email string) error
+	ResetPassword(token, newPassword string) error
+	DeleteAccount(userID, password string) (*time.Time, error)
+	PurgeDeletedAccounts() (int, error)
+	// Wait blocks until the password reset emails being sent in the background are sent.
+	Wait()
+}
+
+// SessionRevoker ends a user's sessions when their password changes.
+type SessionRevoker interface {
+	RevokeAllForUser(userID, exceptSessionID string) error
 }
 
 // service implements the Service interface for user operations.
 type service struct {
-	store Store // Dependency on the Store interface for data persistence
+	store       Store           // Dependency on the Store interface for data persistence
+	resetTokens ResetTokenStore // Persists password reset tokens
+	mailer      mailer.Mailer   // Delivers password reset links
+	sessions    SessionRevoker  // Revokes sessions after a password change
+	resetURL    string          // Frontend page that redeems reset tokens
+	gracePeriod time.Duration   // How long deleted accounts are kept before being purged
+	now         func() time.Time
+	resets      sync.WaitGroup // Password resets being prepared and mailed in the background
 }
 
 // NewService creates and returns a new user service instance.
-func NewService(store Store) Service {
-	return &service{store: store}
+func NewService(store Store, resetTokens ResetTokenStore, mailer mailer.Mailer, sessions SessionRevoker) Service {
+	resetURL := os.Getenv("PASSWORD_RESET_URL")
+	if resetURL == "" {
+		resetURL = defaultPasswordResetURL
+	}
+	return &service{
+		store:       store,
+		resetTokens: resetTokens,
+		mailer:      mailer,
+		sessions:    sessions,
+		resetURL:    resetURL,
+		gracePeriod: gracePeriodFromEnv(),
+		now:         time.Now,
+	}
 }
 
 const (
 	minPasswordLength = 8
 	bcryptCost        = bcrypt.DefaultCost // Or a higher cost like 12-14 for better security
+
+	resetTokenTTL           = time.Hour
+	defaultPasswordResetURL = "http://localhost:3000/reset-password"
 )
 
 // emailRegex is a basic regex for email validation.
@@ -65,6 +112,29 @@ func (s *service) checkPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
+// validatePassword enforces the length and complexity rules described by
+// ErrPasswordTooShort and ErrPasswordComplexity.
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength {
+		return ErrPasswordTooShort
+	}
+	var hasUpper, hasLower, hasDigit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		}
+	}
+	if !hasUpper || !hasLower || !hasDigit {
+		return ErrPasswordComplexity
+	}
+	return nil
+}
+
 func (s *service) sanitizeAndValidateEmail(email string) (string, error) {
 	saneEmail := strings.ToLower(strings.TrimSpace(email))
 	if !emailRegex.MatchString(saneEmail) {
@@ -186,3 +256,230 @@ func (s *service) AuthenticateUser(email, password string) (*models.User, error)
 	user.HashedPassword = ""
 	return user, nil
 }
+
+// ChangePassword sets a new password after verifying the current one.
+// Every other session of the user is revoked; sessionID (the caller's own) stays signed in.
+func (s *service) ChangePassword(userID, sessionID, currentPassword, newPassword string) error {
+	user, err := s.store.GetByID(userID)
+	if err != nil {
+		return fmt.Errorf("store error fetching user by ID: %w", err)
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+
+	if !s.checkPasswordHash(currentPassword, user.HashedPassword) {
+		return ErrIncorrectPassword
+	}
+	if err := validatePassword(newPassword); err != nil {
+		return err
+	}
+
+	hashedPassword, err := s.hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	user.HashedPassword = hashedPassword
+	if err := s.store.Update(user); err != nil {
+		return fmt.Errorf("could not update password: %w", err)
+	}
+
+	if err := s.sessions.RevokeAllForUser(userID, sessionID); err != nil {
+		return fmt.Errorf("could not revoke sessions: %w", err)
+	}
+	return nil
+}
+
+// RequestPasswordReset emails a single-use reset link to the account with the given email.
+// Unknown emails are silently ignored so the endpoint cannot be used to discover accounts.
+// The link is created and mailed in the background, so known and unknown emails take as long
+// to answer.
+func (s *service) RequestPasswordReset(email string) error {
+	saneEmail, err := s.sanitizeAndValidateEmail(email)
+	if err != nil {
+		return nil
+	}
+
+	user, err := s.store.GetByEmail(saneEmail)
+	if err != nil {
+		return fmt.Errorf("store error fetching user by email: %w", err)
+	}
+	if user == nil {
+		return nil
+	}
+
+	s.resets.Add(1)
+	go func() {
+		defer s.resets.Done()
+		if err := s.sendPasswordReset(user); err != nil {
+			log.Printf("Error sending password reset to user %s: %v", user.ID, err)
+		}
+	}()
+	return nil
+}
+
+func (s *service) Wait() {
+	s.resets.Wait()
+}
+
+// sendPasswordReset stores a new reset token for user and emails them the link redeeming it.
+func (s *service) sendPasswordReset(user *models.User) error {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return fmt.Errorf("could not generate reset token: %w", err)
+	}
+	plain := base64.RawURLEncoding.EncodeToString(raw)
+
+	now := s.now()
+	token := &models.PasswordResetToken{
+		ID:        uuid.NewString(),
+		UserID:    user.ID,
+		TokenHash: hashResetToken(plain),
+		ExpiresAt: now.Add(resetTokenTTL),
+		CreatedAt: now,
+	}
+	if err := s.resetTokens.CreateResetToken(token); err != nil {
+		return fmt.Errorf("could not store reset token: %w", err)
+	}
+
+	link := s.resetURL + "?token=" + url.QueryEscape(plain)
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in %s and can only be used once.\n\n%s\n\nIf you did not ask for a password reset, you can ignore this email.\n",
+			user.Username, resetTokenTTL, link),
+	}
+	if err := s.mailer.Send(msg); err != nil {
+		return fmt.Errorf("could not send reset email: %w", err)
+	}
+	return nil
+}
+
+// ResetPassword redeems a reset token, sets the new password and revokes every session of the user.
+func (s *service) ResetPassword(token, newPassword string) error {
+	if token == "" {
+		return ErrInvalidResetToken
+	}
+
+	record, err := s.resetTokens.GetResetToken(hashResetToken(token))
+	if err != nil {
+		return fmt.Errorf("store error fetching reset token: %w", err)
+	}
+	now := s.now()
+	if record == nil || record.UsedAt != nil || !now.Before(record.ExpiresAt) {
+		return ErrInvalidResetToken
+	}
+
+	if err := validatePassword(newPassword); err != nil {
+		return err
+	}
+	hashedPassword, err := s.hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := s.resetTokens.RedeemResetToken(record.ID, record.UserID, hashedPassword, now); err != nil {
+		if errors.Is(err, ErrInvalidResetToken) {
+			return err
+		}
+		return fmt.Errorf("could not reset password: %w", err)
+	}
+
+	if err := s.sessions.RevokeAllForUser(record.UserID, ""); err != nil {
+		return fmt.Errorf("could not revoke sessions: %w", err)
+	}
+	return nil
+}
+
+// hashResetToken returns the hex-encoded SHA-256 of a reset token.
+// Tokens are high-entropy random values, so a fast hash is sufficient.
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// DeleteAccount deletes the user's account after re-confirming their password.
+// Without a grace period all data is removed immediately and the returned time is nil.
+// Otherwise the account is disabled, its sessions are revoked, and the returned time
+// is when the purge job will remove it.
+func (s *service) DeleteAccount(userID, password string) (*time.Time, error) {
+	user, err := s.store.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("store error fetching user by ID: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if !s.checkPasswordHash(password, user.HashedPassword) {
+		return nil, ErrIncorrectPassword
+	}
+
+	if s.gracePeriod <= 0 {
+		if err := s.store.Delete(userID); err != nil {
+			return nil, fmt.Errorf("could not delete account: %w", err)
+		}
+		return nil, nil
+	}
+
+	now := s.now()
+	if err := s.store.SoftDelete(userID, now); err != nil {
+		return nil, fmt.Errorf("could not schedule account deletion: %w", err)
+	}
+	if err := s.sessions.RevokeAllForUser(userID, ""); err != nil {
+		return nil, fmt.Errorf("could not revoke sessions: %w", err)
+	}
+	purgeAt := now.Add(s.gracePeriod)
+	return &purgeAt, nil
+}
+
+// PurgeDeletedAccounts permanently removes accounts whose grace period has ended.
+// It returns how many accounts were purged.
+func (s *service) PurgeDeletedAccounts() (int, error) {
+	ids, err := s.store.ListDeletedBefore(s.now().Add(-s.gracePeriod))
+	if err != nil {
+		return 0, fmt.Errorf("could not list deleted accounts: %w", err)
+	}
+	purged := 0
+	for _, id := range ids {
+		if err := s.store.Delete(id); err != nil {
+			return purged, fmt.Errorf("could not purge account %s: %w", id, err)
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// RunPurgeJob calls PurgeDeletedAccounts every interval until ctx is cancelled.
+func RunPurgeJob(ctx context.Context, service Service, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := service.PurgeDeletedAccounts()
+			if err != nil {
+				log.Printf("Error purging deleted accounts: %v", err)
+			}
+			if purged > 0 {
+				log.Printf("Purged %d deleted account(s).", purged)
+			}
+		}
+	}
+}
+
+// gracePeriodFromEnv reads ACCOUNT_DELETION_GRACE_PERIOD (e.g., "720h").
+// Zero, the default, deletes accounts immediately.
+func gracePeriodFromEnv() time.Duration {
+	value := os.Getenv("ACCOUNT_DELETION_GRACE_PERIOD")
+	if value == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		log.Printf("Warning: invalid ACCOUNT_DELETION_GRACE_PERIOD value %q. Deleting accounts immediately.", value)
+		return 0
+	}
+	return d
+}
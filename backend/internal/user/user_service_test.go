@@ -3,11 +3,15 @@ package user
 import (
 	"errors"
 	"fmt"
+	"net/url"
 	"regexp" // For matching UUIDs
 	"testing"
-	// "time" // Not directly used in this initial set of tests, but might be for CreatedAt/UpdatedAt checks
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
 
 	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/mailer"
 	// We don't import bcrypt here directly for tests if we trust our hashPassword,
 	// but for mockStore, we might need to simulate password checking if we were testing AuthenticateUser more deeply.
 )
@@ -19,6 +23,9 @@ type mockUserStore struct {
 	GetByEmailFunc    func(email string) (*models.User, error)
 	GetByUsernameFunc func(username string) (*models.User, error)
 	UpdateFunc        func(user *models.User) error
+	DeleteFunc        func(id string) error
+	SoftDeleteFunc    func(id string, at time.Time) error
+	ListDeletedFunc   func(cutoff time.Time) ([]string, error)
 }
 
 // Implement the Store interface for mockUserStore
@@ -58,12 +65,33 @@ func (m *mockUserStore) Update(user *models.User) error {
 	return fmt.Errorf("UpdateFunc not implemented in mockUserStore for this test")
 }
 
+func (m *mockUserStore) Delete(id string) error {
+	if m.DeleteFunc != nil {
+		return m.DeleteFunc(id)
+	}
+	return fmt.Errorf("DeleteFunc not implemented in mockUserStore for this test")
+}
+
+func (m *mockUserStore) SoftDelete(id string, at time.Time) error {
+	if m.SoftDeleteFunc != nil {
+		return m.SoftDeleteFunc(id, at)
+	}
+	return fmt.Errorf("SoftDeleteFunc not implemented in mockUserStore for this test")
+}
+
+func (m *mockUserStore) ListDeletedBefore(cutoff time.Time) ([]string, error) {
+	if m.ListDeletedFunc != nil {
+		return m.ListDeletedFunc(cutoff)
+	}
+	return nil, fmt.Errorf("ListDeletedFunc not implemented in mockUserStore for this test")
+}
+
 // uuidRegex helps validate that an ID string is in UUID format.
 var uuidRegex = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
 
 func TestUserService_RegisterUser_Success(t *testing.T) {
 	mockStore := &mockUserStore{}
-	userService := NewService(mockStore) // userService is of type Service (interface)
+	userService := NewService(mockStore, nil, nil, nil) // userService is of type Service (interface)
 
 	username := "testuser"
 	email := "test@example.com"
@@ -135,7 +163,7 @@ func TestUserService_RegisterUser_Success(t *testing.T) {
 // TestUserService_RegisterUser_EmailTaken tests the scenario where the email is already in use.
 func TestUserService_RegisterUser_EmailTaken(t *testing.T) {
 	mockStore := &mockUserStore{}
-	userService := NewService(mockStore)
+	userService := NewService(mockStore, nil, nil, nil)
 
 	username := "newuser"
 	email := "taken@example.com"
@@ -165,7 +193,7 @@ func TestUserService_RegisterUser_EmailTaken(t *testing.T) {
 // TestUserService_RegisterUser_UsernameTaken tests username already in use.
 func TestUserService_RegisterUser_UsernameTaken(t *testing.T) {
 	mockStore := &mockUserStore{}
-	userService := NewService(mockStore)
+	userService := NewService(mockStore, nil, nil, nil)
 
 	username := "takenUser"
 	email := "unique@example.com"
@@ -213,4 +241,226 @@ func TestUserService_RegisterUser_UsernameTaken(t *testing.T) {
 // TODO: Add tests for GetUserByEmail:
 // - Success
 // - UserNotFound
-// - Store GetByEmail returning an error
\ No newline at end of file
+// - Store GetByEmail returning an error
+
+func TestValidatePassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		wantErr  error
+	}{
+		{"valid", "ValidPass123", nil},
+		{"too short", "Ab1", ErrPasswordTooShort},
+		{"no uppercase", "validpass123", ErrPasswordComplexity},
+		{"no lowercase", "VALIDPASS123", ErrPasswordComplexity},
+		{"no digit", "ValidPassword", ErrPasswordComplexity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validatePassword(tt.password); !errors.Is(err, tt.wantErr) {
+				t.Errorf("validatePassword(%q) = %v, want %v", tt.password, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// memoryResetTokenStore is an in-memory ResetTokenStore that applies redeemed passwords to users.
+type memoryResetTokenStore struct {
+	tokens map[string]*models.PasswordResetToken // keyed by token hash
+	users  map[string]*models.User
+}
+
+func (m *memoryResetTokenStore) CreateResetToken(token *models.PasswordResetToken) error {
+	m.tokens[token.TokenHash] = token
+	return nil
+}
+
+func (m *memoryResetTokenStore) GetResetToken(tokenHash string) (*models.PasswordResetToken, error) {
+	token, ok := m.tokens[tokenHash]
+	if !ok {
+		return nil, nil
+	}
+	copied := *token
+	return &copied, nil
+}
+
+func (m *memoryResetTokenStore) RedeemResetToken(tokenID, userID, hashedPassword string, at time.Time) error {
+	for _, token := range m.tokens {
+		if token.ID == tokenID && token.UsedAt != nil {
+			return ErrInvalidResetToken
+		}
+	}
+	for _, token := range m.tokens {
+		if token.UserID == userID && token.UsedAt == nil {
+			token.UsedAt = &at
+		}
+	}
+	m.users[userID].HashedPassword = hashedPassword
+	return nil
+}
+
+// recordingMailer keeps every message it is asked to send.
+type recordingMailer struct {
+	sent []mailer.Message
+}
+
+func (m *recordingMailer) Send(msg mailer.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// recordingRevoker records RevokeAllForUser calls.
+type recordingRevoker struct {
+	calls [][2]string // userID, exceptSessionID
+}
+
+func (r *recordingRevoker) RevokeAllForUser(userID, exceptSessionID string) error {
+	r.calls = append(r.calls, [2]string{userID, exceptSessionID})
+	return nil
+}
+
+// newPasswordTestService wires a service around a single stored user with the given password.
+func newPasswordTestService(t *testing.T, password string) (*service, *models.User, *memoryResetTokenStore, *recordingMailer, *recordingRevoker) {
+	t.Helper()
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hashing password: %v", err)
+	}
+	user := &models.User{ID: "user-1", Username: "hero", Email: "hero@example.com", HashedPassword: string(hashed)}
+
+	store := &mockUserStore{
+		GetByIDFunc: func(id string) (*models.User, error) {
+			if id != user.ID {
+				return nil, nil
+			}
+			copied := *user
+			return &copied, nil
+		},
+		GetByEmailFunc: func(email string) (*models.User, error) {
+			if email != user.Email {
+				return nil, nil
+			}
+			copied := *user
+			return &copied, nil
+		},
+		UpdateFunc: func(u *models.User) error {
+			user.HashedPassword = u.HashedPassword
+			return nil
+		},
+	}
+	resetTokens := &memoryResetTokenStore{
+		tokens: map[string]*models.PasswordResetToken{},
+		users:  map[string]*models.User{user.ID: user},
+	}
+	mail := &recordingMailer{}
+	revoker := &recordingRevoker{}
+
+	svc := NewService(store, resetTokens, mail, revoker).(*service)
+	return svc, user, resetTokens, mail, revoker
+}
+
+func TestUserService_ChangePassword(t *testing.T) {
+	t.Run("wrong current password", func(t *testing.T) {
+		svc, _, _, _, revoker := newPasswordTestService(t, "OldPass123")
+
+		err := svc.ChangePassword("user-1", "session-1", "NotMyPass1", "NewPass456")
+		if !errors.Is(err, ErrIncorrectPassword) {
+			t.Fatalf("ChangePassword() error = %v, want %v", err, ErrIncorrectPassword)
+		}
+		if len(revoker.calls) != 0 {
+			t.Errorf("sessions revoked after a failed change: %v", revoker.calls)
+		}
+	})
+
+	t.Run("weak new password", func(t *testing.T) {
+		svc, _, _, _, _ := newPasswordTestService(t, "OldPass123")
+
+		err := svc.ChangePassword("user-1", "session-1", "OldPass123", "weakpass")
+		if !errors.Is(err, ErrPasswordComplexity) {
+			t.Fatalf("ChangePassword() error = %v, want %v", err, ErrPasswordComplexity)
+		}
+	})
+
+	t.Run("success keeps the current session", func(t *testing.T) {
+		svc, user, _, _, revoker := newPasswordTestService(t, "OldPass123")
+
+		if err := svc.ChangePassword("user-1", "session-1", "OldPass123", "NewPass456"); err != nil {
+			t.Fatalf("ChangePassword() error = %v", err)
+		}
+		if !svc.checkPasswordHash("NewPass456", user.HashedPassword) {
+			t.Error("stored hash does not match the new password")
+		}
+		if len(revoker.calls) != 1 || revoker.calls[0] != [2]string{"user-1", "session-1"} {
+			t.Errorf("revoke calls = %v, want one call revoking all but session-1", revoker.calls)
+		}
+	})
+}
+
+func TestUserService_PasswordResetFlow(t *testing.T) {
+	svc, user, _, mail, revoker := newPasswordTestService(t, "OldPass123")
+
+	if err := svc.RequestPasswordReset("unknown@example.com"); err != nil {
+		t.Fatalf("RequestPasswordReset(unknown) error = %v", err)
+	}
+	svc.Wait()
+	if len(mail.sent) != 0 {
+		t.Fatalf("email sent for an unknown account: %+v", mail.sent)
+	}
+
+	if err := svc.RequestPasswordReset("  Hero@Example.com "); err != nil {
+		t.Fatalf("RequestPasswordReset() error = %v", err)
+	}
+	svc.Wait()
+	if len(mail.sent) != 1 || mail.sent[0].To != user.Email {
+		t.Fatalf("sent = %+v, want one email to %s", mail.sent, user.Email)
+	}
+	token := resetTokenFromBody(t, mail.sent[0].Body)
+
+	if err := svc.ResetPassword(token, "short"); !errors.Is(err, ErrPasswordTooShort) {
+		t.Fatalf("ResetPassword(weak) error = %v, want %v", err, ErrPasswordTooShort)
+	}
+	if err := svc.ResetPassword(token, "NewPass456"); err != nil {
+		t.Fatalf("ResetPassword() error = %v", err)
+	}
+	if !svc.checkPasswordHash("NewPass456", user.HashedPassword) {
+		t.Error("stored hash does not match the new password")
+	}
+	if len(revoker.calls) != 1 || revoker.calls[0] != [2]string{"user-1", ""} {
+		t.Errorf("revoke calls = %v, want one call revoking every session", revoker.calls)
+	}
+
+	if err := svc.ResetPassword(token, "OtherPass789"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("second ResetPassword() error = %v, want %v", err, ErrInvalidResetToken)
+	}
+}
+
+func TestUserService_ResetPassword_Expired(t *testing.T) {
+	svc, _, _, mail, _ := newPasswordTestService(t, "OldPass123")
+
+	if err := svc.RequestPasswordReset("hero@example.com"); err != nil {
+		t.Fatalf("RequestPasswordReset() error = %v", err)
+	}
+	svc.Wait()
+	token := resetTokenFromBody(t, mail.sent[0].Body)
+
+	svc.now = func() time.Time { return time.Now().Add(resetTokenTTL + time.Minute) }
+	if err := svc.ResetPassword(token, "NewPass456"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("ResetPassword() error = %v, want %v", err, ErrInvalidResetToken)
+	}
+}
+
+// resetTokenFromBody extracts the token query parameter from the reset link in an email body.
+func resetTokenFromBody(t *testing.T, body string) string {
+	t.Helper()
+	match := regexp.MustCompile(`token=(\S+)`).FindStringSubmatch(body)
+	if match == nil {
+		t.Fatalf("no reset link in email body: %q", body)
+	}
+	token, err := url.QueryUnescape(match[1])
+	if err != nil {
+		t.Fatalf("unescaping token: %v", err)
+	}
+	return token
+}
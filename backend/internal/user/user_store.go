@@ -2,6 +2,7 @@ package user
 
 import (
 	"errors"
+	"time"
 
 	"gorm.io/gorm"
 
@@ -17,7 +18,18 @@ type Store interface {
 	GetByEmail(email string) (*models.User, error)
 	GetByUsername(username string) (*models.User, error)
 	Update(user *models.User) error
-	// Delete(id string) error // To be added if/when deletion functionality is required
+	Delete(id string) error
+	SoftDelete(id string, at time.Time) error
+	ListDeletedBefore(cutoff time.Time) ([]string, error)
+}
+
+// ResetTokenStore defines storage operations for password reset tokens.
+type ResetTokenStore interface {
+	CreateResetToken(token *models.PasswordResetToken) error
+	GetResetToken(tokenHash string) (*models.PasswordResetToken, error)
+	// RedeemResetToken marks the token as used and sets the user's new password hash atomically.
+	// It returns ErrInvalidResetToken if the token was already used.
+	RedeemResetToken(tokenID, userID, hashedPassword string, at time.Time) error
 }
 
 // GormStore implements the Store interface using GORM for database interactions.
@@ -88,4 +100,129 @@ func (s *GormStore) GetByUsername(username string) (*models.User, error) {
 // For partial updates, s.db.Model(user).Updates(changesMap) would be more appropriate.
 func (s *GormStore) Update(user *models.User) error {
 	return s.db.Save(user).Error
-} 
\ No newline at end of file
+}
+
+// Delete permanently removes a user and everything they own in a single transaction:
+// journal entries with their revisions and tag links, folders, quests, character and its rewards, progress, sessions,
+// refresh tokens and password reset tokens. Shared rows (tags, achievements) are kept.
+// Soft-deleted users are included, so this is also used by the purge job.
+func (s *GormStore) Delete(id string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		entryIDs := tx.Model(&models.JournalEntry{}).Select("id").Where("user_id = ?", id)
+		if err := tx.Table("journal_entry_tags").Where("journal_entry_id IN (?)", entryIDs).Delete(nil).Error; err != nil {
+			return err
+		}
+
+		sessionIDs := tx.Model(&models.Session{}).Select("id").Where("user_id = ?", id)
+		if err := tx.Where("session_id IN (?)", sessionIDs).Delete(&models.RefreshToken{}).Error; err != nil {
+			return err
+		}
+
+		owned := []interface{}{
+			&models.JournalRevision{},
+			&models.ScoredParagraph{},
+			&models.Job{},
+			&models.JournalEntry{},
+			&models.Tag{},
+			&models.Folder{},
+			&models.Quest{},
+			&models.XPTransaction{},
+			&models.CharacterReward{},
+			&models.Character{},
+			&models.UserAchievement{},
+			&models.UserProgress{},
+			&models.StreakRecord{},
+			&models.UserSettings{},
+			&models.Session{},
+			&models.PasswordResetToken{},
+		}
+		for _, model := range owned {
+			// Unscoped so soft-deleted rows (e.g. folders) are removed as well.
+			if err := tx.Unscoped().Where("user_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		result := tx.Unscoped().Where("id = ?", id).Delete(&models.User{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// SoftDelete marks a user as deleted without removing their data.
+// The user can no longer be found or log in; Delete purges the data later.
+// The email and username are replaced by placeholders derived from the ID, so both can be
+// registered again during the grace period.
+func (s *GormStore) SoftDelete(id string, at time.Time) error {
+	result := s.db.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"deleted_at": at,
+		"email":      id + "@deleted.invalid",
+		"username":   "deleted-" + id,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListDeletedBefore returns the IDs of users soft-deleted before cutoff.
+func (s *GormStore) ListDeletedBefore(cutoff time.Time) ([]string, error) {
+	var ids []string
+	err := s.db.Unscoped().Model(&models.User{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// CreateResetToken inserts a new password reset token.
+func (s *GormStore) CreateResetToken(token *models.PasswordResetToken) error {
+	return s.db.Create(token).Error
+}
+
+// GetResetToken retrieves a password reset token by the hash of its value.
+// Returns (nil, nil) if the record is not found.
+func (s *GormStore) GetResetToken(tokenHash string) (*models.PasswordResetToken, error) {
+	var token models.PasswordResetToken
+	if err := s.db.Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// RedeemResetToken consumes a reset token and updates the user's password in one transaction.
+// Any other outstanding reset tokens of the user are invalidated as well.
+func (s *GormStore) RedeemResetToken(tokenID, userID, hashedPassword string, at time.Time) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// The used_at condition makes concurrent redemptions of the same token race-safe.
+		result := tx.Model(&models.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL", tokenID).
+			Update("used_at", at)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidResetToken
+		}
+
+		if err := tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", userID).
+			Update("used_at", at).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.User{}).
+			Where("id = ?", userID).
+			Update("hashed_password", hashedPassword).Error
+	})
+}
@@ -0,0 +1,235 @@
+package user
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	"github.com/adrianvalentim/gamify_journal/internal/models"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/database"
+	"github.com/adrianvalentim/gamify_journal/internal/platform/database/databasetest"
+)
+
+// seedAccount creates a user owning a row in every user-scoped table.
+func seedAccount(t *testing.T, db *gorm.DB, userID string) {
+	t.Helper()
+
+	now := time.Now()
+	parentID := "folder-" + userID + "-parent"
+	deletedFolder := &models.Folder{ID: "folder-" + userID + "-deleted", Name: "Old", UserID: userID, ParentID: &parentID}
+	character := &models.Character{UserID: userID, Name: "Hero", Class: string(models.Warrior)}
+	tag := models.Tag{ID: "tag-" + userID, UserID: userID, Name: "travel"}
+	records := []interface{}{
+		&models.User{ID: userID, Username: userID, Email: userID + "@example.com", HashedPassword: "hash"},
+		&models.Folder{ID: parentID, Name: "Adventures", UserID: userID},
+		&models.Folder{ID: "folder-" + userID + "-child", Name: "Dungeons", UserID: userID, ParentID: &parentID},
+		deletedFolder,
+		&models.JournalEntry{ID: "entry-" + userID, UserID: userID, Title: "Day one", FolderID: &parentID, Tags: []models.Tag{tag}},
+		&models.JournalRevision{ID: "revision-" + userID, EntryID: "entry-" + userID, UserID: userID, Number: 1, Title: "Day one"},
+		&models.ScoredParagraph{ID: "paragraph-" + userID, EntryID: "entry-" + userID, UserID: userID, Hash: "hash"},
+		&models.Job{Kind: "test", UserID: userID, Status: models.JobPending, RunAt: now},
+		&models.Quest{UserID: userID, Title: "Write daily"},
+		character,
+		&models.Session{ID: "session-" + userID, UserID: userID, CreatedAt: now, LastUsedAt: now, ExpiresAt: now.Add(time.Hour)},
+		&models.RefreshToken{ID: "refresh-" + userID, SessionID: "session-" + userID, TokenHash: "refresh-hash-" + userID, ExpiresAt: now.Add(time.Hour)},
+		&models.PasswordResetToken{ID: "reset-" + userID, UserID: userID, TokenHash: "reset-hash-" + userID, ExpiresAt: now.Add(time.Hour)},
+		&models.UserSettings{UserID: userID, Timezone: "America/Sao_Paulo", Locale: models.LocalePortugueseBrazilian, WeekStart: "sunday"},
+		&models.StreakRecord{ID: "streak-" + userID, UserID: userID, Kind: models.StreakDailyWriting, Length: 3, StartedAt: now.AddDate(0, 0, -5), LastUpdateAt: now.AddDate(0, 0, -3), BrokenAt: now},
+	}
+	for _, record := range records {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatalf("seeding %T: %v", record, err)
+		}
+	}
+	if err := db.Delete(deletedFolder).Error; err != nil {
+		t.Fatalf("soft-deleting folder: %v", err)
+	}
+	// The character's ID is only known once it was created.
+	xp := &models.XPTransaction{ID: "xp-" + userID, CharacterID: character.ID, UserID: userID, Amount: 10, SourceType: models.XPSourceAgent, CreatedAt: now}
+	if err := db.Create(xp).Error; err != nil {
+		t.Fatalf("seeding XP transaction: %v", err)
+	}
+	reward := &models.CharacterReward{ID: "reward-" + userID, CharacterID: character.ID, UserID: userID, Level: 2, Kind: models.RewardTitle, Value: "Apprentice"}
+	if err := db.Create(reward).Error; err != nil {
+		t.Fatalf("seeding character reward: %v", err)
+	}
+	// Achievements are shared by all users.
+	if err := db.FirstOrCreate(&models.Achievement{ID: "first_entry", Name: "First Words", Criteria: models.CriteriaEntries, Threshold: 1}).Error; err != nil {
+		t.Fatalf("seeding achievement: %v", err)
+	}
+	achievement := &models.UserAchievement{UserID: userID, AchievementID: "first_entry", UnlockedAt: now}
+	if err := db.Create(achievement).Error; err != nil {
+		t.Fatalf("seeding user achievement: %v", err)
+	}
+	// The array column of UserProgress is PostgreSQL-specific, so only set the scalar ones.
+	progress := map[string]interface{}{"id": "progress-" + userID, "user_id": userID, "points": 10, "level": 2}
+	if err := db.Model(&models.UserProgress{}).Create(progress).Error; err != nil {
+		t.Fatalf("seeding user progress: %v", err)
+	}
+}
+
+// countRows counts rows of a table, including soft-deleted ones.
+func countRows(t *testing.T, db *gorm.DB, query string, args ...interface{}) int64 {
+	t.Helper()
+	var count int64
+	if err := db.Raw(query, args...).Scan(&count).Error; err != nil {
+		t.Fatalf("counting rows with %q: %v", query, err)
+	}
+	return count
+}
+
+// assertNoOrphans checks every model from database.MigrateAll for rows that point at
+// missing parents, through a user_id column, a belongs-to association or a join table.
+func assertNoOrphans(t *testing.T, db *gorm.DB) {
+	t.Helper()
+
+	for _, model := range database.Models() {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			t.Fatalf("parsing %T: %v", model, err)
+		}
+		s := stmt.Schema
+
+		if field := s.LookUpField("user_id"); field != nil && s.Table != "users" {
+			query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE user_id NOT IN (SELECT id FROM users)", s.Table)
+			if n := countRows(t, db, query); n != 0 {
+				t.Errorf("%s: %d row(s) reference a missing user", s.Table, n)
+			}
+		}
+
+		for _, rel := range s.Relationships.Relations {
+			switch {
+			case rel.Type == schema.BelongsTo:
+				for _, ref := range rel.References {
+					query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s IS NOT NULL AND %s NOT IN (SELECT %s FROM %s)",
+						s.Table, ref.ForeignKey.DBName, ref.ForeignKey.DBName, ref.PrimaryKey.DBName, rel.FieldSchema.Table)
+					if n := countRows(t, db, query); n != 0 {
+						t.Errorf("%s.%s: %d orphan row(s)", s.Table, ref.ForeignKey.DBName, n)
+					}
+				}
+			case rel.JoinTable != nil:
+				for _, ref := range rel.References {
+					query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s NOT IN (SELECT %s FROM %s)",
+						rel.JoinTable.Table, ref.ForeignKey.DBName, ref.PrimaryKey.DBName, ref.PrimaryKey.Schema.Table)
+					if n := countRows(t, db, query); n != 0 {
+						t.Errorf("%s.%s: %d orphan row(s)", rel.JoinTable.Table, ref.ForeignKey.DBName, n)
+					}
+				}
+			}
+		}
+	}
+}
+
+// userScopedTables returns the tables of every model with a user_id column.
+func userScopedTables(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+	var tables []string
+	for _, model := range database.Models() {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			t.Fatalf("parsing %T: %v", model, err)
+		}
+		if stmt.Schema.LookUpField("user_id") != nil {
+			tables = append(tables, stmt.Schema.Table)
+		}
+	}
+	return tables
+}
+
+func TestGormStore_Delete_RemovesAllUserData(t *testing.T) {
+	db := databasetest.Open(t)
+	store := &GormStore{db: db}
+
+	seedAccount(t, db, "user-gone")
+	seedAccount(t, db, "user-kept")
+
+	if err := store.Delete("user-gone"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	assertNoOrphans(t, db)
+
+	for _, table := range userScopedTables(t, db) {
+		query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE user_id = ?", table)
+		if n := countRows(t, db, query, "user-gone"); n != 0 {
+			t.Errorf("%s: %d row(s) of the deleted user remain", table, n)
+		}
+		if n := countRows(t, db, query, "user-kept"); n == 0 {
+			t.Errorf("%s: rows of the other user were deleted", table)
+		}
+	}
+	if n := countRows(t, db, "SELECT COUNT(*) FROM users WHERE id = ?", "user-gone"); n != 0 {
+		t.Error("deleted user row remains")
+	}
+	if n := countRows(t, db, "SELECT COUNT(*) FROM refresh_tokens WHERE session_id = ?", "session-user-gone"); n != 0 {
+		t.Error("refresh tokens of the deleted user remain")
+	}
+	if n := countRows(t, db, "SELECT COUNT(*) FROM journal_entry_tags"); n != 1 {
+		t.Errorf("journal_entry_tags has %d row(s), want only the other user's link", n)
+	}
+
+	if err := store.Delete("user-gone"); err != gorm.ErrRecordNotFound {
+		t.Errorf("second Delete() error = %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+}
+
+func TestService_DeleteAccount_GracePeriod(t *testing.T) {
+	db := databasetest.Open(t)
+	store := &GormStore{db: db}
+	seedAccount(t, db, "user-gone")
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte("ValidPass123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hashing password: %v", err)
+	}
+	if err := db.Model(&models.User{}).Where("id = ?", "user-gone").Update("hashed_password", string(hashed)).Error; err != nil {
+		t.Fatalf("setting password: %v", err)
+	}
+
+	revoker := &recordingRevoker{}
+	svc := NewService(store, store, nil, revoker).(*service)
+	svc.gracePeriod = 24 * time.Hour
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+
+	if _, err := svc.DeleteAccount("user-gone", "WrongPass123"); err != ErrIncorrectPassword {
+		t.Fatalf("DeleteAccount(wrong password) error = %v, want %v", err, ErrIncorrectPassword)
+	}
+
+	purgeAt, err := svc.DeleteAccount("user-gone", "ValidPass123")
+	if err != nil {
+		t.Fatalf("DeleteAccount() error = %v", err)
+	}
+	if purgeAt == nil || !purgeAt.Equal(now.Add(24*time.Hour)) {
+		t.Fatalf("purgeAt = %v, want %v", purgeAt, now.Add(24*time.Hour))
+	}
+	if len(revoker.calls) != 1 || revoker.calls[0] != [2]string{"user-gone", ""} {
+		t.Errorf("revoke calls = %v, want every session of user-gone revoked", revoker.calls)
+	}
+	if _, err := svc.GetUserByID("user-gone"); err != ErrUserNotFound {
+		t.Errorf("GetUserByID() after deletion error = %v, want %v", err, ErrUserNotFound)
+	}
+	if _, err := svc.RegisterUser("user-gone", "user-gone@example.com", "ValidPass123"); err != nil {
+		t.Errorf("RegisterUser() with the email and username of a deleted account error = %v", err)
+	}
+
+	if purged, err := svc.PurgeDeletedAccounts(); err != nil || purged != 0 {
+		t.Fatalf("PurgeDeletedAccounts() within grace period = %d, %v; want 0, nil", purged, err)
+	}
+	if n := countRows(t, db, "SELECT COUNT(*) FROM journal_entries WHERE user_id = ?", "user-gone"); n == 0 {
+		t.Fatal("data was removed before the grace period ended")
+	}
+
+	svc.now = func() time.Time { return now.Add(25 * time.Hour) }
+	if purged, err := svc.PurgeDeletedAccounts(); err != nil || purged != 1 {
+		t.Fatalf("PurgeDeletedAccounts() after grace period = %d, %v; want 1, nil", purged, err)
+	}
+	assertNoOrphans(t, db)
+	if n := countRows(t, db, "SELECT COUNT(*) FROM users WHERE id = ?", "user-gone"); n != 0 {
+		t.Errorf("users has %d row(s) of user-gone after purge, want 0", n)
+	}
+}
@@ -39,7 +39,9 @@ export async function GET(
       throw new Error(`Backend fetch failed with status: ${res.status}`);
     }
     const data = await res.json();
-    return NextResponse.json(data);
+    return NextResponse.json(data, {
+      headers: { ETag: res.headers.get("etag") ?? "" },
+    });
   } catch (error) {
     console.error(
       `Failed to fetch document ${documentId} from Go backend:`,
@@ -73,21 +75,38 @@ export async function PUT(
 
   try {
     const body = await request.json();
+    const headers: Record<string, string> = {
+      "Content-Type": "application/json",
+      Authorization: authorization,
+    };
+    // Forward the version the client edited so concurrent saves are detected.
+    const ifMatch = request.headers.get("if-match");
+    if (ifMatch) {
+      headers["If-Match"] = ifMatch;
+    }
     const res = await fetch(`${GO_API_URL}/api/v1/journal/${documentId}`, {
       method: "PUT",
-      headers: {
-        "Content-Type": "application/json",
-        Authorization: authorization,
-      },
+      headers,
       body: JSON.stringify(body),
     });
 
+    // The document changed since it was loaded; pass the server copy on.
+    if (res.status === 412) {
+      const current = await res.json();
+      return NextResponse.json(current, {
+        status: 412,
+        headers: { ETag: res.headers.get("etag") ?? "" },
+      });
+    }
+
     if (!res.ok) {
       throw new Error(`Backend update failed with status: ${res.status}`);
     }
 
     const data = await res.json();
-    return NextResponse.json(data);
+    return NextResponse.json(data, {
+      headers: { ETag: res.headers.get("etag") ?? "" },
+    });
   } catch (error) {
     console.error(
       `Failed to update document ${documentId} in Go backend:`,
@@ -98,23 +98,13 @@ export const useDocument = (documentId?: string) => {
       return;
     }
 
-    let newTextForAI = "";
-    // If the user is just appending text, send only the new part.
-    if (currentText.startsWith(lastProcessedText.current)) {
-      newTextForAI = currentText.substring(lastProcessedText.current.length);
-    } else {
-      // For more complex changes (editing in the middle), send the whole text.
-      // This is a safe fallback to ensure the AI doesn't miss context.
-      newTextForAI = currentText;
-    }
-
     setSaving(true);
 
     try {
       const payload = {
         title: docToSave.title,
-        content: currentContent, // Always save the full HTML content.
-        new_text: newTextForAI,    // Send the new/relevant text to the AI.
+        // Always save the full HTML content; the server works out which paragraphs are new.
+        content: currentContent,
       };
 
       await fetch(`/api/documents/${documentId}`, {